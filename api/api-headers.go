@@ -19,16 +19,21 @@ package api
 import (
 	"bytes"
 	"encoding/xml"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	. "github.com/journeymidnight/yig/api/datatype"
+	"github.com/journeymidnight/yig/helper"
 	meta "github.com/journeymidnight/yig/meta/types"
 )
 
 // Refer: https://docs.aws.amazon.com/AmazonS3/latest/API/RESTCommonResponseHeaders.html
 var CommonS3ResponseHeaders = []string{"Content-Length", "Content-Type", "Connection", "Date", "ETag", "Server",
-	"x-amz-delete-marker", "x-amz-id-2", "x-amz-request-id", "x-amz-version-id"}
+	"x-amz-delete-marker", "x-amz-expiration", "x-amz-id-2", "x-amz-object-lock-legal-hold",
+	"x-amz-object-lock-mode", "x-amz-object-lock-retain-until-date", "x-amz-replication-status",
+	"x-amz-restore", "x-amz-request-id", "x-amz-tagging-count", "x-amz-version-id"}
 
 // Encodes the response headers into XML format.
 func EncodeResponse(response interface{}) []byte {
@@ -39,6 +44,33 @@ func EncodeResponse(response interface{}) []byte {
 	return bytesBuffer.Bytes()
 }
 
+// Set x-amz-expiration if lifecycle of bucket expires current version of the object,
+// refer: https://docs.aws.amazon.com/AmazonS3/latest/API/API_HeadObject.html#API_HeadObject_ResponseSyntax
+func SetExpirationHeader(w http.ResponseWriter, bucket *meta.Bucket, objectName string,
+	size int64, tags map[string]string, lastModified time.Time) {
+
+	if bucket == nil || len(bucket.Lifecycle.Rule) == 0 {
+		return
+	}
+	expiration, ruleId, ok := bucket.Lifecycle.ExpirationTime(objectName, size, tags,
+		lastModified, helper.CONFIG.DebugMode)
+	if !ok {
+		return
+	}
+	w.Header().Set("x-amz-expiration", fmt.Sprintf("expiry-date=\"%s\", rule-id=\"%s\"",
+		expiration.UTC().Format(http.TimeFormat), ruleId))
+}
+
+// Set x-amz-restore for glacier object with a restore request
+func SetRestoreHeader(w http.ResponseWriter, freezer *meta.Freezer) {
+	if freezer.Status != meta.ObjectHasRestored {
+		w.Header().Set("x-amz-restore", "ongoing-request=\"true\"")
+		return
+	}
+	w.Header().Set("x-amz-restore", fmt.Sprintf("ongoing-request=\"false\", expiry-date=\"%s\"",
+		freezer.ExpireTime.UTC().Format(http.TimeFormat)))
+}
+
 // Write object header
 func SetObjectHeaders(w http.ResponseWriter, object *meta.Object, contentRange *HttpRange, statusCode int) {
 	// set object-related metadata headers
@@ -61,6 +93,20 @@ func SetObjectHeaders(w http.ResponseWriter, object *meta.Object, contentRange *
 
 	w.Header().Set("X-Amz-Object-Type", object.ObjectTypeToString())
 	w.Header().Set("X-Amz-Storage-Class", object.StorageClass.ToString())
+	if len(object.Tags) != 0 {
+		w.Header().Set("X-Amz-Tagging-Count", strconv.Itoa(len(object.Tags)))
+	}
+	if object.ReplicationStatus != meta.ReplicationNone {
+		w.Header().Set("X-Amz-Replication-Status", object.ReplicationStatus.ToString())
+	}
+	if object.ObjectLock.Mode != "" {
+		w.Header().Set(ObjectLockModeHeader, object.ObjectLock.Mode)
+		w.Header().Set(ObjectLockRetainUntilDateHeader,
+			object.ObjectLock.RetainUntilDate.UTC().Format(ObjectLockDateFormat))
+	}
+	if object.ObjectLock.LegalHold {
+		w.Header().Set(ObjectLockLegalHoldHeader, LegalHoldOn)
+	}
 	w.Header().Set("Content-Length", strconv.FormatInt(object.Size, 10))
 	if object.Type == meta.ObjectTypeAppendable {
 		w.Header().Set("X-Amz-Next-Append-Position", strconv.FormatInt(object.Size, 10))
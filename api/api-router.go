@@ -78,6 +78,27 @@ func RegisterAPIRouter(mux *router.Router, api ObjectAPIHandlers) {
 		// GetObjectAcl
 		bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectAclHandler).
 			Queries("acl", "")
+		// PutObjectTagging
+		bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectTaggingHandler).
+			Queries("tagging", "")
+		// GetObjectTagging
+		bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectTaggingHandler).
+			Queries("tagging", "")
+		// DeleteObjectTagging
+		bucket.Methods("DELETE").Path("/{object:.+}").HandlerFunc(api.DeleteObjectTaggingHandler).
+			Queries("tagging", "")
+		// PutObjectRetention
+		bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectRetentionHandler).
+			Queries("retention", "")
+		// GetObjectRetention
+		bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectRetentionHandler).
+			Queries("retention", "")
+		// PutObjectLegalHold
+		bucket.Methods("PUT").Path("/{object:.+}").HandlerFunc(api.PutObjectLegalHoldHandler).
+			Queries("legal-hold", "")
+		// GetObjectLegalHold
+		bucket.Methods("GET").Path("/{object:.+}").HandlerFunc(api.GetObjectLegalHoldHandler).
+			Queries("legal-hold", "")
 
 		// AppendObject
 		bucket.Methods("POST").Path("/{object:.+}").HandlerFunc(api.AppendObjectHandler).Queries("append", "")
@@ -137,6 +158,26 @@ func RegisterAPIRouter(mux *router.Router, api ObjectAPIHandlers) {
 		bucket.Methods("GET").HandlerFunc(api.GetBucketWebsiteHandler).Queries("website", "")
 		// DeleteBucketWebsite
 		bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketWebsiteHandler).Queries("website", "")
+		// PutBucketTagging
+		bucket.Methods("PUT").HandlerFunc(api.PutBucketTaggingHandler).Queries("tagging", "")
+		// GetBucketTagging
+		bucket.Methods("GET").HandlerFunc(api.GetBucketTaggingHandler).Queries("tagging", "")
+		// DeleteBucketTagging
+		bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketTaggingHandler).Queries("tagging", "")
+		// PutBucketNotification
+		bucket.Methods("PUT").HandlerFunc(api.PutBucketNotificationHandler).Queries("notification", "")
+		// GetBucketNotification
+		bucket.Methods("GET").HandlerFunc(api.GetBucketNotificationHandler).Queries("notification", "")
+		// PutBucketReplication
+		bucket.Methods("PUT").HandlerFunc(api.PutBucketReplicationHandler).Queries("replication", "")
+		// GetBucketReplication
+		bucket.Methods("GET").HandlerFunc(api.GetBucketReplicationHandler).Queries("replication", "")
+		// DeleteBucketReplication
+		bucket.Methods("DELETE").HandlerFunc(api.DeleteBucketReplicationHandler).Queries("replication", "")
+		// PutObjectLockConfiguration
+		bucket.Methods("PUT").HandlerFunc(api.PutObjectLockConfigurationHandler).Queries("object-lock", "")
+		// GetObjectLockConfiguration
+		bucket.Methods("GET").HandlerFunc(api.GetObjectLockConfigurationHandler).Queries("object-lock", "")
 		//
 		bucket.Methods("PUT").HandlerFunc(api.PutBucketEncryption).Queries("encryption", "")
 		//
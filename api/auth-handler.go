@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strings"
 
+	. "github.com/journeymidnight/yig/api/datatype"
 	"github.com/journeymidnight/yig/api/datatype/policy"
 	. "github.com/journeymidnight/yig/error"
 	"github.com/journeymidnight/yig/helper"
@@ -75,6 +76,22 @@ func IsBucketPolicyAllowed(userId string, bucket *meta.Bucket, r *http.Request,
 
 }
 
+// Returns true if the request sets x-amz-bypass-governance-retention, and the
+// requester is the bucket owner or allowed s3:BypassGovernanceRetention
+func isBypassGovernanceAllowed(r *http.Request, credential common.Credential,
+	bucket *meta.Bucket, objectName string) bool {
+
+	if !IsBypassGovernanceRetention(r.Header) || bucket == nil {
+		return false
+	}
+	if bucket.OwnerId == credential.UserId {
+		return true
+	}
+	isAllow, err := IsBucketPolicyAllowed(credential.UserId, bucket, r,
+		policy.BypassGovernanceRetentionAction, objectName)
+	return err == nil && isAllow
+}
+
 func getConditionValues(request *http.Request, locationConstraint string) map[string][]string {
 	args := make(map[string][]string)
 
@@ -96,6 +113,26 @@ func getConditionValues(request *http.Request, locationConstraint string) map[st
 
 	args["SourceIp"] = []string{GetSourceIP(request)}
 
+	// condition keys s3:ExistingObjectTag/<tag-key> and s3:RequestObjectTag/<tag-key>,
+	// existing tags are of the version requested if versionId is set
+	ctx := getRequestContext(request)
+	object := ctx.ObjectInfo
+	if request.URL.Query().Get("versionId") != "" {
+		object = ctx.ObjectVersionInfo
+	}
+	if object != nil {
+		for key, value := range object.Tags {
+			args["ExistingObjectTag/"+key] = []string{value}
+		}
+	}
+	requestTags, ok := request.Context().Value(RequestTagsKey).(map[string]string)
+	if !ok {
+		requestTags, _ = ParseTaggingHeader(request.Header.Get("X-Amz-Tagging"))
+	}
+	for key, value := range requestTags {
+		args["RequestObjectTag/"+key] = []string{value}
+	}
+
 	if locationConstraint != "" {
 		args["LocationConstraint"] = []string{locationConstraint}
 	}
@@ -314,9 +314,11 @@ func (api ObjectAPIHandlers) DeleteMultipleObjectsHandler(w http.ResponseWriter,
 	var deleteErrors []DeleteError
 	var deletedObjects []ObjectIdentifier
 	// Loop through all the objects and delete them sequentially.
+	bucketInfo := getRequestContext(r).BucketInfo
 	for _, object := range deleteObjects.Objects {
+		bypassGovernance := isBypassGovernanceAllowed(r, credential, bucketInfo, object.ObjectName)
 		result, err := api.ObjectAPI.DeleteObject(bucket, object.ObjectName,
-			object.VersionId, credential)
+			object.VersionId, bypassGovernance, false, credential)
 		if err == nil {
 			deletedObjects = append(deletedObjects, ObjectIdentifier{
 				ObjectName:   object.ObjectName,
@@ -384,10 +386,20 @@ func (api ObjectAPIHandlers) PutBucketHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	var objectLockEnabled bool
+	switch strings.ToLower(r.Header.Get(BucketObjectLockEnabledHeader)) {
+	case "", "false":
+	case "true":
+		objectLockEnabled = true
+	default:
+		WriteErrorResponse(w, r, ErrInvalidObjectLockHeaders)
+		return
+	}
+
 	// TODO:the location value in the request body should match the Region in serverConfig.
 
 	// Make bucket.
-	err = api.ObjectAPI.MakeBucket(bucketName, acl, credential)
+	err = api.ObjectAPI.MakeBucket(bucketName, acl, objectLockEnabled, credential)
 	if err != nil {
 		logger.Error("Unable to create bucket", bucketName, "error:", err)
 		WriteErrorResponse(w, r, err)
@@ -514,22 +526,15 @@ func (api ObjectAPIHandlers) PutBucketLifeCycleHandler(w http.ResponseWriter, r
 		return
 	}
 
-	var lc Lifecycle
-	lcBuffer, err := ioutil.ReadAll(io.LimitReader(r.Body, 4096))
+	lc, err := ParseLifecycleConfig(r.Body)
 	if err != nil {
-		logger.Error("Unable to read lifecycle body:", err)
-		WriteErrorResponse(w, r, ErrInvalidLc)
-		return
-	}
-	err = xml.Unmarshal(lcBuffer, &lc)
-	if err != nil {
-		logger.Error("Unable to parse lifecycle XML body:", err)
-		WriteErrorResponse(w, r, ErrInternalError)
+		logger.Error("Invalid lifecycle:", err)
+		WriteErrorResponse(w, r, err)
 		return
 	}
 
 	logger.Info("Setting lifecycle:", lc)
-	err = api.ObjectAPI.SetBucketLifecycle(bucket, lc, credential)
+	err = api.ObjectAPI.SetBucketLifecycle(bucket, *lc, credential)
 	if err != nil {
 		logger.Error(err, "Unable to set lifecycle for bucket:", err)
 		WriteErrorResponse(w, r, err)
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	. "github.com/journeymidnight/yig/api/datatype"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/iam/common"
+	"github.com/journeymidnight/yig/signature"
+)
+
+// PutBucketNotificationHandler - PUT Bucket notification
+// ----------
+// Replaces notification configuration of the bucket, an empty configuration
+// disables notifications. Only the bucket owner is allowed.
+func (api ObjectAPIHandlers) PutBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	switch ctx.AuthType {
+	default:
+		// For all unknown auth types return error.
+		WriteErrorResponse(w, r, ErrAccessDenied)
+		return
+	case signature.AuthTypeAnonymous:
+		break
+	case signature.AuthTypePresignedV4, signature.AuthTypeSignedV4,
+		signature.AuthTypePresignedV2, signature.AuthTypeSignedV2:
+		if credential, err = signature.IsReqAuthenticated(r); err != nil {
+			WriteErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if ctx.BucketInfo == nil {
+		WriteErrorResponse(w, r, ErrNoSuchBucket)
+		return
+	}
+	if credential.UserId != ctx.BucketInfo.OwnerId {
+		WriteErrorResponse(w, r, ErrBucketAccessForbidden)
+		return
+	}
+	// Error out if Content-Length is missing.
+	if r.ContentLength <= 0 {
+		WriteErrorResponse(w, r, ErrMissingContentLength)
+		return
+	}
+
+	notificationConfig, err := ParseNotificationConfig(io.LimitReader(r.Body, r.ContentLength))
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.ObjectAPI.SetBucketNotification(ctx.BucketInfo, *notificationConfig)
+	if err != nil {
+		logger.Error("Unable to set notification for bucket:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "PutBucketNotification"
+
+	WriteSuccessResponse(w, nil)
+}
+
+// GetBucketNotificationHandler - GET Bucket notification
+// ----------
+// Returns notification configuration of the bucket, empty if not configured.
+func (api ObjectAPIHandlers) GetBucketNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	switch ctx.AuthType {
+	default:
+		// For all unknown auth types return error.
+		WriteErrorResponse(w, r, ErrAccessDenied)
+		return
+	case signature.AuthTypeAnonymous:
+		break
+	case signature.AuthTypePresignedV4, signature.AuthTypeSignedV4,
+		signature.AuthTypePresignedV2, signature.AuthTypeSignedV2:
+		if credential, err = signature.IsReqAuthenticated(r); err != nil {
+			WriteErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if ctx.BucketInfo == nil {
+		WriteErrorResponse(w, r, ErrNoSuchBucket)
+		return
+	}
+	if credential.UserId != ctx.BucketInfo.OwnerId {
+		WriteErrorResponse(w, r, ErrBucketAccessForbidden)
+		return
+	}
+
+	notificationConfig, err := api.ObjectAPI.GetBucketNotification(ctx.BucketName)
+	if err != nil {
+		logger.Error("Unable to get notification for bucket", ctx.BucketName, "error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	notificationConfig.Xmlns = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+	encodedSuccessResponse, err := xmlFormat(notificationConfig)
+	if err != nil {
+		logger.Error("Failed to marshal Notification XML for bucket", ctx.BucketName,
+			"error:", err)
+		WriteErrorResponse(w, r, ErrInternalError)
+		return
+	}
+
+	setXmlHeader(w)
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "GetBucketNotification"
+
+	WriteSuccessResponse(w, encodedSuccessResponse)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	. "github.com/journeymidnight/yig/api/datatype"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/iam/common"
+	"github.com/journeymidnight/yig/signature"
+)
+
+// PutObjectLockConfigurationHandler - PUT Bucket object lock configuration
+// ----------
+// Enables object lock of the bucket and replaces its default retention,
+// versioning of the bucket must be enabled. Object lock could not be
+// disabled once enabled. Only the bucket owner is allowed.
+func (api ObjectAPIHandlers) PutObjectLockConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	switch ctx.AuthType {
+	default:
+		// For all unknown auth types return error.
+		WriteErrorResponse(w, r, ErrAccessDenied)
+		return
+	case signature.AuthTypeAnonymous:
+		break
+	case signature.AuthTypePresignedV4, signature.AuthTypeSignedV4,
+		signature.AuthTypePresignedV2, signature.AuthTypeSignedV2:
+		if credential, err = signature.IsReqAuthenticated(r); err != nil {
+			WriteErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if ctx.BucketInfo == nil {
+		WriteErrorResponse(w, r, ErrNoSuchBucket)
+		return
+	}
+	if credential.UserId != ctx.BucketInfo.OwnerId {
+		WriteErrorResponse(w, r, ErrBucketAccessForbidden)
+		return
+	}
+	// Error out if Content-Length is missing.
+	if r.ContentLength <= 0 {
+		WriteErrorResponse(w, r, ErrMissingContentLength)
+		return
+	}
+
+	objectLockConfig, err := ParseObjectLockConfig(io.LimitReader(r.Body, r.ContentLength))
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	err = api.ObjectAPI.SetBucketObjectLock(ctx.BucketInfo, *objectLockConfig)
+	if err != nil {
+		logger.Error("Unable to set object lock for bucket:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "PutObjectLockConfiguration"
+
+	WriteSuccessResponse(w, nil)
+}
+
+// GetObjectLockConfigurationHandler - GET Bucket object lock configuration
+// ----------
+// Returns object lock configuration of the bucket.
+func (api ObjectAPIHandlers) GetObjectLockConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	var credential common.Credential
+	var err error
+	switch ctx.AuthType {
+	default:
+		// For all unknown auth types return error.
+		WriteErrorResponse(w, r, ErrAccessDenied)
+		return
+	case signature.AuthTypeAnonymous:
+		break
+	case signature.AuthTypePresignedV4, signature.AuthTypeSignedV4,
+		signature.AuthTypePresignedV2, signature.AuthTypeSignedV2:
+		if credential, err = signature.IsReqAuthenticated(r); err != nil {
+			WriteErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if ctx.BucketInfo == nil {
+		WriteErrorResponse(w, r, ErrNoSuchBucket)
+		return
+	}
+	if credential.UserId != ctx.BucketInfo.OwnerId {
+		WriteErrorResponse(w, r, ErrBucketAccessForbidden)
+		return
+	}
+
+	objectLockConfig, err := api.ObjectAPI.GetBucketObjectLock(ctx.BucketName)
+	if err != nil {
+		logger.Error("Unable to get object lock for bucket", ctx.BucketName, "error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	objectLockConfig.Xmlns = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+	encodedSuccessResponse, err := xmlFormat(objectLockConfig)
+	if err != nil {
+		logger.Error("Failed to marshal ObjectLockConfiguration XML for bucket", ctx.BucketName,
+			"error:", err)
+		WriteErrorResponse(w, r, ErrInternalError)
+		return
+	}
+
+	setXmlHeader(w)
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "GetObjectLockConfiguration"
+
+	WriteSuccessResponse(w, encodedSuccessResponse)
+}
@@ -2,18 +2,665 @@ package datatype
 
 import (
 	"encoding/xml"
-	//	. "github.com/journeymidnight/yig/error"
-	//	"github.com/journeymidnight/yig/helper"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	LifecycleDateFormat              = "2006-01-02T15:04:05.000Z"
+	MaxLifecycleRulesCount           = 1000
+	MaxLifecycleRuleIdLength         = 255
+	MaxLifecycleConfigurationSize    = 256 * humanize.KiByte
+	MaxNewerNoncurrentVersions       = 100
+	MinInfrequentAccessTransitionDay = 30
+
+	LifecycleRuleEnabled  = "Enabled"
+	LifecycleRuleDisabled = "Disabled"
 )
 
+// Storage classes objects could be transitioned to, in order from hot to cold.
+// Objects are never transitioned to a hotter class.
+var LifecycleTransitionStorageClasses = []string{
+	"STANDARD_IA",
+	"INTELLIGENT_TIERING",
+	"ONEZONE_IA",
+	"GLACIER",
+	"DEEP_ARCHIVE",
+}
+
+type LifecycleTag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type LifecycleAnd struct {
+	Prefix                string         `xml:"Prefix,omitempty"`
+	Tags                  []LifecycleTag `xml:"Tag,omitempty"`
+	ObjectSizeGreaterThan *int64         `xml:"ObjectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    *int64         `xml:"ObjectSizeLessThan,omitempty"`
+}
+
+// A filter has at most one of its elements, conditions are combined with And.
+// An empty filter matches all objects.
+type LifecycleFilter struct {
+	Prefix                *string       `xml:"Prefix"`
+	Tag                   *LifecycleTag `xml:"Tag,omitempty"`
+	And                   *LifecycleAnd `xml:"And,omitempty"`
+	ObjectSizeGreaterThan *int64        `xml:"ObjectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    *int64        `xml:"ObjectSizeLessThan,omitempty"`
+}
+
+type LifecycleTransition struct {
+	Days         int    `xml:"Days,omitempty"`
+	Date         string `xml:"Date,omitempty"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// Noncurrent days are counted from the time a version becomes noncurrent, i.e.
+// when its successor is created. The newest NewerNoncurrentVersions noncurrent
+// versions are always retained.
+type NoncurrentVersionExpiration struct {
+	NoncurrentDays          int `xml:"NoncurrentDays"`
+	NewerNoncurrentVersions int `xml:"NewerNoncurrentVersions,omitempty"`
+}
+
+type NoncurrentVersionTransition struct {
+	NoncurrentDays          int    `xml:"NoncurrentDays"`
+	NewerNoncurrentVersions int    `xml:"NewerNoncurrentVersions,omitempty"`
+	StorageClass            string `xml:"StorageClass"`
+}
+
+type AbortIncompleteMultipartUpload struct {
+	DaysAfterInitiation int `xml:"DaysAfterInitiation"`
+}
+
 type LifecycleRule struct {
-	ID         string `xml:"ID"`
-	Prefix     string `xml:"Prefix"`
+	ID     string           `xml:"ID,omitempty"`
+	Filter *LifecycleFilter `xml:"Filter,omitempty"`
+	// deprecated by Filter, but still used by old clients
+	Prefix     string `xml:"Prefix,omitempty"`
 	Status     string `xml:"Status"`
-	Expiration string `xml:"Expiration>Days"`
+	Expiration string `xml:"Expiration>Days,omitempty"`
+	// exactly one of Expiration, ExpirationDate and ExpiredObjectDeleteMarker
+	ExpirationDate string `xml:"Expiration>Date,omitempty"`
+	// remove delete markers with no noncurrent versions left
+	ExpiredObjectDeleteMarker      bool                            `xml:"Expiration>ExpiredObjectDeleteMarker,omitempty"`
+	Transition                     []LifecycleTransition           `xml:"Transition,omitempty"`
+	NoncurrentVersionExpiration    *NoncurrentVersionExpiration    `xml:"NoncurrentVersionExpiration,omitempty"`
+	NoncurrentVersionTransition    []NoncurrentVersionTransition   `xml:"NoncurrentVersionTransition,omitempty"`
+	AbortIncompleteMultipartUpload *AbortIncompleteMultipartUpload `xml:"AbortIncompleteMultipartUpload,omitempty"`
 }
 
 type Lifecycle struct {
 	XMLName xml.Name        `xml:"LifecycleConfiguration"`
 	Rule    []LifecycleRule `xml:"Rule"`
 }
+
+// Returns the rank of storageClass in LifecycleTransitionStorageClasses,
+// -1 if objects could not be transitioned to it
+func TransitionStorageClassRank(storageClass string) int {
+	for i, class := range LifecycleTransitionStorageClasses {
+		if class == storageClass {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the time days after since. Like S3, the result is rounded up to the
+// next midnight in UTC. In debug mode days are counted as seconds.
+func DaysAfter(since time.Time, days int, debugMode bool) time.Time {
+	if debugMode {
+		return since.Add(time.Duration(days) * time.Second)
+	}
+	t := since.UTC().Add(time.Duration(days) * 24 * time.Hour)
+	midnight := t.Truncate(24 * time.Hour)
+	if midnight.Equal(t) {
+		return t
+	}
+	return midnight.Add(24 * time.Hour)
+}
+
+// Returns true if days have passed since, see DaysAfter
+func DaysPassed(since, now time.Time, days int, debugMode bool) bool {
+	return !now.Before(DaysAfter(since, days, debugMode))
+}
+
+// Date should be midnight in UTC
+func parseLifecycleDate(date string) (time.Time, error) {
+	t, err := time.Parse(LifecycleDateFormat, date)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, date)
+		if err != nil {
+			return t, ErrInvalidLc
+		}
+	}
+	if !t.Equal(t.Truncate(24 * time.Hour)) {
+		return t, ErrInvalidLc
+	}
+	return t, nil
+}
+
+// Days to IA classes should be at least MinInfrequentAccessTransitionDay
+func validTransitionDays(days int, storageClass string) bool {
+	if days <= 0 {
+		return false
+	}
+	if storageClass == "STANDARD_IA" || storageClass == "ONEZONE_IA" {
+		return days >= MinInfrequentAccessTransitionDay
+	}
+	return true
+}
+
+// Exactly one of Days and Date should be specified
+func (t LifecycleTransition) Validate() error {
+	if TransitionStorageClassRank(t.StorageClass) < 0 {
+		return ErrInvalidStorageClass
+	}
+	if (t.Days != 0) == (t.Date != "") {
+		return ErrInvalidLc
+	}
+	if t.Date != "" {
+		_, err := parseLifecycleDate(t.Date)
+		return err
+	}
+	if !validTransitionDays(t.Days, t.StorageClass) {
+		return ErrInvalidLc
+	}
+	return nil
+}
+
+// Returns true if objects last modified at modifiedTime should be transitioned
+// at now
+func (t LifecycleTransition) Due(modifiedTime, now time.Time, debugMode bool) bool {
+	if t.Date != "" {
+		date, err := parseLifecycleDate(t.Date)
+		if err != nil {
+			return false
+		}
+		return !now.Before(date)
+	}
+	return DaysPassed(modifiedTime, now, t.Days, debugMode)
+}
+
+func (n *NoncurrentVersionExpiration) Validate() error {
+	if n.NoncurrentDays <= 0 {
+		return ErrInvalidLc
+	}
+	if n.NewerNoncurrentVersions < 0 || n.NewerNoncurrentVersions > MaxNewerNoncurrentVersions {
+		return ErrInvalidLc
+	}
+	return nil
+}
+
+func (t NoncurrentVersionTransition) Validate() error {
+	if TransitionStorageClassRank(t.StorageClass) < 0 {
+		return ErrInvalidStorageClass
+	}
+	if !validTransitionDays(t.NoncurrentDays, t.StorageClass) {
+		return ErrInvalidLc
+	}
+	if t.NewerNoncurrentVersions < 0 || t.NewerNoncurrentVersions > MaxNewerNoncurrentVersions {
+		return ErrInvalidLc
+	}
+	return nil
+}
+
+func validObjectSizeRange(greaterThan, lessThan *int64) bool {
+	if greaterThan != nil && *greaterThan < 0 {
+		return false
+	}
+	if lessThan != nil && *lessThan <= 0 {
+		return false
+	}
+	if greaterThan != nil && lessThan != nil && *greaterThan >= *lessThan {
+		return false
+	}
+	return true
+}
+
+// And combines at least two predicates
+func (a *LifecycleAnd) Validate() error {
+	predicates := len(a.Tags)
+	if a.Prefix != "" {
+		predicates++
+	}
+	if a.ObjectSizeGreaterThan != nil {
+		predicates++
+	}
+	if a.ObjectSizeLessThan != nil {
+		predicates++
+	}
+	if predicates < 2 {
+		return ErrInvalidLc
+	}
+	keys := make(map[string]bool)
+	for _, tag := range a.Tags {
+		if tag.Key == "" || keys[tag.Key] {
+			return ErrInvalidLc
+		}
+		keys[tag.Key] = true
+	}
+	if !validObjectSizeRange(a.ObjectSizeGreaterThan, a.ObjectSizeLessThan) {
+		return ErrInvalidLc
+	}
+	return nil
+}
+
+func (f *LifecycleFilter) Validate() error {
+	elements := 0
+	if f.Prefix != nil {
+		elements++
+	}
+	if f.Tag != nil {
+		elements++
+		if f.Tag.Key == "" {
+			return ErrInvalidLc
+		}
+	}
+	if f.And != nil {
+		elements++
+		if err := f.And.Validate(); err != nil {
+			return err
+		}
+	}
+	if f.ObjectSizeGreaterThan != nil {
+		elements++
+	}
+	if f.ObjectSizeLessThan != nil {
+		elements++
+	}
+	if elements > 1 {
+		return ErrInvalidLc
+	}
+	if !validObjectSizeRange(f.ObjectSizeGreaterThan, f.ObjectSizeLessThan) {
+		return ErrInvalidLc
+	}
+	return nil
+}
+
+// Returns the key prefix objects should have to match the rule
+func (rule *LifecycleRule) KeyPrefix() string {
+	if rule.Filter == nil {
+		return rule.Prefix
+	}
+	if rule.Filter.Prefix != nil {
+		return *rule.Filter.Prefix
+	}
+	if rule.Filter.And != nil {
+		return rule.Filter.And.Prefix
+	}
+	return ""
+}
+
+// Returns true if the rule filters objects by tags
+func (rule *LifecycleRule) HasTagFilter() bool {
+	if rule.Filter == nil {
+		return false
+	}
+	return rule.Filter.Tag != nil || (rule.Filter.And != nil && len(rule.Filter.And.Tags) != 0)
+}
+
+// Returns true if the rule filters objects by size
+func (rule *LifecycleRule) HasSizeFilter() bool {
+	if rule.Filter == nil {
+		return false
+	}
+	f := rule.Filter
+	return f.ObjectSizeGreaterThan != nil || f.ObjectSizeLessThan != nil ||
+		(f.And != nil && (f.And.ObjectSizeGreaterThan != nil || f.And.ObjectSizeLessThan != nil))
+}
+
+func matchSize(size int64, greaterThan, lessThan *int64) bool {
+	if greaterThan != nil && size <= *greaterThan {
+		return false
+	}
+	if lessThan != nil && size >= *lessThan {
+		return false
+	}
+	return true
+}
+
+func matchTag(tags map[string]string, tag LifecycleTag) bool {
+	value, ok := tags[tag.Key]
+	return ok && value == tag.Value
+}
+
+// Returns true if the rule is enabled and applies to object with name, size
+// and tags
+func (rule *LifecycleRule) Match(name string, size int64, tags map[string]string) bool {
+	if rule.Status != LifecycleRuleEnabled {
+		return false
+	}
+	if !strings.HasPrefix(name, rule.KeyPrefix()) {
+		return false
+	}
+	f := rule.Filter
+	if f == nil {
+		return true
+	}
+	if f.Tag != nil && !matchTag(tags, *f.Tag) {
+		return false
+	}
+	if !matchSize(size, f.ObjectSizeGreaterThan, f.ObjectSizeLessThan) {
+		return false
+	}
+	if f.And != nil {
+		for _, tag := range f.And.Tags {
+			if !matchTag(tags, tag) {
+				return false
+			}
+		}
+		if !matchSize(size, f.And.ObjectSizeGreaterThan, f.And.ObjectSizeLessThan) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the time objects last modified at modifiedTime expire, and false if
+// the rule does not expire current versions
+func (rule *LifecycleRule) ExpirationTime(modifiedTime time.Time, debugMode bool) (time.Time, bool) {
+	if rule.ExpirationDate != "" {
+		date, err := parseLifecycleDate(rule.ExpirationDate)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return date, true
+	}
+	if rule.Expiration != "" {
+		days, err := strconv.Atoi(rule.Expiration)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return DaysAfter(modifiedTime, days, debugMode), true
+	}
+	return time.Time{}, false
+}
+
+func (rule *LifecycleRule) Validate() error {
+	if len(rule.ID) > MaxLifecycleRuleIdLength {
+		return ErrInvalidLc
+	}
+	if rule.Status != LifecycleRuleEnabled && rule.Status != LifecycleRuleDisabled {
+		return ErrInvalidLc
+	}
+	if rule.Filter != nil {
+		if rule.Prefix != "" {
+			return ErrInvalidLc
+		}
+		if err := rule.Filter.Validate(); err != nil {
+			return err
+		}
+	}
+	if rule.Expiration == "" && rule.ExpirationDate == "" && !rule.ExpiredObjectDeleteMarker &&
+		len(rule.Transition) == 0 && rule.NoncurrentVersionExpiration == nil &&
+		len(rule.NoncurrentVersionTransition) == 0 && rule.AbortIncompleteMultipartUpload == nil {
+		return ErrInvalidLc
+	}
+
+	expirations := 0
+	if rule.Expiration != "" {
+		expirations++
+		days, err := strconv.Atoi(rule.Expiration)
+		if err != nil || days <= 0 {
+			return ErrInvalidLc
+		}
+	}
+	if rule.ExpirationDate != "" {
+		expirations++
+		if _, err := parseLifecycleDate(rule.ExpirationDate); err != nil {
+			return err
+		}
+	}
+	if rule.ExpiredObjectDeleteMarker {
+		expirations++
+		if rule.HasTagFilter() || rule.HasSizeFilter() {
+			return ErrInvalidLc
+		}
+	}
+	if expirations > 1 {
+		return ErrInvalidLc
+	}
+
+	// transitions should all use Days or Date, and happen before expiration
+	seen := make(map[string]bool)
+	for i, t := range rule.Transition {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+		if seen[t.StorageClass] {
+			return ErrInvalidLc
+		}
+		seen[t.StorageClass] = true
+		if (t.Date != "") != (rule.Transition[0].Date != "") {
+			return ErrInvalidLc
+		}
+		if rule.Expiration != "" && t.Days != 0 {
+			days, _ := strconv.Atoi(rule.Expiration)
+			if days <= t.Days {
+				return ErrInvalidLc
+			}
+		}
+		if rule.ExpirationDate != "" && t.Date != "" {
+			expirationDate, _ := parseLifecycleDate(rule.ExpirationDate)
+			date, _ := parseLifecycleDate(rule.Transition[i].Date)
+			if !date.Before(expirationDate) {
+				return ErrInvalidLc
+			}
+		}
+	}
+
+	if rule.NoncurrentVersionExpiration != nil {
+		if err := rule.NoncurrentVersionExpiration.Validate(); err != nil {
+			return err
+		}
+	}
+	seen = make(map[string]bool)
+	for _, t := range rule.NoncurrentVersionTransition {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+		if seen[t.StorageClass] {
+			return ErrInvalidLc
+		}
+		seen[t.StorageClass] = true
+		if rule.NoncurrentVersionExpiration != nil &&
+			rule.NoncurrentVersionExpiration.NoncurrentDays <= t.NoncurrentDays {
+			return ErrInvalidLc
+		}
+	}
+
+	// uploads have no tags or size yet
+	if rule.AbortIncompleteMultipartUpload != nil {
+		if rule.AbortIncompleteMultipartUpload.DaysAfterInitiation <= 0 {
+			return ErrInvalidLc
+		}
+		if rule.HasTagFilter() || rule.HasSizeFilter() {
+			return ErrInvalidLc
+		}
+	}
+	return nil
+}
+
+// Reference: https://docs.aws.amazon.com/AmazonS3/latest/API/API_PutBucketLifecycleConfiguration.html
+func (lc *Lifecycle) Validate() error {
+	if len(lc.Rule) == 0 || len(lc.Rule) > MaxLifecycleRulesCount {
+		return ErrInvalidLc
+	}
+	ids := make(map[string]bool)
+	for i := range lc.Rule {
+		rule := &lc.Rule[i]
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if rule.ID != "" {
+			if ids[rule.ID] {
+				return ErrInvalidLc
+			}
+			ids[rule.ID] = true
+		}
+	}
+	return nil
+}
+
+// Actions are evaluated as S3 does: every enabled rule matching an object
+// applies, expiration takes precedence over transition, and among transitions
+// the coldest storage class wins. Used by both the API and the lc tool.
+
+// Returns the earliest time the current version of an object expires, and ID
+// of the rule expiring it. ok is false if no rule expires it.
+func (lc *Lifecycle) ExpirationTime(name string, size int64, tags map[string]string,
+	modifiedTime time.Time, debugMode bool) (expiration time.Time, ruleId string, ok bool) {
+
+	for i := range lc.Rule {
+		rule := &lc.Rule[i]
+		if !rule.Match(name, size, tags) {
+			continue
+		}
+		t, expires := rule.ExpirationTime(modifiedTime, debugMode)
+		if !expires {
+			continue
+		}
+		if !ok || t.Before(expiration) {
+			expiration, ruleId, ok = t, rule.ID, true
+		}
+	}
+	return
+}
+
+// Returns the action due for the current version of an object: expire it, or
+// transition it to storageClass. storageClass is empty if no transition is due.
+func (lc *Lifecycle) CurrentVersionAction(name string, size int64, tags map[string]string,
+	modifiedTime, now time.Time, debugMode bool) (expire bool, storageClass string) {
+
+	expiration, _, ok := lc.ExpirationTime(name, size, tags, modifiedTime, debugMode)
+	if ok && !now.Before(expiration) {
+		return true, ""
+	}
+	for i := range lc.Rule {
+		rule := &lc.Rule[i]
+		if !rule.Match(name, size, tags) {
+			continue
+		}
+		for _, t := range rule.Transition {
+			if t.Due(modifiedTime, now, debugMode) &&
+				TransitionStorageClassRank(t.StorageClass) > TransitionStorageClassRank(storageClass) {
+				storageClass = t.StorageClass
+			}
+		}
+	}
+	return false, storageClass
+}
+
+// Returns the action due for the n-th newest noncurrent version of an object,
+// which became noncurrent at noncurrentSince
+func (lc *Lifecycle) NoncurrentVersionAction(name string, size int64, tags map[string]string,
+	n int, noncurrentSince, now time.Time, debugMode bool) (expire bool, storageClass string) {
+
+	for i := range lc.Rule {
+		rule := &lc.Rule[i]
+		if !rule.Match(name, size, tags) {
+			continue
+		}
+		expiration := rule.NoncurrentVersionExpiration
+		if expiration != nil && n > expiration.NewerNoncurrentVersions &&
+			DaysPassed(noncurrentSince, now, expiration.NoncurrentDays, debugMode) {
+			return true, ""
+		}
+		for _, t := range rule.NoncurrentVersionTransition {
+			if n > t.NewerNoncurrentVersions &&
+				DaysPassed(noncurrentSince, now, t.NoncurrentDays, debugMode) &&
+				TransitionStorageClassRank(t.StorageClass) > TransitionStorageClassRank(storageClass) {
+				storageClass = t.StorageClass
+			}
+		}
+	}
+	return false, storageClass
+}
+
+// Returns true if a delete marker with no noncurrent versions should be removed
+func (lc *Lifecycle) ExpireDeleteMarker(name string) bool {
+	for i := range lc.Rule {
+		rule := &lc.Rule[i]
+		if rule.ExpiredObjectDeleteMarker && rule.Match(name, 0, nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if a multipart upload initiated at initiated should be aborted
+func (lc *Lifecycle) AbortUpload(name string, initiated, now time.Time, debugMode bool) bool {
+	for i := range lc.Rule {
+		rule := &lc.Rule[i]
+		if rule.AbortIncompleteMultipartUpload == nil || !rule.Match(name, 0, nil) {
+			continue
+		}
+		if DaysPassed(initiated, now, rule.AbortIncompleteMultipartUpload.DaysAfterInitiation, debugMode) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns prefixes to list to find all objects enabled rules could apply to,
+// or a single empty prefix if some rule applies to all keys. Prefixes covered
+// by another one are dropped, so no object is listed twice.
+func (lc *Lifecycle) KeyPrefixes() []string {
+	var prefixes []string
+	for i := range lc.Rule {
+		rule := &lc.Rule[i]
+		if rule.Status != LifecycleRuleEnabled {
+			continue
+		}
+		prefix := rule.KeyPrefix()
+		if prefix == "" {
+			return []string{""}
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	var result []string
+	for i, prefix := range prefixes {
+		covered := false
+		for j, other := range prefixes {
+			if i != j && strings.HasPrefix(prefix, other) && (prefix != other || j < i) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			result = append(result, prefix)
+		}
+	}
+	return result
+}
+
+func ParseLifecycleConfig(reader io.Reader) (*Lifecycle, error) {
+	lc := new(Lifecycle)
+	lcBuffer, err := ioutil.ReadAll(io.LimitReader(reader, MaxLifecycleConfigurationSize+1))
+	if err != nil {
+		helper.Logger.Error("Unable to read lifecycle body:", err)
+		return nil, ErrInvalidLc
+	}
+	if len(lcBuffer) > MaxLifecycleConfigurationSize {
+		return nil, ErrEntityTooLarge
+	}
+	err = xml.Unmarshal(lcBuffer, lc)
+	if err != nil {
+		helper.Logger.Error("Unable to parse lifecycle XML body:", err)
+		return nil, ErrMalformedXML
+	}
+	err = lc.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return lc, nil
+}
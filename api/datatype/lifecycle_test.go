@@ -0,0 +1,381 @@
+package datatype_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/journeymidnight/yig/api/datatype"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/stretchr/testify/assert"
+)
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestDaysAfter(t *testing.T) {
+	cases := []struct {
+		since    time.Time
+		days     int
+		debug    bool
+		expected time.Time
+	}{
+		// rounded up to the next midnight in UTC
+		{time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC), 1, false,
+			time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), 1, false,
+			time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2020, 1, 1, 23, 0, 0, 0, time.FixedZone("CST", 8*3600)), 30, false,
+			time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)},
+		// seconds in debug mode
+		{time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC), 5, true,
+			time.Date(2020, 1, 1, 10, 0, 5, 0, time.UTC)},
+	}
+	for i, c := range cases {
+		assert.True(t, c.expected.Equal(DaysAfter(c.since, c.days, c.debug)), "case %d", i)
+	}
+
+	since := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	assert.False(t, DaysPassed(since, time.Date(2020, 1, 2, 23, 59, 59, 0, time.UTC), 1, false))
+	assert.True(t, DaysPassed(since, time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC), 1, false))
+}
+
+func TestLifecycleRule_Validate(t *testing.T) {
+	cases := []struct {
+		name     string
+		rule     LifecycleRule
+		expected error
+	}{
+		{"expiration days",
+			LifecycleRule{Status: "Enabled", Expiration: "30"}, nil},
+		{"invalid status",
+			LifecycleRule{Status: "enabled", Expiration: "30"}, ErrInvalidLc},
+		{"id too long",
+			LifecycleRule{ID: strings.Repeat("a", 256), Status: "Enabled", Expiration: "30"},
+			ErrInvalidLc},
+		{"no action",
+			LifecycleRule{Status: "Enabled"}, ErrInvalidLc},
+		{"zero expiration days",
+			LifecycleRule{Status: "Enabled", Expiration: "0"}, ErrInvalidLc},
+		{"expiration date",
+			LifecycleRule{Status: "Enabled", ExpirationDate: "2020-01-01T00:00:00.000Z"}, nil},
+		{"expiration date not midnight",
+			LifecycleRule{Status: "Enabled", ExpirationDate: "2020-01-01T08:00:00.000Z"},
+			ErrInvalidLc},
+		{"both expiration days and date",
+			LifecycleRule{Status: "Enabled", Expiration: "30",
+				ExpirationDate: "2020-01-01T00:00:00Z"}, ErrInvalidLc},
+		{"both prefix and filter",
+			LifecycleRule{Status: "Enabled", Prefix: "a", Filter: &LifecycleFilter{},
+				Expiration: "30"}, ErrInvalidLc},
+		{"filter of two elements",
+			LifecycleRule{Status: "Enabled", Expiration: "30", Filter: &LifecycleFilter{
+				Prefix: stringPtr("a"), Tag: &LifecycleTag{Key: "k", Value: "v"}}},
+			ErrInvalidLc},
+		{"filter tag without key",
+			LifecycleRule{Status: "Enabled", Expiration: "30", Filter: &LifecycleFilter{
+				Tag: &LifecycleTag{Value: "v"}}}, ErrInvalidLc},
+		{"and of one predicate",
+			LifecycleRule{Status: "Enabled", Expiration: "30", Filter: &LifecycleFilter{
+				And: &LifecycleAnd{Prefix: "a"}}}, ErrInvalidLc},
+		{"and of duplicate tags",
+			LifecycleRule{Status: "Enabled", Expiration: "30", Filter: &LifecycleFilter{
+				And: &LifecycleAnd{Tags: []LifecycleTag{{"k", "1"}, {"k", "2"}}}}},
+			ErrInvalidLc},
+		{"and of prefix and size",
+			LifecycleRule{Status: "Enabled", Expiration: "30", Filter: &LifecycleFilter{
+				And: &LifecycleAnd{Prefix: "a", ObjectSizeGreaterThan: int64Ptr(10)}}}, nil},
+		{"empty size range",
+			LifecycleRule{Status: "Enabled", Expiration: "30", Filter: &LifecycleFilter{
+				And: &LifecycleAnd{ObjectSizeGreaterThan: int64Ptr(10),
+					ObjectSizeLessThan: int64Ptr(10)}}}, ErrInvalidLc},
+		{"transitions",
+			LifecycleRule{Status: "Enabled", Expiration: "365", Transition: []LifecycleTransition{
+				{Days: 30, StorageClass: "STANDARD_IA"}, {Days: 90, StorageClass: "GLACIER"}}},
+			nil},
+		{"transition to unknown class",
+			LifecycleRule{Status: "Enabled", Transition: []LifecycleTransition{
+				{Days: 30, StorageClass: "STANDARD"}}}, ErrInvalidStorageClass},
+		{"transition to IA too early",
+			LifecycleRule{Status: "Enabled", Transition: []LifecycleTransition{
+				{Days: 29, StorageClass: "STANDARD_IA"}}}, ErrInvalidLc},
+		{"transition to GLACIER at once",
+			LifecycleRule{Status: "Enabled", Transition: []LifecycleTransition{
+				{Days: 1, StorageClass: "GLACIER"}}}, nil},
+		{"transition of both days and date",
+			LifecycleRule{Status: "Enabled", Transition: []LifecycleTransition{
+				{Days: 30, Date: "2020-01-01T00:00:00Z", StorageClass: "GLACIER"}}},
+			ErrInvalidLc},
+		{"transitions of days and date mixed",
+			LifecycleRule{Status: "Enabled", Transition: []LifecycleTransition{
+				{Days: 30, StorageClass: "STANDARD_IA"},
+				{Date: "2020-01-01T00:00:00Z", StorageClass: "GLACIER"}}}, ErrInvalidLc},
+		{"duplicate transition classes",
+			LifecycleRule{Status: "Enabled", Transition: []LifecycleTransition{
+				{Days: 30, StorageClass: "GLACIER"}, {Days: 60, StorageClass: "GLACIER"}}},
+			ErrInvalidLc},
+		{"transition not before expiration",
+			LifecycleRule{Status: "Enabled", Expiration: "30", Transition: []LifecycleTransition{
+				{Days: 30, StorageClass: "GLACIER"}}}, ErrInvalidLc},
+		{"transition date not before expiration date",
+			LifecycleRule{Status: "Enabled", ExpirationDate: "2020-01-01T00:00:00Z",
+				Transition: []LifecycleTransition{
+					{Date: "2020-01-01T00:00:00Z", StorageClass: "GLACIER"}}}, ErrInvalidLc},
+		{"noncurrent version actions",
+			LifecycleRule{Status: "Enabled",
+				NoncurrentVersionExpiration: &NoncurrentVersionExpiration{NoncurrentDays: 60,
+					NewerNoncurrentVersions: 3},
+				NoncurrentVersionTransition: []NoncurrentVersionTransition{
+					{NoncurrentDays: 30, StorageClass: "STANDARD_IA"}}}, nil},
+		{"too many newer noncurrent versions",
+			LifecycleRule{Status: "Enabled",
+				NoncurrentVersionExpiration: &NoncurrentVersionExpiration{NoncurrentDays: 60,
+					NewerNoncurrentVersions: 101}}, ErrInvalidLc},
+		{"noncurrent transition not before expiration",
+			LifecycleRule{Status: "Enabled",
+				NoncurrentVersionExpiration: &NoncurrentVersionExpiration{NoncurrentDays: 30},
+				NoncurrentVersionTransition: []NoncurrentVersionTransition{
+					{NoncurrentDays: 30, StorageClass: "GLACIER"}}}, ErrInvalidLc},
+		{"abort upload",
+			LifecycleRule{Status: "Enabled", Filter: &LifecycleFilter{Prefix: stringPtr("a")},
+				AbortIncompleteMultipartUpload: &AbortIncompleteMultipartUpload{7}}, nil},
+		{"abort upload of zero days",
+			LifecycleRule{Status: "Enabled",
+				AbortIncompleteMultipartUpload: &AbortIncompleteMultipartUpload{0}}, ErrInvalidLc},
+		{"abort upload with tag filter",
+			LifecycleRule{Status: "Enabled", Filter: &LifecycleFilter{
+				Tag: &LifecycleTag{Key: "k", Value: "v"}},
+				AbortIncompleteMultipartUpload: &AbortIncompleteMultipartUpload{7}}, ErrInvalidLc},
+		{"expired delete marker with size filter",
+			LifecycleRule{Status: "Enabled", Filter: &LifecycleFilter{
+				ObjectSizeLessThan: int64Ptr(10)}, ExpiredObjectDeleteMarker: true},
+			ErrInvalidLc},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, c.rule.Validate(), c.name)
+	}
+}
+
+func TestLifecycle_Validate(t *testing.T) {
+	rule := LifecycleRule{ID: "a", Status: "Enabled", Expiration: "30"}
+	lc := Lifecycle{}
+	assert.Equal(t, ErrInvalidLc, lc.Validate())
+	lc.Rule = []LifecycleRule{rule, rule}
+	assert.Equal(t, ErrInvalidLc, lc.Validate())
+	lc.Rule[1].ID = "b"
+	assert.Nil(t, lc.Validate())
+	lc.Rule = make([]LifecycleRule, MaxLifecycleRulesCount+1)
+	for i := range lc.Rule {
+		lc.Rule[i] = LifecycleRule{Status: "Enabled", Expiration: "30"}
+	}
+	assert.Equal(t, ErrInvalidLc, lc.Validate())
+}
+
+func TestLifecycleRule_Match(t *testing.T) {
+	tags := map[string]string{"k1": "v1", "k2": "v2"}
+	cases := []struct {
+		name     string
+		rule     LifecycleRule
+		key      string
+		size     int64
+		expected bool
+	}{
+		{"no filter", LifecycleRule{Status: "Enabled"}, "a", 1, true},
+		{"disabled", LifecycleRule{Status: "Disabled"}, "a", 1, false},
+		{"deprecated prefix", LifecycleRule{Status: "Enabled", Prefix: "a/"}, "a/b", 1, true},
+		{"deprecated prefix mismatch", LifecycleRule{Status: "Enabled", Prefix: "a/"},
+			"b/a", 1, false},
+		{"empty filter", LifecycleRule{Status: "Enabled", Filter: &LifecycleFilter{}},
+			"a", 1, true},
+		{"filter prefix", LifecycleRule{Status: "Enabled",
+			Filter: &LifecycleFilter{Prefix: stringPtr("b")}}, "a", 1, false},
+		{"filter tag", LifecycleRule{Status: "Enabled",
+			Filter: &LifecycleFilter{Tag: &LifecycleTag{"k1", "v1"}}}, "a", 1, true},
+		{"filter tag of other value", LifecycleRule{Status: "Enabled",
+			Filter: &LifecycleFilter{Tag: &LifecycleTag{"k1", "v2"}}}, "a", 1, false},
+		{"filter size greater than", LifecycleRule{Status: "Enabled",
+			Filter: &LifecycleFilter{ObjectSizeGreaterThan: int64Ptr(10)}}, "a", 10, false},
+		{"filter size less than", LifecycleRule{Status: "Enabled",
+			Filter: &LifecycleFilter{ObjectSizeLessThan: int64Ptr(10)}}, "a", 9, true},
+		{"and", LifecycleRule{Status: "Enabled", Filter: &LifecycleFilter{And: &LifecycleAnd{
+			Prefix: "a", Tags: []LifecycleTag{{"k1", "v1"}, {"k2", "v2"}},
+			ObjectSizeGreaterThan: int64Ptr(1), ObjectSizeLessThan: int64Ptr(10)}}},
+			"ab", 5, true},
+		{"and of missing tag", LifecycleRule{Status: "Enabled", Filter: &LifecycleFilter{
+			And: &LifecycleAnd{Tags: []LifecycleTag{{"k1", "v1"}, {"k3", "v3"}}}}},
+			"a", 1, false},
+		{"and of size out of range", LifecycleRule{Status: "Enabled", Filter: &LifecycleFilter{
+			And: &LifecycleAnd{Prefix: "a", ObjectSizeLessThan: int64Ptr(10)}}},
+			"a", 10, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, c.rule.Match(c.key, c.size, tags), c.name)
+	}
+}
+
+func TestLifecycle_KeyPrefixes(t *testing.T) {
+	rule := func(prefix, status string) LifecycleRule {
+		return LifecycleRule{Status: status, Filter: &LifecycleFilter{Prefix: stringPtr(prefix)}}
+	}
+	cases := []struct {
+		name     string
+		rules    []LifecycleRule
+		expected []string
+	}{
+		{"covered prefixes dropped",
+			[]LifecycleRule{rule("a/b", "Enabled"), rule("a/", "Enabled"), rule("c", "Enabled")},
+			[]string{"a/", "c"}},
+		{"duplicate prefixes",
+			[]LifecycleRule{rule("a", "Enabled"), rule("a", "Enabled")}, []string{"a"}},
+		{"disabled rules skipped",
+			[]LifecycleRule{rule("", "Disabled"), rule("a", "Enabled")}, []string{"a"}},
+		{"all keys",
+			[]LifecycleRule{rule("a", "Enabled"), {Status: "Enabled"}}, []string{""}},
+		{"and prefix",
+			[]LifecycleRule{{Status: "Enabled", Filter: &LifecycleFilter{And: &LifecycleAnd{
+				Prefix: "a", Tags: []LifecycleTag{{"k", "v"}}}}}}, []string{"a"}},
+	}
+	for _, c := range cases {
+		lc := Lifecycle{Rule: c.rules}
+		assert.Equal(t, c.expected, lc.KeyPrefixes(), c.name)
+	}
+}
+
+func TestLifecycle_CurrentVersionAction(t *testing.T) {
+	modified := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	lc := Lifecycle{Rule: []LifecycleRule{
+		{ID: "ia", Status: "Enabled", Transition: []LifecycleTransition{
+			{Days: 30, StorageClass: "STANDARD_IA"}}},
+		{ID: "glacier", Status: "Enabled", Filter: &LifecycleFilter{Prefix: stringPtr("cold/")},
+			Expiration: "365", Transition: []LifecycleTransition{
+				{Days: 60, StorageClass: "GLACIER"}}},
+		{ID: "expire", Status: "Enabled", Filter: &LifecycleFilter{
+			Tag: &LifecycleTag{"tmp", "true"}}, Expiration: "1"},
+	}}
+	cases := []struct {
+		name         string
+		key          string
+		tags         map[string]string
+		days         int
+		expire       bool
+		storageClass string
+	}{
+		{"nothing due", "a", nil, 10, false, ""},
+		{"transition due", "a", nil, 31, false, "STANDARD_IA"},
+		{"coldest class wins", "cold/a", nil, 61, false, "GLACIER"},
+		{"only warmer class due", "cold/a", nil, 31, false, "STANDARD_IA"},
+		{"expiration wins", "a", map[string]string{"tmp": "true"}, 31, true, ""},
+		{"expiration of prefix", "cold/a", nil, 366, true, ""},
+	}
+	for _, c := range cases {
+		now := modified.AddDate(0, 0, c.days)
+		expire, storageClass := lc.CurrentVersionAction(c.key, 1, c.tags, modified, now, false)
+		assert.Equal(t, c.expire, expire, c.name)
+		assert.Equal(t, c.storageClass, storageClass, c.name)
+	}
+
+	expiration, ruleId, ok := lc.ExpirationTime("cold/a", 1, map[string]string{"tmp": "true"},
+		modified, false)
+	assert.True(t, ok)
+	assert.Equal(t, "expire", ruleId)
+	assert.True(t, time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC).Equal(expiration))
+	_, _, ok = lc.ExpirationTime("a", 1, nil, modified, false)
+	assert.False(t, ok)
+}
+
+func TestLifecycle_NoncurrentVersionAction(t *testing.T) {
+	since := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	lc := Lifecycle{Rule: []LifecycleRule{
+		{Status: "Enabled",
+			NoncurrentVersionExpiration: &NoncurrentVersionExpiration{NoncurrentDays: 60,
+				NewerNoncurrentVersions: 2},
+			NoncurrentVersionTransition: []NoncurrentVersionTransition{
+				{NoncurrentDays: 30, StorageClass: "STANDARD_IA"},
+				{NoncurrentDays: 40, StorageClass: "GLACIER", NewerNoncurrentVersions: 1}}},
+	}}
+	cases := []struct {
+		name         string
+		n            int
+		days         int
+		expire       bool
+		storageClass string
+	}{
+		{"nothing due", 1, 10, false, ""},
+		{"transition due", 1, 31, false, "STANDARD_IA"},
+		{"newer versions retained in warmer class", 1, 41, false, "STANDARD_IA"},
+		{"coldest class wins", 2, 41, false, "GLACIER"},
+		{"newer versions retained", 2, 61, false, "GLACIER"},
+		{"expiration due", 3, 61, true, ""},
+	}
+	for _, c := range cases {
+		now := since.AddDate(0, 0, c.days)
+		expire, storageClass := lc.NoncurrentVersionAction("a", 1, nil, c.n, since, now, false)
+		assert.Equal(t, c.expire, expire, c.name)
+		assert.Equal(t, c.storageClass, storageClass, c.name)
+	}
+}
+
+func TestLifecycle_AbortUpload(t *testing.T) {
+	initiated := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	lc := Lifecycle{Rule: []LifecycleRule{
+		{Status: "Enabled", Filter: &LifecycleFilter{Prefix: stringPtr("tmp/")},
+			AbortIncompleteMultipartUpload: &AbortIncompleteMultipartUpload{1}},
+		{Status: "Disabled", AbortIncompleteMultipartUpload: &AbortIncompleteMultipartUpload{1}},
+		{Status: "Enabled", Expiration: "1"},
+	}}
+	cases := []struct {
+		name     string
+		key      string
+		now      time.Time
+		expected bool
+	}{
+		{"not due", "tmp/a", time.Date(2020, 1, 2, 23, 59, 59, 0, time.UTC), false},
+		{"due", "tmp/a", time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC), true},
+		{"prefix mismatch", "a", time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, lc.AbortUpload(c.key, initiated, c.now, false), c.name)
+	}
+}
+
+func TestLifecycle_ExpireDeleteMarker(t *testing.T) {
+	lc := Lifecycle{Rule: []LifecycleRule{
+		{Status: "Enabled", Filter: &LifecycleFilter{Prefix: stringPtr("a/")},
+			ExpiredObjectDeleteMarker: true},
+		{Status: "Enabled", Expiration: "1"},
+	}}
+	assert.True(t, lc.ExpireDeleteMarker("a/b"))
+	assert.False(t, lc.ExpireDeleteMarker("b"))
+}
+
+func TestParseLifecycleConfig(t *testing.T) {
+	config := `<LifecycleConfiguration>
+  <Rule>
+    <ID>logs</ID>
+    <Filter><And><Prefix>logs/</Prefix><ObjectSizeGreaterThan>1024</ObjectSizeGreaterThan></And></Filter>
+    <Status>Enabled</Status>
+    <Transition><Days>30</Days><StorageClass>STANDARD_IA</StorageClass></Transition>
+    <Expiration><Days>365</Days></Expiration>
+    <NoncurrentVersionExpiration><NoncurrentDays>7</NoncurrentDays></NoncurrentVersionExpiration>
+  </Rule>
+</LifecycleConfiguration>`
+	lc, err := ParseLifecycleConfig(strings.NewReader(config))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(lc.Rule))
+	rule := lc.Rule[0]
+	assert.Equal(t, "logs/", rule.KeyPrefix())
+	assert.True(t, rule.HasSizeFilter())
+	assert.False(t, rule.HasTagFilter())
+	assert.Equal(t, "365", rule.Expiration)
+	assert.Equal(t, []LifecycleTransition{{Days: 30, StorageClass: "STANDARD_IA"}}, rule.Transition)
+	assert.Equal(t, 7, rule.NoncurrentVersionExpiration.NoncurrentDays)
+
+	_, err = ParseLifecycleConfig(strings.NewReader(strings.Repeat(" ",
+		MaxLifecycleConfigurationSize+1)))
+	assert.Equal(t, ErrEntityTooLarge, err)
+}
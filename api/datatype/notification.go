@@ -0,0 +1,283 @@
+package datatype
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	MaxNotificationConfigurationsCount = 100
+	MaxNotificationConfigurationSize   = 20 * humanize.KiByte
+)
+
+// Supported event types, see
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/NotificationHowTo.html#notification-how-to-event-types-and-destinations
+const (
+	EventObjectCreatedAll                     = "s3:ObjectCreated:*"
+	EventObjectCreatedPut                     = "s3:ObjectCreated:Put"
+	EventObjectCreatedPost                    = "s3:ObjectCreated:Post"
+	EventObjectCreatedCopy                    = "s3:ObjectCreated:Copy"
+	EventObjectCreatedCompleteMultipartUpload = "s3:ObjectCreated:CompleteMultipartUpload"
+	EventObjectRemovedAll                     = "s3:ObjectRemoved:*"
+	EventObjectRemovedDelete                  = "s3:ObjectRemoved:Delete"
+	EventObjectRemovedDeleteMarkerCreated     = "s3:ObjectRemoved:DeleteMarkerCreated"
+	EventObjectRestoreAll                     = "s3:ObjectRestore:*"
+	EventObjectRestorePost                    = "s3:ObjectRestore:Post"
+	EventObjectRestoreCompleted               = "s3:ObjectRestore:Completed"
+)
+
+var supportedEvents = map[string]bool{
+	EventObjectCreatedAll:                     true,
+	EventObjectCreatedPut:                     true,
+	EventObjectCreatedPost:                    true,
+	EventObjectCreatedCopy:                    true,
+	EventObjectCreatedCompleteMultipartUpload: true,
+	EventObjectRemovedAll:                     true,
+	EventObjectRemovedDelete:                  true,
+	EventObjectRemovedDeleteMarkerCreated:     true,
+	EventObjectRestoreAll:                     true,
+	EventObjectRestorePost:                    true,
+	EventObjectRestoreCompleted:               true,
+}
+
+type FilterRule struct {
+	Name  string `xml:"Name"` // "prefix" or "suffix"
+	Value string `xml:"Value"`
+}
+
+type NotificationFilter struct {
+	FilterRules []FilterRule `xml:"S3Key>FilterRule"`
+}
+
+// Topic and queue configurations only differ in their destination, which is
+// recorded in events. All events are delivered through the message queue
+// plugin, see mq.MessageSender.
+type TopicConfiguration struct {
+	Id     string              `xml:"Id"`
+	Topic  string              `xml:"Topic"`
+	Events []string            `xml:"Event"`
+	Filter *NotificationFilter `xml:"Filter,omitempty"`
+}
+
+type QueueConfiguration struct {
+	Id     string              `xml:"Id"`
+	Queue  string              `xml:"Queue"`
+	Events []string            `xml:"Event"`
+	Filter *NotificationFilter `xml:"Filter,omitempty"`
+}
+
+type NotificationConfiguration struct {
+	XMLName             xml.Name             `xml:"NotificationConfiguration"`
+	Xmlns               string               `xml:"xmlns,attr,omitempty"`
+	TopicConfigurations []TopicConfiguration `xml:"TopicConfiguration,omitempty"`
+	QueueConfigurations []QueueConfiguration `xml:"QueueConfiguration,omitempty"`
+}
+
+func (n NotificationConfiguration) IsEmpty() bool {
+	return len(n.TopicConfigurations) == 0 && len(n.QueueConfigurations) == 0
+}
+
+// Returns ids of configurations interested in event of object key,
+// eventName is one of the specific event types, e.g EventObjectCreatedPut
+func (n NotificationConfiguration) Match(eventName, key string) (ids []string) {
+	for _, c := range n.TopicConfigurations {
+		if matchEvents(c.Events, eventName) && c.Filter.match(key) {
+			ids = append(ids, c.Id)
+		}
+	}
+	for _, c := range n.QueueConfigurations {
+		if matchEvents(c.Events, eventName) && c.Filter.match(key) {
+			ids = append(ids, c.Id)
+		}
+	}
+	return
+}
+
+func matchEvents(events []string, eventName string) bool {
+	for _, event := range events {
+		if event == eventName {
+			return true
+		}
+		if strings.HasSuffix(event, ":*") &&
+			strings.HasPrefix(eventName, strings.TrimSuffix(event, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *NotificationFilter) match(key string) bool {
+	if f == nil {
+		return true
+	}
+	for _, rule := range f.FilterRules {
+		switch strings.ToLower(rule.Name) {
+		case "prefix":
+			if !strings.HasPrefix(key, rule.Value) {
+				return false
+			}
+		case "suffix":
+			if !strings.HasSuffix(key, rule.Value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (f *NotificationFilter) validate() error {
+	if f == nil {
+		return nil
+	}
+	var prefix, suffix bool
+	for _, rule := range f.FilterRules {
+		switch strings.ToLower(rule.Name) {
+		case "prefix":
+			if prefix {
+				return ErrInvalidNotificationFilter
+			}
+			prefix = true
+		case "suffix":
+			if suffix {
+				return ErrInvalidNotificationFilter
+			}
+			suffix = true
+		default:
+			return ErrInvalidNotificationFilter
+		}
+		if len(rule.Value) > 1024 {
+			return ErrInvalidNotificationFilter
+		}
+	}
+	return nil
+}
+
+func validateNotification(id *string, destination string, events []string,
+	filter *NotificationFilter, ids map[string]bool) error {
+
+	if destination == "" {
+		return ErrInvalidNotificationDestination
+	}
+	if len(events) == 0 {
+		return ErrInvalidNotificationEvent
+	}
+	for _, event := range events {
+		if !supportedEvents[event] {
+			return ErrInvalidNotificationEvent
+		}
+	}
+	if err := filter.validate(); err != nil {
+		return err
+	}
+	if *id == "" {
+		*id = string(helper.GenerateRandomId())
+	}
+	if ids[*id] {
+		return ErrInvalidNotificationConfiguration
+	}
+	ids[*id] = true
+	return nil
+}
+
+// Validate configurations, and generate ids for those without one
+func (n *NotificationConfiguration) Validate() error {
+	if len(n.TopicConfigurations)+len(n.QueueConfigurations) > MaxNotificationConfigurationsCount {
+		return ErrInvalidNotificationConfiguration
+	}
+	ids := make(map[string]bool)
+	for i := range n.TopicConfigurations {
+		c := &n.TopicConfigurations[i]
+		err := validateNotification(&c.Id, c.Topic, c.Events, c.Filter, ids)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range n.QueueConfigurations {
+		c := &n.QueueConfigurations[i]
+		err := validateNotification(&c.Id, c.Queue, c.Events, c.Filter, ids)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// An empty configuration disables notifications of the bucket
+func ParseNotificationConfig(reader io.Reader) (*NotificationConfiguration, error) {
+	notificationConfig := new(NotificationConfiguration)
+	notificationBuffer, err := ioutil.ReadAll(io.LimitReader(reader, MaxNotificationConfigurationSize+1))
+	if err != nil {
+		helper.Logger.Error("Unable to read notification config body:", err)
+		return nil, err
+	}
+	if len(notificationBuffer) > MaxNotificationConfigurationSize {
+		return nil, ErrEntityTooLarge
+	}
+	err = xml.Unmarshal(notificationBuffer, notificationConfig)
+	if err != nil {
+		helper.Logger.Error("Unable to parse notification config XML body:", err)
+		return nil, ErrMalformedXML
+	}
+	err = notificationConfig.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return notificationConfig, nil
+}
+
+// Event message in S3 format, see
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/notification-content-structure.html
+type EventMessage struct {
+	Records []EventRecord `json:"Records"`
+}
+
+type EventRecord struct {
+	EventVersion     string            `json:"eventVersion"`
+	EventSource      string            `json:"eventSource"`
+	AwsRegion        string            `json:"awsRegion"`
+	EventTime        string            `json:"eventTime"`
+	EventName        string            `json:"eventName"` // without the "s3:" prefix
+	UserIdentity     EventIdentity     `json:"userIdentity"`
+	S3               EventS3           `json:"s3"`
+	GlacierEventData *GlacierEventData `json:"glacierEventData,omitempty"`
+}
+
+type EventIdentity struct {
+	PrincipalId string `json:"principalId"`
+}
+
+type EventS3 struct {
+	SchemaVersion   string      `json:"s3SchemaVersion"`
+	ConfigurationId string      `json:"configurationId"`
+	Bucket          EventBucket `json:"bucket"`
+	Object          EventObject `json:"object"`
+}
+
+type EventBucket struct {
+	Name          string        `json:"name"`
+	OwnerIdentity EventIdentity `json:"ownerIdentity"`
+	Arn           string        `json:"arn"`
+}
+
+type EventObject struct {
+	Key       string `json:"key"` // URL encoded
+	Size      int64  `json:"size,omitempty"`
+	ETag      string `json:"eTag,omitempty"`
+	VersionId string `json:"versionId,omitempty"`
+	Sequencer string `json:"sequencer"`
+}
+
+type GlacierEventData struct {
+	RestoreEventData RestoreEventData `json:"restoreEventData"`
+}
+
+type RestoreEventData struct {
+	LifecycleRestorationExpiryTime string `json:"lifecycleRestorationExpiryTime"`
+	LifecycleRestoreStorageClass   string `json:"lifecycleRestoreStorageClass"`
+}
@@ -0,0 +1,256 @@
+package datatype
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	MaxObjectLockConfigurationSize = 16 * humanize.KiByte
+	MaxObjectLockRetentionDays     = 100 * 365
+
+	ObjectLockEnabled = "Enabled"
+
+	RetentionModeGovernance = "GOVERNANCE"
+	RetentionModeCompliance = "COMPLIANCE"
+
+	LegalHoldOn  = "ON"
+	LegalHoldOff = "OFF"
+
+	// request headers of PUT Object, also returned by GET and HEAD Object
+	ObjectLockModeHeader            = "X-Amz-Object-Lock-Mode"
+	ObjectLockRetainUntilDateHeader = "X-Amz-Object-Lock-Retain-Until-Date"
+	ObjectLockLegalHoldHeader       = "X-Amz-Object-Lock-Legal-Hold"
+	BypassGovernanceRetentionHeader = "X-Amz-Bypass-Governance-Retention"
+	BucketObjectLockEnabledHeader   = "X-Amz-Bucket-Object-Lock-Enabled"
+
+	ObjectLockDateFormat = "2006-01-02T15:04:05.000Z"
+)
+
+// Exactly one of Days and Years is set
+type DefaultRetention struct {
+	Mode  string `xml:"Mode"`
+	Days  int    `xml:"Days,omitempty"`
+	Years int    `xml:"Years,omitempty"`
+}
+
+type ObjectLockRule struct {
+	DefaultRetention DefaultRetention `xml:"DefaultRetention"`
+}
+
+// Object lock of a bucket could not be disabled once enabled, versions
+// created without retention get the default retention of Rule, if any.
+type ObjectLockConfiguration struct {
+	XMLName           xml.Name        `xml:"ObjectLockConfiguration" json:"-"`
+	Xmlns             string          `xml:"xmlns,attr,omitempty" json:"-"`
+	ObjectLockEnabled string          `xml:"ObjectLockEnabled,omitempty"`
+	Rule              *ObjectLockRule `xml:"Rule,omitempty"`
+}
+
+// Body of PUT and GET Object retention, an empty retention removes the
+// retention of a version, which needs bypassing governance mode
+type ObjectLockRetention struct {
+	XMLName         xml.Name   `xml:"Retention"`
+	Xmlns           string     `xml:"xmlns,attr,omitempty"`
+	Mode            string     `xml:"Mode,omitempty"`
+	RetainUntilDate *time.Time `xml:"RetainUntilDate,omitempty"`
+}
+
+type ObjectLegalHold struct {
+	XMLName xml.Name `xml:"LegalHold"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	Status  string   `xml:"Status"`
+}
+
+// ObjectLock of a version, a version could not be removed or overwritten
+// while its legal hold is on or until RetainUntilDate, unless governance
+// mode is bypassed with x-amz-bypass-governance-retention.
+type ObjectLock struct {
+	Mode            string `json:",omitempty"` // GOVERNANCE, COMPLIANCE, or empty if not retained
+	RetainUntilDate time.Time
+	LegalHold       bool `json:",omitempty"`
+}
+
+func (c ObjectLockConfiguration) IsEnabled() bool {
+	return c.ObjectLockEnabled == ObjectLockEnabled
+}
+
+// Retain until date of versions created at now
+func (r DefaultRetention) RetainUntil(now time.Time) time.Time {
+	if r.Years > 0 {
+		return now.AddDate(r.Years, 0, 0)
+	}
+	return now.AddDate(0, 0, r.Days)
+}
+
+func (l ObjectLock) IsEmpty() bool {
+	return l.Mode == "" && !l.LegalHold
+}
+
+// Returns true if retention of the version has not expired at now
+func (l ObjectLock) IsRetained(now time.Time) bool {
+	return l.Mode != "" && now.Before(l.RetainUntilDate)
+}
+
+// Returns true if retention of l could be changed to mode and retainUntil
+// without bypassing governance mode, i.e. it's not less restrictive
+func (l ObjectLock) CanExtendTo(mode string, retainUntil time.Time, now time.Time) bool {
+	if !l.IsRetained(now) {
+		return true
+	}
+	if mode == "" || retainUntil.Before(l.RetainUntilDate) {
+		return false
+	}
+	return l.Mode == mode || mode == RetentionModeCompliance
+}
+
+func (l ObjectLock) Retention() ObjectLockRetention {
+	retention := ObjectLockRetention{Mode: l.Mode}
+	if l.Mode != "" {
+		retainUntil := l.RetainUntilDate.UTC()
+		retention.RetainUntilDate = &retainUntil
+	}
+	return retention
+}
+
+func (l ObjectLock) LegalHoldStatus() string {
+	if l.LegalHold {
+		return LegalHoldOn
+	}
+	return LegalHoldOff
+}
+
+func validRetentionMode(mode string) bool {
+	return mode == RetentionModeGovernance || mode == RetentionModeCompliance
+}
+
+func (r *DefaultRetention) validate() error {
+	if !validRetentionMode(r.Mode) {
+		return ErrInvalidObjectLockConfiguration
+	}
+	if (r.Days > 0) == (r.Years > 0) || r.Days < 0 || r.Years < 0 {
+		return ErrInvalidObjectLockConfiguration
+	}
+	if r.Days > MaxObjectLockRetentionDays || r.Years > MaxObjectLockRetentionDays/365 {
+		return ErrInvalidObjectLockConfiguration
+	}
+	return nil
+}
+
+func (c *ObjectLockConfiguration) Validate() error {
+	if !c.IsEnabled() {
+		return ErrInvalidObjectLockConfiguration
+	}
+	if c.Rule != nil {
+		return c.Rule.DefaultRetention.validate()
+	}
+	return nil
+}
+
+func readObjectLockBody(reader io.Reader, v interface{}) error {
+	buffer, err := ioutil.ReadAll(io.LimitReader(reader, MaxObjectLockConfigurationSize+1))
+	if err != nil {
+		helper.Logger.Error("Unable to read object lock body:", err)
+		return err
+	}
+	if len(buffer) > MaxObjectLockConfigurationSize {
+		return ErrEntityTooLarge
+	}
+	err = xml.Unmarshal(buffer, v)
+	if err != nil {
+		helper.Logger.Error("Unable to parse object lock XML body:", err)
+		return ErrMalformedXML
+	}
+	return nil
+}
+
+func ParseObjectLockConfig(reader io.Reader) (*ObjectLockConfiguration, error) {
+	config := new(ObjectLockConfiguration)
+	err := readObjectLockBody(reader, config)
+	if err != nil {
+		return nil, err
+	}
+	err = config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Returns retention mode and retain until date, both empty if retention is
+// removed
+func ParseObjectLockRetention(reader io.Reader, now time.Time) (mode string,
+	retainUntil time.Time, err error) {
+
+	retention := new(ObjectLockRetention)
+	err = readObjectLockBody(reader, retention)
+	if err != nil {
+		return
+	}
+	if retention.Mode == "" && retention.RetainUntilDate == nil {
+		return "", time.Time{}, nil
+	}
+	if !validRetentionMode(retention.Mode) || retention.RetainUntilDate == nil {
+		return "", time.Time{}, ErrInvalidObjectRetention
+	}
+	if !retention.RetainUntilDate.After(now) {
+		return "", time.Time{}, ErrPastObjectLockRetainDate
+	}
+	return retention.Mode, retention.RetainUntilDate.UTC(), nil
+}
+
+func ParseObjectLegalHold(reader io.Reader) (legalHold bool, err error) {
+	hold := new(ObjectLegalHold)
+	err = readObjectLockBody(reader, hold)
+	if err != nil {
+		return
+	}
+	switch hold.Status {
+	case LegalHoldOn:
+		return true, nil
+	case LegalHoldOff:
+		return false, nil
+	}
+	return false, ErrMalformedXML
+}
+
+// Parse x-amz-object-lock-* headers of PUT Object, Copy Object and Initiate
+// Multipart Upload
+func ParseObjectLockHeaders(header http.Header, now time.Time) (lock ObjectLock, err error) {
+	mode := header.Get(ObjectLockModeHeader)
+	date := header.Get(ObjectLockRetainUntilDateHeader)
+	if mode != "" || date != "" {
+		if !validRetentionMode(mode) || date == "" {
+			return lock, ErrInvalidObjectLockHeaders
+		}
+		lock.RetainUntilDate, err = time.Parse(time.RFC3339, date)
+		if err != nil {
+			return lock, ErrInvalidObjectLockHeaders
+		}
+		if !lock.RetainUntilDate.After(now) {
+			return lock, ErrPastObjectLockRetainDate
+		}
+		lock.Mode = mode
+		lock.RetainUntilDate = lock.RetainUntilDate.UTC()
+	}
+	switch strings.ToUpper(header.Get(ObjectLockLegalHoldHeader)) {
+	case "", LegalHoldOff:
+	case LegalHoldOn:
+		lock.LegalHold = true
+	default:
+		return lock, ErrInvalidObjectLockHeaders
+	}
+	return lock, nil
+}
+
+func IsBypassGovernanceRetention(header http.Header) bool {
+	return strings.EqualFold(header.Get(BypassGovernanceRetentionHeader), "true")
+}
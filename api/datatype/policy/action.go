@@ -44,6 +44,9 @@ const (
 	// DeleteObjectAction - DeleteObject Rest API action.
 	DeleteObjectAction = "s3:DeleteObject"
 
+	// DeleteObjectTaggingAction - DeleteObjectTagging Rest API action.
+	DeleteObjectTaggingAction = "s3:DeleteObjectTagging"
+
 	// GetBucketLocationAction - GetBucketLocation Rest API action.
 	GetBucketLocationAction = "s3:GetBucketLocation"
 
@@ -56,6 +59,9 @@ const (
 	// GetObjectAction - GetObject Rest API action.
 	GetObjectAction = "s3:GetObject"
 
+	// GetObjectTaggingAction - GetObjectTagging Rest API action.
+	GetObjectTaggingAction = "s3:GetObjectTagging"
+
 	// HeadBucketAction - HeadBucket Rest API action. This action is unused in minio.
 	HeadBucketAction = "s3:HeadBucket"
 
@@ -83,6 +89,25 @@ const (
 
 	// PutObjectAction - PutObject Rest API action.
 	PutObjectAction = "s3:PutObject"
+
+	// PutObjectTaggingAction - PutObjectTagging Rest API action.
+	PutObjectTaggingAction = "s3:PutObjectTagging"
+
+	// GetObjectRetentionAction - GetObjectRetention Rest API action.
+	GetObjectRetentionAction = "s3:GetObjectRetention"
+
+	// PutObjectRetentionAction - PutObjectRetention Rest API action.
+	PutObjectRetentionAction = "s3:PutObjectRetention"
+
+	// GetObjectLegalHoldAction - GetObjectLegalHold Rest API action.
+	GetObjectLegalHoldAction = "s3:GetObjectLegalHold"
+
+	// PutObjectLegalHoldAction - PutObjectLegalHold Rest API action.
+	PutObjectLegalHoldAction = "s3:PutObjectLegalHold"
+
+	// BypassGovernanceRetentionAction - allows x-amz-bypass-governance-retention
+	// of DeleteObject and PutObjectRetention.
+	BypassGovernanceRetentionAction = "s3:BypassGovernanceRetention"
 )
 
 // isObjectAction - returns whether action is object type or not.
@@ -91,6 +116,12 @@ func (action Action) isObjectAction() bool {
 	case AbortMultipartUploadAction, DeleteObjectAction, GetObjectAction:
 		fallthrough
 	case ListMultipartUploadPartsAction, PutObjectAction:
+		fallthrough
+	case DeleteObjectTaggingAction, GetObjectTaggingAction, PutObjectTaggingAction:
+		fallthrough
+	case GetObjectRetentionAction, PutObjectRetentionAction, GetObjectLegalHoldAction:
+		fallthrough
+	case PutObjectLegalHoldAction, BypassGovernanceRetentionAction:
 		return true
 	}
 
@@ -113,6 +144,12 @@ func (action Action) IsValid() bool {
 	case ListMultipartUploadPartsAction, PutBucketNotificationAction:
 		fallthrough
 	case PutBucketPolicyAction, PutObjectAction:
+		fallthrough
+	case DeleteObjectTaggingAction, GetObjectTaggingAction, PutObjectTaggingAction:
+		fallthrough
+	case GetObjectRetentionAction, PutObjectRetentionAction, GetObjectLegalHoldAction:
+		fallthrough
+	case PutObjectLegalHoldAction, BypassGovernanceRetentionAction:
 		return true
 	}
 
@@ -178,6 +215,12 @@ var actionConditionKeyMap = map[Action]condition.KeySet{
 		condition.AWSSourceIP,
 	),
 
+	DeleteObjectTaggingAction: condition.NewKeySet(
+		condition.S3ExistingObjectTag,
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
 	GetBucketLocationAction: condition.NewKeySet(
 		condition.AWSReferer,
 		condition.AWSSourceIP,
@@ -197,6 +240,13 @@ var actionConditionKeyMap = map[Action]condition.KeySet{
 		condition.S3XAmzServerSideEncryption,
 		condition.S3XAmzServerSideEncryptionAwsKMSKeyID,
 		condition.S3XAmzStorageClass,
+		condition.S3ExistingObjectTag,
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	GetObjectTaggingAction: condition.NewKeySet(
+		condition.S3ExistingObjectTag,
 		condition.AWSReferer,
 		condition.AWSSourceIP,
 	),
@@ -250,6 +300,39 @@ var actionConditionKeyMap = map[Action]condition.KeySet{
 		condition.S3XAmzServerSideEncryptionAwsKMSKeyID,
 		condition.S3XAmzMetadataDirective,
 		condition.S3XAmzStorageClass,
+		condition.S3RequestObjectTag,
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	PutObjectTaggingAction: condition.NewKeySet(
+		condition.S3ExistingObjectTag,
+		condition.S3RequestObjectTag,
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	GetObjectRetentionAction: condition.NewKeySet(
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	PutObjectRetentionAction: condition.NewKeySet(
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	GetObjectLegalHoldAction: condition.NewKeySet(
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	PutObjectLegalHoldAction: condition.NewKeySet(
+		condition.AWSReferer,
+		condition.AWSSourceIP,
+	),
+
+	BypassGovernanceRetentionAction: condition.NewKeySet(
 		condition.AWSReferer,
 		condition.AWSSourceIP,
 	),
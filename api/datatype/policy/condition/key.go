@@ -68,10 +68,35 @@ const (
 
 	// AWSSourceIP - key representing client's IP address (not intermittent proxies) of any API.
 	AWSSourceIP = "aws:SourceIp"
+
+	// S3ExistingObjectTag - key representing a tag of the existing object, used as
+	// "s3:ExistingObjectTag/<tag-key>".
+	S3ExistingObjectTag = "s3:ExistingObjectTag"
+
+	// S3RequestObjectTag - key representing a tag in the request, i.e x-amz-tagging HTTP header
+	// or body of PutObjectTagging API, used as "s3:RequestObjectTag/<tag-key>".
+	S3RequestObjectTag = "s3:RequestObjectTag"
 )
 
+// Base - returns key without its tag key part, e.g. "s3:ExistingObjectTag" for
+// "s3:ExistingObjectTag/<tag-key>". Other keys are returned as is.
+func (key Key) Base() Key {
+	keyString := string(key)
+	for _, base := range []Key{S3ExistingObjectTag, S3RequestObjectTag} {
+		if strings.HasPrefix(keyString, string(base)+"/") && len(keyString) > len(base)+1 {
+			return base
+		}
+	}
+
+	return key
+}
+
 // IsValid - checks if key is valid or not.
 func (key Key) IsValid() bool {
+	if key.Base() != key {
+		return true
+	}
+
 	switch key {
 	case S3XAmzCopySource, S3XAmzServerSideEncryption, S3XAmzServerSideEncryptionAwsKMSKeyID:
 		fallthrough
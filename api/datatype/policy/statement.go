@@ -93,7 +93,11 @@ func (statement Statement) isValid() error {
 			}
 		}
 
-		keys := statement.Conditions.Keys()
+		// tag keys are supported by their base key, regardless of tag key
+		keys := condition.NewKeySet()
+		for key := range statement.Conditions.Keys() {
+			keys.Add(key.Base())
+		}
 		keyDiff := keys.Difference(actionConditionKeyMap[action])
 		if !keyDiff.IsEmpty() {
 			return fmt.Errorf("unsupported condition keys '%v' used for action '%v'", keyDiff, action)
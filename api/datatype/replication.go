@@ -0,0 +1,329 @@
+package datatype
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	MaxReplicationConfigurationSize = 128 * humanize.KiByte
+	MaxReplicationRulesCount        = 1000
+	MaxReplicationRuleIdLength      = 255
+
+	ReplicationRuleEnabled  = "Enabled"
+	ReplicationRuleDisabled = "Disabled"
+
+	// values of x-amz-replication-status
+	ReplicationStatusPending   = "PENDING"
+	ReplicationStatusCompleted = "COMPLETED"
+	ReplicationStatusFailed    = "FAILED"
+	ReplicationStatusReplica   = "REPLICA"
+
+	// sent with REPLICA by yig_replicate, so replicas are never replicated again
+	ReplicationStatusHeader = "X-Amz-Replication-Status"
+
+	bucketArnPrefix = "arn:aws:s3:"
+)
+
+type ReplicationAnd struct {
+	Prefix string `xml:"Prefix,omitempty"`
+	Tags   []Tag  `xml:"Tag,omitempty"`
+}
+
+// A filter has at most one of its elements, an empty filter matches all objects
+type ReplicationFilter struct {
+	Prefix *string         `xml:"Prefix"`
+	Tag    *Tag            `xml:"Tag,omitempty"`
+	And    *ReplicationAnd `xml:"And,omitempty"`
+}
+
+type DeleteMarkerReplication struct {
+	Status string `xml:"Status"`
+}
+
+// Bucket is an ARN like "arn:aws:s3:<target>::<bucket>", where target is the
+// ID of a replication target in config, see helper.ReplicationTargetConfig.
+// Objects keep their storage class if StorageClass is empty.
+type ReplicationDestination struct {
+	Bucket       string `xml:"Bucket"`
+	StorageClass string `xml:"StorageClass,omitempty"`
+}
+
+type ReplicationRule struct {
+	ID       string             `xml:"ID,omitempty"`
+	Priority int                `xml:"Priority,omitempty"`
+	Status   string             `xml:"Status"`
+	Filter   *ReplicationFilter `xml:"Filter,omitempty"`
+	// deprecated by Filter, but still used by old clients
+	Prefix *string `xml:"Prefix"`
+	// only valid with Filter, delete markers are always replicated by rules
+	// without Filter, and never replicated by rules with tag filters
+	DeleteMarkerReplication *DeleteMarkerReplication `xml:"DeleteMarkerReplication,omitempty"`
+	Destination             ReplicationDestination   `xml:"Destination"`
+}
+
+// Role is kept for compatibility only, destinations are accessed with keys
+// of their replication targets
+type ReplicationConfiguration struct {
+	XMLName xml.Name          `xml:"ReplicationConfiguration"`
+	Xmlns   string            `xml:"xmlns,attr,omitempty"`
+	Role    string            `xml:"Role"`
+	Rules   []ReplicationRule `xml:"Rule"`
+}
+
+// Returns replication target and bucket name of ARN, the target could be
+// omitted if only one is configured
+func ParseReplicationDestination(arn string) (target helper.ReplicationTargetConfig,
+	bucket string, err error) {
+
+	if !strings.HasPrefix(arn, bucketArnPrefix) {
+		return target, "", ErrInvalidReplicationDestination
+	}
+	// <target>::<bucket>
+	fields := strings.Split(strings.TrimPrefix(arn, bucketArnPrefix), ":")
+	if len(fields) != 3 || fields[1] != "" || fields[2] == "" ||
+		strings.Contains(fields[2], "/") {
+		return target, "", ErrInvalidReplicationDestination
+	}
+	targets := helper.CONFIG.ReplicationTargets
+	if fields[0] == "" {
+		if len(targets) != 1 {
+			return target, "", ErrInvalidReplicationDestination
+		}
+		return targets[0], fields[2], nil
+	}
+	for _, t := range targets {
+		if t.ID == fields[0] {
+			return t, fields[2], nil
+		}
+	}
+	return target, "", ErrInvalidReplicationDestination
+}
+
+func (c ReplicationConfiguration) IsEmpty() bool {
+	return len(c.Rules) == 0
+}
+
+func (rule *ReplicationRule) keyPrefix() string {
+	if rule.Filter == nil {
+		if rule.Prefix != nil {
+			return *rule.Prefix
+		}
+		return ""
+	}
+	if rule.Filter.Prefix != nil {
+		return *rule.Filter.Prefix
+	}
+	if rule.Filter.And != nil {
+		return rule.Filter.And.Prefix
+	}
+	return ""
+}
+
+func (rule *ReplicationRule) hasTagFilter() bool {
+	if rule.Filter == nil {
+		return false
+	}
+	return rule.Filter.Tag != nil || (rule.Filter.And != nil && len(rule.Filter.And.Tags) != 0)
+}
+
+func hasTag(tags map[string]string, tag Tag) bool {
+	value, ok := tags[tag.Key]
+	return ok && value == tag.Value
+}
+
+// Returns true if the rule is enabled and applies to object with name and tags
+func (rule *ReplicationRule) Match(name string, tags map[string]string) bool {
+	if rule.Status != ReplicationRuleEnabled {
+		return false
+	}
+	if !strings.HasPrefix(name, rule.keyPrefix()) {
+		return false
+	}
+	f := rule.Filter
+	if f == nil {
+		return true
+	}
+	if f.Tag != nil && !hasTag(tags, *f.Tag) {
+		return false
+	}
+	if f.And != nil {
+		for _, tag := range f.And.Tags {
+			if !hasTag(tags, tag) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (rule *ReplicationRule) replicateDeleteMarker() bool {
+	if rule.Filter == nil {
+		return true
+	}
+	if rule.hasTagFilter() || rule.DeleteMarkerReplication == nil {
+		return false
+	}
+	return rule.DeleteMarkerReplication.Status == ReplicationRuleEnabled
+}
+
+// Returns the rule with highest priority which applies to object, nil if
+// the object is not replicated
+func (c ReplicationConfiguration) Match(name string, tags map[string]string) *ReplicationRule {
+	var matched *ReplicationRule
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		if !rule.Match(name, tags) {
+			continue
+		}
+		if matched == nil || rule.Priority > matched.Priority {
+			matched = rule
+		}
+	}
+	return matched
+}
+
+// Like Match, but for delete markers, which have no tags
+func (c ReplicationConfiguration) MatchDeleteMarker(name string) *ReplicationRule {
+	rule := c.Match(name, nil)
+	if rule == nil || !rule.replicateDeleteMarker() {
+		return nil
+	}
+	return rule
+}
+
+func validReplicationStatus(status string) bool {
+	return status == ReplicationRuleEnabled || status == ReplicationRuleDisabled
+}
+
+func validReplicationStorageClass(storageClass string) bool {
+	if storageClass == "" || storageClass == "STANDARD" {
+		return true
+	}
+	for _, s := range LifecycleTransitionStorageClasses {
+		if s == storageClass {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *ReplicationAnd) validate() error {
+	predicates := len(a.Tags)
+	if a.Prefix != "" {
+		predicates++
+	}
+	if predicates < 2 {
+		return ErrInvalidReplicationConfiguration
+	}
+	keys := make(map[string]bool)
+	for _, tag := range a.Tags {
+		if err := validateTag(tag.Key, tag.Value); err != nil {
+			return err
+		}
+		if keys[tag.Key] {
+			return ErrInvalidReplicationConfiguration
+		}
+		keys[tag.Key] = true
+	}
+	return nil
+}
+
+func (f *ReplicationFilter) validate() error {
+	elements := 0
+	if f.Prefix != nil {
+		elements++
+	}
+	if f.Tag != nil {
+		elements++
+		if err := validateTag(f.Tag.Key, f.Tag.Value); err != nil {
+			return err
+		}
+	}
+	if f.And != nil {
+		elements++
+		if err := f.And.validate(); err != nil {
+			return err
+		}
+	}
+	if elements > 1 {
+		return ErrInvalidReplicationConfiguration
+	}
+	return nil
+}
+
+func (rule *ReplicationRule) validate() error {
+	if len(rule.ID) > MaxReplicationRuleIdLength || !validReplicationStatus(rule.Status) {
+		return ErrInvalidReplicationConfiguration
+	}
+	if rule.Filter != nil {
+		if rule.Prefix != nil {
+			return ErrInvalidReplicationConfiguration
+		}
+		if err := rule.Filter.validate(); err != nil {
+			return err
+		}
+	} else if rule.DeleteMarkerReplication != nil {
+		return ErrInvalidReplicationConfiguration
+	}
+	if rule.DeleteMarkerReplication != nil &&
+		!validReplicationStatus(rule.DeleteMarkerReplication.Status) {
+		return ErrInvalidReplicationConfiguration
+	}
+	if rule.Priority < 0 || !validReplicationStorageClass(rule.Destination.StorageClass) {
+		return ErrInvalidReplicationConfiguration
+	}
+	_, _, err := ParseReplicationDestination(rule.Destination.Bucket)
+	return err
+}
+
+// Validate rules, and generate ids for those without one
+func (c *ReplicationConfiguration) Validate() error {
+	if len(c.Rules) == 0 || len(c.Rules) > MaxReplicationRulesCount {
+		return ErrInvalidReplicationConfiguration
+	}
+	ids := make(map[string]bool)
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		err := rule.validate()
+		if err != nil {
+			return err
+		}
+		if rule.ID == "" {
+			rule.ID = string(helper.GenerateRandomId())
+		}
+		if ids[rule.ID] {
+			return ErrInvalidReplicationConfiguration
+		}
+		ids[rule.ID] = true
+	}
+	return nil
+}
+
+func ParseReplicationConfig(reader io.Reader) (*ReplicationConfiguration, error) {
+	replicationConfig := new(ReplicationConfiguration)
+	replicationBuffer, err := ioutil.ReadAll(io.LimitReader(reader, MaxReplicationConfigurationSize+1))
+	if err != nil {
+		helper.Logger.Error("Unable to read replication config body:", err)
+		return nil, err
+	}
+	if len(replicationBuffer) > MaxReplicationConfigurationSize {
+		return nil, ErrEntityTooLarge
+	}
+	err = xml.Unmarshal(replicationBuffer, replicationConfig)
+	if err != nil {
+		helper.Logger.Error("Unable to parse replication config XML body:", err)
+		return nil, ErrMalformedXML
+	}
+	err = replicationConfig.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return replicationConfig, nil
+}
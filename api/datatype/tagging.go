@@ -0,0 +1,188 @@
+package datatype
+
+import (
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+	. "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	MaxTaggingConfigurationSize = 16 * humanize.KiByte
+	MaxObjectTagsCount          = 10
+	MaxBucketTagsCount          = 50
+	MaxTagKeyLength             = 128
+	MaxTagValueLength           = 256
+
+	TaggingDirectiveCopy    = "COPY"
+	TaggingDirectiveReplace = "REPLACE"
+)
+
+type Tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type Tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	Xmlns   string   `xml:"xmlns,attr,omitempty"`
+	TagSet  []Tag    `xml:"TagSet>Tag"`
+}
+
+// Besides letters and numbers, only spaces and + - = . _ : / @ are allowed
+func validTagString(s string) bool {
+	for _, c := range s {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			continue
+		}
+		if !strings.ContainsRune(" +-=._:/@", c) {
+			return false
+		}
+	}
+	return true
+}
+
+func validateTag(key, value string) error {
+	keyLength := utf8.RuneCountInString(key)
+	if keyLength == 0 || keyLength > MaxTagKeyLength ||
+		utf8.RuneCountInString(value) > MaxTagValueLength {
+		return ErrInvalidTag
+	}
+	// "aws:" prefix is reserved
+	if strings.HasPrefix(strings.ToLower(key), "aws:") {
+		return ErrInvalidTag
+	}
+	if !validTagString(key) || !validTagString(value) {
+		return ErrInvalidTag
+	}
+	return nil
+}
+
+// Validate tag set of an object
+func (t Tagging) Validate() error {
+	if len(t.TagSet) > MaxObjectTagsCount {
+		return ErrObjectTagsTooMany
+	}
+	return t.validateTags()
+}
+
+// Validate tag set of a bucket
+func (t Tagging) ValidateBucket() error {
+	if len(t.TagSet) > MaxBucketTagsCount {
+		return ErrBucketTagsTooMany
+	}
+	return t.validateTags()
+}
+
+func (t Tagging) validateTags() error {
+	keys := make(map[string]bool)
+	for _, tag := range t.TagSet {
+		if keys[tag.Key] {
+			return ErrInvalidTag
+		}
+		keys[tag.Key] = true
+		if err := validateTag(tag.Key, tag.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t Tagging) ToMap() map[string]string {
+	if len(t.TagSet) == 0 {
+		return nil
+	}
+	tags := make(map[string]string)
+	for _, tag := range t.TagSet {
+		tags[tag.Key] = tag.Value
+	}
+	return tags
+}
+
+// Tags are sorted by key, to make responses stable
+func TaggingFromMap(tags map[string]string) Tagging {
+	tagging := Tagging{
+		Xmlns:  "http://s3.amazonaws.com/doc/2006-03-01/",
+		TagSet: make([]Tag, 0, len(tags)),
+	}
+	for key, value := range tags {
+		tagging.TagSet = append(tagging.TagSet, Tag{Key: key, Value: value})
+	}
+	sort.Slice(tagging.TagSet, func(i, j int) bool {
+		return tagging.TagSet[i].Key < tagging.TagSet[j].Key
+	})
+	return tagging
+}
+
+func parseTagging(reader io.Reader) (tagging Tagging, err error) {
+	taggingBuffer, err := ioutil.ReadAll(io.LimitReader(reader, MaxTaggingConfigurationSize))
+	if err != nil {
+		helper.Logger.Error("Unable to read tagging body:", err)
+		return tagging, err
+	}
+	err = xml.Unmarshal(taggingBuffer, &tagging)
+	if err != nil {
+		helper.Logger.Error("Unable to parse tagging XML body:", err)
+		return tagging, ErrMalformedXML
+	}
+	return tagging, nil
+}
+
+// Parse tag set of an object
+func ParseTagging(reader io.Reader) (tags map[string]string, err error) {
+	tagging, err := parseTagging(reader)
+	if err != nil {
+		return nil, err
+	}
+	err = tagging.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return tagging.ToMap(), nil
+}
+
+// Parse tag set of a bucket
+func ParseBucketTagging(reader io.Reader) (tags map[string]string, err error) {
+	tagging, err := parseTagging(reader)
+	if err != nil {
+		return nil, err
+	}
+	err = tagging.ValidateBucket()
+	if err != nil {
+		return nil, err
+	}
+	return tagging.ToMap(), nil
+}
+
+// Parse value of header "x-amz-tagging", which is URL query encoded,
+// e.g. "key1=value1&key2=value2"
+func ParseTaggingHeader(header string) (tags map[string]string, err error) {
+	if header == "" {
+		return nil, nil
+	}
+	values, err := url.ParseQuery(header)
+	if err != nil {
+		return nil, ErrInvalidTag
+	}
+	if len(values) > MaxObjectTagsCount {
+		return nil, ErrObjectTagsTooMany
+	}
+	tags = make(map[string]string)
+	for key, value := range values {
+		if len(value) != 1 {
+			return nil, ErrInvalidTag
+		}
+		if err = validateTag(key, value[0]); err != nil {
+			return nil, err
+		}
+		tags[key] = value[0]
+	}
+	return tags, nil
+}
@@ -18,6 +18,7 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"strings"
@@ -170,16 +171,12 @@ func ignoreNotImplementedObjectResources(req *http.Request) bool {
 
 // List of not implemented bucket queries
 var notImplementedBucketResourceNames = map[string]bool{
-	"notification":   true,
-	"replication":    true,
-	"tagging":        true,
 	"requestPayment": true,
 }
 
 // List of not implemented object queries
 var notImplementedObjectResourceNames = map[string]bool{
 	"torrent": true,
-	"tagging": true,
 }
 
 func ContextLogger(r *http.Request) log.Logger {
@@ -212,7 +209,7 @@ type GenerateContextHandler struct {
 // handler for validating incoming authorization headers.
 func (h GenerateContextHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var bucketInfo *types.Bucket
-	var objectInfo *types.Object
+	var objectInfo, objectVersionInfo *types.Object
 	var err error
 	requestId := r.Context().Value(RequestIdKey).(string)
 	logger := r.Context().Value(ContextLoggerKey).(log.Logger)
@@ -229,6 +226,13 @@ func (h GenerateContextHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 				WriteErrorResponse(w, r, err)
 				return
 			}
+			if version := r.URL.Query().Get("versionId"); version != "" {
+				objectVersionInfo, err = h.getObjectVersion(objectInfo, version)
+				if err != nil && err != ErrNoSuchKey {
+					WriteErrorResponse(w, r, err)
+					return
+				}
+			}
 		}
 	}
 
@@ -242,20 +246,42 @@ func (h GenerateContextHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		r.Context(),
 		RequestContextKey,
 		RequestContext{
-			RequestID:      requestId,
-			Logger:         logger,
-			BucketName:     bucketName,
-			ObjectName:     objectName,
-			BucketInfo:     bucketInfo,
-			ObjectInfo:     objectInfo,
-			AuthType:       authType,
-			IsBucketDomain: isBucketDomain,
+			RequestID:         requestId,
+			Logger:            logger,
+			BucketName:        bucketName,
+			ObjectName:        objectName,
+			BucketInfo:        bucketInfo,
+			ObjectInfo:        objectInfo,
+			ObjectVersionInfo: objectVersionInfo,
+			AuthType:          authType,
+			IsBucketDomain:    isBucketDomain,
 		})
 	logger.Info(fmt.Sprintf("BucketName: %s, ObjectName: %s, BucketInfo: %+v, ObjectInfo: %+v, AuthType: %d",
 		bucketName, objectName, bucketInfo, objectInfo, authType))
 	h.handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
+// Returns the version of object requested by versionId, latest is the latest
+// version of object, nil if the object has no version at all
+func (h GenerateContextHandler) getObjectVersion(latest *types.Object, version string) (*types.Object, error) {
+	if latest == nil {
+		return nil, ErrNoSuchKey
+	}
+	if version == "null" {
+		if latest.NullVersion {
+			return latest, nil
+		}
+		objMap, err := h.meta.Client.GetObjectMap(latest.BucketName, latest.Name)
+		if err == sql.ErrNoRows {
+			return nil, ErrNoSuchKey
+		} else if err != nil {
+			return nil, err
+		}
+		version = objMap.NullVerId
+	}
+	return h.meta.GetObjectVersion(latest.BucketName, latest.Name, version, true)
+}
+
 // setAuthHandler to validate authorization header for the incoming request.
 func SetGenerateContextHandler(h http.Handler, meta *meta.Meta) http.Handler {
 	return GenerateContextHandler{h, meta}
@@ -27,6 +27,7 @@ import (
 	"github.com/journeymidnight/yig/crypto"
 	. "github.com/journeymidnight/yig/error"
 	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/iam/common"
 )
 
 // validates location constraint from the request body.
@@ -70,6 +71,30 @@ var supportedHeaders = []string{
 	// Add more supported headers here
 }
 
+// Writes of yig_replicate are marked with x-amz-replication-status: REPLICA.
+// The mark is only honored for keys in replica_access_keys, otherwise anyone
+// could keep their writes from being replicated.
+func isReplicaRequest(header http.Header, credential common.Credential) bool {
+	if header.Get(ReplicationStatusHeader) != ReplicationStatusReplica {
+		return false
+	}
+	for _, key := range helper.CONFIG.ReplicaAccessKeys {
+		if key == credential.AccessKeyID {
+			return true
+		}
+	}
+	return false
+}
+
+// Mark metadata of objects written by yig_replicate, see markReplication
+func markReplicaMetadata(metadata map[string]string, header http.Header,
+	credential common.Credential) {
+
+	if isReplicaRequest(header, credential) {
+		metadata[ReplicationStatusHeader] = ReplicationStatusReplica
+	}
+}
+
 // extractMetadataFromHeader extracts metadata from HTTP header.
 func extractMetadataFromHeader(header http.Header) map[string]string {
 	metadata := make(map[string]string)
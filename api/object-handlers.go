@@ -26,6 +26,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	. "github.com/journeymidnight/yig/api/datatype"
@@ -296,6 +297,11 @@ func (api ObjectAPIHandlers) GetObjectHandler(w http.ResponseWriter, r *http.Req
 
 	// io.Writer type which keeps track if any data was written.
 	writer := newGetObjectResponseWriter(w, r, object, hrange, http.StatusOK, version)
+	// Noncurrent versions are not expired by Expiration actions
+	if version == "" {
+		SetExpirationHeader(w, ctx.BucketInfo, object.Name, object.Size, object.Tags,
+			object.LastModifiedTime)
+	}
 
 	switch object.SseType {
 	case "":
@@ -367,11 +373,10 @@ func (api ObjectAPIHandlers) HeadObjectHandler(w http.ResponseWriter, r *http.Re
 			logger.Error("Unable to get restore object status", object.BucketName, object.Name, version,
 				"error:", err)
 			WriteErrorResponse(w, r, err)
+			return
 		}
-		if freezer.Status == meta.ObjectHasRestored {
-			w.Header().Set("x-amz-restore", "ongoing-request='true'")
-		} else {
-			w.Header().Set("x-amz-restore", "ongoing-request='false'")
+		if err == nil {
+			SetRestoreHeader(w, freezer)
 		}
 	}
 
@@ -433,6 +438,11 @@ func (api ObjectAPIHandlers) HeadObjectHandler(w http.ResponseWriter, r *http.Re
 			r.Header.Get("X-Amz-Server-Side-Encryption-Customer-Key-Md5"))
 	}
 
+	if version == "" {
+		SetExpirationHeader(w, ctx.BucketInfo, object.Name, object.Size, object.Tags,
+			object.LastModifiedTime)
+	}
+
 	//ResponseRecorder
 	w.(*ResponseRecorder).operationName = "HeadObject"
 
@@ -588,18 +598,22 @@ func (api ObjectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	pipeReader, pipeWriter := io.Pipe()
-	go func() {
-		startOffset := int64(0) // Read the whole file.
-		// Get the object.
-		err = api.ObjectAPI.GetObject(sourceObject, startOffset, sourceObject.Size,
-			pipeWriter, sseRequest)
-		if err != nil {
-			logger.Error("Unable to read an object:", err)
-			pipeWriter.CloseWithError(err)
-			return
-		}
-		pipeWriter.Close()
-	}()
+	defer pipeReader.Close()
+	// Source is only read when data is not copied inside backend cluster
+	sourceReader := &lazyReader{reader: pipeReader, start: func() {
+		go func() {
+			startOffset := int64(0) // Read the whole file.
+			// Get the object.
+			err := api.ObjectAPI.GetObject(sourceObject, startOffset, sourceObject.Size,
+				pipeWriter, sseRequest)
+			if err != nil {
+				logger.Error("Unable to read an object:", err)
+				pipeWriter.CloseWithError(err)
+				return
+			}
+			pipeWriter.Close()
+		}()
+	}}
 
 	targetACL, err := getAclFromHeader(r.Header)
 	if err != nil {
@@ -638,6 +652,27 @@ func (api ObjectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	taggingDirective := r.Header.Get("X-Amz-Tagging-Directive")
+	if taggingDirective == TaggingDirectiveCopy || taggingDirective == "" {
+		targetObject.Tags = sourceObject.Tags
+	} else if taggingDirective == TaggingDirectiveReplace {
+		targetObject.Tags, err = ParseTaggingHeader(r.Header.Get("X-Amz-Tagging"))
+		if err != nil {
+			WriteErrorResponse(w, r, err)
+			return
+		}
+	} else {
+		WriteErrorResponse(w, r, ErrInvalidTaggingDirective)
+		return
+	}
+
+	// object lock is not copied from source
+	targetObject.ObjectLock, err = ParseObjectLockHeaders(r.Header, time.Now().UTC())
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
 	var isMetadataOnly bool
 	isMetadataOnly = false
 	if sourceBucketName == targetBucketName && sourceObjectName == targetObjectName {
@@ -649,7 +684,7 @@ func (api ObjectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 	}
 
 	// Create the object.
-	result, err := api.ObjectAPI.CopyObject(targetObject, truelySourceObject, pipeReader, credential, sseRequest, isMetadataOnly)
+	result, err := api.ObjectAPI.CopyObject(targetObject, truelySourceObject, sourceReader, credential, sseRequest, isMetadataOnly)
 	if err != nil {
 		logger.Error("CopyObject failed:", err)
 		WriteErrorResponse(w, r, err)
@@ -668,6 +703,8 @@ func (api ObjectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 	if result.VersionId != "" {
 		w.Header().Set("x-amz-version-id", result.VersionId)
 	}
+	SetExpirationHeader(w, getRequestContext(r).BucketInfo, targetObject.Name, targetObject.Size,
+		targetObject.Tags, result.LastModified)
 	// Set SSE related headers
 	for _, headerName := range []string{
 		"X-Amz-Server-Side-Encryption",
@@ -693,6 +730,8 @@ func (api ObjectAPIHandlers) CopyObjectHandler(w http.ResponseWriter, r *http.Re
 // ----------
 // Do not support bucket to enable multiVersion renaming;
 // Folder renaming operation is not supported.
+// Objects are only renamed within a bucket, to move an object to another
+// bucket, CopyObject it, which shares data with the source, then delete it.
 func (api ObjectAPIHandlers) RenameObjectHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := getRequestContext(r)
 	logger := ctx.Logger
@@ -891,15 +930,28 @@ func (api ObjectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	tags, err := ParseTaggingHeader(r.Header.Get("X-Amz-Tagging"))
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	objectLock, err := ParseObjectLockHeaders(r.Header, time.Now().UTC())
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
 	credential, dataReadCloser, err := signature.VerifyUpload(r)
 	if err != nil {
 		WriteErrorResponse(w, r, err)
 		return
 	}
+	markReplicaMetadata(metadata, r.Header, credential)
 
 	var result PutObjectResult
 	result, err = api.ObjectAPI.PutObject(bucketName, objectName, credential, size, dataReadCloser,
-		metadata, acl, sseRequest, storageClass)
+		metadata, acl, sseRequest, storageClass, tags, objectLock)
 	logger.Info("Value of result:", result)
 	logger.Info("value of error:", err)
 	if err != nil {
@@ -914,6 +966,8 @@ func (api ObjectAPIHandlers) PutObjectHandler(w http.ResponseWriter, r *http.Req
 	if result.VersionId != "" {
 		w.Header().Set("x-amz-version-id", result.VersionId)
 	}
+	SetExpirationHeader(w, getRequestContext(r).BucketInfo, objectName, size, tags,
+		result.LastModified)
 	// Set SSE related headers
 	for _, headerName := range []string{
 		"X-Amz-Server-Side-Encryption",
@@ -996,6 +1050,12 @@ func (api ObjectAPIHandlers) AppendObjectHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// appendable objects are modified in place, which breaks WORM
+	if ctx.BucketInfo != nil && ctx.BucketInfo.ObjectLock.IsEnabled() {
+		WriteErrorResponse(w, r, ErrObjectLockAppendNotSupported)
+		return
+	}
+
 	// Save metadata.
 	metadata := extractMetadataFromHeader(r.Header)
 	// Get Content-Md5 sent by client and verify if valid
@@ -1277,67 +1337,62 @@ func (api ObjectAPIHandlers) RestoreObjectHandler(w http.ResponseWriter, r *http
 	}
 
 	info, err := GetRestoreInfo(r)
-	if err != nil {
+	if err != nil || info.Days < 1 {
 		logger.Error("Unable to get freezer info:", err)
 		WriteErrorResponse(w, r, ErrInvalidRestoreInfo)
+		return
 	}
-
-	freezer, err := api.ObjectAPI.GetFreezerStatus(object.BucketName, object.Name, object.VersionId)
-	if err != nil && err != ErrNoSuchKey {
-		logger.Error("Unable to get restore object status", object.BucketName, object.Name,
-			"error:", err)
+	tier, err := meta.MatchRestoreTier(info.GlacierJobParameters.Tier)
+	if err != nil {
 		WriteErrorResponse(w, r, err)
+		return
 	}
-	if err == ErrNoSuchKey || freezer.Name == "" {
-		status, err := meta.MatchStatusIndex("READY")
-		if err != nil {
-			logger.Error("Unable to get freezer status:", err)
-			WriteErrorResponse(w, r, ErrInvalidRestoreInfo)
-		}
 
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "RestoreObject"
+
+	freezer, err := api.ObjectAPI.GetFreezerStatus(object.BucketName, object.Name, object.VersionId)
+	if err == ErrNoSuchKey {
 		lifeTime := info.Days
-		if lifeTime < 1 || lifeTime > 30 {
-			lifeTime = 1
+		if lifeTime > helper.CONFIG.RestoreMaxDays {
+			lifeTime = helper.CONFIG.RestoreMaxDays
 		}
-
 		targetFreezer := &meta.Freezer{}
 		targetFreezer.BucketName = object.BucketName
 		targetFreezer.Name = object.Name
-		targetFreezer.Status = status
+		targetFreezer.Status = meta.ObjectNeedRestore
 		targetFreezer.LifeTime = lifeTime
+		targetFreezer.Tier = tier
+		targetFreezer.LastModifiedTime = time.Now().UTC()
 		err = api.ObjectAPI.CreateFreezer(targetFreezer)
 		if err != nil {
 			logger.Error("Unable to create freezer:", err)
 			WriteErrorResponse(w, r, ErrCreateRestoreObject)
+			return
 		}
 		logger.Info("Submit thaw request successfully")
-
-		// ResponseRecorder
-		w.WriteHeader(http.StatusAccepted)
-		w.(*ResponseRecorder).operationName = "RestoreObject"
-
 		WriteSuccessResponseWithStatus(w, nil, http.StatusAccepted)
+		return
 	}
-	if freezer.Status == meta.ObjectHasRestored {
-		err = api.ObjectAPI.UpdateFreezerDate(freezer, info.Days, true)
+	if err != nil {
+		logger.Error("Unable to get restore object status", object.BucketName, object.Name,
+			"error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	// Restore request of a restored object only extends the lifetime of its copy
+	if freezer.LifeTime != info.Days || freezer.Status == meta.ObjectHasRestored {
+		err = api.ObjectAPI.UpdateFreezerDate(freezer, info.Days)
 		if err != nil {
 			logger.Error("Unable to Update freezer date:", err)
 			WriteErrorResponse(w, r, ErrInvalidRestoreInfo)
+			return
 		}
-
-		// ResponseRecorder
-		w.(*ResponseRecorder).operationName = "RestoreObject"
+	}
+	if freezer.Status == meta.ObjectHasRestored {
 		WriteSuccessResponse(w, nil)
 	} else {
-		if freezer.LifeTime != info.Days {
-			err = api.ObjectAPI.UpdateFreezerDate(freezer, info.Days, false)
-			if err != nil {
-				logger.Error("Unable to Update freezer date:", err)
-				WriteErrorResponse(w, r, ErrInvalidRestoreInfo)
-			}
-		}
-		// ResponseRecorder
-		w.(*ResponseRecorder).operationName = "RestoreObject"
 		WriteSuccessResponseWithStatus(w, nil, http.StatusAccepted)
 	}
 }
@@ -1431,6 +1486,7 @@ func (api ObjectAPIHandlers) NewMultipartUploadHandler(w http.ResponseWriter, r
 
 	// Save metadata.
 	metadata := extractMetadataFromHeader(r.Header)
+	markReplicaMetadata(metadata, r.Header, credential)
 
 	var sseRequest SseRequest
 	if hasServerSideEncryptionHeader(r.Header) && !hasSuffix(objectName, "/") { // handle SSE requests
@@ -1452,8 +1508,20 @@ func (api ObjectAPIHandlers) NewMultipartUploadHandler(w http.ResponseWriter, r
 		return
 	}
 
+	tags, err := ParseTaggingHeader(r.Header.Get("X-Amz-Tagging"))
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	objectLock, err := ParseObjectLockHeaders(r.Header, time.Now().UTC())
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
 	uploadID, err := api.ObjectAPI.NewMultipartUpload(credential, bucketName, objectName,
-		metadata, acl, sseRequest, storageClass)
+		metadata, acl, sseRequest, storageClass, tags, objectLock)
 	if err != nil {
 		logger.Error("Unable to initiate new multipart upload id:", err)
 		WriteErrorResponse(w, r, err)
@@ -2017,9 +2085,12 @@ func (api ObjectAPIHandlers) DeleteObjectHandler(w http.ResponseWriter, r *http.
 		}
 	}
 	version := r.URL.Query().Get("versionId")
+	bypassGovernance := isBypassGovernanceAllowed(r, credential, getRequestContext(r).BucketInfo,
+		objectName)
 	// http://docs.aws.amazon.com/AmazonS3/latest/API/RESTObjectDELETE.html
 	// Ignore delete object errors, since we are supposed to reply only 204.
-	result, err := api.ObjectAPI.DeleteObject(bucketName, objectName, version, credential)
+	result, err := api.ObjectAPI.DeleteObject(bucketName, objectName, version, bypassGovernance,
+		isReplicaRequest(r.Header, credential), credential)
 	if err != nil {
 		WriteErrorResponse(w, r, err)
 		return
@@ -2145,8 +2216,25 @@ func (api ObjectAPIHandlers) PostObjectHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// form field "tagging" is a Tagging XML document, rather than
+	// URL query encoded as header "x-amz-tagging"
+	var tags map[string]string
+	if tagging := headerfiedFormValues.Get("Tagging"); tagging != "" {
+		tags, err = ParseTagging(strings.NewReader(tagging))
+		if err != nil {
+			WriteErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	objectLock, err := ParseObjectLockHeaders(headerfiedFormValues, time.Now().UTC())
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
 	result, err := api.ObjectAPI.PutObject(bucketName, objectName, credential, -1, fileBody,
-		metadata, acl, sseRequest, storageClass)
+		metadata, acl, sseRequest, storageClass, tags, objectLock)
 	if err != nil {
 		logger.Error("Unable to create object", objectName, "error:", err)
 		WriteErrorResponse(w, r, err)
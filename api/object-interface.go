@@ -18,6 +18,7 @@ package api
 
 import (
 	"io"
+	"time"
 
 	"github.com/journeymidnight/yig/api/datatype"
 	"github.com/journeymidnight/yig/api/datatype/policy"
@@ -28,7 +29,7 @@ import (
 // ObjectLayer implements primitives for object API layer.
 type ObjectLayer interface {
 	// Bucket operations.
-	MakeBucket(bucket string, acl datatype.Acl, credential common.Credential) error
+	MakeBucket(bucket string, acl datatype.Acl, objectLockEnabled bool, credential common.Credential) error
 	SetBucketLogging(bucket string, config datatype.BucketLoggingStatus) error
 	GetBucketLogging(bucket string) (datatype.BucketLoggingStatus, error)
 	SetBucketLifecycle(bucket string, config datatype.Lifecycle,
@@ -63,6 +64,24 @@ type ObjectLayer interface {
 	GetBucketWebsite(bucket string) (datatype.WebsiteConfiguration, error)
 	DeleteBucketWebsite(bucket *meta.Bucket) error
 
+	// Tagging operations
+	SetBucketTagging(bucket *meta.Bucket, tags map[string]string) error
+	GetBucketTagging(bucket string) (map[string]string, error)
+	DeleteBucketTagging(bucket *meta.Bucket) error
+
+	// Notification operations
+	SetBucketNotification(bucket *meta.Bucket, config datatype.NotificationConfiguration) error
+	GetBucketNotification(bucket string) (datatype.NotificationConfiguration, error)
+
+	// Replication operations
+	SetBucketReplication(bucket *meta.Bucket, config datatype.ReplicationConfiguration) error
+	GetBucketReplication(bucket string) (datatype.ReplicationConfiguration, error)
+	DeleteBucketReplication(bucket *meta.Bucket) error
+
+	// Object lock operations
+	SetBucketObjectLock(bucket *meta.Bucket, config datatype.ObjectLockConfiguration) error
+	GetBucketObjectLock(bucket string) (datatype.ObjectLockConfiguration, error)
+
 	// Encryption operations
 	SetBucketEncryption(bucket *meta.Bucket, config datatype.EncryptionConfiguration) error
 	GetBucketEncryption(bucket string) (datatype.EncryptionConfiguration, error)
@@ -75,8 +94,9 @@ type ObjectLayer interface {
 	GetObjectInfo(bucket, object, version string, credential common.Credential) (objInfo *meta.Object, err error)
 	GetObjectInfoByCtx(ctx RequestContext, version string, credential common.Credential) (objInfo *meta.Object, err error)
 	PutObject(bucket, object string, credential common.Credential, size int64, data io.ReadCloser,
-		metadata map[string]string, acl datatype.Acl,
-		sse datatype.SseRequest, storageClass meta.StorageClass) (result datatype.PutObjectResult, err error)
+		metadata map[string]string, acl datatype.Acl, sse datatype.SseRequest,
+		storageClass meta.StorageClass, tags map[string]string,
+		objectLock datatype.ObjectLock) (result datatype.PutObjectResult, err error)
 	AppendObject(bucket, object string, credential common.Credential, offset uint64, size int64, data io.ReadCloser,
 		metadata map[string]string, acl datatype.Acl,
 		sse datatype.SseRequest, storageClass meta.StorageClass, objInfo *meta.Object) (result datatype.AppendObjectResult, err error)
@@ -89,15 +109,29 @@ type ObjectLayer interface {
 		acl datatype.Acl, credential common.Credential) error
 	GetObjectAcl(bucket string, object string, version string, credential common.Credential) (
 		policy datatype.AccessControlPolicyResponse, err error)
-	DeleteObject(bucket, object, version string, credential common.Credential) (datatype.DeleteObjectResult,
-		error)
+	DeleteObject(bucket, object, version string, bypassGovernance bool, replica bool,
+		credential common.Credential) (datatype.DeleteObjectResult, error)
+	GetObjectTagging(bucket, object, version string, credential common.Credential) (
+		tags map[string]string, err error)
+	PutObjectTagging(bucket, object, version string, tags map[string]string,
+		credential common.Credential) error
+	DeleteObjectTagging(bucket, object, version string, credential common.Credential) error
+	GetObjectRetention(bucket, object, version string, credential common.Credential) (
+		retention datatype.ObjectLockRetention, err error)
+	PutObjectRetention(bucket, object, version string, mode string, retainUntil time.Time,
+		bypassGovernance bool, credential common.Credential) error
+	GetObjectLegalHold(bucket, object, version string, credential common.Credential) (
+		legalHold datatype.ObjectLegalHold, err error)
+	PutObjectLegalHold(bucket, object, version string, legalHold bool,
+		credential common.Credential) error
 
 	// Multipart operations.
 	ListMultipartUploads(credential common.Credential, bucket string,
 		request datatype.ListUploadsRequest) (result datatype.ListMultipartUploadsResponse, err error)
 	NewMultipartUpload(credential common.Credential, bucket, object string,
-		metadata map[string]string, acl datatype.Acl,
-		sse datatype.SseRequest, storageClass meta.StorageClass) (uploadID string, err error)
+		metadata map[string]string, acl datatype.Acl, sse datatype.SseRequest,
+		storageClass meta.StorageClass, tags map[string]string,
+		objectLock datatype.ObjectLock) (uploadID string, err error)
 	PutObjectPart(bucket, object string, credential common.Credential, uploadID string, partID int,
 		size int64, data io.ReadCloser, md5Hex string,
 		sse datatype.SseRequest) (result datatype.PutObjectPartResult, err error)
@@ -114,5 +148,5 @@ type ObjectLayer interface {
 	GetFreezer(bucketName string, objectName string, version string) (freezer *meta.Freezer, err error)
 	GetFreezerStatus(bucketName string, objectName string, version string) (freezer *meta.Freezer, err error)
 	CreateFreezer(freezer *meta.Freezer) (err error)
-	UpdateFreezerDate(freezer *meta.Freezer, date int, isIncrement bool) (err error)
+	UpdateFreezerDate(freezer *meta.Freezer, days int) (err error)
 }
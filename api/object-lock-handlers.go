@@ -0,0 +1,158 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/journeymidnight/yig/api/datatype"
+	"github.com/journeymidnight/yig/api/datatype/policy"
+)
+
+// PutObjectRetentionHandler - PUT Object retention
+// ----------
+// Replaces retention of the object, or of the version specified by
+// "versionId". Retention in governance mode could be shortened or removed
+// with x-amz-bypass-governance-retention.
+func (api ObjectAPIHandlers) PutObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	credential, err := checkRequestAuth(r, policy.PutObjectRetentionAction)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	mode, retainUntil, err := ParseObjectLockRetention(r.Body, time.Now().UTC())
+	if err != nil {
+		logger.Error("Unable to parse retention body:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	bypassGovernance := isBypassGovernanceAllowed(r, credential, ctx.BucketInfo, ctx.ObjectName)
+	err = api.ObjectAPI.PutObjectRetention(ctx.BucketName, ctx.ObjectName, version, mode,
+		retainUntil, bypassGovernance, credential)
+	if err != nil {
+		logger.Error("Unable to set retention for object", ctx.ObjectName, "error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "PutObjectRetention"
+
+	WriteSuccessResponse(w, nil)
+}
+
+// GetObjectRetentionHandler - GET Object retention
+// ----------
+// Returns retention of the object, or of the version specified by "versionId".
+func (api ObjectAPIHandlers) GetObjectRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	credential, err := checkRequestAuth(r, policy.GetObjectRetentionAction)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	retention, err := api.ObjectAPI.GetObjectRetention(ctx.BucketName, ctx.ObjectName, version,
+		credential)
+	if err != nil {
+		logger.Error("Unable to get retention for object", ctx.ObjectName, "error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+	retention.Xmlns = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+	encodedSuccessResponse := EncodeResponse(retention)
+	setXmlHeader(w)
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "GetObjectRetention"
+
+	WriteSuccessResponse(w, encodedSuccessResponse)
+}
+
+// PutObjectLegalHoldHandler - PUT Object legal hold
+// ----------
+// Turns legal hold of the object, or of the version specified by "versionId",
+// on or off.
+func (api ObjectAPIHandlers) PutObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	credential, err := checkRequestAuth(r, policy.PutObjectLegalHoldAction)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	legalHold, err := ParseObjectLegalHold(r.Body)
+	if err != nil {
+		logger.Error("Unable to parse legal hold body:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	err = api.ObjectAPI.PutObjectLegalHold(ctx.BucketName, ctx.ObjectName, version, legalHold,
+		credential)
+	if err != nil {
+		logger.Error("Unable to set legal hold for object", ctx.ObjectName, "error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "PutObjectLegalHold"
+
+	WriteSuccessResponse(w, nil)
+}
+
+// GetObjectLegalHoldHandler - GET Object legal hold
+// ----------
+// Returns legal hold status of the object, or of the version specified by
+// "versionId".
+func (api ObjectAPIHandlers) GetObjectLegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	credential, err := checkRequestAuth(r, policy.GetObjectLegalHoldAction)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	legalHold, err := api.ObjectAPI.GetObjectLegalHold(ctx.BucketName, ctx.ObjectName, version,
+		credential)
+	if err != nil {
+		logger.Error("Unable to get legal hold for object", ctx.ObjectName, "error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+	legalHold.Xmlns = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+	encodedSuccessResponse := EncodeResponse(legalHold)
+	setXmlHeader(w)
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "GetObjectLegalHold"
+
+	WriteSuccessResponse(w, encodedSuccessResponse)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/journeymidnight/yig/api/datatype"
+	"github.com/journeymidnight/yig/api/datatype/policy"
+)
+
+// PutObjectTaggingHandler - PUT Object tagging
+// ----------
+// Replaces tag set of the object, or of the version specified by "versionId".
+func (api ObjectAPIHandlers) PutObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	// parse tags before auth, so policy conditions on request tags could be checked
+	tags, err := ParseTagging(r.Body)
+	if err != nil {
+		logger.Error("Unable to parse tagging body:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), RequestTagsKey, tags))
+
+	credential, err := checkRequestAuth(r, policy.PutObjectTaggingAction)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	err = api.ObjectAPI.PutObjectTagging(ctx.BucketName, ctx.ObjectName, version, tags, credential)
+	if err != nil {
+		logger.Error("Unable to set tagging for object", ctx.ObjectName, "error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "PutObjectTagging"
+
+	WriteSuccessResponse(w, nil)
+}
+
+// GetObjectTaggingHandler - GET Object tagging
+// ----------
+// Returns tag set of the object, or of the version specified by "versionId".
+func (api ObjectAPIHandlers) GetObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	credential, err := checkRequestAuth(r, policy.GetObjectTaggingAction)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	tags, err := api.ObjectAPI.GetObjectTagging(ctx.BucketName, ctx.ObjectName, version, credential)
+	if err != nil {
+		logger.Error("Unable to get tagging for object", ctx.ObjectName, "error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+
+	encodedSuccessResponse := EncodeResponse(TaggingFromMap(tags))
+	setXmlHeader(w)
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "GetObjectTagging"
+
+	WriteSuccessResponse(w, encodedSuccessResponse)
+}
+
+// DeleteObjectTaggingHandler - DELETE Object tagging
+// ----------
+// Removes all tags of the object, or of the version specified by "versionId".
+func (api ObjectAPIHandlers) DeleteObjectTaggingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := getRequestContext(r)
+	logger := ctx.Logger
+
+	credential, err := checkRequestAuth(r, policy.DeleteObjectTaggingAction)
+	if err != nil {
+		WriteErrorResponse(w, r, err)
+		return
+	}
+
+	version := r.URL.Query().Get("versionId")
+	err = api.ObjectAPI.DeleteObjectTagging(ctx.BucketName, ctx.ObjectName, version, credential)
+	if err != nil {
+		logger.Error("Unable to delete tagging for object", ctx.ObjectName, "error:", err)
+		WriteErrorResponse(w, r, err)
+		return
+	}
+	if version != "" {
+		w.Header().Set("x-amz-version-id", version)
+	}
+
+	// ResponseRecorder
+	w.(*ResponseRecorder).operationName = "DeleteObjectTagging"
+
+	WriteSuccessNoContent(w)
+}
@@ -24,6 +24,11 @@ type ContextLoggerKeyType string
 
 const ContextLoggerKey ContextLoggerKeyType = "ContextLogger"
 
+type RequestTagsKeyType string
+
+// Object tags in request body, e.g. PutObjectTagging, rather than in header x-amz-tagging
+const RequestTagsKey RequestTagsKeyType = "RequestTags"
+
 type RequestContext struct {
 	RequestID      string
 	Logger         log.Logger
@@ -33,6 +38,8 @@ type RequestContext struct {
 	ObjectInfo     *types.Object
 	AuthType       signature.AuthType
 	IsBucketDomain bool
+	// version of query versionId, nil if versionId is not set or not found
+	ObjectVersionInfo *types.Object
 }
 
 type Server struct {
@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/journeymidnight/yig/crypto"
 )
@@ -135,3 +136,16 @@ func setXmlHeader(w http.ResponseWriter) {
 func hasServerSideEncryptionHeader(header http.Header) bool {
 	return crypto.S3.IsRequested(header) || crypto.SSEC.IsRequested(header)
 }
+
+// lazyReader calls start once before the first Read, so data behind it is
+// not fetched if it's never read
+type lazyReader struct {
+	once   sync.Once
+	start  func()
+	reader io.Reader
+}
+
+func (r *lazyReader) Read(p []byte) (int, error) {
+	r.once.Do(r.start)
+	return r.reader.Read(p)
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/journeymidnight/yig/helper"
 	"github.com/journeymidnight/yig/log"
 	"io"
+	"sort"
+	"time"
 )
 
 const (
@@ -12,6 +14,26 @@ const (
 	GLACIER_FILE_POOLNAME = "turtle"
 )
 
+// Pools data could be written to, i.e the default pools above and
+// pools configured for storage classes in yig.toml
+func Pools() []string {
+	pools := []string{SMALL_FILE_POOLNAME, BIG_FILE_POOLNAME, GLACIER_FILE_POOLNAME}
+	var classes []string
+	for class := range helper.CONFIG.StorageClasses {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		config := helper.CONFIG.StorageClasses[class]
+		for _, pool := range []string{config.SmallFilePool, config.BigFilePool} {
+			if pool != "" && !helper.StringInSlice(pool, pools) {
+				pools = append(pools, pool)
+			}
+		}
+	}
+	return pools
+}
+
 type Usage struct {
 	UsedSpacePercent int // range 0 ~ 100
 }
@@ -34,6 +56,22 @@ type Cluster interface {
 	Remove(poolName, objectName string) error
 }
 
+// Clusters able to enumerate their objects could implement this interface,
+// used by tools/orphan to find data no longer referenced by meta
+type Lister interface {
+	// call walkFn for every object in pool with its last modified time,
+	// stop and return the error if walkFn returns one
+	ListObjects(poolName string,
+		walkFn func(objectName string, modifiedTime time.Time) error) error
+}
+
+// Clusters able to copy data internally could implement this interface,
+// so copying objects inside a cluster does not stream data through yig
+type Copier interface {
+	// copy the whole object to dstPool of the same cluster as a new object
+	Copy(srcPool, srcName, dstPool string) (objectName string, size uint64, err error)
+}
+
 // Backend plugins should implement this interface
 type Plugin interface {
 	// initialize backend cluster handlers,
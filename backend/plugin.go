@@ -0,0 +1,36 @@
+package backend
+
+import (
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/mods"
+)
+
+// Initialize clusters from all enabled BACKEND_PLUGIN plugins.
+// The plugin's Create function should return a Plugin, whose Initialize
+// is then called with the global logger and config.
+func InitializeFromPlugins(plugins map[string]*mods.YigPlugin) map[string]Cluster {
+	clusters := make(map[string]Cluster)
+	for name, p := range plugins {
+		if p.PluginType != mods.BACKEND_PLUGIN {
+			continue
+		}
+		c, err := p.Create(helper.CONFIG.Plugins[name].Args)
+		if err != nil {
+			helper.Logger.Error("failed to initial backend plugin:", name, "\nerr:", err)
+			continue
+		}
+		plugin, ok := c.(Plugin)
+		if !ok {
+			helper.Logger.Error("backend plugin", name, "does not implement backend.Plugin")
+			continue
+		}
+		for id, cluster := range plugin.Initialize(&helper.Logger, helper.CONFIG) {
+			if _, ok := clusters[id]; ok {
+				panic("Duplicated backend cluster ID " + id + " from plugin " + name)
+			}
+			clusters[id] = cluster
+		}
+		helper.Logger.Info("Backend plugin", name, "is loaded")
+	}
+	return clusters
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"time"
 )
@@ -25,6 +26,8 @@ const (
 	MIN_CHUNK_SIZE             = 512 << 10       // 512K
 	BUFFER_SIZE                = 1 << 20         // 1M
 	MAX_CHUNK_SIZE             = 8 * BUFFER_SIZE // 8M
+	// striped objects are stored as rados objects named <oid>.<16 hex digits of index>
+	FIRST_STRIPE_SUFFIX = ".0000000000000000"
 )
 
 func Initialize(config helper.Config) map[string]backend.Cluster {
@@ -349,7 +352,7 @@ func (cluster *CephCluster) Append(poolname string, existName string, data io.Re
 	if len(oid) == 0 {
 		oid = cluster.getUniqUploadName()
 	}
-	if poolname != backend.BIG_FILE_POOLNAME {
+	if poolname == backend.SMALL_FILE_POOLNAME {
 		return oid, 0,
 			errors.New("specified pool must be used for storing big file.")
 	}
@@ -572,3 +575,132 @@ func (cluster *CephCluster) GetUsage() (usage backend.Usage, err error) {
 	usage.UsedSpacePercent = int(stat.Kb_used * uint64(100) / stat.Kb)
 	return
 }
+
+// Objects in pools other than SMALL_FILE_POOLNAME are striped, list the first
+// rados object of each to get the object names.
+func (cluster *CephCluster) ListObjects(poolName string,
+	walkFn func(objectName string, modifiedTime time.Time) error) error {
+
+	pool, err := cluster.Conn.OpenPool(poolName)
+	if err != nil {
+		return fmt.Errorf("Bad poolname %s", poolName)
+	}
+	defer pool.Destroy()
+
+	striped := poolName != backend.SMALL_FILE_POOLNAME
+	var walkErr error
+	err = pool.ListObjects(func(rawName string) {
+		if walkErr != nil {
+			return
+		}
+		oid := rawName
+		if striped {
+			if !strings.HasSuffix(rawName, FIRST_STRIPE_SUFFIX) {
+				return
+			}
+			oid = strings.TrimSuffix(rawName, FIRST_STRIPE_SUFFIX)
+		}
+		_, mtime, err := pool.Stat(rawName)
+		if err != nil {
+			// removed during listing
+			return
+		}
+		walkErr = walkFn(oid, time.Unix(int64(mtime), 0))
+	})
+	if err != nil {
+		return err
+	}
+	return walkErr
+}
+
+// Name of the index-th rados object of striped object oid, see libradosstriper
+func stripeObjectName(oid string, index uint64) string {
+	return fmt.Sprintf("%s.%016x", oid, index)
+}
+
+// Number of rados objects of a striped object written with the layout of
+// setStripeLayout, the first one always exists since it keeps the size
+func stripeObjectCount(size uint64) uint64 {
+	units := (size + STRIPE_UNIT - 1) / STRIPE_UNIT
+	if units == 0 {
+		return 1
+	}
+	unitsPerSet := uint64(STRIPE_COUNT * (OBJECT_SIZE / STRIPE_UNIT))
+	count := units / unitsPerSet * STRIPE_COUNT
+	if rest := units % unitsPerSet; rest > STRIPE_COUNT {
+		count += STRIPE_COUNT
+	} else {
+		count += rest
+	}
+	return count
+}
+
+func (cluster *CephCluster) objectSize(poolName string, oid string) (size uint64, err error) {
+	pool, err := cluster.Conn.OpenPool(poolName)
+	if err != nil {
+		return 0, fmt.Errorf("Bad poolname %s", poolName)
+	}
+	defer pool.Destroy()
+	if poolName == backend.SMALL_FILE_POOLNAME {
+		size, _, err = pool.Stat(oid)
+		return
+	}
+	striper, err := pool.CreateStriper()
+	if err != nil {
+		return 0, fmt.Errorf("Bad ioctx of pool %s", poolName)
+	}
+	defer striper.Destroy()
+	size, _, err = striper.State(oid)
+	return
+}
+
+// Rados objects are copied by OSDs with copy-from, data never leaves the
+// cluster. Objects moved between SMALL_FILE_POOLNAME and striped pools are
+// streamed instead, since their layouts differ.
+func (cluster *CephCluster) Copy(srcPool, srcName, dstPool string) (oid string,
+	size uint64, err error) {
+
+	size, err = cluster.objectSize(srcPool, srcName)
+	if err != nil {
+		return "", 0, err
+	}
+	striped := srcPool != backend.SMALL_FILE_POOLNAME
+	if striped != (dstPool != backend.SMALL_FILE_POOLNAME) {
+		reader, err := cluster.GetReader(srcPool, srcName, 0, size)
+		if err != nil {
+			return "", 0, err
+		}
+		defer reader.Close()
+		return cluster.Put(dstPool, reader)
+	}
+
+	src, err := cluster.Conn.OpenPool(srcPool)
+	if err != nil {
+		return "", 0, fmt.Errorf("Bad poolname %s", srcPool)
+	}
+	defer src.Destroy()
+	dst, err := cluster.Conn.OpenPool(dstPool)
+	if err != nil {
+		return "", 0, fmt.Errorf("Bad poolname %s", dstPool)
+	}
+	defer dst.Destroy()
+
+	oid = cluster.getUniqUploadName()
+	if !striped {
+		err = dst.CopyFrom(oid, src, srcName)
+		return oid, size, err
+	}
+	// the first object keeps the size, copy it last
+	count := stripeObjectCount(size)
+	for i := count; i > 0; i-- {
+		err = dst.CopyFrom(stripeObjectName(oid, i-1), src, stripeObjectName(srcName, i-1))
+		if err != nil {
+			for j := i; j < count; j++ {
+				dst.Delete(stripeObjectName(oid, j))
+			}
+			return "", 0, fmt.Errorf("copy %s of pool %s to pool %s failed: %v",
+				srcName, srcPool, dstPool, err)
+		}
+	}
+	return oid, size, nil
+}
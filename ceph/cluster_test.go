@@ -2,6 +2,7 @@ package ceph_test
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/journeymidnight/yig/backend"
 	"github.com/journeymidnight/yig/ceph"
 	"github.com/journeymidnight/yig/helper"
@@ -92,3 +93,73 @@ func BenchmarkCephCluster_Put(b *testing.B) {
 		}
 	})
 }
+
+func TestCephCluster_Copy(t *testing.T) {
+	var copies []string
+	cluster := SetupMockCeph()
+	conn := cluster.Conn.(MockRadosConn)
+	conn.MockPool.Copies = &copies
+	conn.MockPool.ObjectSize = 100 << 10
+	conn.MockPool.MockStriper.ObjectSize = 20 << 20
+	cluster.Conn = conn
+
+	oid, size, err := cluster.Copy(backend.SMALL_FILE_POOLNAME, "small", backend.SMALL_FILE_POOLNAME)
+	if err != nil {
+		t.Fatal("Copy error:", err)
+	}
+	if size != 100<<10 || len(copies) != 1 || copies[0] != oid {
+		t.Fatal("Bad copy of small object:", oid, size, copies)
+	}
+
+	// 20M is striped into 4 rados objects, 2 in each 16M object set
+	copies = nil
+	oid, size, err = cluster.Copy(backend.BIG_FILE_POOLNAME, "big", backend.GLACIER_FILE_POOLNAME)
+	if err != nil {
+		t.Fatal("Copy error:", err)
+	}
+	if size != 20<<20 || len(copies) != 4 {
+		t.Fatal("Bad copy of striped object:", oid, size, copies)
+	}
+	for i, name := range copies {
+		// the first object is copied last
+		expected := fmt.Sprintf("%s.%016x", oid, 3-i)
+		if name != expected {
+			t.Fatal("Copied", name, "expected", expected)
+		}
+	}
+}
+
+func TestCephCluster_ListObjects(t *testing.T) {
+	cluster := SetupMockCeph()
+	conn := cluster.Conn.(MockRadosConn)
+	conn.MockPool.Objects = []string{
+		"a.0000000000000000", "a.0000000000000001", "b.0000000000000000",
+	}
+	cluster.Conn = conn
+
+	var listed []string
+	err := cluster.ListObjects(backend.BIG_FILE_POOLNAME,
+		func(objectName string, modifiedTime time.Time) error {
+			listed = append(listed, objectName)
+			return nil
+		})
+	if err != nil {
+		t.Fatal("ListObjects error:", err)
+	}
+	if len(listed) != 2 || listed[0] != "a" || listed[1] != "b" {
+		t.Fatal("Bad objects of striped pool:", listed)
+	}
+
+	listed = nil
+	err = cluster.ListObjects(backend.SMALL_FILE_POOLNAME,
+		func(objectName string, modifiedTime time.Time) error {
+			listed = append(listed, objectName)
+			return nil
+		})
+	if err != nil {
+		t.Fatal("ListObjects error:", err)
+	}
+	if len(listed) != 3 {
+		t.Fatal("Bad objects of small file pool:", listed)
+	}
+}
@@ -18,6 +18,12 @@ type Pool interface {
 	Destroy()
 	CreateStriper() (StriperPool, error)
 	WriteSmallObject(oid string, data []byte) error
+	// size and modified time(unix seconds) of a raw rados object
+	Stat(oid string) (size uint64, mtime uint64, err error)
+	// call listFn for every raw rados object in pool
+	ListObjects(listFn func(oid string)) error
+	// copy raw rados object srcOid of pool src to dstOid inside the cluster
+	CopyFrom(dstOid string, src Pool, srcOid string) error
 }
 
 type StriperPool interface {
@@ -25,6 +31,7 @@ type StriperPool interface {
 	Write(oid string, data []byte, offset uint64) (int, error)
 	WriteAIO(oid string, data []byte, offset uint64) (AioCompletion, error)
 	Delete(oid string) error
+	State(oid string) (size uint64, mtime uint64, err error)
 	Destroy()
 	SetLayoutStripeUnit(uint uint) int
 	SetLayoutStripeCount(count uint) int
@@ -0,0 +1,13 @@
+#include <rados/librados.hpp>
+#include "rados_copy.h"
+
+int rados_copy_object(rados_ioctx_t dst_io, const char *dst, rados_ioctx_t src_io, const char *src)
+{
+	librados::IoCtx dst_ctx, src_ctx;
+	librados::IoCtx::from_rados_ioctx_t(dst_io, dst_ctx);
+	librados::IoCtx::from_rados_ioctx_t(src_io, src_ctx);
+
+	librados::ObjectWriteOperation op;
+	op.copy_from(src, src_ctx, 0, 0);
+	return dst_ctx.operate(dst, &op);
+}
@@ -0,0 +1,38 @@
+package ceph
+
+// #cgo LDFLAGS: -lrados -lstdc++
+// #include <stdlib.h>
+// #include "rados_copy.h"
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/journeymidnight/radoshttpd/rados"
+)
+
+// radoshttpd has no accessor of the rados_ioctx_t of a pool, so it's read
+// through the private layout of rados.Pool, which in v0.0.7 pinned by go.mod
+// holds nothing but the rados_ioctx_t. TestRadosPoolLayout fails if an upgrade
+// of radoshttpd changes the layout.
+func ioctxOf(p *rados.Pool) C.rados_ioctx_t {
+	return *(*C.rados_ioctx_t)(unsafe.Pointer(p))
+}
+
+func (p pool) CopyFrom(dstOid string, src Pool, srcOid string) error {
+	s, ok := src.(pool)
+	if !ok {
+		return errors.New("source is not a rados pool")
+	}
+	c_dst := C.CString(dstOid)
+	defer C.free(unsafe.Pointer(c_dst))
+	c_src := C.CString(srcOid)
+	defer C.free(unsafe.Pointer(c_src))
+
+	ret := C.rados_copy_object(ioctxOf(p.Pool), c_dst, ioctxOf(s.Pool), c_src)
+	if ret < 0 {
+		return rados.RadosError(int(ret))
+	}
+	return nil
+}
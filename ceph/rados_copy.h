@@ -0,0 +1,17 @@
+#ifndef RADOS_COPY_H
+#define RADOS_COPY_H
+
+#include <rados/librados.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+/* copy raw object src of src_io to dst of dst_io, data and xattrs are copied by OSDs */
+int rados_copy_object(rados_ioctx_t dst_io, const char *dst, rados_ioctx_t src_io, const char *src);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
@@ -0,0 +1,26 @@
+package ceph_test
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/journeymidnight/radoshttpd/rados"
+)
+
+// ioctxOf in rados_copy.go reads the rados_ioctx_t of rados.Pool through its
+// private layout, which must be a single pointer field
+func TestRadosPoolLayout(t *testing.T) {
+	typ := reflect.TypeOf(rados.Pool{})
+	if typ.NumField() != 1 {
+		t.Fatal("rados.Pool should have only 1 field, got", typ.NumField())
+	}
+	field := typ.Field(0)
+	if field.Name != "ioctx" || field.Offset != 0 || field.Type.Kind() != reflect.UnsafePointer {
+		t.Fatal("rados.Pool should hold rados_ioctx_t only, got field", field.Name,
+			field.Type.Kind())
+	}
+	if typ.Size() != unsafe.Sizeof(unsafe.Pointer(nil)) {
+		t.Fatal("rados.Pool should be of pointer size, got", typ.Size())
+	}
+}
@@ -0,0 +1,32 @@
+package ceph
+
+// #cgo LDFLAGS: -lrados
+// #include <errno.h>
+// #include <rados/librados.h>
+import "C"
+
+import (
+	"github.com/journeymidnight/radoshttpd/rados"
+)
+
+// radoshttpd does not wrap pool listing, list with rados_nobjects_list_* of librados
+func (p pool) ListObjects(listFn func(oid string)) error {
+	var ctx C.rados_list_ctx_t
+	ret := C.rados_nobjects_list_open(ioctxOf(p.Pool), &ctx)
+	if ret < 0 {
+		return rados.RadosError(int(ret))
+	}
+	defer C.rados_nobjects_list_close(ctx)
+
+	for {
+		var entry *C.char
+		ret = C.rados_nobjects_list_next(ctx, &entry, nil, nil)
+		if ret == -C.ENOENT {
+			return nil
+		}
+		if ret < 0 {
+			return rados.RadosError(int(ret))
+		}
+		listFn(C.GoString(entry))
+	}
+}
@@ -31,6 +31,9 @@ type MockPool struct {
 	MockStriper        MockStriperPool
 	FixedReadOverhead  time.Duration
 	FixedWriteOverhead time.Duration
+	ObjectSize         uint64    // size of every object
+	Copies             *[]string // destination objects copied to, if not nil
+	Objects            []string  // raw rados objects listed
 }
 
 func (p MockPool) Write(oid string, data []byte, offset uint64) error {
@@ -59,6 +62,27 @@ func (p MockPool) Destroy() {
 	return
 }
 
+func (p MockPool) Stat(oid string) (uint64, uint64, error) {
+	time.Sleep(p.FixedReadOverhead)
+	return p.ObjectSize, uint64(time.Now().Unix()), nil
+}
+
+func (p MockPool) ListObjects(listFn func(oid string)) error {
+	time.Sleep(p.FixedReadOverhead)
+	for _, oid := range p.Objects {
+		listFn(oid)
+	}
+	return nil
+}
+
+func (p MockPool) CopyFrom(dstOid string, src ceph.Pool, srcOid string) error {
+	time.Sleep(p.FixedWriteOverhead)
+	if p.Copies != nil {
+		*p.Copies = append(*p.Copies, dstOid)
+	}
+	return nil
+}
+
 func (p MockPool) CreateStriper() (ceph.StriperPool, error) {
 	return p.MockStriper, nil
 }
@@ -72,6 +96,7 @@ func (p MockPool) WriteSmallObject(oid string, data []byte) error {
 type MockStriperPool struct {
 	FixedReadOverhead  time.Duration
 	FixedWriteOverhead time.Duration
+	ObjectSize         uint64 // size of every object
 }
 
 func (sp MockStriperPool) Read(oid string, data []byte, offset uint64) (int, error) {
@@ -98,6 +123,11 @@ func (sp MockStriperPool) Delete(oid string) error {
 	return nil
 }
 
+func (sp MockStriperPool) State(oid string) (uint64, uint64, error) {
+	time.Sleep(sp.FixedReadOverhead)
+	return sp.ObjectSize, uint64(time.Now().Unix()), nil
+}
+
 func (sp MockStriperPool) Destroy() {
 	return
 }
@@ -2,27 +2,39 @@ package main
 
 import (
 	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/meta/types"
 	"github.com/journeymidnight/yig/redis"
 	"github.com/prometheus/client_golang/prometheus"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
 	PidUsagePrefix    = "u_p_" // User usage redis key prefix ,eg. u_p_hehehehe
 	BucketUsagePrefix = "u_b_" // Bucket usage redis ket prefix ,eg u_b_test
+	// lifecycle progress is read from table `lifecycle` at most once an interval,
+	// so scrapes don't page through the whole table
+	LifecycleMetricsInterval = 5 * time.Minute
 )
 
 type Metrics struct {
 	metrics map[string]*prometheus.Desc
 	mutex   sync.Mutex
+	// bucket tags exposed as labels of bucket_usage_byte_metric,
+	// fixed when the metrics are created
+	bucketTagKeys []string
+	// lifecycle progress of buckets and when it's read
+	lifecycles        []types.LifeCycle
+	lifecyclesRefresh time.Time
 }
 
 type UsageDataWithBucket struct {
 	value        int64
 	owner        string
 	storageClass string
+	tagValues    []string // values of Metrics.bucketTagKeys, empty if not tagged
 }
 
 type UsageData struct {
@@ -34,11 +46,38 @@ func newGlobalMetric(namespace string, metricName string, docString string, labe
 	return prometheus.NewDesc(namespace+"_"+metricName, docString, labels, nil)
 }
 
+// Convert tag key to a valid label name, e.g "cost-center" to "tag_cost_center"
+func tagLabelName(key string) string {
+	return "tag_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, key)
+}
+
 func NewMetrics(namespace string) *Metrics {
+	bucketLabels := []string{"bucket_name", "owner", "storage_class"}
+	var bucketTagKeys []string
+	seen := make(map[string]bool)
+	for _, key := range helper.CONFIG.MetricBucketTagKeys {
+		label := tagLabelName(key)
+		if seen[label] {
+			helper.Logger.Warn("Bucket tag", key, "ignored for metrics, duplicated label", label)
+			continue
+		}
+		seen[label] = true
+		bucketLabels = append(bucketLabels, label)
+		bucketTagKeys = append(bucketTagKeys, key)
+	}
 	return &Metrics{
+		bucketTagKeys: bucketTagKeys,
 		metrics: map[string]*prometheus.Desc{
-			"bucket_usage_byte_metric": newGlobalMetric(namespace, "bucket_usage_byte_metric", "The description of bucket_usage_byte_metric", []string{"bucket_name", "owner", "storage_class"}),
-			"user_usage_byte_metric":   newGlobalMetric(namespace, "user_usage_byte_metric", "The description of User_usage_byte_metric", []string{"owner_id", "storage_class"}),
+			"bucket_usage_byte_metric":      newGlobalMetric(namespace, "bucket_usage_byte_metric", "The description of bucket_usage_byte_metric", bucketLabels),
+			"user_usage_byte_metric":        newGlobalMetric(namespace, "user_usage_byte_metric", "The description of User_usage_byte_metric", []string{"owner_id", "storage_class"}),
+			"scrub_findings":                newGlobalMetric(namespace, "scrub_findings", "Number of problems found by data scrubber", []string{"cluster", "type"}),
+			"lifecycle_scanned_objects":     newGlobalMetric(namespace, "lifecycle_scanned_objects", "Object versions walked by lc in current or last pass of bucket", []string{"bucket_name"}),
+			"lifecycle_last_finish_seconds": newGlobalMetric(namespace, "lifecycle_last_finish_seconds", "Unix time lc last finished a pass of bucket", []string{"bucket_name"}),
 		},
 	}
 }
@@ -56,7 +95,8 @@ func (c *Metrics) Collect(ch chan<- prometheus.Metric) {
 	GaugeMetricDataForBucket := c.GenerateBucketUsageData()
 	for bucket, data := range GaugeMetricDataForBucket {
 		for _, v := range data {
-			ch <- prometheus.MustNewConstMetric(c.metrics["bucket_usage_byte_metric"], prometheus.GaugeValue, float64(v.value), bucket, v.owner, v.storageClass)
+			labelValues := append([]string{bucket, v.owner, v.storageClass}, v.tagValues...)
+			ch <- prometheus.MustNewConstMetric(c.metrics["bucket_usage_byte_metric"], prometheus.GaugeValue, float64(v.value), labelValues...)
 		}
 	}
 
@@ -66,6 +106,45 @@ func (c *Metrics) Collect(ch chan<- prometheus.Metric) {
 			ch <- prometheus.MustNewConstMetric(c.metrics["user_usage_byte_metric"], prometheus.GaugeValue, float64(v.value), uid, v.storageClass)
 		}
 	}
+
+	scrubFindings, err := adminServer.Yig.MetaStorage.CountScrubFindings()
+	if err != nil {
+		helper.Logger.Error("Get scrub findings for prometheus failed:", err.Error())
+		return
+	}
+	for _, v := range scrubFindings {
+		ch <- prometheus.MustNewConstMetric(c.metrics["scrub_findings"], prometheus.GaugeValue, float64(v.Count), v.Location, v.Type)
+	}
+
+	if time.Since(c.lifecyclesRefresh) >= LifecycleMetricsInterval {
+		lifecycles, err := listLifecycles()
+		if err != nil {
+			helper.Logger.Error("Get lifecycle progress for prometheus failed:", err.Error())
+		} else {
+			c.lifecycles, c.lifecyclesRefresh = lifecycles, time.Now()
+		}
+	}
+	for _, lc := range c.lifecycles {
+		ch <- prometheus.MustNewConstMetric(c.metrics["lifecycle_scanned_objects"], prometheus.GaugeValue, float64(lc.Scanned), lc.BucketName)
+		if !lc.LastFinish.IsZero() {
+			ch <- prometheus.MustNewConstMetric(c.metrics["lifecycle_last_finish_seconds"], prometheus.GaugeValue, float64(lc.LastFinish.Unix()), lc.BucketName)
+		}
+	}
+}
+
+func listLifecycles() (lifecycles []types.LifeCycle, err error) {
+	var marker string
+	for {
+		result, err := adminServer.Yig.MetaStorage.ScanLifeCycle(1000, marker)
+		if err != nil {
+			return nil, err
+		}
+		lifecycles = append(lifecycles, result.Lcs...)
+		if !result.Truncated {
+			return lifecycles, nil
+		}
+		marker = result.NextMarker
+	}
 }
 
 // Get bucket usage cache which like <key><value> = <u_b_test><STANDARD:233333>
@@ -91,8 +170,12 @@ func (c *Metrics) GenerateBucketUsageData() (GaugeMetricData map[string][]UsageD
 				err.Error())
 			return
 		}
+		tagValues := make([]string, len(c.bucketTagKeys))
+		for i, key := range c.bucketTagKeys {
+			tagValues[i] = bucket.Tags[key]
+		}
 		for _, data := range datas {
-			GaugeMetricData[bucket.Name] = append(GaugeMetricData[bucket.Name], UsageDataWithBucket{data.value, bucket.OwnerId, data.storageClass})
+			GaugeMetricData[bucket.Name] = append(GaugeMetricData[bucket.Name], UsageDataWithBucket{data.value, bucket.OwnerId, data.storageClass, tagValues})
 		}
 	}
 	return
@@ -80,3 +80,12 @@ type KMS interface {
 
 	GetKeyID() string
 }
+
+// KeySealer is implemented by KMS able to seal a data key it generated with
+// another context, so data encrypted by the key could be used by another
+// object without decryption, e.g. when copied by the backend cluster.
+type KeySealer interface {
+	// SealKey seals the plaintext key using the master key
+	// referenced by the keyID, bound to the context.
+	SealKey(keyID string, key [32]byte, context Context) (sealedKey []byte, err error)
+}
@@ -0,0 +1,520 @@
+package erasure
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+const (
+	DEFAULT_BLOCK_SIZE = 256 << 10 // 256K
+	MIN_BLOCK_SIZE     = 16
+	LAYOUT_PREFIX      = "ec"
+)
+
+// LayoutStore keeps layouts of erasure coded objects, the object ID returned
+// by ErasureCluster.Put only identifies the layout, e.g
+//
+//	ec:<random id>
+//
+// Shards are located by the member cluster IDs recorded in the layout, so
+// changing members of a group does not affect objects already written.
+type LayoutStore interface {
+	PutErasureLayout(layout types.ErasureLayout) error
+	GetErasureLayout(location, pool, objectId string) (types.ErasureLayout, error)
+	RemoveErasureLayout(layout types.ErasureLayout) error
+}
+
+// ErasureCluster stripes every object into DataShards data and ParityShards
+// parity shards, and stores each shard in a different member cluster.
+// Objects stay readable as long as any DataShards of the member clusters are available.
+type ErasureCluster struct {
+	Name      string
+	BlockSize int64
+	Members   []string                   // clusters new objects are written to
+	clusters  map[string]backend.Cluster // all loaded clusters, shards may be in former members
+	codec     *Codec
+	layouts   LayoutStore
+}
+
+// Build erasure groups from yig.toml on top of already loaded clusters
+func Initialize(config helper.Config, clusters map[string]backend.Cluster,
+	layouts LayoutStore) map[string]backend.Cluster {
+
+	groups := make(map[string]backend.Cluster)
+	for _, g := range config.ErasureGroups {
+		c, err := NewErasureCluster(g, clusters, layouts)
+		if err != nil {
+			panic("Failed to initialize erasure group " + g.ID + ": " + err.Error())
+		}
+		groups[c.Name] = c
+	}
+	return groups
+}
+
+func NewErasureCluster(config helper.ErasureGroupConfig, clusters map[string]backend.Cluster,
+	layouts LayoutStore) (*ErasureCluster, error) {
+
+	if config.ID == "" {
+		return nil, errors.New("empty erasure group ID")
+	}
+	if len(config.Clusters) != config.DataShards+config.ParityShards {
+		return nil, fmt.Errorf("group needs %d clusters, got %d",
+			config.DataShards+config.ParityShards, len(config.Clusters))
+	}
+	codec, err := NewCodec(config.DataShards, config.ParityShards)
+	if err != nil {
+		return nil, err
+	}
+	blockSize := config.BlockSize
+	if blockSize == 0 {
+		blockSize = DEFAULT_BLOCK_SIZE
+	}
+	if blockSize < MIN_BLOCK_SIZE {
+		return nil, fmt.Errorf("block size should be at least %d", MIN_BLOCK_SIZE)
+	}
+	cluster := &ErasureCluster{
+		Name:      config.ID,
+		BlockSize: blockSize,
+		Members:   config.Clusters,
+		clusters:  make(map[string]backend.Cluster),
+		codec:     codec,
+		layouts:   layouts,
+	}
+	for id, c := range clusters {
+		cluster.clusters[id] = c
+	}
+	seen := make(map[string]bool)
+	for _, id := range config.Clusters {
+		if seen[id] {
+			return nil, errors.New("duplicated cluster " + id)
+		}
+		seen[id] = true
+		if _, ok := clusters[id]; !ok {
+			helper.Logger.Warn("Erasure group", config.ID, "member cluster",
+				id, "is not available")
+		}
+	}
+	helper.Logger.Info("Erasure group", cluster.Name, "is ready, data shards:",
+		config.DataShards, "parity shards:", config.ParityShards, "clusters:", config.Clusters)
+	return cluster, nil
+}
+
+func (cluster *ErasureCluster) ID() string {
+	return cluster.Name
+}
+
+func (cluster *ErasureCluster) member(id string) (backend.Cluster, error) {
+	c, ok := cluster.clusters[id]
+	if !ok {
+		return nil, errors.New("member cluster " + id + " is not available")
+	}
+	return c, nil
+}
+
+// Usage of the fullest member cluster
+func (cluster *ErasureCluster) GetUsage() (usage backend.Usage, err error) {
+	for _, id := range cluster.Members {
+		c, err := cluster.member(id)
+		if err != nil {
+			return usage, err
+		}
+		u, err := c.GetUsage()
+		if err != nil {
+			return usage, err
+		}
+		if u.UsedSpacePercent > usage.UsedSpacePercent {
+			usage = u
+		}
+	}
+	return usage, nil
+}
+
+func (cluster *ErasureCluster) newLayout(poolname string) (layout types.ErasureLayout, err error) {
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return
+	}
+	return types.ErasureLayout{
+		Location: cluster.Name,
+		Pool:     poolname,
+		ObjectId: LAYOUT_PREFIX + ":" + hex.EncodeToString(id),
+	}, nil
+}
+
+type shardWriteResult struct {
+	oid string
+	err error
+}
+
+func (cluster *ErasureCluster) Put(poolname string, data io.Reader) (oid string,
+	size uint64, err error) {
+
+	layout, err := cluster.newLayout(poolname)
+	if err != nil {
+		return "", 0, err
+	}
+	k := cluster.codec.DataShards
+	total := k + cluster.codec.ParityShards
+	members := make([]backend.Cluster, total)
+	for i, id := range cluster.Members {
+		if members[i], err = cluster.member(id); err != nil {
+			return "", 0, err
+		}
+	}
+
+	// Read the first stripe before choosing block size, so small objects
+	// are not padded to a whole stripe of default size.
+	stripe := make([]byte, int64(k)*cluster.BlockSize)
+	n, err := io.ReadFull(data, stripe)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", 0, err
+	}
+	blockSize := cluster.BlockSize
+	if err != nil { // whole object fits in one stripe
+		blockSize = (int64(n) + int64(k) - 1) / int64(k)
+		if blockSize < MIN_BLOCK_SIZE {
+			blockSize = MIN_BLOCK_SIZE
+		}
+		if int64(len(stripe)) > int64(k)*blockSize {
+			stripe = stripe[:int64(k)*blockSize]
+		}
+	}
+	pending := n
+	readErr := err
+
+	writers := make([]*io.PipeWriter, total)
+	results := make([]shardWriteResult, total)
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		pr, pw := io.Pipe()
+		writers[i] = pw
+		wg.Add(1)
+		go func(i int, pr *io.PipeReader) {
+			defer wg.Done()
+			oid, _, err := members[i].Put(poolname, pr)
+			results[i] = shardWriteResult{oid: oid, err: err}
+			if err != nil {
+				pr.CloseWithError(err)
+			} else {
+				pr.Close()
+			}
+		}(i, pr)
+	}
+
+	shards := make([][]byte, total)
+	for i := k; i < total; i++ {
+		shards[i] = make([]byte, blockSize)
+	}
+	var writeErr error
+	for pending > 0 && writeErr == nil {
+		size += uint64(pending)
+		for i := pending; i < len(stripe); i++ {
+			stripe[i] = 0
+		}
+		for i := 0; i < k; i++ {
+			shards[i] = stripe[int64(i)*blockSize : int64(i+1)*blockSize]
+		}
+		cluster.codec.Encode(shards)
+		for i, w := range writers {
+			if _, writeErr = w.Write(shards[i]); writeErr != nil {
+				break
+			}
+		}
+		if readErr != nil {
+			break
+		}
+		pending, readErr = io.ReadFull(data, stripe)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			writeErr = readErr
+		}
+	}
+	for _, w := range writers {
+		if writeErr != nil {
+			w.CloseWithError(writeErr)
+		} else {
+			w.Close()
+		}
+	}
+	wg.Wait()
+
+	layout.DataShards = k
+	layout.ParityShards = cluster.codec.ParityShards
+	layout.BlockSize = blockSize
+	layout.Size = int64(size)
+	layout.Shards = make([]types.ErasureShard, total)
+	err = writeErr
+	for i, r := range results {
+		layout.Shards[i] = types.ErasureShard{Cluster: cluster.Members[i], ObjectId: r.oid}
+		if r.err != nil && err == nil {
+			err = fmt.Errorf("write shard %d to cluster %s failed: %v",
+				i, cluster.Members[i], r.err)
+		}
+	}
+	if err == nil {
+		err = cluster.layouts.PutErasureLayout(layout)
+	}
+	if err != nil {
+		cluster.removeShards(layout)
+		return "", 0, err
+	}
+	return layout.ObjectId, size, nil
+}
+
+func (cluster *ErasureCluster) Append(poolname string, existName string, data io.Reader,
+	offset int64) (oid string, size uint64, err error) {
+
+	return existName, 0, errors.New("append is not supported by erasure coded cluster")
+}
+
+// Shards are copied by their clusters, only supported when all of them
+// implement backend.Copier
+func (cluster *ErasureCluster) Copy(srcPool, srcName, dstPool string) (oid string,
+	size uint64, err error) {
+
+	layout, err := cluster.layouts.GetErasureLayout(cluster.Name, srcPool, srcName)
+	if err != nil {
+		return "", 0, err
+	}
+	newLayout, err := cluster.newLayout(dstPool)
+	if err != nil {
+		return "", 0, err
+	}
+	newLayout.DataShards = layout.DataShards
+	newLayout.ParityShards = layout.ParityShards
+	newLayout.BlockSize = layout.BlockSize
+	newLayout.Size = layout.Size
+	newLayout.Shards = make([]types.ErasureShard, len(layout.Shards))
+	for i, shard := range layout.Shards {
+		newLayout.Shards[i].Cluster = shard.Cluster
+		var c backend.Cluster
+		if c, err = cluster.member(shard.Cluster); err != nil {
+			break
+		}
+		copier, ok := c.(backend.Copier)
+		if !ok {
+			err = errors.New("member cluster " + shard.Cluster + " does not support copy")
+			break
+		}
+		newLayout.Shards[i].ObjectId, _, err = copier.Copy(srcPool, shard.ObjectId, dstPool)
+		if err != nil {
+			err = fmt.Errorf("copy shard %d in cluster %s failed: %v",
+				i, shard.Cluster, err)
+			break
+		}
+	}
+	if err == nil {
+		err = cluster.layouts.PutErasureLayout(newLayout)
+	}
+	if err != nil {
+		cluster.removeShards(newLayout)
+		return "", 0, err
+	}
+	return newLayout.ObjectId, uint64(layout.Size), nil
+}
+
+func (cluster *ErasureCluster) GetReader(poolName string, oid string, startOffset int64,
+	length uint64) (reader io.ReadCloser, err error) {
+
+	layout, err := cluster.layouts.GetErasureLayout(cluster.Name, poolName, oid)
+	if err != nil {
+		return nil, err
+	}
+	if len(layout.Shards) != layout.DataShards+layout.ParityShards || layout.BlockSize <= 0 {
+		return nil, fmt.Errorf("invalid erasure layout of %s", oid)
+	}
+	// the group may be configured with other shard counts after the object is written
+	codec := cluster.codec
+	if layout.DataShards != codec.DataShards || layout.ParityShards != codec.ParityShards {
+		codec, err = NewCodec(layout.DataShards, layout.ParityShards)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if startOffset < 0 || startOffset > layout.Size {
+		return nil, fmt.Errorf("invalid offset %d, object size %d", startOffset, layout.Size)
+	}
+	remaining := layout.Size - startOffset
+	if length > 0 && int64(length) < remaining {
+		remaining = int64(length)
+	}
+
+	stripeSize := int64(layout.DataShards) * layout.BlockSize
+	r := &stripeReader{
+		cluster:    cluster,
+		codec:      codec,
+		layout:     layout,
+		stripe:     startOffset / stripeSize,
+		lastStripe: (layout.Size - 1) / stripeSize,
+		skip:       startOffset % stripeSize,
+		remaining:  remaining,
+		readers:    make([]io.ReadCloser, len(layout.Shards)),
+		positions:  make([]int64, len(layout.Shards)),
+		failed:     make([]bool, len(layout.Shards)),
+	}
+	if remaining > 0 {
+		if startOffset+remaining < layout.Size {
+			r.lastStripe = (startOffset + remaining - 1) / stripeSize
+		}
+		// read the first stripe here so that errors are returned early
+		if err = r.readStripe(); err != nil {
+			r.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// The layout is removed after all its shards, so failed removals could be retried
+func (cluster *ErasureCluster) Remove(poolname string, oid string) error {
+	layout, err := cluster.layouts.GetErasureLayout(cluster.Name, poolname, oid)
+	if err != nil {
+		return err
+	}
+	if err = cluster.removeShards(layout); err != nil {
+		return err
+	}
+	return cluster.layouts.RemoveErasureLayout(layout)
+}
+
+func (cluster *ErasureCluster) removeShards(layout types.ErasureLayout) (err error) {
+	for _, shard := range layout.Shards {
+		if shard.ObjectId == "" {
+			continue
+		}
+		c, e := cluster.member(shard.Cluster)
+		if e != nil {
+			err = e
+			continue
+		}
+		if e = c.Remove(layout.Pool, shard.ObjectId); e != nil {
+			helper.Logger.Error("Remove shard", shard.ObjectId, "from cluster",
+				shard.Cluster, "error:", e)
+			err = e
+		}
+	}
+	return err
+}
+
+// stripeReader reads stripes from the first DataShards available shards,
+// and reconstructs data blocks with parity shards when some shards are unreadable.
+type stripeReader struct {
+	cluster    *ErasureCluster
+	codec      *Codec
+	layout     types.ErasureLayout
+	stripe     int64 // next stripe to read
+	lastStripe int64
+	skip       int64 // bytes to skip in the next stripe
+	remaining  int64 // bytes left to return
+	readers    []io.ReadCloser
+	positions  []int64 // stripe index the shard reader is positioned at
+	failed     []bool
+	buffer     []byte
+}
+
+func (r *stripeReader) readShardBlock(i int) ([]byte, error) {
+	shard := r.layout.Shards[i]
+	c, err := r.cluster.member(shard.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	blockSize := r.layout.BlockSize
+	if r.readers[i] != nil && r.positions[i] != r.stripe {
+		r.readers[i].Close()
+		r.readers[i] = nil
+	}
+	if r.readers[i] == nil {
+		reader, err := c.GetReader(r.layout.Pool, shard.ObjectId, r.stripe*blockSize,
+			uint64((r.lastStripe-r.stripe+1)*blockSize))
+		if err != nil {
+			return nil, err
+		}
+		r.readers[i] = reader
+		r.positions[i] = r.stripe
+	}
+	block := make([]byte, blockSize)
+	_, err = io.ReadFull(r.readers[i], block)
+	if err != nil {
+		return nil, err
+	}
+	r.positions[i]++
+	return block, nil
+}
+
+func (r *stripeReader) readStripe() error {
+	k := r.layout.DataShards
+	shards := make([][]byte, len(r.layout.Shards))
+	got := 0
+	for i := range shards {
+		if got == k {
+			break
+		}
+		if r.failed[i] {
+			continue
+		}
+		block, err := r.readShardBlock(i)
+		if err != nil {
+			helper.Logger.Warn("Read shard", r.layout.Shards[i].ObjectId, "from cluster",
+				r.layout.Shards[i].Cluster, "error:", err)
+			r.failed[i] = true
+			if r.readers[i] != nil {
+				r.readers[i].Close()
+				r.readers[i] = nil
+			}
+			continue
+		}
+		shards[i] = block
+		got++
+	}
+	if got < k {
+		return fmt.Errorf("only %d shards available, %d needed", got, k)
+	}
+	if err := r.codec.ReconstructData(shards); err != nil {
+		return err
+	}
+
+	data := make([]byte, 0, int64(k)*r.layout.BlockSize)
+	for i := 0; i < k; i++ {
+		data = append(data, shards[i]...)
+	}
+	data = data[r.skip:]
+	if int64(len(data)) > r.remaining {
+		data = data[:r.remaining]
+	}
+	r.remaining -= int64(len(data))
+	r.skip = 0
+	r.stripe++
+	r.buffer = data
+	return nil
+}
+
+func (r *stripeReader) Read(p []byte) (n int, err error) {
+	if len(r.buffer) == 0 {
+		if r.remaining == 0 {
+			return 0, io.EOF
+		}
+		if err = r.readStripe(); err != nil {
+			return 0, err
+		}
+	}
+	n = copy(p, r.buffer)
+	r.buffer = r.buffer[n:]
+	return n, nil
+}
+
+func (r *stripeReader) Close() error {
+	for i, reader := range r.readers {
+		if reader != nil {
+			reader.Close()
+			r.readers[i] = nil
+		}
+	}
+	return nil
+}
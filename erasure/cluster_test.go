@@ -0,0 +1,359 @@
+package erasure_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/erasure"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+// memCluster keeps objects in memory, reads fail when it's down
+type memCluster struct {
+	sync.Mutex
+	name    string
+	down    bool
+	counter int
+	objects map[string][]byte
+}
+
+func (c *memCluster) ID() string { return c.name }
+
+func (c *memCluster) GetUsage() (backend.Usage, error) { return backend.Usage{}, nil }
+
+func (c *memCluster) Put(pool string, data io.Reader) (string, uint64, error) {
+	buf, err := ioutil.ReadAll(data)
+	if err != nil {
+		return "", 0, err
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.counter++
+	oid := fmt.Sprintf("%s-%d", c.name, c.counter)
+	c.objects[pool+"/"+oid] = buf
+	return oid, uint64(len(buf)), nil
+}
+
+func (c *memCluster) Append(pool, oid string, data io.Reader, offset int64) (string, uint64, error) {
+	return oid, 0, errors.New("not supported")
+}
+
+func (c *memCluster) GetReader(pool, oid string, offset int64, length uint64) (io.ReadCloser, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.down {
+		return nil, errors.New("cluster is down")
+	}
+	buf, ok := c.objects[pool+"/"+oid]
+	if !ok {
+		return nil, errors.New("no such object")
+	}
+	end := int64(len(buf))
+	if length > 0 && offset+int64(length) < end {
+		end = offset + int64(length)
+	}
+	return ioutil.NopCloser(bytes.NewReader(buf[offset:end])), nil
+}
+
+func (c *memCluster) Copy(srcPool, srcName, dstPool string) (string, uint64, error) {
+	c.Lock()
+	buf, ok := c.objects[srcPool+"/"+srcName]
+	c.Unlock()
+	if !ok {
+		return "", 0, errors.New("no such object")
+	}
+	return c.Put(dstPool, bytes.NewReader(buf))
+}
+
+func (c *memCluster) Remove(pool, oid string) error {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.objects, pool+"/"+oid)
+	return nil
+}
+
+// memLayoutStore keeps erasure layouts in memory
+type memLayoutStore struct {
+	sync.Mutex
+	layouts map[string]types.ErasureLayout
+}
+
+func (s *memLayoutStore) PutErasureLayout(layout types.ErasureLayout) error {
+	s.Lock()
+	defer s.Unlock()
+	s.layouts[layout.Location+"/"+layout.Pool+"/"+layout.ObjectId] = layout
+	return nil
+}
+
+func (s *memLayoutStore) GetErasureLayout(location, pool, oid string) (types.ErasureLayout, error) {
+	s.Lock()
+	defer s.Unlock()
+	layout, ok := s.layouts[location+"/"+pool+"/"+oid]
+	if !ok {
+		return layout, errors.New("no such layout")
+	}
+	return layout, nil
+}
+
+func (s *memLayoutStore) RemoveErasureLayout(layout types.ErasureLayout) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.layouts, layout.Location+"/"+layout.Pool+"/"+layout.ObjectId)
+	return nil
+}
+
+func setupErasureCluster(t *testing.T, k, m int, blockSize int64) (*erasure.ErasureCluster, []*memCluster) {
+	helper.Logger = log.NewLogger(os.Stderr, log.ErrorLevel)
+	clusters := make(map[string]backend.Cluster)
+	var members []*memCluster
+	var ids []string
+	for i := 0; i < k+m; i++ {
+		c := &memCluster{name: fmt.Sprintf("c%d", i), objects: make(map[string][]byte)}
+		clusters[c.name] = c
+		members = append(members, c)
+		ids = append(ids, c.name)
+	}
+	cluster, err := erasure.NewErasureCluster(helper.ErasureGroupConfig{
+		ID:           "ec-test",
+		DataShards:   k,
+		ParityShards: m,
+		BlockSize:    blockSize,
+		Clusters:     ids,
+	}, clusters, &memLayoutStore{layouts: make(map[string]types.ErasureLayout)})
+	if err != nil {
+		t.Fatal("NewErasureCluster error:", err)
+	}
+	return cluster, members
+}
+
+func read(t *testing.T, cluster *erasure.ErasureCluster, oid string, offset int64, length uint64) []byte {
+	reader, err := cluster.GetReader(backend.BIG_FILE_POOLNAME, oid, offset, length)
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Read error:", err)
+	}
+	return data
+}
+
+func TestCodec_Reconstruct(t *testing.T) {
+	codec, err := erasure.NewCodec(4, 2)
+	if err != nil {
+		t.Fatal("NewCodec error:", err)
+	}
+	shards := make([][]byte, 6)
+	for i := range shards {
+		shards[i] = make([]byte, 100)
+		if i < 4 {
+			rand.Read(shards[i])
+		}
+	}
+	if err = codec.Encode(shards); err != nil {
+		t.Fatal("Encode error:", err)
+	}
+	expected := make([][]byte, 4)
+	copy(expected, shards)
+
+	shards[0], shards[2] = nil, nil
+	if err = codec.ReconstructData(shards); err != nil {
+		t.Fatal("ReconstructData error:", err)
+	}
+	for i := 0; i < 4; i++ {
+		if !bytes.Equal(shards[i], expected[i]) {
+			t.Fatal("Reconstructed shard mismatch:", i)
+		}
+	}
+
+	shards[0], shards[1], shards[4] = nil, nil, nil
+	if err = codec.ReconstructData(shards); err == nil {
+		t.Fatal("ReconstructData should fail with too few shards")
+	}
+}
+
+func TestErasureCluster_PutAndGetReader(t *testing.T) {
+	cluster, members := setupErasureCluster(t, 4, 2, 64)
+
+	data := make([]byte, 1000)
+	rand.Read(data)
+	oid, size, err := cluster.Put(backend.BIG_FILE_POOLNAME, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("Put error:", err)
+	}
+	if size != uint64(len(data)) {
+		t.Fatal("Put size mismatch:", size)
+	}
+	for _, m := range members {
+		if len(m.objects) != 1 {
+			t.Fatal("Shard not stored in cluster", m.name)
+		}
+	}
+
+	if got := read(t, cluster, oid, 0, 0); !bytes.Equal(got, data) {
+		t.Fatal("Whole object mismatch")
+	}
+	if got := read(t, cluster, oid, 250, 500); !bytes.Equal(got, data[250:750]) {
+		t.Fatal("Ranged read mismatch")
+	}
+
+	// lose two whole clusters, including a data shard
+	members[1].down = true
+	members[5].down = true
+	if got := read(t, cluster, oid, 0, 0); !bytes.Equal(got, data) {
+		t.Fatal("Reconstructed object mismatch")
+	}
+	if got := read(t, cluster, oid, 999, 1); !bytes.Equal(got, data[999:]) {
+		t.Fatal("Reconstructed ranged read mismatch")
+	}
+
+	members[2].down = true
+	if _, err = cluster.GetReader(backend.BIG_FILE_POOLNAME, oid, 0, 0); err == nil {
+		t.Fatal("GetReader should fail when too many clusters are down")
+	}
+
+	if err = cluster.Remove(backend.BIG_FILE_POOLNAME, oid); err != nil {
+		t.Fatal("Remove error:", err)
+	}
+	for _, m := range members {
+		if len(m.objects) != 0 {
+			t.Fatal("Shard not removed from cluster", m.name)
+		}
+	}
+}
+
+func TestErasureCluster_SmallObject(t *testing.T) {
+	cluster, members := setupErasureCluster(t, 3, 1, 1<<20)
+
+	oid, _, err := cluster.Put(backend.SMALL_FILE_POOLNAME, bytes.NewReader([]byte("hello")))
+	if err != nil {
+		t.Fatal("Put error:", err)
+	}
+	for _, m := range members {
+		for _, shard := range m.objects {
+			if len(shard) != erasure.MIN_BLOCK_SIZE {
+				t.Fatal("Small object should not be padded to default block size:", len(shard))
+			}
+		}
+	}
+	reader, err := cluster.GetReader(backend.SMALL_FILE_POOLNAME, oid, 0, 0)
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	got, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	if string(got) != "hello" {
+		t.Fatal("Small object mismatch:", string(got))
+	}
+
+	if _, _, err = cluster.Append(backend.BIG_FILE_POOLNAME, "", bytes.NewReader(nil), 0); err == nil {
+		t.Fatal("Append should not be supported")
+	}
+}
+
+func TestErasureCluster_Copy(t *testing.T) {
+	cluster, members := setupErasureCluster(t, 2, 1, 64)
+
+	data := make([]byte, 300)
+	rand.Read(data)
+	oid, _, err := cluster.Put(backend.BIG_FILE_POOLNAME, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("Put error:", err)
+	}
+	newOid, size, err := cluster.Copy(backend.BIG_FILE_POOLNAME, oid, backend.GLACIER_FILE_POOLNAME)
+	if err != nil {
+		t.Fatal("Copy error:", err)
+	}
+	if size != uint64(len(data)) || newOid == oid {
+		t.Fatal("Bad copy result:", newOid, size)
+	}
+	if err = cluster.Remove(backend.BIG_FILE_POOLNAME, oid); err != nil {
+		t.Fatal("Remove error:", err)
+	}
+	members[0].down = true
+	reader, err := cluster.GetReader(backend.GLACIER_FILE_POOLNAME, newOid, 0, 0)
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	got, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(got, data) {
+		t.Fatal("Copied object mismatch")
+	}
+}
+
+// Shards are looked up by the clusters recorded in layouts, so objects stay
+// readable after members and shard counts of the group are changed
+func TestErasureCluster_MembersChanged(t *testing.T) {
+	helper.Logger = log.NewLogger(os.Stderr, log.ErrorLevel)
+	clusters := make(map[string]backend.Cluster)
+	var members []*memCluster
+	for i := 0; i < 4; i++ {
+		c := &memCluster{name: fmt.Sprintf("c%d", i), objects: make(map[string][]byte)}
+		clusters[c.name] = c
+		members = append(members, c)
+	}
+	store := &memLayoutStore{layouts: make(map[string]types.ErasureLayout)}
+	cluster, err := erasure.NewErasureCluster(helper.ErasureGroupConfig{
+		ID:           "ec-test",
+		DataShards:   2,
+		ParityShards: 1,
+		BlockSize:    64,
+		Clusters:     []string{"c0", "c1", "c2"},
+	}, clusters, store)
+	if err != nil {
+		t.Fatal("NewErasureCluster error:", err)
+	}
+	data := make([]byte, 500)
+	rand.Read(data)
+	oid, _, err := cluster.Put(backend.BIG_FILE_POOLNAME, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("Put error:", err)
+	}
+	layout, err := store.GetErasureLayout("ec-test", backend.BIG_FILE_POOLNAME, oid)
+	if err != nil {
+		t.Fatal("GetErasureLayout error:", err)
+	}
+	for i, shard := range layout.Shards {
+		if shard.Cluster != members[i].name {
+			t.Fatal("Shard", i, "is recorded in cluster", shard.Cluster)
+		}
+	}
+
+	cluster, err = erasure.NewErasureCluster(helper.ErasureGroupConfig{
+		ID:           "ec-test",
+		DataShards:   3,
+		ParityShards: 1,
+		BlockSize:    64,
+		Clusters:     []string{"c3", "c2", "c1", "c0"},
+	}, clusters, store)
+	if err != nil {
+		t.Fatal("NewErasureCluster error:", err)
+	}
+	members[0].down = true
+	if got := read(t, cluster, oid, 0, 0); !bytes.Equal(got, data) {
+		t.Fatal("Object mismatch after members changed")
+	}
+	if err = cluster.Remove(backend.BIG_FILE_POOLNAME, oid); err != nil {
+		t.Fatal("Remove error:", err)
+	}
+	for _, m := range members {
+		if len(m.objects) != 0 {
+			t.Fatal("Shard not removed from cluster", m.name)
+		}
+	}
+	if _, err = store.GetErasureLayout("ec-test", backend.BIG_FILE_POOLNAME, oid); err == nil {
+		t.Fatal("Layout not removed")
+	}
+}
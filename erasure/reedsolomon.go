@@ -0,0 +1,241 @@
+package erasure
+
+import (
+	"errors"
+)
+
+// Reed-Solomon codec over GF(2^8), generated by polynomial x^8+x^4+x^3+x^2+1.
+// The encoding matrix is a Vandermonde matrix transformed so that its top k rows
+// form the identity matrix, i.e. data shards are stored as is and any k shards
+// out of k+m are enough to recover the data.
+
+const GF_POLYNOMIAL = 0x11d
+
+var (
+	gfExp [512]byte
+	gfLog [256]int
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= GF_POLYNOMIAL
+		}
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]+gfLog[b]]
+}
+
+func gfInverse(a byte) byte {
+	return gfExp[255-gfLog[a]]
+}
+
+func gfPow(a byte, n int) byte {
+	if n == 0 {
+		return 1
+	}
+	if a == 0 {
+		return 0
+	}
+	return gfExp[(gfLog[a]*n)%255]
+}
+
+type matrix [][]byte
+
+func newMatrix(rows, cols int) matrix {
+	m := make(matrix, rows)
+	for i := range m {
+		m[i] = make([]byte, cols)
+	}
+	return m
+}
+
+func (m matrix) multiply(right matrix) matrix {
+	result := newMatrix(len(m), len(right[0]))
+	for r := range m {
+		for c := range right[0] {
+			var v byte
+			for i := range right {
+				v ^= gfMul(m[r][i], right[i][c])
+			}
+			result[r][c] = v
+		}
+	}
+	return result
+}
+
+// Gauss-Jordan elimination on a square matrix
+func (m matrix) invert() (matrix, error) {
+	n := len(m)
+	work := newMatrix(n, 2*n)
+	for r := 0; r < n; r++ {
+		copy(work[r], m[r])
+		work[r][n+r] = 1
+	}
+	for c := 0; c < n; c++ {
+		if work[c][c] == 0 {
+			for r := c + 1; r < n; r++ {
+				if work[r][c] != 0 {
+					work[c], work[r] = work[r], work[c]
+					break
+				}
+			}
+		}
+		if work[c][c] == 0 {
+			return nil, errors.New("singular matrix")
+		}
+		if work[c][c] != 1 {
+			scale := gfInverse(work[c][c])
+			for i := range work[c] {
+				work[c][i] = gfMul(work[c][i], scale)
+			}
+		}
+		for r := 0; r < n; r++ {
+			if r == c || work[r][c] == 0 {
+				continue
+			}
+			factor := work[r][c]
+			for i := range work[r] {
+				work[r][i] ^= gfMul(factor, work[c][i])
+			}
+		}
+	}
+	result := newMatrix(n, n)
+	for r := 0; r < n; r++ {
+		copy(result[r], work[r][n:])
+	}
+	return result, nil
+}
+
+type Codec struct {
+	DataShards   int
+	ParityShards int
+	matrix       matrix // (DataShards+ParityShards) x DataShards
+}
+
+func NewCodec(dataShards, parityShards int) (*Codec, error) {
+	if dataShards <= 0 || parityShards < 0 {
+		return nil, errors.New("invalid number of shards")
+	}
+	if dataShards+parityShards > 256 {
+		return nil, errors.New("too many shards, at most 256")
+	}
+	total := dataShards + parityShards
+	vandermonde := newMatrix(total, dataShards)
+	for r := 0; r < total; r++ {
+		for c := 0; c < dataShards; c++ {
+			vandermonde[r][c] = gfPow(byte(r), c)
+		}
+	}
+	top, err := matrix(vandermonde[:dataShards]).invert()
+	if err != nil {
+		return nil, err
+	}
+	return &Codec{
+		DataShards:   dataShards,
+		ParityShards: parityShards,
+		matrix:       vandermonde.multiply(top),
+	}, nil
+}
+
+// Encode fills parity shards from data shards, all shards must have the same size
+func (c *Codec) Encode(shards [][]byte) error {
+	if len(shards) != c.DataShards+c.ParityShards {
+		return errors.New("wrong number of shards")
+	}
+	size := len(shards[0])
+	for _, s := range shards {
+		if len(s) != size {
+			return errors.New("shards have different size")
+		}
+	}
+	for p := c.DataShards; p < len(shards); p++ {
+		c.combine(c.matrix[p], shards[:c.DataShards], shards[p])
+	}
+	return nil
+}
+
+// ReconstructData recovers missing(nil) data shards, parity shards are left untouched.
+// At least DataShards shards must be present.
+func (c *Codec) ReconstructData(shards [][]byte) error {
+	if len(shards) != c.DataShards+c.ParityShards {
+		return errors.New("wrong number of shards")
+	}
+	missing := false
+	for i := 0; i < c.DataShards; i++ {
+		if shards[i] == nil {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	rows := make(matrix, 0, c.DataShards)
+	inputs := make([][]byte, 0, c.DataShards)
+	size := -1
+	for i := 0; i < len(shards) && len(inputs) < c.DataShards; i++ {
+		if shards[i] == nil {
+			continue
+		}
+		if size >= 0 && len(shards[i]) != size {
+			return errors.New("shards have different size")
+		}
+		size = len(shards[i])
+		rows = append(rows, c.matrix[i])
+		inputs = append(inputs, shards[i])
+	}
+	if len(inputs) < c.DataShards {
+		return errors.New("too few shards to reconstruct data")
+	}
+	decode, err := rows.invert()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < c.DataShards; i++ {
+		if shards[i] != nil {
+			continue
+		}
+		shards[i] = make([]byte, size)
+		c.combine(decode[i], inputs, shards[i])
+	}
+	return nil
+}
+
+// out = sum(coefficients[i] * inputs[i])
+func (c *Codec) combine(coefficients []byte, inputs [][]byte, out []byte) {
+	for i := range out {
+		out[i] = 0
+	}
+	for i, in := range inputs {
+		coefficient := coefficients[i]
+		if coefficient == 0 {
+			continue
+		}
+		if coefficient == 1 {
+			for j, b := range in {
+				out[j] ^= b
+			}
+			continue
+		}
+		logC := gfLog[coefficient]
+		for j, b := range in {
+			if b != 0 {
+				out[j] ^= gfExp[logC+gfLog[b]]
+			}
+		}
+	}
+}
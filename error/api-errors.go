@@ -178,6 +178,31 @@ const (
 	ErrInvalidRestoreInfo
 	ErrCreateRestoreObject
 	ErrInvalidGlacierObject
+	ErrInvalidTag
+	ErrObjectTagsTooMany
+	ErrInvalidTaggingDirective
+	ErrBucketTagsTooMany
+	ErrNoSuchTagSet
+	ErrInvalidNotificationConfiguration
+	ErrInvalidNotificationEvent
+	ErrInvalidNotificationFilter
+	ErrInvalidNotificationDestination
+	ErrInvalidReplicationConfiguration
+	ErrInvalidReplicationDestination
+	ErrReplicationConfigurationNotFound
+	ErrReplicationRequiresVersioning
+	ErrReplicationVersioningSuspended
+	ErrInvalidObjectLockConfiguration
+	ErrObjectLockConfigurationNotFound
+	ErrObjectLockRequiresVersioning
+	ErrObjectLockVersioningSuspended
+	ErrObjectLockNotEnabled
+	ErrInvalidObjectLockHeaders
+	ErrInvalidObjectRetention
+	ErrPastObjectLockRetainDate
+	ErrNoSuchObjectLockConfiguration
+	ErrObjectLocked
+	ErrObjectLockAppendNotSupported
 )
 
 // error code to APIError structure, these fields carry respective
@@ -834,6 +859,131 @@ var ErrorCodeResponse = map[ApiErrorCode]ApiErrorStruct{
 		Description: "Temporary maintenance, please retry your request",
 		HttpStatusCode: http.StatusServiceUnavailable,
 	},
+	ErrInvalidTag: {
+		AwsErrorCode:   "InvalidTag",
+		Description:    "The tag provided was not a valid tag.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrObjectTagsTooMany: {
+		AwsErrorCode:   "BadRequest",
+		Description:    "Object tags cannot be greater than 10.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidTaggingDirective: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "Unknown tagging directive.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrBucketTagsTooMany: {
+		AwsErrorCode:   "BadRequest",
+		Description:    "Bucket tag count cannot be greater than 50.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchTagSet: {
+		AwsErrorCode:   "NoSuchTagSet",
+		Description:    "The TagSet does not exist.",
+		HttpStatusCode: http.StatusNotFound,
+	},
+	ErrInvalidNotificationConfiguration: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "Configurations are too many or have duplicated ids.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidNotificationEvent: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "The event is not supported for notifications.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidNotificationFilter: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "Filter rule name must be either prefix or suffix, and appear at most once.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidNotificationDestination: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "Destination of notification configuration is missing.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidReplicationConfiguration: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "The replication configuration is invalid.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidReplicationDestination: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "Destination bucket must be an ARN of a bucket on a configured replication target.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrReplicationConfigurationNotFound: {
+		AwsErrorCode:   "ReplicationConfigurationNotFoundError",
+		Description:    "The replication configuration was not found.",
+		HttpStatusCode: http.StatusNotFound,
+	},
+	ErrReplicationRequiresVersioning: {
+		AwsErrorCode:   "InvalidRequest",
+		Description:    "Versioning must be 'Enabled' on the bucket to apply a replication configuration.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrReplicationVersioningSuspended: {
+		AwsErrorCode:   "InvalidBucketState",
+		Description:    "A replication configuration is present on this bucket, so you cannot change the versioning state.",
+		HttpStatusCode: http.StatusConflict,
+	},
+	ErrInvalidObjectLockConfiguration: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "The object lock configuration is invalid.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrObjectLockConfigurationNotFound: {
+		AwsErrorCode:   "ObjectLockConfigurationNotFoundError",
+		Description:    "Object Lock configuration does not exist for this bucket.",
+		HttpStatusCode: http.StatusNotFound,
+	},
+	ErrObjectLockRequiresVersioning: {
+		AwsErrorCode:   "InvalidBucketState",
+		Description:    "Versioning must be 'Enabled' on the bucket to apply a Object Lock configuration.",
+		HttpStatusCode: http.StatusConflict,
+	},
+	ErrObjectLockVersioningSuspended: {
+		AwsErrorCode:   "InvalidBucketState",
+		Description:    "An Object Lock configuration is present on this bucket, so the versioning state cannot be changed.",
+		HttpStatusCode: http.StatusConflict,
+	},
+	ErrObjectLockNotEnabled: {
+		AwsErrorCode:   "InvalidRequest",
+		Description:    "Bucket is missing Object Lock Configuration.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidObjectLockHeaders: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "x-amz-object-lock-retain-until-date and x-amz-object-lock-mode must both be supplied with valid values.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrInvalidObjectRetention: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "Unknown retention mode or invalid retain until date.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrPastObjectLockRetainDate: {
+		AwsErrorCode:   "InvalidArgument",
+		Description:    "The retain until date must be in the future.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
+	ErrNoSuchObjectLockConfiguration: {
+		AwsErrorCode:   "NoSuchObjectLockConfiguration",
+		Description:    "The specified object does not have a ObjectLock configuration.",
+		HttpStatusCode: http.StatusNotFound,
+	},
+	ErrObjectLocked: {
+		AwsErrorCode:   "AccessDenied",
+		Description:    "Access Denied because object protected by object lock.",
+		HttpStatusCode: http.StatusForbidden,
+	},
+	ErrObjectLockAppendNotSupported: {
+		AwsErrorCode:   "InvalidRequest",
+		Description:    "Appending objects is not supported in buckets with Object Lock enabled.",
+		HttpStatusCode: http.StatusBadRequest,
+	},
 }
 
 func (e ApiErrorCode) AwsErrorCode() string {
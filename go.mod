@@ -41,7 +41,7 @@ require (
 	github.com/gorilla/mux v1.6.2
 	github.com/jackc/pgx/v4 v4.15.0
 	github.com/journeymidnight/aws-sdk-go v1.18.1
-	github.com/journeymidnight/radoshttpd v0.0.7
+	github.com/journeymidnight/radoshttpd v0.0.7 // layout of rados.Pool is relied on by ceph/rados_copy.go
 	github.com/kr/pretty v0.2.1 // indirect
 	github.com/minio/highwayhash v1.0.0
 	github.com/prometheus/client_golang v0.9.3
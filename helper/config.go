@@ -30,6 +30,8 @@ type Config struct {
 	SSLKeyPath           string                  `toml:"ssl_key_path"`
 	SSLCertPath          string                  `toml:"ssl_cert_path"`
 	ZookeeperAddress     string                  `toml:"zk_address"`
+	// keys of bucket tags exposed as labels of bucket usage metrics, e.g "cost-center"
+	MetricBucketTagKeys []string `toml:"metric_bucket_tag_keys"`
 
 	InstanceId             string // if empty, generated one at server startup
 	ConcurrentRequestLimit int
@@ -39,7 +41,16 @@ type Config struct {
 	AdminKey               string `toml:"admin_key"` //used for tools/admin to communicate with yig
 	GcThread               int    `toml:"gc_thread"`
 	LcThread               int    //used for tools/lc only, set worker numbers to do lc
-	LogLevel               string `toml:"log_level"` // "info", "warn", "error"
+	LcLease                int    `toml:"lc_lease"`           // seconds a lc daemon holds a bucket without renewing
+	ScrubThread            int    `toml:"scrub_thread"`       // used for tools/scrub only, number of objects verified concurrently
+	ScrubBandwidth         int64  `toml:"scrub_bandwidth"`    // max data read by tools/scrub, in MB/s
+	ScrubInterval          int    `toml:"scrub_interval"`     // hours to wait between two scrub passes
+	RestoreThread          int    `toml:"restore_thread"`     // used for tools/restore only, number of objects restored concurrently
+	RestoreMaxDays         int    `toml:"restore_max_days"`   // max days a restored copy of glacier object is kept
+	TieringThread          int    `toml:"tiering_thread"`     // used for tools/tiering only, number of objects moved concurrently
+	TieringDays            int    `toml:"tiering_days"`       // days without access before moved to infrequent access tier
+	ReplicationThread      int    `toml:"replication_thread"` // used for tools/replicate only, number of objects replicated concurrently
+	LogLevel               string `toml:"log_level"`          // "info", "warn", "error"
 	CephConfigPattern      string `toml:"ceph_config_pattern"`
 	ReservedOrigins        string `toml:"reserved_origins"` // www.ccc.com,www.bbb.com,127.0.0.1
 	MetaStore              string `toml:"meta_store"`
@@ -48,6 +59,18 @@ type Config struct {
 	KeepAlive              bool   `toml:"keepalive"`
 	EnableCompression      bool   `toml:"enable_compression"`
 
+	// About data storage backend
+	DataStore  string   `toml:"data_store"`  // "ceph" or "posix", defaults to "ceph"
+	PosixRoots []string `toml:"posix_roots"` // data directories, one cluster per directory, used when data_store is "posix"
+	// erasure coded clusters built on top of the clusters above
+	ErasureGroups []ErasureGroupConfig `toml:"erasure_groups"`
+	// pools used by each storage class, keyed by class name e.g "STANDARD_IA"
+	StorageClasses map[string]StorageClassConfig `toml:"storage_classes"`
+	// remote endpoints objects are replicated to, see datatype.ReplicationConfiguration
+	ReplicationTargets []ReplicationTargetConfig `toml:"replication_targets"`
+	// access keys allowed to write replicas, i.e keys other YIG replicate to this one with
+	ReplicaAccessKeys []string `toml:"replica_access_keys"`
+
 	//About cache
 	EnableUsagePush       bool   `toml:"enable_usage_push"`
 	RedisAddress          string `toml:"redis_address"`           // redis connection string, e.g localhost:1234
@@ -84,6 +107,37 @@ type Config struct {
 	UploadMaxChunkSize  int64 `toml:"upload_max_chunk_size"`
 }
 
+// An erasure group stripes objects into DataShards+ParityShards shards,
+// shard i is stored in Clusters[i]. Never modify a group in use,
+// since existing objects depend on the order of its clusters.
+type ErasureGroupConfig struct {
+	ID           string   `toml:"id"`
+	DataShards   int      `toml:"data_shards"`
+	ParityShards int      `toml:"parity_shards"`
+	BlockSize    int64    `toml:"block_size"` // bytes of one shard block, defaults to 256K
+	Clusters     []string `toml:"clusters"`
+}
+
+// Pools objects of a storage class are written to, clusters of the pools are
+// picked from table `cluster`. Empty pools use the defaults, i.e turtle for
+// GLACIER, rabbit and tiger for other classes.
+type StorageClassConfig struct {
+	SmallFilePool string `toml:"small_file_pool"` // objects smaller than 128K
+	BigFilePool   string `toml:"big_file_pool"`   // other objects, multipart and appendable objects
+}
+
+// A YIG or other S3-compatible endpoint buckets could be replicated to. A
+// replication rule picks it by ID in the region field of its destination
+// bucket ARN, e.g "arn:aws:s3:dc2::backup" for bucket backup of target dc2.
+type ReplicationTargetConfig struct {
+	ID             string `toml:"id"`
+	Endpoint       string `toml:"endpoint"` // e.g http://s3.dc2.com
+	Region         string `toml:"region"`
+	AccessKey      string `toml:"access_key"`
+	SecretKey      string `toml:"secret_key"`
+	ForcePathStyle bool   `toml:"force_path_style"`
+}
+
 type PluginConfig struct {
 	Path   string                 `toml:"path"`
 	Enable bool                   `toml:"enable"`
@@ -123,11 +177,24 @@ func MarshalTOMLConfig() error {
 	CONFIG.SSLKeyPath = c.SSLKeyPath
 	CONFIG.SSLCertPath = c.SSLCertPath
 	CONFIG.ZookeeperAddress = c.ZookeeperAddress
+	CONFIG.MetricBucketTagKeys = c.MetricBucketTagKeys
 	CONFIG.DebugMode = c.DebugMode
 	CONFIG.EnablePProf = c.EnablePProf
 	CONFIG.BindPProfAddress = c.BindPProfAddress
 	CONFIG.AdminKey = c.AdminKey
 	CONFIG.CephConfigPattern = c.CephConfigPattern
+	CONFIG.PosixRoots = c.PosixRoots
+	CONFIG.ErasureGroups = c.ErasureGroups
+	for class, pools := range c.StorageClasses {
+		// pools other than rabbit are striped in ceph, data of multipart and
+		// appendable objects could not be stored in rabbit
+		if pools.BigFilePool == "rabbit" {
+			panic("load yig.toml error: rabbit cannot be big_file_pool of storage class " + class)
+		}
+	}
+	CONFIG.StorageClasses = c.StorageClasses
+	CONFIG.ReplicationTargets = c.ReplicationTargets
+	CONFIG.ReplicaAccessKeys = c.ReplicaAccessKeys
 	CONFIG.ReservedOrigins = c.ReservedOrigins
 	CONFIG.DBInfo = c.DBInfo
 	CONFIG.TimeFormat = c.TimeFormat
@@ -141,8 +208,27 @@ func MarshalTOMLConfig() error {
 		1, c.GcThread).(int)
 	CONFIG.LcThread = Ternary(c.LcThread == 0,
 		1, c.LcThread).(int)
+	CONFIG.LcLease = Ternary(c.LcLease == 0,
+		300, c.LcLease).(int)
+	CONFIG.ScrubThread = Ternary(c.ScrubThread == 0,
+		1, c.ScrubThread).(int)
+	CONFIG.ScrubBandwidth = Ternary(c.ScrubBandwidth == 0,
+		int64(20), c.ScrubBandwidth).(int64)
+	CONFIG.ScrubInterval = Ternary(c.ScrubInterval == 0,
+		24*7, c.ScrubInterval).(int)
+	CONFIG.RestoreThread = Ternary(c.RestoreThread == 0,
+		1, c.RestoreThread).(int)
+	CONFIG.RestoreMaxDays = Ternary(c.RestoreMaxDays == 0,
+		30, c.RestoreMaxDays).(int)
+	CONFIG.TieringThread = Ternary(c.TieringThread == 0,
+		1, c.TieringThread).(int)
+	CONFIG.TieringDays = Ternary(c.TieringDays == 0,
+		30, c.TieringDays).(int)
+	CONFIG.ReplicationThread = Ternary(c.ReplicationThread == 0,
+		1, c.ReplicationThread).(int)
 	CONFIG.LogLevel = Ternary(len(c.LogLevel) == 0, "info", c.LogLevel).(string)
 	CONFIG.MetaStore = Ternary(c.MetaStore == "", "cockroachdb", c.MetaStore).(string)
+	CONFIG.DataStore = Ternary(c.DataStore == "", "ceph", c.DataStore).(string)
 
 	CONFIG.EnableUsagePush = c.EnableUsagePush
 	CONFIG.RedisAddress = c.RedisAddress
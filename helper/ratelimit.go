@@ -0,0 +1,63 @@
+package helper
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits total throughput of all readers sharing it,
+// rate is in bytes per second and 0 means unlimited.
+type RateLimiter struct {
+	mutex sync.Mutex
+	rate  int64
+	next  time.Time
+}
+
+func NewRateLimiter(rate int64) *RateLimiter {
+	return &RateLimiter{rate: rate}
+}
+
+func (l *RateLimiter) SetRate(rate int64) {
+	l.mutex.Lock()
+	l.rate = rate
+	l.mutex.Unlock()
+}
+
+// Wait blocks until n more bytes are allowed
+func (l *RateLimiter) Wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+	l.mutex.Lock()
+	if l.rate <= 0 {
+		l.mutex.Unlock()
+		return
+	}
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	l.mutex.Unlock()
+	time.Sleep(delay)
+}
+
+type limitedReader struct {
+	reader  io.Reader
+	limiter *RateLimiter
+}
+
+func (r *limitedReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.limiter.Wait(n)
+	return
+}
+
+func (l *RateLimiter) Reader(reader io.Reader) io.Reader {
+	if l == nil {
+		return reader
+	}
+	return &limitedReader{reader: reader, limiter: l}
+}
@@ -0,0 +1,13 @@
+-- Upgrade an existing cockroachdb deployment with table blobrefs, counting
+-- objects which share data in a cluster after copies
+
+CREATE TABLE IF NOT EXISTS yig.blobrefs (
+    location character varying(255) NOT NULL DEFAULT '',
+    pool character varying(255) NOT NULL DEFAULT '',
+    objectid character varying(255) NOT NULL DEFAULT '',
+    refcount bigint NOT NULL DEFAULT 0
+);
+
+ALTER TABLE yig.blobrefs OWNER TO yig;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_blobrefs_rowkey ON yig.blobrefs USING btree (location, pool, objectid);
@@ -0,0 +1,10 @@
+-- Upgrade an existing tidb deployment with table `blobrefs`, counting objects
+-- which share data in a cluster after copies
+
+CREATE TABLE IF NOT EXISTS `blobrefs` (
+                       `location` varchar(255) NOT NULL DEFAULT '',
+                       `pool` varchar(255) NOT NULL DEFAULT '',
+                       `objectid` varchar(255) NOT NULL DEFAULT '',
+                       `refcount` bigint(20) UNSIGNED NOT NULL DEFAULT 0,
+                       UNIQUE KEY `rowkey` (`location`,`pool`,`objectid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
@@ -0,0 +1,6 @@
+-- Upgrade table buckets of an existing cockroachdb deployment, GetBucket and
+-- GetBuckets select columns tags and notification
+
+ALTER TABLE yig.buckets ADD COLUMN tags json DEFAULT NULL;
+
+ALTER TABLE yig.buckets ADD COLUMN notification json DEFAULT NULL;
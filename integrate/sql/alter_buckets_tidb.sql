@@ -0,0 +1,6 @@
+-- Upgrade table `buckets` of an existing tidb deployment, GetBucket and
+-- GetBuckets select columns tags and notification
+
+ALTER TABLE `buckets` ADD COLUMN `tags` JSON DEFAULT NULL;
+
+ALTER TABLE `buckets` ADD COLUMN `notification` JSON DEFAULT NULL;
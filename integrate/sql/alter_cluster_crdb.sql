@@ -0,0 +1,8 @@
+-- Upgrade table cluster of an existing cockroachdb deployment, so clusters
+-- could be dedicated to storage classes, see [storage_classes] in yig.toml
+
+ALTER TABLE yig.cluster ADD COLUMN storageclass character varying(64) DEFAULT '' NOT NULL;
+
+DROP INDEX yig.cluster@idx_16394_rowkey CASCADE;
+
+CREATE UNIQUE INDEX idx_16394_rowkey ON yig.cluster USING btree (fsid, pool, storageclass);
@@ -0,0 +1,8 @@
+-- Upgrade table `cluster` of an existing tidb deployment, so clusters could be
+-- dedicated to storage classes, see [storage_classes] in yig.toml
+
+ALTER TABLE `cluster` ADD COLUMN `storageclass` varchar(64) NOT NULL DEFAULT '';
+
+ALTER TABLE `cluster` DROP INDEX `rowkey`;
+
+ALTER TABLE `cluster` ADD UNIQUE KEY `rowkey` (`fsid`,`pool`,`storageclass`);
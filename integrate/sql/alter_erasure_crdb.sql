@@ -0,0 +1,17 @@
+-- Upgrade an existing cockroachdb deployment with table erasurelayouts, where
+-- the shards of erasure coded objects and their clusters are recorded
+
+CREATE TABLE IF NOT EXISTS yig.erasurelayouts (
+    location character varying(255) NOT NULL DEFAULT '',
+    pool character varying(255) NOT NULL DEFAULT '',
+    objectid character varying(255) NOT NULL DEFAULT '',
+    datashards bigint NOT NULL DEFAULT 0,
+    parityshards bigint NOT NULL DEFAULT 0,
+    blocksize bigint NOT NULL DEFAULT 0,
+    size bigint NOT NULL DEFAULT 0,
+    shards json DEFAULT NULL
+);
+
+ALTER TABLE yig.erasurelayouts OWNER TO yig;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_erasurelayouts_rowkey ON yig.erasurelayouts USING btree (location, pool, objectid);
@@ -0,0 +1,14 @@
+-- Upgrade an existing tidb deployment with table `erasurelayouts`, where the
+-- shards of erasure coded objects and their clusters are recorded
+
+CREATE TABLE IF NOT EXISTS `erasurelayouts` (
+                       `location` varchar(255) NOT NULL DEFAULT '',
+                       `pool` varchar(255) NOT NULL DEFAULT '',
+                       `objectid` varchar(255) NOT NULL DEFAULT '',
+                       `datashards` int(11) NOT NULL DEFAULT 0,
+                       `parityshards` int(11) NOT NULL DEFAULT 0,
+                       `blocksize` bigint(20) NOT NULL DEFAULT 0,
+                       `size` bigint(20) NOT NULL DEFAULT 0,
+                       `shards` JSON DEFAULT NULL,
+                       UNIQUE KEY `rowkey` (`location`,`pool`,`objectid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
@@ -0,0 +1,21 @@
+-- Upgrade table lifecycle of an existing cockroachdb deployment, so buckets
+-- could be split among lc daemons by leases. Older yig inserted a row on every
+-- put of a bucket lifecycle, duplicated rows of a bucket are removed before the
+-- unique index is added. Stop all yig and lc before running it.
+
+DELETE FROM yig.lifecycle WHERE rowid NOT IN
+    (SELECT min(rowid) FROM yig.lifecycle GROUP BY bucketname);
+
+ALTER TABLE yig.lifecycle ADD COLUMN owner character varying(255) NOT NULL DEFAULT '';
+
+ALTER TABLE yig.lifecycle ADD COLUMN leaseexpire timestamp with time zone DEFAULT NULL;
+
+ALTER TABLE yig.lifecycle ADD COLUMN marker character varying(1024) NOT NULL DEFAULT '';
+
+ALTER TABLE yig.lifecycle ADD COLUMN scanned bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE yig.lifecycle ADD COLUMN laststart timestamp with time zone DEFAULT NULL;
+
+ALTER TABLE yig.lifecycle ADD COLUMN lastfinish timestamp with time zone DEFAULT NULL;
+
+CREATE UNIQUE INDEX idx_lifecycle_rowkey ON yig.lifecycle USING btree (bucketname);
@@ -0,0 +1,25 @@
+-- Upgrade table `lifecycle` of an existing tidb deployment, so buckets could be
+-- split among lc daemons by leases. Older yig inserted a row on every put of a
+-- bucket lifecycle, the table is rebuilt with one row per bucket before the
+-- unique key is added. Stop all yig and lc before running it.
+
+CREATE TABLE `lifecycle_upgrade` (
+                       `bucketname` varchar(255) DEFAULT NULL,
+                       `status` varchar(255) DEFAULT NULL,
+                       `owner` varchar(255) NOT NULL DEFAULT '',
+                       `leaseexpire` datetime DEFAULT NULL,
+                       `marker` varchar(1024) NOT NULL DEFAULT '',
+                       `scanned` bigint(20) NOT NULL DEFAULT 0,
+                       `laststart` datetime DEFAULT NULL,
+                       `lastfinish` datetime DEFAULT NULL,
+                       UNIQUE KEY `rowkey` (`bucketname`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+INSERT INTO `lifecycle_upgrade` (`bucketname`,`status`)
+    SELECT `bucketname`, MAX(`status`) FROM `lifecycle` GROUP BY `bucketname`;
+
+RENAME TABLE `lifecycle` TO `lifecycle_old`;
+
+RENAME TABLE `lifecycle_upgrade` TO `lifecycle`;
+
+DROP TABLE `lifecycle_old`;
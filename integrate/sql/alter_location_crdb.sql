@@ -0,0 +1,4 @@
+-- Upgrade table objects of an existing cockroachdb deployment, so yig_rebalance
+-- could find objects of a cluster by location without scanning the table
+
+CREATE INDEX idx_objects_location ON yig.objects USING btree (location, bucketname, name, version);
@@ -0,0 +1,4 @@
+-- Upgrade table `objects` of an existing tidb deployment, so yig_rebalance
+-- could find objects of a cluster by location without scanning the table
+
+ALTER TABLE `objects` ADD INDEX `location` (`location`,`bucketname`,`name`,`version`);
@@ -0,0 +1,9 @@
+-- Upgrade tables objects, multiparts and buckets of an existing cockroachdb
+-- deployment for object lock, GetObject, GetMultipart and GetBucket select
+-- column objectlock
+
+ALTER TABLE yig.objects ADD COLUMN objectlock json DEFAULT NULL;
+
+ALTER TABLE yig.multiparts ADD COLUMN objectlock json DEFAULT NULL;
+
+ALTER TABLE yig.buckets ADD COLUMN objectlock json DEFAULT NULL;
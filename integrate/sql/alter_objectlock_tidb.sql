@@ -0,0 +1,9 @@
+-- Upgrade tables `objects`, `multiparts` and `buckets` of an existing tidb
+-- deployment for object lock, GetObject, GetMultipart and GetBucket select
+-- column objectlock
+
+ALTER TABLE `objects` ADD COLUMN `objectlock` JSON DEFAULT NULL;
+
+ALTER TABLE `multiparts` ADD COLUMN `objectlock` JSON DEFAULT NULL;
+
+ALTER TABLE `buckets` ADD COLUMN `objectlock` JSON DEFAULT NULL;
@@ -0,0 +1,6 @@
+-- Upgrade tables objects and multiparts of an existing cockroachdb deployment
+-- for object tagging, GetObject and GetMultipart select column tags
+
+ALTER TABLE yig.objects ADD COLUMN tags json DEFAULT NULL;
+
+ALTER TABLE yig.multiparts ADD COLUMN tags json DEFAULT NULL;
@@ -0,0 +1,6 @@
+-- Upgrade tables `objects` and `multiparts` of an existing tidb deployment for
+-- object tagging, GetObject and GetMultipart select column tags
+
+ALTER TABLE `objects` ADD COLUMN `tags` JSON DEFAULT NULL;
+
+ALTER TABLE `multiparts` ADD COLUMN `tags` JSON DEFAULT NULL;
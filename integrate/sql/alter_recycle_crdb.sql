@@ -0,0 +1,13 @@
+-- Upgrade an existing cockroachdb deployment with table recycle, where data
+-- left by failed writes is queued until it is removed from its cluster
+
+CREATE TABLE IF NOT EXISTS yig.recycle (
+    location character varying(255) NOT NULL DEFAULT '',
+    pool character varying(255) NOT NULL DEFAULT '',
+    objectid character varying(255) NOT NULL DEFAULT '',
+    createtime timestamp with time zone DEFAULT NULL
+);
+
+ALTER TABLE yig.recycle OWNER TO yig;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recycle_rowkey ON yig.recycle USING btree (location, pool, objectid);
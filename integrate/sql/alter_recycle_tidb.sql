@@ -0,0 +1,10 @@
+-- Upgrade an existing tidb deployment with table `recycle`, where data left
+-- by failed writes is queued until it is removed from its cluster
+
+CREATE TABLE IF NOT EXISTS `recycle` (
+                       `location` varchar(255) NOT NULL DEFAULT '',
+                       `pool` varchar(255) NOT NULL DEFAULT '',
+                       `objectid` varchar(255) NOT NULL DEFAULT '',
+                       `createtime` datetime DEFAULT NULL,
+                       UNIQUE KEY `rowkey` (`location`,`pool`,`objectid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
@@ -0,0 +1,21 @@
+-- Upgrade an existing cockroachdb deployment for bucket replication, GetObject
+-- selects replicationstatus and GetBucket selects replication
+
+ALTER TABLE yig.objects ADD COLUMN replicationstatus smallint DEFAULT '0'::smallint;
+
+ALTER TABLE yig.buckets ADD COLUMN replication json DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS yig.replication (
+    bucketname character varying(255) NOT NULL DEFAULT '',
+    objectname character varying(1024) NOT NULL DEFAULT '',
+    version decimal(20) NOT NULL DEFAULT 0,
+    status smallint NOT NULL DEFAULT 0,
+    attempts integer NOT NULL DEFAULT 0,
+    updatetime timestamp with time zone DEFAULT NULL
+);
+
+ALTER TABLE yig.replication OWNER TO yig;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_replication_rowkey ON yig.replication USING btree (bucketname, objectname, version);
+
+CREATE INDEX IF NOT EXISTS idx_replication_status ON yig.replication USING btree (status, updatetime);
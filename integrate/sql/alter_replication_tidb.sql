@@ -0,0 +1,17 @@
+-- Upgrade an existing tidb deployment for bucket replication, GetObject selects
+-- replicationstatus and GetBucket selects replication
+
+ALTER TABLE `objects` ADD COLUMN `replicationstatus` tinyint(1) DEFAULT 0;
+
+ALTER TABLE `buckets` ADD COLUMN `replication` JSON DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS `replication` (
+                       `bucketname` varchar(255) NOT NULL DEFAULT '',
+                       `objectname` varchar(1024) NOT NULL DEFAULT '',
+                       `version` bigint(20) unsigned NOT NULL DEFAULT 0,
+                       `status` tinyint(1) NOT NULL DEFAULT 0,
+                       `attempts` int(11) NOT NULL DEFAULT 0,
+                       `updatetime` datetime DEFAULT NULL,
+                       UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`),
+                       KEY `status` (`status`,`updatetime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
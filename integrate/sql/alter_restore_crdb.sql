@@ -0,0 +1,10 @@
+-- Upgrade table restoreobjects of an existing cockroachdb deployment, for
+-- restore tiers and expiry of restored copies, see yig_restore
+
+ALTER TABLE yig.restoreobjects ALTER COLUMN lifetime TYPE integer;
+
+ALTER TABLE yig.restoreobjects ADD COLUMN tier smallint DEFAULT '1'::smallint;
+
+ALTER TABLE yig.restoreobjects ADD COLUMN expiretime timestamp with time zone DEFAULT NULL;
+
+CREATE INDEX idx_restoreobjects_status ON yig.restoreobjects USING btree (status, tier, lastmodifiedtime);
@@ -0,0 +1,10 @@
+-- Upgrade table `restoreobjects` of an existing tidb deployment, for restore
+-- tiers and expiry of restored copies, see yig_restore
+
+ALTER TABLE `restoreobjects` MODIFY COLUMN `lifetime` int(11) DEFAULT '1';
+
+ALTER TABLE `restoreobjects` ADD COLUMN `tier` tinyint(1) DEFAULT '1';
+
+ALTER TABLE `restoreobjects` ADD COLUMN `expiretime` datetime DEFAULT NULL;
+
+ALTER TABLE `restoreobjects` ADD INDEX `status` (`status`,`tier`,`lastmodifiedtime`);
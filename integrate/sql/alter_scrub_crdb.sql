@@ -0,0 +1,19 @@
+-- Upgrade an existing cockroachdb deployment with table scrubfindings, where
+-- yig_scrub records objects whose data does not match their Etag
+
+CREATE TABLE IF NOT EXISTS yig.scrubfindings (
+    bucketname character varying(255) NOT NULL DEFAULT '',
+    objectname character varying(1024) NOT NULL DEFAULT '',
+    version character varying(255) NOT NULL DEFAULT '',
+    partnumber bigint NOT NULL DEFAULT 0,
+    location character varying(255) DEFAULT NULL,
+    pool character varying(255) DEFAULT NULL,
+    objectid character varying(255) DEFAULT NULL,
+    type character varying(255) DEFAULT NULL,
+    detail character varying(1024) DEFAULT NULL,
+    detecttime timestamp with time zone DEFAULT NULL
+);
+
+ALTER TABLE yig.scrubfindings OWNER TO yig;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scrubfindings_rowkey ON yig.scrubfindings USING btree (bucketname, objectname, version, partnumber);
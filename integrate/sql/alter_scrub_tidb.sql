@@ -0,0 +1,16 @@
+-- Upgrade an existing tidb deployment with table `scrubfindings`, where
+-- yig_scrub records objects whose data does not match their Etag
+
+CREATE TABLE IF NOT EXISTS `scrubfindings` (
+                       `bucketname` varchar(255) NOT NULL DEFAULT '',
+                       `objectname` varchar(1024) NOT NULL DEFAULT '',
+                       `version` varchar(255) NOT NULL DEFAULT '',
+                       `partnumber` int(11) NOT NULL DEFAULT 0,
+                       `location` varchar(255) DEFAULT NULL,
+                       `pool` varchar(255) DEFAULT NULL,
+                       `objectid` varchar(255) DEFAULT NULL,
+                       `type` varchar(255) DEFAULT NULL,
+                       `detail` varchar(1024) DEFAULT NULL,
+                       `detecttime` datetime DEFAULT NULL,
+                       UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`,`partnumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
@@ -0,0 +1,17 @@
+-- Upgrade an existing cockroachdb deployment with table objectaccess, last
+-- access times of INTELLIGENT_TIERING objects used by yig_tiering
+
+CREATE TABLE IF NOT EXISTS yig.objectaccess (
+    bucketname character varying(255) NOT NULL DEFAULT '',
+    objectname character varying(1024) NOT NULL DEFAULT '',
+    version decimal(20) NOT NULL DEFAULT 0,
+    lastaccesstime timestamp with time zone DEFAULT NULL,
+    tier smallint NOT NULL DEFAULT 0,
+    tiertime timestamp with time zone DEFAULT NULL
+);
+
+ALTER TABLE yig.objectaccess OWNER TO yig;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_objectaccess_rowkey ON yig.objectaccess USING btree (bucketname, objectname, version);
+
+CREATE INDEX IF NOT EXISTS idx_objectaccess_tier ON yig.objectaccess USING btree (tier, lastaccesstime);
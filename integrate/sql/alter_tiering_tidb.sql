@@ -0,0 +1,13 @@
+-- Upgrade an existing tidb deployment with table `objectaccess`, last access
+-- times of INTELLIGENT_TIERING objects used by yig_tiering
+
+CREATE TABLE IF NOT EXISTS `objectaccess` (
+                       `bucketname` varchar(255) NOT NULL DEFAULT '',
+                       `objectname` varchar(1024) NOT NULL DEFAULT '',
+                       `version` bigint(20) unsigned NOT NULL DEFAULT 0,
+                       `lastaccesstime` datetime DEFAULT NULL,
+                       `tier` tinyint(1) NOT NULL DEFAULT 0,
+                       `tiertime` datetime DEFAULT NULL,
+                       UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`),
+                       KEY `tier` (`tier`,`lastaccesstime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
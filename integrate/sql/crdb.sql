@@ -34,7 +34,11 @@ CREATE TABLE yig.buckets (
     encryption json DEFAULT NULL,
     createtime timestamp with time zone DEFAULT NULL,
     usages bigint DEFAULT NULL,
-    versioning character varying(255)
+    versioning character varying(255),
+    tags json DEFAULT NULL,
+    notification json DEFAULT NULL,
+    replication json DEFAULT NULL,
+    objectlock json DEFAULT NULL
 );
 
 
@@ -47,7 +51,8 @@ ALTER TABLE yig.buckets OWNER TO yig;
 CREATE TABLE yig.cluster (
     fsid character varying(255) DEFAULT NULL,
     pool character varying(255) DEFAULT NULL,
-    weight bigint DEFAULT NULL
+    weight bigint DEFAULT NULL,
+    storageclass character varying(64) DEFAULT '' NOT NULL
 );
 
 
@@ -99,7 +104,13 @@ ALTER TABLE yig.gcpart OWNER TO yig;
 
 CREATE TABLE yig.lifecycle (
     bucketname character varying(255) DEFAULT NULL,
-    status character varying(255) DEFAULT NULL
+    status character varying(255) DEFAULT NULL,
+    owner character varying(255) NOT NULL DEFAULT '',
+    leaseexpire timestamp with time zone DEFAULT NULL,
+    marker character varying(1024) NOT NULL DEFAULT '',
+    scanned bigint NOT NULL DEFAULT 0,
+    laststart timestamp with time zone DEFAULT NULL,
+    lastfinish timestamp with time zone DEFAULT NULL
 );
 
 
@@ -143,7 +154,9 @@ CREATE TABLE yig.multiparts (
     encryption bytea DEFAULT NULL,
     cipher bytea DEFAULT NULL,
     attrs json DEFAULT NULL,
-    storageclass smallint DEFAULT '0'::smallint 
+    storageclass smallint DEFAULT '0'::smallint,
+    tags json DEFAULT NULL,
+    objectlock json DEFAULT NULL
 );
 
 
@@ -192,7 +205,10 @@ CREATE TABLE yig.objects (
     encryptionkey bytea DEFAULT NULL,
     initializationvector bytea DEFAULT NULL,
     type smallint DEFAULT '0'::smallint,
-    storageclass smallint DEFAULT '0'::smallint
+    storageclass smallint DEFAULT '0'::smallint,
+    tags json DEFAULT NULL,
+    replicationstatus smallint DEFAULT '0'::smallint,
+    objectlock json DEFAULT NULL
 );
 
 ALTER TABLE yig.objects OWNER TO yig;
@@ -239,19 +255,119 @@ CREATE TABLE yig.restoreobjects (
     objectname character varying(255) DEFAULT NULL,
     version decimal(20) DEFAULT NULL,
     status smallint DEFAULT '0'::smallint,
-    lifetime smallint DEFAULT '1'::smallint,
+    lifetime integer DEFAULT 1,
     lastmodifiedtime timestamp with time zone DEFAULT NULL,
     location character varying(255) DEFAULT NULL,
     pool character varying(255) DEFAULT NULL,
     ownerid character varying(255) DEFAULT NULL,
     size bigint DEFAULT NULL,
     objectid character varying(255) DEFAULT NULL,
-    etag character varying(255) DEFAULT NULL
+    etag character varying(255) DEFAULT NULL,
+    tier smallint DEFAULT '1'::smallint,
+    expiretime timestamp with time zone DEFAULT NULL
 );
 
 
 ALTER TABLE yig.restoreobjects OWNER TO yig;
 
+--
+-- Name: scrubfindings; Type: TABLE; Schema: yig; Owner: yig
+--
+
+CREATE TABLE yig.scrubfindings (
+    bucketname character varying(255) NOT NULL DEFAULT '',
+    objectname character varying(1024) NOT NULL DEFAULT '',
+    version character varying(255) NOT NULL DEFAULT '',
+    partnumber bigint NOT NULL DEFAULT 0,
+    location character varying(255) DEFAULT NULL,
+    pool character varying(255) DEFAULT NULL,
+    objectid character varying(255) DEFAULT NULL,
+    type character varying(255) DEFAULT NULL,
+    detail character varying(1024) DEFAULT NULL,
+    detecttime timestamp with time zone DEFAULT NULL
+);
+
+
+ALTER TABLE yig.scrubfindings OWNER TO yig;
+
+--
+-- Name: recycle; Type: TABLE; Schema: yig; Owner: yig
+--
+
+CREATE TABLE yig.recycle (
+    location character varying(255) NOT NULL DEFAULT '',
+    pool character varying(255) NOT NULL DEFAULT '',
+    objectid character varying(255) NOT NULL DEFAULT '',
+    createtime timestamp with time zone DEFAULT NULL
+);
+
+
+ALTER TABLE yig.recycle OWNER TO yig;
+
+--
+-- Name: blobrefs; Type: TABLE; Schema: yig; Owner: yig
+--
+
+CREATE TABLE yig.blobrefs (
+    location character varying(255) NOT NULL DEFAULT '',
+    pool character varying(255) NOT NULL DEFAULT '',
+    objectid character varying(255) NOT NULL DEFAULT '',
+    refcount bigint NOT NULL DEFAULT 0
+);
+
+
+ALTER TABLE yig.blobrefs OWNER TO yig;
+
+--
+-- Name: erasurelayouts; Type: TABLE; Schema: yig; Owner: yig
+--
+
+CREATE TABLE yig.erasurelayouts (
+    location character varying(255) NOT NULL DEFAULT '',
+    pool character varying(255) NOT NULL DEFAULT '',
+    objectid character varying(255) NOT NULL DEFAULT '',
+    datashards bigint NOT NULL DEFAULT 0,
+    parityshards bigint NOT NULL DEFAULT 0,
+    blocksize bigint NOT NULL DEFAULT 0,
+    size bigint NOT NULL DEFAULT 0,
+    shards json DEFAULT NULL
+);
+
+
+ALTER TABLE yig.erasurelayouts OWNER TO yig;
+
+--
+-- Name: objectaccess; Type: TABLE; Schema: yig; Owner: yig
+--
+
+CREATE TABLE yig.objectaccess (
+    bucketname character varying(255) NOT NULL DEFAULT '',
+    objectname character varying(1024) NOT NULL DEFAULT '',
+    version decimal(20) NOT NULL DEFAULT 0,
+    lastaccesstime timestamp with time zone DEFAULT NULL,
+    tier smallint NOT NULL DEFAULT 0,
+    tiertime timestamp with time zone DEFAULT NULL
+);
+
+
+ALTER TABLE yig.objectaccess OWNER TO yig;
+
+--
+-- Name: replication; Type: TABLE; Schema: yig; Owner: yig
+--
+
+CREATE TABLE yig.replication (
+    bucketname character varying(255) NOT NULL DEFAULT '',
+    objectname character varying(1024) NOT NULL DEFAULT '',
+    version decimal(20) NOT NULL DEFAULT 0,
+    status smallint NOT NULL DEFAULT 0,
+    attempts integer NOT NULL DEFAULT 0,
+    updatetime timestamp with time zone DEFAULT NULL
+);
+
+
+ALTER TABLE yig.replication OWNER TO yig;
+
 --
 -- Name: users; Type: TABLE; Schema: yig; Owner: yig
 --
@@ -274,7 +390,7 @@ ALTER TABLE ONLY yig.buckets
 -- Name: idx_16394_rowkey; Type: INDEX; Schema: yig; Owner: yig
 --
 
-CREATE UNIQUE INDEX idx_16394_rowkey ON yig.cluster USING btree (fsid, pool);
+CREATE UNIQUE INDEX idx_16394_rowkey ON yig.cluster USING btree (fsid, pool, storageclass);
 
 --
 -- Name: idx_16399_rowkey; Type: INDEX; Schema: yig; Owner: yig
@@ -312,6 +428,12 @@ CREATE INDEX idx_16425_rowkey ON yig.objectpart USING btree (bucketname, objectn
 
 CREATE UNIQUE INDEX idx_16430_rowkey ON yig.objects USING btree (bucketname, name, version);
 
+--
+-- Name: idx_objects_location; Type: INDEX; Schema: yig; Owner: yig
+--
+
+CREATE INDEX idx_objects_location ON yig.objects USING btree (location, bucketname, name, version);
+
 --
 -- Name: idx_16437_objmap; Type: INDEX; Schema: yig; Owner: yig
 --
@@ -329,3 +451,63 @@ CREATE INDEX idx_16442_rowkey ON yig.restoreobjectpart USING btree (bucketname,
 --
 
 CREATE UNIQUE INDEX idx_16447_rowkey ON yig.restoreobjects USING btree (bucketname, objectname, version);
+
+--
+-- Name: idx_scrubfindings_rowkey; Type: INDEX; Schema: yig; Owner: yig
+--
+
+CREATE UNIQUE INDEX idx_scrubfindings_rowkey ON yig.scrubfindings USING btree (bucketname, objectname, version, partnumber);
+
+--
+-- Name: idx_recycle_rowkey; Type: INDEX; Schema: yig; Owner: yig
+--
+
+CREATE UNIQUE INDEX idx_recycle_rowkey ON yig.recycle USING btree (location, pool, objectid);
+
+--
+-- Name: idx_blobrefs_rowkey; Type: INDEX; Schema: yig; Owner: yig
+--
+
+CREATE UNIQUE INDEX idx_blobrefs_rowkey ON yig.blobrefs USING btree (location, pool, objectid);
+
+--
+-- Name: idx_erasurelayouts_rowkey; Type: INDEX; Schema: yig; Owner: yig
+--
+
+CREATE UNIQUE INDEX idx_erasurelayouts_rowkey ON yig.erasurelayouts USING btree (location, pool, objectid);
+
+--
+-- Name: idx_lifecycle_rowkey; Type: INDEX; Schema: yig; Owner: yig
+--
+
+CREATE UNIQUE INDEX idx_lifecycle_rowkey ON yig.lifecycle USING btree (bucketname);
+
+--
+-- Name: idx_restoreobjects_status; Type: INDEX; Schema: yig; Owner: yig
+--
+
+CREATE INDEX idx_restoreobjects_status ON yig.restoreobjects USING btree (status, tier, lastmodifiedtime);
+
+--
+-- Name: idx_objectaccess_rowkey; Type: INDEX; Schema: yig; Owner: yig
+--
+
+CREATE UNIQUE INDEX idx_objectaccess_rowkey ON yig.objectaccess USING btree (bucketname, objectname, version);
+
+--
+-- Name: idx_objectaccess_tier; Type: INDEX; Schema: yig; Owner: yig
+--
+
+CREATE INDEX idx_objectaccess_tier ON yig.objectaccess USING btree (tier, lastaccesstime);
+
+--
+-- Name: idx_replication_rowkey; Type: INDEX; Schema: yig; Owner: yig
+--
+
+CREATE UNIQUE INDEX idx_replication_rowkey ON yig.replication USING btree (bucketname, objectname, version);
+
+--
+-- Name: idx_replication_status; Type: INDEX; Schema: yig; Owner: yig
+--
+
+CREATE INDEX idx_replication_status ON yig.replication USING btree (status, updatetime);
@@ -35,6 +35,10 @@ CREATE TABLE `buckets` (
   `createtime` datetime DEFAULT NULL,
   `usages` bigint(20) DEFAULT NULL,
   `versioning` varchar(255) DEFAULT NULL,
+  `tags` JSON DEFAULT NULL,
+  `notification` JSON DEFAULT NULL,
+  `replication` JSON DEFAULT NULL,
+  `objectlock` JSON DEFAULT NULL,
   PRIMARY KEY (`bucketname`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 /*!40101 SET character_set_client = @saved_cs_client */;
@@ -50,7 +54,8 @@ CREATE TABLE `cluster` (
   `fsid` varchar(255) DEFAULT NULL,
   `pool` varchar(255) DEFAULT NULL,
   `weight` int(11) DEFAULT NULL,
-   UNIQUE KEY `rowkey` (`fsid`,`pool`)
+  `storageclass` varchar(64) NOT NULL DEFAULT '',
+   UNIQUE KEY `rowkey` (`fsid`,`pool`,`storageclass`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -142,6 +147,8 @@ CREATE TABLE `multiparts` (
   `cipher` blob DEFAULT NULL,
   `attrs` JSON DEFAULT NULL,
   `storageclass` tinyint(1) DEFAULT 0,
+  `tags` JSON DEFAULT NULL,
+  `objectlock` JSON DEFAULT NULL,
   UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`uploadtime`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 /*!40101 SET character_set_client = @saved_cs_client */;
@@ -197,7 +204,11 @@ CREATE TABLE `objects` (
   `initializationvector` blob DEFAULT NULL,
   `type` tinyint(1) DEFAULT 0,
   `storageclass` tinyint(1) DEFAULT 0,
-   UNIQUE KEY `rowkey` (`bucketname`,`name`,`version`)
+  `tags` JSON DEFAULT NULL,
+  `replicationstatus` tinyint(1) DEFAULT 0,
+  `objectlock` JSON DEFAULT NULL,
+   UNIQUE KEY `rowkey` (`bucketname`,`name`,`version`),
+   KEY `location` (`location`,`bucketname`,`name`,`version`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -235,7 +246,7 @@ CREATE TABLE `restoreobjects` (
   `objectname` varchar(255) DEFAULT NULL,
   `version` bigint(20) unsigned DEFAULT NULL,
   `status` tinyint(1) DEFAULT '0',
-  `lifetime` tinyint(2) DEFAULT '1',
+  `lifetime` int(11) DEFAULT '1',
   `lastmodifiedtime` datetime DEFAULT NULL,
   `location` varchar(255) DEFAULT NULL,
   `pool` varchar(255) DEFAULT NULL,
@@ -243,7 +254,10 @@ CREATE TABLE `restoreobjects` (
   `size` bigint(20) DEFAULT NULL,
   `objectid` varchar(255) DEFAULT NULL,
   `etag` varchar(255) DEFAULT NULL,
-  UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`)
+  `tier` tinyint(1) DEFAULT '1',
+  `expiretime` datetime DEFAULT NULL,
+  UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`),
+  KEY `status` (`status`,`tier`,`lastmodifiedtime`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 /*!40101 SET character_set_client = @saved_cs_client */;
 
@@ -290,5 +304,81 @@ CREATE TABLE `users` (
 DROP TABLE IF EXISTS `lifecycle`;
 CREATE TABLE `lifecycle` (
                        `bucketname` varchar(255) DEFAULT NULL,
-                       `status` varchar(255) DEFAULT NULL
-) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
\ No newline at end of file
+                       `status` varchar(255) DEFAULT NULL,
+                       `owner` varchar(255) NOT NULL DEFAULT '',
+                       `leaseexpire` datetime DEFAULT NULL,
+                       `marker` varchar(1024) NOT NULL DEFAULT '',
+                       `scanned` bigint(20) NOT NULL DEFAULT 0,
+                       `laststart` datetime DEFAULT NULL,
+                       `lastfinish` datetime DEFAULT NULL,
+                       UNIQUE KEY `rowkey` (`bucketname`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+DROP TABLE IF EXISTS `scrubfindings`;
+CREATE TABLE `scrubfindings` (
+                       `bucketname` varchar(255) NOT NULL DEFAULT '',
+                       `objectname` varchar(1024) NOT NULL DEFAULT '',
+                       `version` varchar(255) NOT NULL DEFAULT '',
+                       `partnumber` int(11) NOT NULL DEFAULT 0,
+                       `location` varchar(255) DEFAULT NULL,
+                       `pool` varchar(255) DEFAULT NULL,
+                       `objectid` varchar(255) DEFAULT NULL,
+                       `type` varchar(255) DEFAULT NULL,
+                       `detail` varchar(1024) DEFAULT NULL,
+                       `detecttime` datetime DEFAULT NULL,
+                       UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`,`partnumber`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+DROP TABLE IF EXISTS `recycle`;
+CREATE TABLE `recycle` (
+                       `location` varchar(255) NOT NULL DEFAULT '',
+                       `pool` varchar(255) NOT NULL DEFAULT '',
+                       `objectid` varchar(255) NOT NULL DEFAULT '',
+                       `createtime` datetime DEFAULT NULL,
+                       UNIQUE KEY `rowkey` (`location`,`pool`,`objectid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+DROP TABLE IF EXISTS `blobrefs`;
+CREATE TABLE `blobrefs` (
+                       `location` varchar(255) NOT NULL DEFAULT '',
+                       `pool` varchar(255) NOT NULL DEFAULT '',
+                       `objectid` varchar(255) NOT NULL DEFAULT '',
+                       `refcount` bigint(20) UNSIGNED NOT NULL DEFAULT 0,
+                       UNIQUE KEY `rowkey` (`location`,`pool`,`objectid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+DROP TABLE IF EXISTS `erasurelayouts`;
+CREATE TABLE `erasurelayouts` (
+                       `location` varchar(255) NOT NULL DEFAULT '',
+                       `pool` varchar(255) NOT NULL DEFAULT '',
+                       `objectid` varchar(255) NOT NULL DEFAULT '',
+                       `datashards` int(11) NOT NULL DEFAULT 0,
+                       `parityshards` int(11) NOT NULL DEFAULT 0,
+                       `blocksize` bigint(20) NOT NULL DEFAULT 0,
+                       `size` bigint(20) NOT NULL DEFAULT 0,
+                       `shards` JSON DEFAULT NULL,
+                       UNIQUE KEY `rowkey` (`location`,`pool`,`objectid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+DROP TABLE IF EXISTS `objectaccess`;
+CREATE TABLE `objectaccess` (
+                       `bucketname` varchar(255) NOT NULL DEFAULT '',
+                       `objectname` varchar(1024) NOT NULL DEFAULT '',
+                       `version` bigint(20) unsigned NOT NULL DEFAULT 0,
+                       `lastaccesstime` datetime DEFAULT NULL,
+                       `tier` tinyint(1) NOT NULL DEFAULT 0,
+                       `tiertime` datetime DEFAULT NULL,
+                       UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`),
+                       KEY `tier` (`tier`,`lastaccesstime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+DROP TABLE IF EXISTS `replication`;
+CREATE TABLE `replication` (
+                       `bucketname` varchar(255) NOT NULL DEFAULT '',
+                       `objectname` varchar(1024) NOT NULL DEFAULT '',
+                       `version` bigint(20) unsigned NOT NULL DEFAULT 0,
+                       `status` tinyint(1) NOT NULL DEFAULT 0,
+                       `attempts` int(11) NOT NULL DEFAULT 0,
+                       `updatetime` datetime DEFAULT NULL,
+                       UNIQUE KEY `rowkey` (`bucketname`,`objectname`,`version`),
+                       KEY `status` (`status`,`updatetime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
@@ -47,7 +47,7 @@ func main() {
 
 	kms := crypto.NewKMS(allPluginMap)
 
-	yig := storage.New(helper.CONFIG.MetaCacheType, helper.CONFIG.EnableDataCache, kms)
+	yig := storage.New(helper.CONFIG.MetaCacheType, helper.CONFIG.EnableDataCache, kms, allPluginMap)
 	adminServerConfig := &adminServerConfig{
 		Address: helper.CONFIG.BindAdminAddress,
 		Logger:  helper.Logger,
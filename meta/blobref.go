@@ -0,0 +1,133 @@
+package meta
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+// Blobs stored in backend clusters could be shared by several objects, e.g.
+// an object and its copies. Shared blobs are reference counted in table
+// `blobrefs`, and removed from backend only after the last reference is
+// garbage collected.
+
+func blobsOfObject(object *types.Object) (blobs []types.Recycle) {
+	if len(object.Parts) == 0 {
+		return []types.Recycle{{
+			Location: object.Location,
+			Pool:     object.Pool,
+			ObjectId: object.ObjectId,
+		}}
+	}
+	for _, p := range object.Parts {
+		blobs = append(blobs, types.Recycle{
+			Location: object.Location,
+			Pool:     object.Pool,
+			ObjectId: p.ObjectId,
+		})
+	}
+	return blobs
+}
+
+// Insert `object` which shares data with `sourceObject`. Returns
+// types.ErrObjectChanged if data of `sourceObject` is moved or deleted.
+func (m *Meta) PutSharedObject(object, sourceObject *types.Object, objMap *types.ObjMap,
+	updateUsage bool) (err error) {
+
+	var tx *sql.Tx
+	tx, err = m.Client.NewTrans()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			err = m.Client.CommitTrans(tx)
+		}
+		if err != nil {
+			m.Client.AbortTrans(tx)
+		}
+	}()
+
+	err = m.Client.LockObjectData(sourceObject, tx)
+	if err != nil {
+		return err
+	}
+	for _, blob := range blobsOfObject(sourceObject) {
+		err = m.Client.AcquireBlob(blob.Location, blob.Pool, blob.ObjectId, tx)
+		if err != nil {
+			return err
+		}
+	}
+	err = m.Client.PutObject(object, tx)
+	if err != nil {
+		return err
+	}
+	if objMap != nil {
+		err = m.Client.PutObjectMap(objMap, tx)
+		if err != nil {
+			return err
+		}
+	}
+	if updateUsage {
+		err = m.Client.UpdateUsage(object.BucketName, object.Size, tx)
+	}
+	return err
+}
+
+// Drop references held by `garbage` and remove it from table `gc`. Blobs no
+// longer referenced are saved to table `recycle` in the same transaction and
+// returned, the caller should remove them from backend.
+func (m *Meta) ReleaseGarbageCollection(garbage types.GarbageCollection) (unshared []types.Recycle, err error) {
+	var tx *sql.Tx
+	tx, err = m.Client.NewTrans()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err == nil {
+			err = m.Client.CommitTrans(tx)
+		}
+		if err != nil {
+			m.Client.AbortTrans(tx)
+			unshared = nil
+		}
+	}()
+
+	var blobs []types.Recycle
+	if len(garbage.Parts) == 0 {
+		blobs = append(blobs, types.Recycle{
+			Location: garbage.Location,
+			Pool:     garbage.Pool,
+			ObjectId: garbage.ObjectId,
+		})
+	}
+	for _, p := range garbage.Parts {
+		blobs = append(blobs, types.Recycle{
+			Location: garbage.Location,
+			Pool:     garbage.Pool,
+			ObjectId: p.ObjectId,
+		})
+	}
+	for _, blob := range blobs {
+		if blob.ObjectId == "" {
+			continue
+		}
+		var shared bool
+		shared, err = m.Client.ReleaseBlob(blob.Location, blob.Pool, blob.ObjectId, tx)
+		if err != nil {
+			return
+		}
+		if shared {
+			continue
+		}
+		blob.CreateTime = time.Now().UTC()
+		err = m.Client.PutRecycle(blob, tx)
+		if err != nil {
+			return
+		}
+		unshared = append(unshared, blob)
+	}
+	err = m.Client.RemoveGarbageCollection(garbage, tx)
+	return
+}
@@ -2,6 +2,8 @@ package client
 
 import (
 	"database/sql"
+	"time"
+
 	"github.com/journeymidnight/yig/api/datatype"
 	. "github.com/journeymidnight/yig/meta/types"
 )
@@ -24,6 +26,10 @@ type Client interface {
 	UpdateObject(object *Object, tx DB) (err error)
 	UpdateObjectAcl(object *Object) error
 	UpdateObjectAttrs(object *Object) error
+	UpdateObjectTags(object *Object) error
+	UpdateObjectLock(object *Object) error
+	ScanObjectsByLocation(location string, limit int, startRowKey string) (objects []*Object, err error)
+	UpdateObjectLocation(object *Object, oldLocation, oldObjectId string, tx DB) (err error)
 	//bucket
 	GetBucket(bucketName string) (bucket *Bucket, err error)
 	GetBuckets() (buckets []Bucket, err error)
@@ -49,6 +55,9 @@ type Client interface {
 	PutBucketToLifeCycle(lifeCycle LifeCycle) error
 	RemoveBucketFromLifeCycle(bucket Bucket) error
 	ScanLifeCycle(limit int, marker string) (result ScanLifeCycleResult, err error)
+	AcquireLifeCycleLease(bucketName, owner string, expire, passStart time.Time) (lc LifeCycle, ok bool, err error)
+	RenewLifeCycleLease(lc LifeCycle) (ok bool, err error)
+	ReleaseLifeCycleLease(lc LifeCycle, finished bool) error
 	//user
 	GetUserBuckets(userId string) (buckets []string, err error)
 	AddBucketForUser(bucketName, userId string) (err error)
@@ -57,11 +66,47 @@ type Client interface {
 	PutObjectToGarbageCollection(object *Object, tx DB) error
 	PutFreezerToGarbageCollection(object *Freezer, tx DB) (err error)
 	ScanGarbageCollection(limit int, startRowKey string) ([]GarbageCollection, error)
-	RemoveGarbageCollection(garbage GarbageCollection) error
+	RemoveGarbageCollection(garbage GarbageCollection, tx DB) error
+	//scrub
+	PutScrubFinding(finding ScrubFinding) error
+	RemoveScrubFindingsBefore(t time.Time) error
+	CountScrubFindings() (counts []ScrubFindingCount, err error)
+	//recycle
+	PutRecycle(recycle Recycle, tx DB) error
+	RemoveRecycle(recycle Recycle) error
+	ScanRecycles(limit int, startRowKey string, before time.Time) (recycles []Recycle, err error)
+	//blob reference
+	AcquireBlob(location, pool, objectId string, tx DB) error
+	ReleaseBlob(location, pool, objectId string, tx DB) (shared bool, err error)
+	LockObjectData(object *Object, tx DB) error
+	//orphan
+	// call walkFn for every blob referenced by meta in cluster `location`
+	WalkBlobReferences(location string, walkFn func(pool, objectId string) error) error
+	//erasure layout
+	PutErasureLayout(layout ErasureLayout) error
+	GetErasureLayout(location, pool, objectId string) (layout ErasureLayout, err error)
+	RemoveErasureLayout(layout ErasureLayout) error
+	WalkErasureLayouts(walkFn func(layout ErasureLayout) error) error
+	//object access
+	PutObjectAccesses(accesses []*ObjectAccess) error
+	ListColdObjects(limit int, before time.Time) (accesses []*ObjectAccess, err error)
+	ListWarmedObjects(limit int) (accesses []*ObjectAccess, err error)
+	UpdateObjectAccessTier(access *ObjectAccess) error
+	RemoveObjectAccess(access *ObjectAccess) error
 	//freezer
 	CreateFreezer(freezer *Freezer) (err error)
 	GetFreezer(bucketName, objectName, version string) (freezer *Freezer, err error)
 	GetFreezerStatus(bucketName, objectName, version string) (freezer *Freezer, err error)
-	UploadFreezerDate(bucketName, objectName string, lifetime int) (err error)
+	UploadFreezerDate(bucketName, objectName string, lifetime int, expireTime time.Time) (err error)
+	ListPendingFreezers(limit int, staleBefore time.Time) (freezers []*Freezer, err error)
+	ClaimFreezer(freezer *Freezer, claimTime time.Time) (ok bool, err error)
+	FinishFreezer(freezer *Freezer) (ok bool, err error)
+	ListExpiredFreezers(limit int, before time.Time) (freezers []*Freezer, err error)
 	DeleteFreezer(bucketName, objectName string, tx DB) (err error)
+	//replication
+	PutReplicationTask(task *ReplicationTask, tx DB) error
+	ListPendingReplicationTasks(limit int, now, staleBefore time.Time) (tasks []*ReplicationTask, err error)
+	ClaimReplicationTask(task *ReplicationTask, claimTime time.Time) (ok bool, err error)
+	RetryReplicationTask(task *ReplicationTask, retryTime time.Time) error
+	FinishReplicationTask(task *ReplicationTask, status ReplicationStatus) error
 }
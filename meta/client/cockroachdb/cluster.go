@@ -5,7 +5,7 @@ import (
 )
 
 func (t *CockroachDBClient) GetClusters() (cluster []types.Cluster, err error) {
-	sqltext := "select fsid,pool,weight from cluster"
+	sqltext := "select fsid,pool,weight,storageclass from cluster"
 	rows, err := t.Client.Query(sqltext)
 	if err != nil {
 		return nil, err
@@ -13,7 +13,7 @@ func (t *CockroachDBClient) GetClusters() (cluster []types.Cluster, err error) {
 	defer rows.Close()
 	for rows.Next() {
 		c := types.Cluster{}
-		err = rows.Scan(&c.Fsid, &c.Pool, &c.Weight)
+		err = rows.Scan(&c.Fsid, &c.Pool, &c.Weight, &c.StorageClass)
 		cluster = append(cluster, c)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,62 @@
+package cockroachdb
+
+import (
+	"encoding/json"
+
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+//erasure layout
+func (t *CockroachDBClient) PutErasureLayout(layout types.ErasureLayout) error {
+	shards, err := json.Marshal(layout.Shards)
+	if err != nil {
+		return err
+	}
+	sqltext := "insert into erasurelayouts(location,pool,objectid,datashards,parityshards,blocksize,size,shards) values($1,$2,$3,$4,$5,$6,$7,$8);"
+	_, err = t.Client.Exec(sqltext, layout.Location, layout.Pool, layout.ObjectId, layout.DataShards,
+		layout.ParityShards, layout.BlockSize, layout.Size, shards)
+	return err
+}
+
+func (t *CockroachDBClient) GetErasureLayout(location, pool, objectId string) (layout types.ErasureLayout, err error) {
+	sqltext := "select location,pool,objectid,datashards,parityshards,blocksize,size,shards from erasurelayouts where location=$1 and pool=$2 and objectid=$3;"
+	var shards []byte
+	err = t.Client.QueryRow(sqltext, location, pool, objectId).Scan(&layout.Location, &layout.Pool,
+		&layout.ObjectId, &layout.DataShards, &layout.ParityShards, &layout.BlockSize, &layout.Size, &shards)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(shards, &layout.Shards)
+	return
+}
+
+func (t *CockroachDBClient) RemoveErasureLayout(layout types.ErasureLayout) error {
+	sqltext := "delete from erasurelayouts where location=$1 and pool=$2 and objectid=$3;"
+	_, err := t.Client.Exec(sqltext, layout.Location, layout.Pool, layout.ObjectId)
+	return err
+}
+
+func (t *CockroachDBClient) WalkErasureLayouts(walkFn func(layout types.ErasureLayout) error) error {
+	sqltext := "select location,pool,objectid,datashards,parityshards,blocksize,size,shards from erasurelayouts;"
+	rows, err := t.Client.Query(sqltext)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var layout types.ErasureLayout
+		var shards []byte
+		err = rows.Scan(&layout.Location, &layout.Pool, &layout.ObjectId, &layout.DataShards,
+			&layout.ParityShards, &layout.BlockSize, &layout.Size, &shards)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(shards, &layout.Shards); err != nil {
+			return err
+		}
+		if err = walkFn(layout); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
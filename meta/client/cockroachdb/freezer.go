@@ -17,7 +17,8 @@ func (t *CockroachDBClient) CreateFreezer(freezer *types.Freezer) (err error) {
 
 func (t *CockroachDBClient) GetFreezer(bucketName, objectName, version string) (freezer *types.Freezer, err error) {
 	var lastmodifiedtime string
-	sqltext := "select bucketname,objectname,IFNULL(version,''),status,lifetime,lastmodifiedtime,IFNULL(location,''),IFNULL(pool,''),IFNULL(ownerid,''),IFNULL(size,'0'),IFNULL(objectid,''),IFNULL(etag,'') from restoreobjects where bucketname=$1 and objectname=$2;"
+	var expireTime sql.NullString
+	sqltext := "select bucketname,objectname,IFNULL(version,''),status,lifetime,lastmodifiedtime,IFNULL(location,''),IFNULL(pool,''),IFNULL(ownerid,''),IFNULL(size,'0'),IFNULL(objectid,''),IFNULL(etag,''),IFNULL(tier,1),expiretime from restoreobjects where bucketname=$1 and objectname=$2;"
 	row := t.Client.QueryRow(sqltext, bucketName, objectName)
 	freezer = &types.Freezer{}
 	err = row.Scan(
@@ -33,6 +34,8 @@ func (t *CockroachDBClient) GetFreezer(bucketName, objectName, version string) (
 		&freezer.Size,
 		&freezer.ObjectId,
 		&freezer.Etag,
+		&freezer.Tier,
+		&expireTime,
 	)
 	if err == sql.ErrNoRows {
 		err = e.ErrNoSuchKey
@@ -42,6 +45,10 @@ func (t *CockroachDBClient) GetFreezer(bucketName, objectName, version string) (
 	}
 	local, _ := time.LoadLocation("Local")
 	freezer.LastModifiedTime, _ = time.ParseInLocation(helper.CONFIG.TimeFormat, lastmodifiedtime, local)
+	freezer.ExpireTime, err = parseNullTime(expireTime)
+	if err != nil {
+		return
+	}
 	freezer.Parts, err = getFreezerParts(freezer.BucketName, freezer.Name, t.Client)
 	//build simple index for multipart
 	if len(freezer.Parts) != 0 {
@@ -55,7 +62,8 @@ func (t *CockroachDBClient) GetFreezer(bucketName, objectName, version string) (
 }
 
 func (t *CockroachDBClient) GetFreezerStatus(bucketName, objectName, version string) (freezer *types.Freezer, err error) {
-	sqltext := "select bucketname,objectname,IFNULL(version,''),status from restoreobjects where bucketname=$1 and objectname=$2;"
+	var expireTime sql.NullString
+	sqltext := "select bucketname,objectname,IFNULL(version,''),status,lifetime,expiretime from restoreobjects where bucketname=$1 and objectname=$2;"
 	row := t.Client.QueryRow(sqltext, bucketName, objectName)
 	freezer = &types.Freezer{}
 	err = row.Scan(
@@ -63,21 +71,135 @@ func (t *CockroachDBClient) GetFreezerStatus(bucketName, objectName, version str
 		&freezer.Name,
 		&freezer.VersionId,
 		&freezer.Status,
+		&freezer.LifeTime,
+		&expireTime,
 	)
 	if err == sql.ErrNoRows || freezer.Name != objectName {
 		err = e.ErrNoSuchKey
 		return
+	} else if err != nil {
+		return
 	}
+	freezer.ExpireTime, err = parseNullTime(expireTime)
 	return
 }
 
-func (t *CockroachDBClient) UploadFreezerDate(bucketName, objectName string, lifetime int) (err error) {
-	sqltext := "update restoreobjects set lifetime=$1 where bucketname=$2 and objectname=$3;"
-	_, err = t.Client.Exec(sqltext, lifetime, bucketName, objectName)
+// expireTime is only updated if not zero, i.e. the object is restored already
+func (t *CockroachDBClient) UploadFreezerDate(bucketName, objectName string, lifetime int,
+	expireTime time.Time) (err error) {
+
+	if expireTime.IsZero() {
+		sqltext := "update restoreobjects set lifetime=$1 where bucketname=$2 and objectname=$3;"
+		_, err = t.Client.Exec(sqltext, lifetime, bucketName, objectName)
+	} else {
+		sqltext := "update restoreobjects set lifetime=$1,expiretime=$2 where bucketname=$3 and objectname=$4;"
+		_, err = t.Client.Exec(sqltext, lifetime, expireTime.Format(helper.CONFIG.TimeFormat),
+			bucketName, objectName)
+	}
+	return err
+}
+
+// Returns freezers waiting to be restored, in order of tier and request time.
+// Freezers being restored since staleBefore are also returned, since their
+// restorer is probably dead.
+func (t *CockroachDBClient) ListPendingFreezers(limit int, staleBefore time.Time) (freezers []*types.Freezer, err error) {
+	sqltext := "select bucketname,objectname,status,lifetime,lastmodifiedtime,IFNULL(tier,1) from restoreobjects " +
+		"where status=$1 or (status=$2 and lastmodifiedtime<$3) order by tier,lastmodifiedtime limit $4;"
+	rows, err := t.Client.Query(sqltext, types.ObjectNeedRestore, types.ObjectRestoring,
+		staleBefore.UTC().Format(helper.CONFIG.TimeFormat), limit)
 	if err != nil {
-		return err
+		return
 	}
-	return nil
+	defer rows.Close()
+	for rows.Next() {
+		f := &types.Freezer{}
+		var lastModifiedTime string
+		err = rows.Scan(&f.BucketName, &f.Name, &f.Status, &f.LifeTime, &lastModifiedTime, &f.Tier)
+		if err != nil {
+			return
+		}
+		f.LastModifiedTime, err = time.Parse(helper.CONFIG.TimeFormat, lastModifiedTime)
+		if err != nil {
+			return
+		}
+		freezers = append(freezers, f)
+	}
+	return freezers, rows.Err()
+}
+
+// Mark freezer as being restored, returns false if it's changed since listed,
+// e.g. claimed by another restorer
+func (t *CockroachDBClient) ClaimFreezer(freezer *types.Freezer, claimTime time.Time) (ok bool, err error) {
+	sqltext := "update restoreobjects set status=$1,lastmodifiedtime=$2 where bucketname=$3 and objectname=$4 " +
+		"and status=$5 and lastmodifiedtime=$6;"
+	result, err := t.Client.Exec(sqltext, types.ObjectRestoring, claimTime.UTC().Format(helper.CONFIG.TimeFormat),
+		freezer.BucketName, freezer.Name, freezer.Status, freezer.LastModifiedTime.Format(helper.CONFIG.TimeFormat))
+	if err != nil {
+		return
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	return n > 0, nil
+}
+
+// Save location of restored copy and mark freezer restored. Returns false if
+// the freezer is not being restored anymore, e.g. removed with its object.
+func (t *CockroachDBClient) FinishFreezer(freezer *types.Freezer) (ok bool, err error) {
+	tx, err := t.Client.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err == nil && ok {
+			err = tx.Commit()
+		}
+		if err != nil || !ok {
+			tx.Rollback()
+		}
+	}()
+	sqltext, args := freezer.GetUpdateSql("crdb", types.ObjectHasRestored)
+	result, err := tx.Exec(sqltext, args...)
+	if err != nil {
+		return
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n == 0 {
+		return
+	}
+	_, err = tx.Exec("delete from restoreobjectpart where bucketname=$1 and objectname=$2;",
+		freezer.BucketName, freezer.Name)
+	if err != nil {
+		return
+	}
+	for _, p := range freezer.Parts {
+		psql, args := p.GetCreateFreezerSql("crdb", freezer.BucketName, freezer.Name)
+		_, err = tx.Exec(psql, args...)
+		if err != nil {
+			return
+		}
+	}
+	return true, nil
+}
+
+// Returns freezers whose restored copy expired before
+func (t *CockroachDBClient) ListExpiredFreezers(limit int, before time.Time) (freezers []*types.Freezer, err error) {
+	sqltext := "select bucketname,objectname from restoreobjects where status=$1 and expiretime<$2 limit $3;"
+	rows, err := t.Client.Query(sqltext, types.ObjectHasRestored, before.UTC().Format(helper.CONFIG.TimeFormat), limit)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		f := &types.Freezer{}
+		err = rows.Scan(&f.BucketName, &f.Name)
+		if err != nil {
+			return
+		}
+		freezers = append(freezers, f)
+	}
+	return freezers, rows.Err()
 }
 
 func (t *CockroachDBClient) DeleteFreezer(bucketName, objectName string, tx types.DB) (err error) {
@@ -100,7 +222,7 @@ func (t *CockroachDBClient) DeleteFreezer(bucketName, objectName string, tx type
 	if err != nil {
 		return err
 	}
-	sqltext = "delete from restoreobjectpart where objectname=$1 and bucketname=$2;"
+	sqltext = "delete from restoreobjectpart where bucketname=$1 and objectname=$2;"
 	_, err = tx.Exec(sqltext, bucketName, objectName)
 	if err != nil {
 		return err
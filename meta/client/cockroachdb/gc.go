@@ -90,20 +90,21 @@ func (t *CockroachDBClient) ScanGarbageCollection(limit int, startRowKey string)
 	return
 }
 
-func (t *CockroachDBClient) RemoveGarbageCollection(garbage types.GarbageCollection) (err error) {
-	var tx *sql.Tx
-	tx, err = t.Client.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		if err == nil {
-			err = tx.Commit()
-		}
+func (t *CockroachDBClient) RemoveGarbageCollection(garbage types.GarbageCollection, tx types.DB) (err error) {
+	if tx == nil {
+		tx, err = t.Client.Begin()
 		if err != nil {
-			tx.Rollback()
+			return err
 		}
-	}()
+		defer func() {
+			if err == nil {
+				err = tx.(*sql.Tx).Commit()
+			}
+			if err != nil {
+				tx.(*sql.Tx).Rollback()
+			}
+		}()
+	}
 
 	version := strings.Split(garbage.Rowkey, types.ObjectNameSeparator)[2]
 	sqltext := "delete from gc where bucketname=$1 and objectname=$2 and version=$3;"
@@ -2,13 +2,16 @@ package cockroachdb
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/journeymidnight/yig/helper"
 	"github.com/journeymidnight/yig/meta/types"
 )
 
+const lifeCycleColumns = "bucketname,status,owner,leaseexpire,marker,scanned,laststart,lastfinish"
+
 func (t *CockroachDBClient) PutBucketToLifeCycle(lifeCycle types.LifeCycle) error {
-	sqltext := "insert into lifecycle(bucketname,status) values ($1,$2);"
+	sqltext := "insert into lifecycle(bucketname,status) values ($1,$2) on conflict do nothing;"
 	_, err := t.Client.Exec(sqltext, lifeCycle.BucketName, lifeCycle.Status)
 	if err != nil {
 		helper.Logger.Error("Failed to execute:", sqltext, "err:", err)
@@ -27,9 +30,51 @@ func (t *CockroachDBClient) RemoveBucketFromLifeCycle(bucket types.Bucket) error
 	return nil
 }
 
+func parseNullTime(s sql.NullString) (time.Time, error) {
+	if !s.Valid {
+		return time.Time{}, nil
+	}
+	return time.Parse(helper.CONFIG.TimeFormat, s.String)
+}
+
+func formatNullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format(helper.CONFIG.TimeFormat)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLifeCycle(row rowScanner) (lc types.LifeCycle, err error) {
+	var leaseExpire, lastStart, lastFinish sql.NullString
+	err = row.Scan(
+		&lc.BucketName,
+		&lc.Status,
+		&lc.Owner,
+		&leaseExpire,
+		&lc.Marker,
+		&lc.Scanned,
+		&lastStart,
+		&lastFinish)
+	if err != nil {
+		return
+	}
+	if lc.LeaseExpire, err = parseNullTime(leaseExpire); err != nil {
+		return
+	}
+	if lc.LastStart, err = parseNullTime(lastStart); err != nil {
+		return
+	}
+	lc.LastFinish, err = parseNullTime(lastFinish)
+	return
+}
+
 func (t *CockroachDBClient) ScanLifeCycle(limit int, marker string) (result types.ScanLifeCycleResult, err error) {
 	result.Truncated = false
-	sqltext := "select bucketname,status from lifecycle where bucketname > $1 limit $2;"
+	sqltext := "select " + lifeCycleColumns + " from lifecycle where bucketname > $1 order by bucketname limit $2;"
 	rows, err := t.Client.Query(sqltext, marker, limit)
 	if err == sql.ErrNoRows {
 		helper.Logger.Error("Failed in sql.ErrNoRows:", sqltext, "err:", err)
@@ -42,9 +87,7 @@ func (t *CockroachDBClient) ScanLifeCycle(limit int, marker string) (result type
 	result.Lcs = make([]types.LifeCycle, 0, limit)
 	var lc types.LifeCycle
 	for rows.Next() {
-		err = rows.Scan(
-			&lc.BucketName,
-			&lc.Status)
+		lc, err = scanLifeCycle(rows)
 		if err != nil {
 			helper.Logger.Error("Failed in scan LifeCycle:", err)
 			return
@@ -57,3 +100,62 @@ func (t *CockroachDBClient) ScanLifeCycle(limit int, marker string) (result type
 	}
 	return result, nil
 }
+
+func (t *CockroachDBClient) getLifeCycle(bucketName string) (lc types.LifeCycle, err error) {
+	sqltext := "select " + lifeCycleColumns + " from lifecycle where bucketname=$1;"
+	return scanLifeCycle(t.Client.QueryRow(sqltext, bucketName))
+}
+
+// Take the lease of bucket if nobody holds it or the lease expired, and the
+// bucket is not finished since passStart
+func (t *CockroachDBClient) AcquireLifeCycleLease(bucketName, owner string, expire,
+	passStart time.Time) (lc types.LifeCycle, ok bool, err error) {
+
+	sqltext := "update lifecycle set owner=$1,leaseexpire=$2 where bucketname=$3 and " +
+		"(owner='' or leaseexpire<$4) and (lastfinish is null or lastfinish<$5);"
+	_, err = t.Client.Exec(sqltext, owner, expire.Format(helper.CONFIG.TimeFormat), bucketName,
+		time.Now().Format(helper.CONFIG.TimeFormat), passStart.Format(helper.CONFIG.TimeFormat))
+	if err != nil {
+		return
+	}
+	lc, err = t.getLifeCycle(bucketName)
+	if err == sql.ErrNoRows {
+		return lc, false, nil
+	} else if err != nil {
+		return
+	}
+	return lc, lc.Owner == owner, nil
+}
+
+// Extend the lease and save progress, returns false if the lease is lost
+func (t *CockroachDBClient) RenewLifeCycleLease(lc types.LifeCycle) (ok bool, err error) {
+	sqltext := "update lifecycle set leaseexpire=$1,marker=$2,scanned=$3,laststart=$4 " +
+		"where bucketname=$5 and owner=$6;"
+	result, err := t.Client.Exec(sqltext, lc.LeaseExpire.Format(helper.CONFIG.TimeFormat), lc.Marker,
+		lc.Scanned, formatNullTime(lc.LastStart), lc.BucketName, lc.Owner)
+	if err != nil {
+		return
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	return n > 0, nil
+}
+
+// Give up the lease. If the pass is finished progress is cleared, otherwise
+// it's kept for whoever takes the lease next.
+func (t *CockroachDBClient) ReleaseLifeCycleLease(lc types.LifeCycle, finished bool) error {
+	var err error
+	if finished {
+		sqltext := "update lifecycle set owner='',leaseexpire=null,marker='',scanned=$1,lastfinish=$2 " +
+			"where bucketname=$3 and owner=$4;"
+		_, err = t.Client.Exec(sqltext, lc.Scanned, time.Now().Format(helper.CONFIG.TimeFormat),
+			lc.BucketName, lc.Owner)
+	} else {
+		sqltext := "update lifecycle set owner='',leaseexpire=null,marker=$1,scanned=$2 " +
+			"where bucketname=$3 and owner=$4;"
+		_, err = t.Client.Exec(sqltext, lc.Marker, lc.Scanned, lc.BucketName, lc.Owner)
+	}
+	return err
+}
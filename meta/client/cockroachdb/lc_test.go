@@ -0,0 +1,87 @@
+package cockroachdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func lifeCycleRows(owner string, leaseExpire time.Time) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"bucketname", "status", "owner", "leaseexpire", "marker",
+		"scanned", "laststart", "lastfinish"}).
+		AddRow("hehe", "Pending", owner, leaseExpire.Format(helper.CONFIG.TimeFormat), "a", 1,
+			nil, nil)
+}
+
+func TestCockroachDBClient_LifeCycleLease(t *testing.T) {
+	client, mock, err := newClient()
+	if err != nil {
+		t.Fatal("Error creating mock client:", err)
+	}
+	defer client.Client.Close()
+
+	helper.CONFIG.TimeFormat = types.TIME_LAYOUT_TIDB
+	now := time.Now().UTC().Truncate(time.Second)
+	expire := now.Add(time.Minute)
+
+	// lc1 acquires the lease
+	mock.ExpectExec("update lifecycle set owner=\\$1,leaseexpire=\\$2 where bucketname=\\$3 and ").
+		WithArgs("lc1", expire.Format(helper.CONFIG.TimeFormat), "hehe", sqlmock.AnyArg(),
+			now.Format(helper.CONFIG.TimeFormat)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("select .+ from lifecycle where bucketname=\\$1").WithArgs("hehe").
+		WillReturnRows(lifeCycleRows("lc1", expire))
+	lc, ok, err := client.AcquireLifeCycleLease("hehe", "lc1", expire, now)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "lc1", lc.Owner)
+	assert.Equal(t, "a", lc.Marker)
+	assert.True(t, expire.Equal(lc.LeaseExpire))
+
+	// lc2 could not take the lease before it expires
+	mock.ExpectExec("update lifecycle set owner=").
+		WithArgs("lc2", sqlmock.AnyArg(), "hehe", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("select .+ from lifecycle where bucketname=\\$1").WithArgs("hehe").
+		WillReturnRows(lifeCycleRows("lc1", expire))
+	_, ok, err = client.AcquireLifeCycleLease("hehe", "lc2", expire, now)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// lc1 renews the lease
+	mock.ExpectExec("update lifecycle set leaseexpire=\\$1,marker=\\$2,scanned=\\$3,laststart=\\$4 ").
+		WithArgs(sqlmock.AnyArg(), "a", 1, nil, "hehe", "lc1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	ok, err = client.RenewLifeCycleLease(lc)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// lc2 steals the expired lease, then lc1 finds it lost on renewal
+	mock.ExpectExec("update lifecycle set owner=").
+		WithArgs("lc2", sqlmock.AnyArg(), "hehe", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("select .+ from lifecycle where bucketname=\\$1").WithArgs("hehe").
+		WillReturnRows(lifeCycleRows("lc2", expire))
+	stolen, ok, err := client.AcquireLifeCycleLease("hehe", "lc2", expire, now)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a", stolen.Marker)
+	mock.ExpectExec("update lifecycle set leaseexpire=").
+		WithArgs(sqlmock.AnyArg(), "a", 1, nil, "hehe", "lc1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	ok, err = client.RenewLifeCycleLease(lc)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// lc2 finishes the pass
+	mock.ExpectExec("update lifecycle set owner='',leaseexpire=null,marker='',scanned=\\$1,lastfinish=\\$2 ").
+		WithArgs(1, sqlmock.AnyArg(), "hehe", "lc2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	err = client.ReleaseLifeCycleLease(stolen, true)
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -30,9 +30,9 @@ func (t *CockroachDBClient) GetMultipart(bucketName, objectName, uploadId string
 	}
 	uploadTime = math.MaxUint64 - uploadTime
 	sqltext := "select bucketname,objectname,uploadtime,initiatorid,ownerid,contenttype,location,pool,acl,sserequest," +
-		"encryption,COALESCE(cipher,''),attrs,storageclass from multiparts where bucketname=$1 and objectname=$2 and uploadtime=$3;"
+		"encryption,COALESCE(cipher,''),attrs,storageclass,coalesce(tags,'null'),coalesce(objectlock,'null') from multiparts where bucketname=$1 and objectname=$2 and uploadtime=$3;"
 	var initialTime uint64
-	var acl, sseRequest, attrs string
+	var acl, sseRequest, attrs, tags, objectLock string
 	err = t.Client.QueryRow(sqltext, bucketName, objectName, uploadTime).Scan(
 		&multipart.BucketName,
 		&multipart.ObjectName,
@@ -48,6 +48,8 @@ func (t *CockroachDBClient) GetMultipart(bucketName, objectName, uploadId string
 		&multipart.Metadata.CipherKey,
 		&attrs,
 		&multipart.Metadata.StorageClass,
+		&tags,
+		&objectLock,
 	)
 	if err != nil && err == sql.ErrNoRows {
 		err = e.ErrNoSuchUpload
@@ -71,6 +73,14 @@ func (t *CockroachDBClient) GetMultipart(bucketName, objectName, uploadId string
 	if err != nil {
 		return
 	}
+	err = json.Unmarshal([]byte(tags), &multipart.Metadata.Tags)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(objectLock), &multipart.Metadata.ObjectLock)
+	if err != nil {
+		return
+	}
 
 	sqltext = "select partnumber,size,objectid,\"offset\",etag,lastmodified,initializationvector from multipartpart where bucketname=$1 and objectname=$2 and uploadtime=$3;"
 	rows, err := t.Client.Query(sqltext, bucketName, objectName, uploadTime)
@@ -108,9 +118,11 @@ func (t *CockroachDBClient) CreateMultipart(multipart types.Multipart) (err erro
 	acl, _ := json.Marshal(m.Acl)
 	sseRequest, _ := json.Marshal(m.SseRequest)
 	attrs, _ := json.Marshal(m.Attrs)
-	sqltext := "insert into multiparts(bucketname,objectname,uploadtime,initiatorid,ownerid,contenttype,location,pool,acl,sserequest,encryption,cipher,attrs,storageclass) " +
-		"values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)"
-	_, err = t.Client.Exec(sqltext, multipart.BucketName, multipart.ObjectName, uploadtime, m.InitiatorId, m.OwnerId, m.ContentType, m.Location, m.Pool, acl, sseRequest, m.EncryptionKey, m.CipherKey, attrs, m.StorageClass)
+	tags, _ := json.Marshal(m.Tags)
+	objectLock, _ := json.Marshal(m.ObjectLock)
+	sqltext := "insert into multiparts(bucketname,objectname,uploadtime,initiatorid,ownerid,contenttype,location,pool,acl,sserequest,encryption,cipher,attrs,storageclass,tags,objectlock) " +
+		"values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)"
+	_, err = t.Client.Exec(sqltext, multipart.BucketName, multipart.ObjectName, uploadtime, m.InitiatorId, m.OwnerId, m.ContentType, m.Location, m.Pool, acl, sseRequest, m.EncryptionKey, m.CipherKey, attrs, m.StorageClass, tags, objectLock)
 	return
 }
 
@@ -0,0 +1,91 @@
+package cockroachdb
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+// All accesses are saved by one multi-row statement, rows of accesses must be
+// different, or the statement fails since a row is updated twice
+func (t *CockroachDBClient) PutObjectAccesses(accesses []*types.ObjectAccess) error {
+	if len(accesses) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(accesses))
+	args := make([]interface{}, 0, len(accesses)*6)
+	for i, a := range accesses {
+		n := i * 6
+		values = append(values, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d)",
+			n+1, n+2, n+3, n+4, n+5, n+6))
+		args = append(args, a.BucketName, a.ObjectName, a.Version,
+			a.LastAccessTime.Format(helper.CONFIG.TimeFormat), a.Tier,
+			a.TierTime.Format(helper.CONFIG.TimeFormat))
+	}
+	sqltext := "insert into objectaccess(bucketname,objectname,version,lastaccesstime,tier,tiertime) " +
+		"values" + strings.Join(values, ",") + " on conflict (bucketname,objectname,version) do update set " +
+		"lastaccesstime=greatest(objectaccess.lastaccesstime,excluded.lastaccesstime);"
+	_, err := t.Client.Exec(sqltext, args...)
+	return err
+}
+
+func (t *CockroachDBClient) ListColdObjects(limit int, before time.Time) (accesses []*types.ObjectAccess, err error) {
+	sqltext := "select bucketname,objectname,version,lastaccesstime,tier,tiertime from objectaccess " +
+		"where tier=$1 and lastaccesstime<$2 order by lastaccesstime limit $3;"
+	rows, err := t.Client.Query(sqltext, types.AccessTierFrequent,
+		before.UTC().Format(helper.CONFIG.TimeFormat), limit)
+	if err != nil {
+		return
+	}
+	return scanObjectAccesses(rows)
+}
+
+func (t *CockroachDBClient) ListWarmedObjects(limit int) (accesses []*types.ObjectAccess, err error) {
+	sqltext := "select bucketname,objectname,version,lastaccesstime,tier,tiertime from objectaccess " +
+		"where tier=$1 and lastaccesstime>tiertime order by lastaccesstime limit $2;"
+	rows, err := t.Client.Query(sqltext, types.AccessTierInfrequent, limit)
+	if err != nil {
+		return
+	}
+	return scanObjectAccesses(rows)
+}
+
+func scanObjectAccesses(rows *sql.Rows) (accesses []*types.ObjectAccess, err error) {
+	defer rows.Close()
+	for rows.Next() {
+		a := &types.ObjectAccess{}
+		var lastAccessTime, tierTime string
+		err = rows.Scan(&a.BucketName, &a.ObjectName, &a.Version, &lastAccessTime,
+			&a.Tier, &tierTime)
+		if err != nil {
+			return
+		}
+		a.LastAccessTime, err = time.Parse(helper.CONFIG.TimeFormat, lastAccessTime)
+		if err != nil {
+			return
+		}
+		a.TierTime, err = time.Parse(helper.CONFIG.TimeFormat, tierTime)
+		if err != nil {
+			return
+		}
+		accesses = append(accesses, a)
+	}
+	return accesses, rows.Err()
+}
+
+func (t *CockroachDBClient) UpdateObjectAccessTier(access *types.ObjectAccess) error {
+	sqltext := "update objectaccess set tier=$1,tiertime=$2 where bucketname=$3 and objectname=$4 and version=$5;"
+	_, err := t.Client.Exec(sqltext, access.Tier, access.TierTime.Format(helper.CONFIG.TimeFormat),
+		access.BucketName, access.ObjectName, access.Version)
+	return err
+}
+
+func (t *CockroachDBClient) RemoveObjectAccess(access *types.ObjectAccess) error {
+	sqltext := "delete from objectaccess where bucketname=$1 and objectname=$2 and version=$3;"
+	_, err := t.Client.Exec(sqltext, access.BucketName, access.ObjectName, access.Version)
+	return err
+}
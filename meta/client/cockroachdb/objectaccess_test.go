@@ -0,0 +1,35 @@
+package cockroachdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCockroachDBClient_PutObjectAccesses(t *testing.T) {
+	client, mock, err := newClient()
+	if err != nil {
+		t.Fatal("Error creating mock client:", err)
+	}
+	defer client.Client.Close()
+
+	now := time.Now().UTC()
+	accesses := []*types.ObjectAccess{
+		{BucketName: "hehe", ObjectName: "a", Version: 1, LastAccessTime: now, TierTime: now},
+		{BucketName: "hehe", ObjectName: "b", Version: 2, LastAccessTime: now, TierTime: now},
+	}
+	// saved by one statement without transaction
+	mock.ExpectExec("insert into objectaccess\\(.+\\) values\\(.+\\),\\(.+\\) on ").
+		WithArgs("hehe", "a", 1, sqlmock.AnyArg(), 0, sqlmock.AnyArg(),
+			"hehe", "b", 2, sqlmock.AnyArg(), 0, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err = client.PutObjectAccesses(accesses)
+	assert.Nil(t, err)
+	err = client.PutObjectAccesses(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,62 @@
+package cockroachdb
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+//recycle
+func (t *CockroachDBClient) PutRecycle(r types.Recycle, tx types.DB) error {
+	if tx == nil {
+		tx = t.Client
+	}
+	sqltext := "insert into recycle(location,pool,objectid,createtime) values($1,$2,$3,$4) on conflict do nothing;"
+	_, err := tx.Exec(sqltext, r.Location, r.Pool, r.ObjectId, r.CreateTime.Format(helper.CONFIG.TimeFormat))
+	return err
+}
+
+func (t *CockroachDBClient) RemoveRecycle(r types.Recycle) error {
+	sqltext := "delete from recycle where location=$1 and pool=$2 and objectid=$3;"
+	_, err := t.Client.Exec(sqltext, r.Location, r.Pool, r.ObjectId)
+	return err
+}
+
+// startRowKey is exclusive
+func (t *CockroachDBClient) ScanRecycles(limit int, startRowKey string,
+	before time.Time) (recycles []types.Recycle, err error) {
+
+	var rows *sql.Rows
+	beforeTime := before.Format(helper.CONFIG.TimeFormat)
+	if startRowKey == "" {
+		sqltext := "select location,pool,objectid,createtime from recycle where createtime<$1 order by location,pool,objectid limit $2;"
+		rows, err = t.Client.Query(sqltext, beforeTime, limit)
+	} else {
+		s := strings.Split(startRowKey, types.ObjectNameSeparator)
+		location, pool, objectId := s[0], s[1], s[2]
+		sqltext := "select location,pool,objectid,createtime from recycle where createtime<$1 and (location>$2 or (location=$3 and pool>$4) or (location=$5 and pool=$6 and objectid>$7)) order by location,pool,objectid limit $8;"
+		rows, err = t.Client.Query(sqltext, beforeTime, location, location, pool,
+			location, pool, objectId, limit)
+	}
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r types.Recycle
+		var createTime string
+		err = rows.Scan(&r.Location, &r.Pool, &r.ObjectId, &createTime)
+		if err != nil {
+			return
+		}
+		r.CreateTime, err = time.Parse(helper.CONFIG.TimeFormat, createTime)
+		if err != nil {
+			return
+		}
+		recycles = append(recycles, r)
+	}
+	return recycles, rows.Err()
+}
@@ -0,0 +1,33 @@
+package cockroachdb
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// tables with an objectid column that do not keep blobs alive
+var nonReferencingTables = map[string]bool{
+	"scrubfindings": true, // reports about blobs referenced by other tables
+}
+
+// Every table holding blob IDs in the schema must be walked, otherwise the
+// orphan tool would reclaim blobs still in use
+func TestBlobReferenceSqlsCoverSchema(t *testing.T) {
+	schema, err := ioutil.ReadFile("../../../integrate/sql/crdb.sql")
+	if err != nil {
+		t.Fatal("Read schema error:", err)
+	}
+	tableRegexp := regexp.MustCompile(`(?s)CREATE TABLE yig\.(\w+) \((.*?)\);`)
+	walked := strings.Join(blobReferenceSqls, "\n")
+	for _, match := range tableRegexp.FindAllStringSubmatch(string(schema), -1) {
+		table, columns := match[1], match[2]
+		if !strings.Contains(columns, "objectid ") || nonReferencingTables[table] {
+			continue
+		}
+		if !regexp.MustCompile(`(from|join) ` + table + `\b`).MatchString(walked) {
+			t.Error("Blob references in table", table, "are not walked")
+		}
+	}
+}
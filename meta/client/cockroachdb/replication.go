@@ -0,0 +1,111 @@
+package cockroachdb
+
+import (
+	"time"
+
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+func (t *CockroachDBClient) PutReplicationTask(task *types.ReplicationTask, tx types.DB) error {
+	if tx == nil {
+		tx = t.Client
+	}
+	sqltext := "insert into replication(bucketname,objectname,version,status,attempts,updatetime) " +
+		"values($1,$2,$3,$4,$5,$6);"
+	_, err := tx.Exec(sqltext, task.BucketName, task.ObjectName, task.Version, task.Status,
+		task.Attempts, task.UpdateTime.UTC().Format(helper.CONFIG.TimeFormat))
+	return err
+}
+
+// Returns tasks due to be replicated, in order of their update time. Tasks
+// being replicated since staleBefore are also returned, since their
+// replicator is probably dead. Only the oldest task of an object is returned,
+// so a newer version is never replicated before an older one is finished.
+func (t *CockroachDBClient) ListPendingReplicationTasks(limit int, now,
+	staleBefore time.Time) (tasks []*types.ReplicationTask, err error) {
+
+	// larger version is older
+	sqltext := "select bucketname,objectname,version,status,attempts,updatetime from replication r " +
+		"where ((status=$1 and updatetime<=$2) or (status=$3 and updatetime<$4)) and not exists " +
+		"(select 1 from replication o where o.bucketname=r.bucketname and o.objectname=r.objectname " +
+		"and o.version>r.version) order by updatetime limit $5;"
+	rows, err := t.Client.Query(sqltext, types.ReplicationTaskPending,
+		now.UTC().Format(helper.CONFIG.TimeFormat), types.ReplicationTaskReplicating,
+		staleBefore.UTC().Format(helper.CONFIG.TimeFormat), limit)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		task := &types.ReplicationTask{}
+		var updateTime string
+		err = rows.Scan(&task.BucketName, &task.ObjectName, &task.Version, &task.Status,
+			&task.Attempts, &updateTime)
+		if err != nil {
+			return
+		}
+		task.UpdateTime, err = time.Parse(helper.CONFIG.TimeFormat, updateTime)
+		if err != nil {
+			return
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// Mark task as being replicated, returns false if it's changed since listed,
+// e.g. claimed by another replicator
+func (t *CockroachDBClient) ClaimReplicationTask(task *types.ReplicationTask,
+	claimTime time.Time) (ok bool, err error) {
+
+	sqltext := "update replication set status=$1,updatetime=$2 where bucketname=$3 and objectname=$4 " +
+		"and version=$5 and status=$6 and updatetime=$7;"
+	result, err := t.Client.Exec(sqltext, types.ReplicationTaskReplicating,
+		claimTime.UTC().Format(helper.CONFIG.TimeFormat), task.BucketName, task.ObjectName,
+		task.Version, task.Status, task.UpdateTime.UTC().Format(helper.CONFIG.TimeFormat))
+	if err != nil {
+		return
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	return n > 0, nil
+}
+
+// Count a failed attempt and put the task back, to be retried at retryTime
+func (t *CockroachDBClient) RetryReplicationTask(task *types.ReplicationTask, retryTime time.Time) error {
+	sqltext := "update replication set status=$1,attempts=attempts+1,updatetime=$2 " +
+		"where bucketname=$3 and objectname=$4 and version=$5;"
+	_, err := t.Client.Exec(sqltext, types.ReplicationTaskPending,
+		retryTime.UTC().Format(helper.CONFIG.TimeFormat), task.BucketName, task.ObjectName,
+		task.Version)
+	return err
+}
+
+// Save final replication status of the object version and remove the task
+func (t *CockroachDBClient) FinishReplicationTask(task *types.ReplicationTask,
+	status types.ReplicationStatus) (err error) {
+
+	tx, err := t.Client.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	_, err = tx.Exec("update objects set replicationstatus=$1 where bucketname=$2 and name=$3 and version=$4;",
+		status, task.BucketName, task.ObjectName, task.Version)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("delete from replication where bucketname=$1 and objectname=$2 and version=$3;",
+		task.BucketName, task.ObjectName, task.Version)
+	return err
+}
@@ -0,0 +1,40 @@
+package cockroachdb
+
+import (
+	"time"
+
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+func (t *CockroachDBClient) PutScrubFinding(f types.ScrubFinding) error {
+	sqltext := "upsert into scrubfindings(bucketname,objectname,version,partnumber,location,pool,objectid," +
+		"type,detail,detecttime) values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10);"
+	_, err := t.Client.Exec(sqltext, f.BucketName, f.ObjectName, f.VersionId, f.PartNumber, f.Location,
+		f.Pool, f.ObjectId, f.Type, f.Detail, f.DetectTime.Format(helper.CONFIG.TimeFormat))
+	return err
+}
+
+func (t *CockroachDBClient) RemoveScrubFindingsBefore(before time.Time) error {
+	sqltext := "delete from scrubfindings where detecttime<$1;"
+	_, err := t.Client.Exec(sqltext, before.Format(helper.CONFIG.TimeFormat))
+	return err
+}
+
+func (t *CockroachDBClient) CountScrubFindings() (counts []types.ScrubFindingCount, err error) {
+	sqltext := "select location,type,count(*) from scrubfindings group by location,type;"
+	rows, err := t.Client.Query(sqltext)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c types.ScrubFindingCount
+		err = rows.Scan(&c.Location, &c.Type, &c.Count)
+		if err != nil {
+			return
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,60 @@
+package tidbclient
+
+import (
+	"database/sql"
+
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+//blob reference
+// Blobs without a row in `blobrefs` are referenced only once
+
+func (t *TidbClient) AcquireBlob(location, pool, objectId string, tx types.DB) error {
+	if tx == nil {
+		tx = t.Client
+	}
+	sqltext := "insert into blobrefs(location,pool,objectid,refcount) values(?,?,?,2) on duplicate key update refcount=refcount+1;"
+	_, err := tx.Exec(sqltext, location, pool, objectId)
+	return err
+}
+
+func (t *TidbClient) ReleaseBlob(location, pool, objectId string, tx types.DB) (shared bool, err error) {
+	if tx == nil {
+		tx = t.Client
+	}
+	sqltext := "update blobrefs set refcount=refcount-1 where location=? and pool=? and objectid=? and refcount>1;"
+	result, err := tx.Exec(sqltext, location, pool, objectId)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+	// referenced once now, same as no row
+	sqltext = "delete from blobrefs where location=? and pool=? and objectid=? and refcount<=1;"
+	_, err = tx.Exec(sqltext, location, pool, objectId)
+	return true, err
+}
+
+// returns ErrObjectChanged if data of object is moved or the object is deleted
+func (t *TidbClient) LockObjectData(object *types.Object, tx types.DB) error {
+	if tx == nil {
+		tx = t.Client
+	}
+	sqltext, args := object.GetLockDataSql("tidb")
+	var location, pool, objectId string
+	err := tx.QueryRow(sqltext, args...).Scan(&location, &pool, &objectId)
+	if err == sql.ErrNoRows {
+		return types.ErrObjectChanged
+	} else if err != nil {
+		return err
+	}
+	if location != object.Location || pool != object.Pool || objectId != object.ObjectId {
+		return types.ErrObjectChanged
+	}
+	return nil
+}
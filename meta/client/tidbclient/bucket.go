@@ -14,8 +14,8 @@ import (
 )
 
 func (t *TidbClient) GetBucket(bucketName string) (bucket *types.Bucket, err error) {
-	var acl, cors, logging, lc, policy, website, encryption, createTime string
-	sqltext := "select bucketname,acl,cors,COALESCE(logging,\"\"),lc,uid,policy,website,COALESCE(encryption,\"\"),createtime,usages,versioning from buckets where bucketname=?;"
+	var acl, cors, logging, lc, policy, website, encryption, tags, notification, replication, objectLock, createTime string
+	sqltext := "select bucketname,acl,cors,COALESCE(logging,\"\"),lc,uid,policy,website,COALESCE(encryption,\"\"),createtime,usages,versioning,coalesce(tags,'null'),coalesce(notification,'null'),coalesce(replication,'null'),coalesce(objectlock,'null') from buckets where bucketname=?;"
 	bucket = new(types.Bucket)
 	err = t.Client.QueryRow(sqltext, bucketName).Scan(
 		&bucket.Name,
@@ -30,6 +30,10 @@ func (t *TidbClient) GetBucket(bucketName string) (bucket *types.Bucket, err err
 		&createTime,
 		&bucket.Usage,
 		&bucket.Versioning,
+		&tags,
+		&notification,
+		&replication,
+		&objectLock,
 	)
 	if err != nil && err == sql.ErrNoRows {
 		err = e.ErrNoSuchBucket
@@ -69,11 +73,27 @@ func (t *TidbClient) GetBucket(bucketName string) (bucket *types.Bucket, err err
 	if err != nil {
 		return
 	}
+	err = json.Unmarshal([]byte(tags), &bucket.Tags)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(notification), &bucket.Notification)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(replication), &bucket.Replication)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(objectLock), &bucket.ObjectLock)
+	if err != nil {
+		return
+	}
 	return
 }
 
 func (t *TidbClient) GetBuckets() (buckets []types.Bucket, err error) {
-	sqltext := "select bucketname,acl,cors,COALESCE(logging,\"\"),lc,uid,policy,website,COALESCE(encryption,\"\"),createtime,usages,versioning from buckets;"
+	sqltext := "select bucketname,acl,cors,COALESCE(logging,\"\"),lc,uid,policy,website,COALESCE(encryption,\"\"),createtime,usages,versioning,coalesce(tags,'null'),coalesce(notification,'null'),coalesce(replication,'null'),coalesce(objectlock,'null') from buckets;"
 	rows, err := t.Client.Query(sqltext)
 	if err == sql.ErrNoRows {
 		err = nil
@@ -85,7 +105,7 @@ func (t *TidbClient) GetBuckets() (buckets []types.Bucket, err error) {
 
 	for rows.Next() {
 		var tmp types.Bucket
-		var acl, cors, logging, lc, policy, website, encryption, createTime string
+		var acl, cors, logging, lc, policy, website, encryption, tags, notification, replication, objectLock, createTime string
 		err = rows.Scan(
 			&tmp.Name,
 			&acl,
@@ -98,7 +118,11 @@ func (t *TidbClient) GetBuckets() (buckets []types.Bucket, err error) {
 			&encryption,
 			&createTime,
 			&tmp.Usage,
-			&tmp.Versioning)
+			&tmp.Versioning,
+			&tags,
+			&notification,
+			&replication,
+			&objectLock)
 		if err != nil {
 			return
 		}
@@ -134,6 +158,22 @@ func (t *TidbClient) GetBuckets() (buckets []types.Bucket, err error) {
 		if err != nil {
 			return
 		}
+		err = json.Unmarshal([]byte(tags), &tmp.Tags)
+		if err != nil {
+			return
+		}
+		err = json.Unmarshal([]byte(notification), &tmp.Notification)
+		if err != nil {
+			return
+		}
+		err = json.Unmarshal([]byte(replication), &tmp.Replication)
+		if err != nil {
+			return
+		}
+		err = json.Unmarshal([]byte(objectLock), &tmp.ObjectLock)
+		if err != nil {
+			return
+		}
 		buckets = append(buckets, tmp)
 	}
 	return
@@ -168,7 +208,7 @@ func (t *TidbClient) CheckAndPutBucket(bucket types.Bucket) (bool, error) {
 
 func (t *TidbClient) ListObjects(bucketName, marker, verIdMarker, prefix, delimiter string, versioned bool, maxKeys int) (retObjects []*types.Object, prefixes []string, truncated bool, nextMarker, nextVerIdMarker string, err error) {
 	if versioned {
-		return
+		return t.listVersionedObjects(bucketName, marker, verIdMarker, prefix, delimiter, maxKeys)
 	}
 	var count int
 	var exit bool
@@ -321,6 +361,106 @@ func (t *TidbClient) ListObjects(bucketName, marker, verIdMarker, prefix, delimi
 	return
 }
 
+// List all versions and delete markers of objects, ordered by name and from
+// newest to oldest version. Markers are the name and raw version in table
+// `objects` of the last entry returned.
+func (t *TidbClient) listVersionedObjects(bucketName, marker, verIdMarker, prefix, delimiter string,
+	maxKeys int) (retObjects []*types.Object, prefixes []string, truncated bool,
+	nextMarker, nextVerIdMarker string, err error) {
+
+	commonPrefixes := make(map[string]struct{})
+	omarker := marker
+	var count int
+	for {
+		var args []interface{}
+		arg := func(v interface{}) string {
+			args = append(args, v)
+			return "?"
+		}
+		sqltext := "select name,version from objects where bucketname=" + arg(bucketName)
+		if prefix != "" {
+			sqltext += " and name like " + arg(prefix+"%")
+		}
+		if marker != "" {
+			if verIdMarker == "" {
+				sqltext += " and name>" + arg(marker)
+			} else {
+				sqltext += " and (name>" + arg(marker) + " or (name=" + arg(marker) +
+					" and version>" + arg(verIdMarker) + "))"
+			}
+		}
+		sqltext += " order by bucketname,name,version limit " + arg(maxKeys)
+		var rows *sql.Rows
+		rows, err = t.Client.Query(sqltext, args...)
+		if err != nil {
+			return
+		}
+		var loopcount int
+		for rows.Next() {
+			loopcount += 1
+			var name string
+			var version uint64
+			err = rows.Scan(&name, &version)
+			if err != nil {
+				_ = rows.Close()
+				return
+			}
+			marker = name
+			verIdMarker = strconv.FormatUint(version, 10)
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if len(delimiter) != 0 {
+				subStr := strings.TrimPrefix(name, prefix)
+				n := strings.Index(subStr, delimiter)
+				if n != -1 {
+					prefixKey := prefix + subStr[0:(n+len(delimiter))]
+					if prefixKey == omarker {
+						continue
+					}
+					if _, ok := commonPrefixes[prefixKey]; !ok {
+						if count == maxKeys {
+							truncated = true
+							break
+						}
+						commonPrefixes[prefixKey] = struct{}{}
+						nextMarker, nextVerIdMarker = prefixKey, ""
+						count += 1
+					}
+					continue
+				}
+			}
+			if count == maxKeys {
+				truncated = true
+				break
+			}
+			var o *types.Object
+			o, err = t.GetObject(bucketName, name, verIdMarker)
+			if err == e.ErrNoSuchKey {
+				// it's possible the object is already deleted
+				err = nil
+				continue
+			}
+			if err != nil {
+				_ = rows.Close()
+				return
+			}
+			retObjects = append(retObjects, o)
+			nextMarker, nextVerIdMarker = name, verIdMarker
+			count += 1
+		}
+		_ = rows.Close()
+		if truncated || loopcount < maxKeys {
+			break
+		}
+	}
+	if !truncated {
+		nextMarker, nextVerIdMarker = "", ""
+	}
+	prefixes = helper.Keys(commonPrefixes)
+	return
+}
+
 func (t *TidbClient) DeleteBucket(bucket types.Bucket) error {
 	sqltext := "delete from buckets where bucketname=?;"
 	_, err := t.Client.Exec(sqltext, bucket.Name)
@@ -0,0 +1,62 @@
+package tidbclient
+
+import (
+	"encoding/json"
+
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+//erasure layout
+func (t *TidbClient) PutErasureLayout(layout types.ErasureLayout) error {
+	shards, err := json.Marshal(layout.Shards)
+	if err != nil {
+		return err
+	}
+	sqltext := "insert into erasurelayouts(location,pool,objectid,datashards,parityshards,blocksize,size,shards) values(?,?,?,?,?,?,?,?);"
+	_, err = t.Client.Exec(sqltext, layout.Location, layout.Pool, layout.ObjectId, layout.DataShards,
+		layout.ParityShards, layout.BlockSize, layout.Size, shards)
+	return err
+}
+
+func (t *TidbClient) GetErasureLayout(location, pool, objectId string) (layout types.ErasureLayout, err error) {
+	sqltext := "select location,pool,objectid,datashards,parityshards,blocksize,size,shards from erasurelayouts where location=? and pool=? and objectid=?;"
+	var shards []byte
+	err = t.Client.QueryRow(sqltext, location, pool, objectId).Scan(&layout.Location, &layout.Pool,
+		&layout.ObjectId, &layout.DataShards, &layout.ParityShards, &layout.BlockSize, &layout.Size, &shards)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(shards, &layout.Shards)
+	return
+}
+
+func (t *TidbClient) RemoveErasureLayout(layout types.ErasureLayout) error {
+	sqltext := "delete from erasurelayouts where location=? and pool=? and objectid=?;"
+	_, err := t.Client.Exec(sqltext, layout.Location, layout.Pool, layout.ObjectId)
+	return err
+}
+
+func (t *TidbClient) WalkErasureLayouts(walkFn func(layout types.ErasureLayout) error) error {
+	sqltext := "select location,pool,objectid,datashards,parityshards,blocksize,size,shards from erasurelayouts;"
+	rows, err := t.Client.Query(sqltext)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var layout types.ErasureLayout
+		var shards []byte
+		err = rows.Scan(&layout.Location, &layout.Pool, &layout.ObjectId, &layout.DataShards,
+			&layout.ParityShards, &layout.BlockSize, &layout.Size, &shards)
+		if err != nil {
+			return err
+		}
+		if err = json.Unmarshal(shards, &layout.Shards); err != nil {
+			return err
+		}
+		if err = walkFn(layout); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
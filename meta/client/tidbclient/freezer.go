@@ -16,7 +16,8 @@ func (t *TidbClient) CreateFreezer(freezer *types.Freezer) (err error) {
 
 func (t *TidbClient) GetFreezer(bucketName, objectName, version string) (freezer *types.Freezer, err error) {
 	var lastmodifiedtime string
-	sqltext := "select bucketname,objectname,IFNULL(version,''),status,lifetime,lastmodifiedtime,IFNULL(location,''),IFNULL(pool,''),IFNULL(ownerid,''),IFNULL(size,'0'),IFNULL(objectid,''),IFNULL(etag,'') from restoreobjects where bucketname=? and objectname=?;"
+	var expireTime sql.NullString
+	sqltext := "select bucketname,objectname,IFNULL(version,''),status,lifetime,lastmodifiedtime,IFNULL(location,''),IFNULL(pool,''),IFNULL(ownerid,''),IFNULL(size,'0'),IFNULL(objectid,''),IFNULL(etag,''),IFNULL(tier,1),expiretime from restoreobjects where bucketname=? and objectname=?;"
 	row := t.Client.QueryRow(sqltext, bucketName, objectName)
 	freezer = &types.Freezer{}
 	err = row.Scan(
@@ -32,6 +33,8 @@ func (t *TidbClient) GetFreezer(bucketName, objectName, version string) (freezer
 		&freezer.Size,
 		&freezer.ObjectId,
 		&freezer.Etag,
+		&freezer.Tier,
+		&expireTime,
 	)
 	if err == sql.ErrNoRows {
 		err = e.ErrNoSuchKey
@@ -41,6 +44,10 @@ func (t *TidbClient) GetFreezer(bucketName, objectName, version string) (freezer
 	}
 	local, _ := time.LoadLocation("Local")
 	freezer.LastModifiedTime, _ = time.ParseInLocation(types.TIME_LAYOUT_TIDB, lastmodifiedtime, local)
+	freezer.ExpireTime, err = parseNullTime(expireTime)
+	if err != nil {
+		return
+	}
 	freezer.Parts, err = getFreezerParts(freezer.BucketName, freezer.Name, t.Client)
 	//build simple index for multipart
 	if len(freezer.Parts) != 0 {
@@ -54,7 +61,8 @@ func (t *TidbClient) GetFreezer(bucketName, objectName, version string) (freezer
 }
 
 func (t *TidbClient) GetFreezerStatus(bucketName, objectName, version string) (freezer *types.Freezer, err error) {
-	sqltext := "select bucketname,objectname,IFNULL(version,''),status from restoreobjects where bucketname=? and objectname=?;"
+	var expireTime sql.NullString
+	sqltext := "select bucketname,objectname,IFNULL(version,''),status,lifetime,expiretime from restoreobjects where bucketname=? and objectname=?;"
 	row := t.Client.QueryRow(sqltext, bucketName, objectName)
 	freezer = &types.Freezer{}
 	err = row.Scan(
@@ -62,21 +70,135 @@ func (t *TidbClient) GetFreezerStatus(bucketName, objectName, version string) (f
 		&freezer.Name,
 		&freezer.VersionId,
 		&freezer.Status,
+		&freezer.LifeTime,
+		&expireTime,
 	)
 	if err == sql.ErrNoRows || freezer.Name != objectName {
 		err = e.ErrNoSuchKey
 		return
+	} else if err != nil {
+		return
 	}
+	freezer.ExpireTime, err = parseNullTime(expireTime)
 	return
 }
 
-func (t *TidbClient) UploadFreezerDate(bucketName, objectName string, lifetime int) (err error) {
-	sqltext := "update restoreobjects set lifetime=? where bucketname=? and objectname=?;"
-	_, err = t.Client.Exec(sqltext, lifetime, bucketName, objectName)
+// expireTime is only updated if not zero, i.e. the object is restored already
+func (t *TidbClient) UploadFreezerDate(bucketName, objectName string, lifetime int,
+	expireTime time.Time) (err error) {
+
+	if expireTime.IsZero() {
+		sqltext := "update restoreobjects set lifetime=? where bucketname=? and objectname=?;"
+		_, err = t.Client.Exec(sqltext, lifetime, bucketName, objectName)
+	} else {
+		sqltext := "update restoreobjects set lifetime=?,expiretime=? where bucketname=? and objectname=?;"
+		_, err = t.Client.Exec(sqltext, lifetime, expireTime.Format(types.TIME_LAYOUT_TIDB),
+			bucketName, objectName)
+	}
+	return err
+}
+
+// Returns freezers waiting to be restored, in order of tier and request time.
+// Freezers being restored since staleBefore are also returned, since their
+// restorer is probably dead.
+func (t *TidbClient) ListPendingFreezers(limit int, staleBefore time.Time) (freezers []*types.Freezer, err error) {
+	sqltext := "select bucketname,objectname,status,lifetime,lastmodifiedtime,IFNULL(tier,1) from restoreobjects " +
+		"where status=? or (status=? and lastmodifiedtime<?) order by tier,lastmodifiedtime limit ?;"
+	rows, err := t.Client.Query(sqltext, types.ObjectNeedRestore, types.ObjectRestoring,
+		staleBefore.UTC().Format(types.TIME_LAYOUT_TIDB), limit)
 	if err != nil {
-		return err
+		return
 	}
-	return nil
+	defer rows.Close()
+	for rows.Next() {
+		f := &types.Freezer{}
+		var lastModifiedTime string
+		err = rows.Scan(&f.BucketName, &f.Name, &f.Status, &f.LifeTime, &lastModifiedTime, &f.Tier)
+		if err != nil {
+			return
+		}
+		f.LastModifiedTime, err = time.Parse(types.TIME_LAYOUT_TIDB, lastModifiedTime)
+		if err != nil {
+			return
+		}
+		freezers = append(freezers, f)
+	}
+	return freezers, rows.Err()
+}
+
+// Mark freezer as being restored, returns false if it's changed since listed,
+// e.g. claimed by another restorer
+func (t *TidbClient) ClaimFreezer(freezer *types.Freezer, claimTime time.Time) (ok bool, err error) {
+	sqltext := "update restoreobjects set status=?,lastmodifiedtime=? where bucketname=? and objectname=? " +
+		"and status=? and lastmodifiedtime=?;"
+	result, err := t.Client.Exec(sqltext, types.ObjectRestoring, claimTime.UTC().Format(types.TIME_LAYOUT_TIDB),
+		freezer.BucketName, freezer.Name, freezer.Status, freezer.LastModifiedTime.Format(types.TIME_LAYOUT_TIDB))
+	if err != nil {
+		return
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	return n > 0, nil
+}
+
+// Save location of restored copy and mark freezer restored. Returns false if
+// the freezer is not being restored anymore, e.g. removed with its object.
+func (t *TidbClient) FinishFreezer(freezer *types.Freezer) (ok bool, err error) {
+	tx, err := t.Client.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err == nil && ok {
+			err = tx.Commit()
+		}
+		if err != nil || !ok {
+			tx.Rollback()
+		}
+	}()
+	sqltext, args := freezer.GetUpdateSql("tidb", types.ObjectHasRestored)
+	result, err := tx.Exec(sqltext, args...)
+	if err != nil {
+		return
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n == 0 {
+		return
+	}
+	_, err = tx.Exec("delete from restoreobjectpart where bucketname=? and objectname=?;",
+		freezer.BucketName, freezer.Name)
+	if err != nil {
+		return
+	}
+	for _, p := range freezer.Parts {
+		psql, args := p.GetCreateFreezerSql("tidb", freezer.BucketName, freezer.Name)
+		_, err = tx.Exec(psql, args...)
+		if err != nil {
+			return
+		}
+	}
+	return true, nil
+}
+
+// Returns freezers whose restored copy expired before
+func (t *TidbClient) ListExpiredFreezers(limit int, before time.Time) (freezers []*types.Freezer, err error) {
+	sqltext := "select bucketname,objectname from restoreobjects where status=? and expiretime<? limit ?;"
+	rows, err := t.Client.Query(sqltext, types.ObjectHasRestored, before.UTC().Format(types.TIME_LAYOUT_TIDB), limit)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		f := &types.Freezer{}
+		err = rows.Scan(&f.BucketName, &f.Name)
+		if err != nil {
+			return
+		}
+		freezers = append(freezers, f)
+	}
+	return freezers, rows.Err()
 }
 
 func (t *TidbClient) DeleteFreezer(bucketName, objectName string, tx types.DB) (err error) {
@@ -99,7 +221,7 @@ func (t *TidbClient) DeleteFreezer(bucketName, objectName string, tx types.DB) (
 	if err != nil {
 		return err
 	}
-	sqltext = "delete from restoreobjectpart where objectname=? and bucketname=?;"
+	sqltext = "delete from restoreobjectpart where bucketname=? and objectname=?;"
 	_, err = tx.Exec(sqltext, bucketName, objectName)
 	if err != nil {
 		return err
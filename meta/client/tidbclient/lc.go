@@ -2,13 +2,16 @@ package tidbclient
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/journeymidnight/yig/helper"
 	"github.com/journeymidnight/yig/meta/types"
 )
 
+const lifeCycleColumns = "bucketname,status,owner,leaseexpire,marker,scanned,laststart,lastfinish"
+
 func (t *TidbClient) PutBucketToLifeCycle(lifeCycle types.LifeCycle) error {
-	sqltext := "insert into lifecycle(bucketname,status) values (?,?);"
+	sqltext := "insert ignore into lifecycle(bucketname,status) values (?,?);"
 	_, err := t.Client.Exec(sqltext, lifeCycle.BucketName, lifeCycle.Status)
 	if err != nil {
 		helper.Logger.Error("Failed to execute:", sqltext, "err:", err)
@@ -27,9 +30,51 @@ func (t *TidbClient) RemoveBucketFromLifeCycle(bucket types.Bucket) error {
 	return nil
 }
 
+func parseNullTime(s sql.NullString) (time.Time, error) {
+	if !s.Valid {
+		return time.Time{}, nil
+	}
+	return time.Parse(types.TIME_LAYOUT_TIDB, s.String)
+}
+
+func formatNullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format(types.TIME_LAYOUT_TIDB)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLifeCycle(row rowScanner) (lc types.LifeCycle, err error) {
+	var leaseExpire, lastStart, lastFinish sql.NullString
+	err = row.Scan(
+		&lc.BucketName,
+		&lc.Status,
+		&lc.Owner,
+		&leaseExpire,
+		&lc.Marker,
+		&lc.Scanned,
+		&lastStart,
+		&lastFinish)
+	if err != nil {
+		return
+	}
+	if lc.LeaseExpire, err = parseNullTime(leaseExpire); err != nil {
+		return
+	}
+	if lc.LastStart, err = parseNullTime(lastStart); err != nil {
+		return
+	}
+	lc.LastFinish, err = parseNullTime(lastFinish)
+	return
+}
+
 func (t *TidbClient) ScanLifeCycle(limit int, marker string) (result types.ScanLifeCycleResult, err error) {
 	result.Truncated = false
-	sqltext := "select bucketname,status from lifecycle where bucketname > ? limit ?;"
+	sqltext := "select " + lifeCycleColumns + " from lifecycle where bucketname > ? order by bucketname limit ?;"
 	rows, err := t.Client.Query(sqltext, marker, limit)
 	if err == sql.ErrNoRows {
 		helper.Logger.Error("Failed in sql.ErrNoRows:", sqltext, "err:", err)
@@ -42,9 +87,7 @@ func (t *TidbClient) ScanLifeCycle(limit int, marker string) (result types.ScanL
 	result.Lcs = make([]types.LifeCycle, 0, limit)
 	var lc types.LifeCycle
 	for rows.Next() {
-		err = rows.Scan(
-			&lc.BucketName,
-			&lc.Status)
+		lc, err = scanLifeCycle(rows)
 		if err != nil {
 			helper.Logger.Error("Failed in scan LifeCycle:", err)
 			return
@@ -57,3 +100,68 @@ func (t *TidbClient) ScanLifeCycle(limit int, marker string) (result types.ScanL
 	}
 	return result, nil
 }
+
+func (t *TidbClient) getLifeCycle(bucketName string) (lc types.LifeCycle, err error) {
+	sqltext := "select " + lifeCycleColumns + " from lifecycle where bucketname=?;"
+	return scanLifeCycle(t.Client.QueryRow(sqltext, bucketName))
+}
+
+// Take the lease of bucket if nobody holds it or the lease expired, and the
+// bucket is not finished since passStart
+func (t *TidbClient) AcquireLifeCycleLease(bucketName, owner string, expire,
+	passStart time.Time) (lc types.LifeCycle, ok bool, err error) {
+
+	sqltext := "update lifecycle set owner=?,leaseexpire=? where bucketname=? and " +
+		"(owner='' or leaseexpire<?) and (lastfinish is null or lastfinish<?);"
+	_, err = t.Client.Exec(sqltext, owner, expire.Format(types.TIME_LAYOUT_TIDB), bucketName,
+		time.Now().Format(types.TIME_LAYOUT_TIDB), passStart.Format(types.TIME_LAYOUT_TIDB))
+	if err != nil {
+		return
+	}
+	lc, err = t.getLifeCycle(bucketName)
+	if err == sql.ErrNoRows {
+		return lc, false, nil
+	} else if err != nil {
+		return
+	}
+	return lc, lc.Owner == owner, nil
+}
+
+// Extend the lease and save progress, returns false if the lease is lost
+func (t *TidbClient) RenewLifeCycleLease(lc types.LifeCycle) (ok bool, err error) {
+	sqltext := "update lifecycle set leaseexpire=?,marker=?,scanned=?,laststart=? " +
+		"where bucketname=? and owner=?;"
+	result, err := t.Client.Exec(sqltext, lc.LeaseExpire.Format(types.TIME_LAYOUT_TIDB), lc.Marker,
+		lc.Scanned, formatNullTime(lc.LastStart), lc.BucketName, lc.Owner)
+	if err != nil {
+		return
+	}
+	// rows unchanged are not counted as affected
+	if n, _ := result.RowsAffected(); n > 0 {
+		return true, nil
+	}
+	current, err := t.getLifeCycle(lc.BucketName)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return
+	}
+	return current.Owner == lc.Owner, nil
+}
+
+// Give up the lease. If the pass is finished progress is cleared, otherwise
+// it's kept for whoever takes the lease next.
+func (t *TidbClient) ReleaseLifeCycleLease(lc types.LifeCycle, finished bool) error {
+	var err error
+	if finished {
+		sqltext := "update lifecycle set owner='',leaseexpire=null,marker='',scanned=?,lastfinish=? " +
+			"where bucketname=? and owner=?;"
+		_, err = t.Client.Exec(sqltext, lc.Scanned, time.Now().Format(types.TIME_LAYOUT_TIDB),
+			lc.BucketName, lc.Owner)
+	} else {
+		sqltext := "update lifecycle set owner='',leaseexpire=null,marker=?,scanned=? " +
+			"where bucketname=? and owner=?;"
+		_, err = t.Client.Exec(sqltext, lc.Marker, lc.Scanned, lc.BucketName, lc.Owner)
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package tidbclient_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func lifeCycleRows(owner string, leaseExpire time.Time) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"bucketname", "status", "owner", "leaseexpire", "marker",
+		"scanned", "laststart", "lastfinish"}).
+		AddRow("hehe", "Pending", owner, leaseExpire.Format(types.TIME_LAYOUT_TIDB), "a", 1,
+			nil, nil)
+}
+
+func TestTidbClient_LifeCycleLease(t *testing.T) {
+	client, mock, err := newClient()
+	if err != nil {
+		t.Fatal("Error creating mock client:", err)
+	}
+	defer client.Client.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expire := now.Add(time.Minute)
+
+	// lc1 acquires the lease
+	mock.ExpectExec("update lifecycle set owner=\\?,leaseexpire=\\? where bucketname=\\? and ").
+		WithArgs("lc1", expire.Format(types.TIME_LAYOUT_TIDB), "hehe", sqlmock.AnyArg(),
+			now.Format(types.TIME_LAYOUT_TIDB)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("select .+ from lifecycle where bucketname=\\?").WithArgs("hehe").
+		WillReturnRows(lifeCycleRows("lc1", expire))
+	lc, ok, err := client.AcquireLifeCycleLease("hehe", "lc1", expire, now)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "lc1", lc.Owner)
+	assert.Equal(t, "a", lc.Marker)
+	assert.True(t, expire.Equal(lc.LeaseExpire))
+
+	// lc2 could not take the lease before it expires
+	mock.ExpectExec("update lifecycle set owner=").
+		WithArgs("lc2", sqlmock.AnyArg(), "hehe", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("select .+ from lifecycle where bucketname=\\?").WithArgs("hehe").
+		WillReturnRows(lifeCycleRows("lc1", expire))
+	_, ok, err = client.AcquireLifeCycleLease("hehe", "lc2", expire, now)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// lc1 renews the lease, unchanged rows are not counted as affected
+	mock.ExpectExec("update lifecycle set leaseexpire=\\?,marker=\\?,scanned=\\?,laststart=\\? ").
+		WithArgs(sqlmock.AnyArg(), "a", 1, nil, "hehe", "lc1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("select .+ from lifecycle where bucketname=\\?").WithArgs("hehe").
+		WillReturnRows(lifeCycleRows("lc1", expire))
+	ok, err = client.RenewLifeCycleLease(lc)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// lc2 steals the expired lease, then lc1 finds it lost on renewal
+	mock.ExpectExec("update lifecycle set owner=").
+		WithArgs("lc2", sqlmock.AnyArg(), "hehe", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("select .+ from lifecycle where bucketname=\\?").WithArgs("hehe").
+		WillReturnRows(lifeCycleRows("lc2", expire))
+	stolen, ok, err := client.AcquireLifeCycleLease("hehe", "lc2", expire, now)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a", stolen.Marker)
+	mock.ExpectExec("update lifecycle set leaseexpire=").
+		WithArgs(sqlmock.AnyArg(), "a", 1, nil, "hehe", "lc1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("select .+ from lifecycle where bucketname=\\?").WithArgs("hehe").
+		WillReturnRows(lifeCycleRows("lc2", expire))
+	ok, err = client.RenewLifeCycleLease(lc)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// lc2 finishes the pass
+	mock.ExpectExec("update lifecycle set owner='',leaseexpire=null,marker='',scanned=\\?,lastfinish=\\? ").
+		WithArgs(1, sqlmock.AnyArg(), "hehe", "lc2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	err = client.ReleaseLifeCycleLease(stolen, true)
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -4,8 +4,10 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	e "github.com/journeymidnight/yig/error"
@@ -14,12 +16,12 @@ import (
 )
 
 func (t *TidbClient) GetObject(bucketName, objectName, version string) (object *types.Object, err error) {
-	var ibucketname, iname, customattributes, acl, lastModifiedTime string
+	var ibucketname, iname, customattributes, acl, tags, objectLock, lastModifiedTime string
 	var iversion uint64
 
 	var row *sql.Row
 	sqltext := "select bucketname,name,version,location,pool,ownerid,size,objectid,lastmodifiedtime,etag,contenttype," +
-		"customattributes,acl,nullversion,deletemarker,ssetype,encryptionkey,initializationvector,type,storageclass from objects where bucketname=? and name=? "
+		"customattributes,acl,nullversion,deletemarker,ssetype,encryptionkey,initializationvector,type,storageclass,coalesce(tags,'null'),replicationstatus,coalesce(objectlock,'null') from objects where bucketname=? and name=? "
 	if version == "" {
 		sqltext += "order by bucketname,name,version limit 1;"
 		row = t.Client.QueryRow(sqltext, bucketName, objectName)
@@ -49,6 +51,9 @@ func (t *TidbClient) GetObject(bucketName, objectName, version string) (object *
 		&object.InitializationVector,
 		&object.Type,
 		&object.StorageClass,
+		&tags,
+		&object.ReplicationStatus,
+		&objectLock,
 	)
 	if err == sql.ErrNoRows {
 		err = e.ErrNoSuchKey
@@ -70,6 +75,14 @@ func (t *TidbClient) GetObject(bucketName, objectName, version string) (object *
 	if err != nil {
 		return
 	}
+	err = json.Unmarshal([]byte(tags), &object.Tags)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(objectLock), &object.ObjectLock)
+	if err != nil {
+		return
+	}
 	object.Parts, err = getParts(object.BucketName, object.Name, iversion, t.Client)
 	//build simple index for multipart
 	if len(object.Parts) != 0 {
@@ -125,6 +138,18 @@ func (t *TidbClient) UpdateObjectAcl(object *types.Object) error {
 	return err
 }
 
+func (t *TidbClient) UpdateObjectTags(object *types.Object) error {
+	sql, args := object.GetUpdateTagsSql("tidb")
+	_, err := t.Client.Exec(sql, args...)
+	return err
+}
+
+func (t *TidbClient) UpdateObjectLock(object *types.Object) error {
+	sql, args := object.GetUpdateObjectLockSql("tidb")
+	_, err := t.Client.Exec(sql, args...)
+	return err
+}
+
 func (t *TidbClient) RenameObject(object *types.Object, sourceObject string, tx types.DB) (err error) {
 	if tx == nil {
 		tx = t.Client
@@ -169,6 +194,16 @@ func (t *TidbClient) PutObject(object *types.Object, tx types.DB) (err error) {
 	}
 	sql, args := object.GetCreateSql("tidb")
 	_, err = tx.Exec(sql, args...)
+	if err != nil {
+		return err
+	}
+	// versions to replicate are queued together
+	if object.ReplicationStatus == types.ReplicationPending {
+		err = t.PutReplicationTask(types.NewReplicationTask(object), tx)
+		if err != nil {
+			return err
+		}
+	}
 	if object.Parts != nil {
 		v := math.MaxUint64 - uint64(object.LastModifiedTime.UnixNano())
 		version := strconv.FormatUint(v, 10)
@@ -252,6 +287,97 @@ func (t *TidbClient) DeleteObject(object *types.Object, tx types.DB) (err error)
 	return nil
 }
 
+// Scan objects whose data are stored in cluster `location`, ordered by rowkey.
+// startRowKey is exclusive, in format bucketname\nname\nversion
+func (t *TidbClient) ScanObjectsByLocation(location string, limit int,
+	startRowKey string) (objects []*types.Object, err error) {
+
+	var rows *sql.Rows
+	if startRowKey == "" {
+		sqltext := "select bucketname,name,version from objects where location=? " +
+			"order by bucketname,name,version limit ?;"
+		rows, err = t.Client.Query(sqltext, location, limit)
+	} else {
+		s := strings.Split(startRowKey, types.ObjectNameSeparator)
+		if len(s) != 3 {
+			return nil, errors.New("invalid start rowkey")
+		}
+		sqltext := "select bucketname,name,version from objects where location=? and " +
+			"(bucketname>? or (bucketname=? and name>?) or (bucketname=? and name=? and version>?)) " +
+			"order by bucketname,name,version limit ?;"
+		rows, err = t.Client.Query(sqltext, location, s[0], s[0], s[1], s[0], s[1], s[2], limit)
+	}
+	if err != nil {
+		return
+	}
+	var keys [][3]string
+	for rows.Next() {
+		var key [3]string
+		err = rows.Scan(&key[0], &key[1], &key[2])
+		if err != nil {
+			rows.Close()
+			return
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	for _, key := range keys {
+		var object *types.Object
+		object, err = t.GetObject(key[0], key[1], key[2])
+		if err == e.ErrNoSuchKey { // removed after scan
+			continue
+		} else if err != nil {
+			return
+		}
+		object.Rowkey = []byte(strings.Join(key[:], types.ObjectNameSeparator))
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// Swap location, pool, objectid and storage class of an object and its parts, returns
+// types.ErrObjectChanged if the object is no longer at old location.
+func (t *TidbClient) UpdateObjectLocation(object *types.Object, oldLocation, oldObjectId string,
+	tx types.DB) (err error) {
+
+	if tx == nil {
+		tx, err = t.Client.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err == nil {
+				err = tx.(*sql.Tx).Commit()
+			}
+			if err != nil {
+				tx.(*sql.Tx).Rollback()
+			}
+		}()
+	}
+	sqltext, args := object.GetUpdateLocationSql("tidb", oldLocation, oldObjectId)
+	result, err := tx.Exec(sqltext, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return types.ErrObjectChanged
+	}
+	v := math.MaxUint64 - uint64(object.LastModifiedTime.UnixNano())
+	version := strconv.FormatUint(v, 10)
+	for _, p := range object.Parts {
+		psql, args := p.GetUpdateObjectIdSql("tidb", object.BucketName, object.Name, version)
+		_, err = tx.Exec(psql, args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 //util function
 func getParts(bucketName, objectName string, version uint64, cli *sql.DB) (parts map[int]*types.Part, err error) {
 	parts = make(map[int]*types.Part)
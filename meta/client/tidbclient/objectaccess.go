@@ -0,0 +1,86 @@
+package tidbclient
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+// All accesses are saved by one multi-row statement
+func (t *TidbClient) PutObjectAccesses(accesses []*types.ObjectAccess) error {
+	if len(accesses) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(accesses))
+	args := make([]interface{}, 0, len(accesses)*6)
+	for _, a := range accesses {
+		values = append(values, "(?,?,?,?,?,?)")
+		args = append(args, a.BucketName, a.ObjectName, a.Version,
+			a.LastAccessTime.Format(types.TIME_LAYOUT_TIDB), a.Tier,
+			a.TierTime.Format(types.TIME_LAYOUT_TIDB))
+	}
+	sqltext := "insert into objectaccess(bucketname,objectname,version,lastaccesstime,tier,tiertime) " +
+		"values" + strings.Join(values, ",") + " on duplicate key update " +
+		"lastaccesstime=greatest(lastaccesstime,values(lastaccesstime));"
+	_, err := t.Client.Exec(sqltext, args...)
+	return err
+}
+
+func (t *TidbClient) ListColdObjects(limit int, before time.Time) (accesses []*types.ObjectAccess, err error) {
+	sqltext := "select bucketname,objectname,version,lastaccesstime,tier,tiertime from objectaccess " +
+		"where tier=? and lastaccesstime<? order by lastaccesstime limit ?;"
+	rows, err := t.Client.Query(sqltext, types.AccessTierFrequent,
+		before.UTC().Format(types.TIME_LAYOUT_TIDB), limit)
+	if err != nil {
+		return
+	}
+	return scanObjectAccesses(rows)
+}
+
+func (t *TidbClient) ListWarmedObjects(limit int) (accesses []*types.ObjectAccess, err error) {
+	sqltext := "select bucketname,objectname,version,lastaccesstime,tier,tiertime from objectaccess " +
+		"where tier=? and lastaccesstime>tiertime order by lastaccesstime limit ?;"
+	rows, err := t.Client.Query(sqltext, types.AccessTierInfrequent, limit)
+	if err != nil {
+		return
+	}
+	return scanObjectAccesses(rows)
+}
+
+func scanObjectAccesses(rows *sql.Rows) (accesses []*types.ObjectAccess, err error) {
+	defer rows.Close()
+	for rows.Next() {
+		a := &types.ObjectAccess{}
+		var lastAccessTime, tierTime string
+		err = rows.Scan(&a.BucketName, &a.ObjectName, &a.Version, &lastAccessTime,
+			&a.Tier, &tierTime)
+		if err != nil {
+			return
+		}
+		a.LastAccessTime, err = time.Parse(types.TIME_LAYOUT_TIDB, lastAccessTime)
+		if err != nil {
+			return
+		}
+		a.TierTime, err = time.Parse(types.TIME_LAYOUT_TIDB, tierTime)
+		if err != nil {
+			return
+		}
+		accesses = append(accesses, a)
+	}
+	return accesses, rows.Err()
+}
+
+func (t *TidbClient) UpdateObjectAccessTier(access *types.ObjectAccess) error {
+	sqltext := "update objectaccess set tier=?,tiertime=? where bucketname=? and objectname=? and version=?;"
+	_, err := t.Client.Exec(sqltext, access.Tier, access.TierTime.Format(types.TIME_LAYOUT_TIDB),
+		access.BucketName, access.ObjectName, access.Version)
+	return err
+}
+
+func (t *TidbClient) RemoveObjectAccess(access *types.ObjectAccess) error {
+	sqltext := "delete from objectaccess where bucketname=? and objectname=? and version=?;"
+	_, err := t.Client.Exec(sqltext, access.BucketName, access.ObjectName, access.Version)
+	return err
+}
@@ -0,0 +1,61 @@
+package tidbclient
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+//recycle
+func (t *TidbClient) PutRecycle(r types.Recycle, tx types.DB) error {
+	if tx == nil {
+		tx = t.Client
+	}
+	sqltext := "insert ignore into recycle(location,pool,objectid,createtime) values(?,?,?,?);"
+	_, err := tx.Exec(sqltext, r.Location, r.Pool, r.ObjectId, r.CreateTime.Format(types.TIME_LAYOUT_TIDB))
+	return err
+}
+
+func (t *TidbClient) RemoveRecycle(r types.Recycle) error {
+	sqltext := "delete from recycle where location=? and pool=? and objectid=?;"
+	_, err := t.Client.Exec(sqltext, r.Location, r.Pool, r.ObjectId)
+	return err
+}
+
+// startRowKey is exclusive
+func (t *TidbClient) ScanRecycles(limit int, startRowKey string,
+	before time.Time) (recycles []types.Recycle, err error) {
+
+	var rows *sql.Rows
+	beforeTime := before.Format(types.TIME_LAYOUT_TIDB)
+	if startRowKey == "" {
+		sqltext := "select location,pool,objectid,createtime from recycle where createtime<? order by location,pool,objectid limit ?;"
+		rows, err = t.Client.Query(sqltext, beforeTime, limit)
+	} else {
+		s := strings.Split(startRowKey, types.ObjectNameSeparator)
+		location, pool, objectId := s[0], s[1], s[2]
+		sqltext := "select location,pool,objectid,createtime from recycle where createtime<? and (location>? or (location=? and pool>?) or (location=? and pool=? and objectid>?)) order by location,pool,objectid limit ?;"
+		rows, err = t.Client.Query(sqltext, beforeTime, location, location, pool,
+			location, pool, objectId, limit)
+	}
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r types.Recycle
+		var createTime string
+		err = rows.Scan(&r.Location, &r.Pool, &r.ObjectId, &createTime)
+		if err != nil {
+			return
+		}
+		r.CreateTime, err = time.Parse(types.TIME_LAYOUT_TIDB, createTime)
+		if err != nil {
+			return
+		}
+		recycles = append(recycles, r)
+	}
+	return recycles, rows.Err()
+}
@@ -0,0 +1,50 @@
+package tidbclient
+
+// Tables are walked in the order data moves between them, i.e. multipart
+// uploads are completed into objects, objects are deleted into gc, and gc
+// entries are moved into recycle, so a blob moving between tables during the
+// walk is still seen at least once. Blobs shared by several objects are also
+// kept in blobrefs, and erasure coded blobs in erasurelayouts until removed.
+var blobReferenceSqls = []string{
+	"select coalesce(m.pool,''),coalesce(p.objectid,'') from multiparts m join multipartpart p on p.bucketname=m.bucketname and p.objectname=m.objectname and p.uploadtime=m.uploadtime where m.location=?;",
+	"select coalesce(pool,''),coalesce(objectid,'') from objects where location=?;",
+	"select coalesce(o.pool,''),coalesce(p.objectid,'') from objects o join objectpart p on p.bucketname=o.bucketname and p.objectname=o.name and p.version=o.version where o.location=?;",
+	"select coalesce(pool,''),coalesce(objectid,'') from restoreobjects where location=?;",
+	"select coalesce(r.pool,''),coalesce(p.objectid,'') from restoreobjects r join restoreobjectpart p on p.bucketname=r.bucketname and p.objectname=r.objectname and p.version=r.version where r.location=?;",
+	"select coalesce(pool,''),coalesce(objectid,'') from gc where location=?;",
+	"select coalesce(g.pool,''),coalesce(p.objectid,'') from gc g join gcpart p on p.bucketname=g.bucketname and p.objectname=g.objectname and p.version=g.version where g.location=?;",
+	"select coalesce(pool,''),coalesce(objectid,'') from recycle where location=?;",
+	"select coalesce(pool,''),coalesce(objectid,'') from blobrefs where location=?;",
+	"select coalesce(pool,''),coalesce(objectid,'') from erasurelayouts where location=?;",
+}
+
+func (t *TidbClient) WalkBlobReferences(location string,
+	walkFn func(pool, objectId string) error) error {
+
+	for _, sqltext := range blobReferenceSqls {
+		err := func() error {
+			rows, err := t.Client.Query(sqltext, location)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var pool, objectId string
+				if err = rows.Scan(&pool, &objectId); err != nil {
+					return err
+				}
+				if objectId == "" {
+					continue
+				}
+				if err = walkFn(pool, objectId); err != nil {
+					return err
+				}
+			}
+			return rows.Err()
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
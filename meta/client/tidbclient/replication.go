@@ -0,0 +1,110 @@
+package tidbclient
+
+import (
+	"time"
+
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+func (t *TidbClient) PutReplicationTask(task *types.ReplicationTask, tx types.DB) error {
+	if tx == nil {
+		tx = t.Client
+	}
+	sqltext := "insert into replication(bucketname,objectname,version,status,attempts,updatetime) " +
+		"values(?,?,?,?,?,?);"
+	_, err := tx.Exec(sqltext, task.BucketName, task.ObjectName, task.Version, task.Status,
+		task.Attempts, task.UpdateTime.UTC().Format(types.TIME_LAYOUT_TIDB))
+	return err
+}
+
+// Returns tasks due to be replicated, in order of their update time. Tasks
+// being replicated since staleBefore are also returned, since their
+// replicator is probably dead. Only the oldest task of an object is returned,
+// so a newer version is never replicated before an older one is finished.
+func (t *TidbClient) ListPendingReplicationTasks(limit int, now,
+	staleBefore time.Time) (tasks []*types.ReplicationTask, err error) {
+
+	// larger version is older
+	sqltext := "select bucketname,objectname,version,status,attempts,updatetime from replication r " +
+		"where ((status=? and updatetime<=?) or (status=? and updatetime<?)) and not exists " +
+		"(select 1 from replication o where o.bucketname=r.bucketname and o.objectname=r.objectname " +
+		"and o.version>r.version) order by updatetime limit ?;"
+	rows, err := t.Client.Query(sqltext, types.ReplicationTaskPending,
+		now.UTC().Format(types.TIME_LAYOUT_TIDB), types.ReplicationTaskReplicating,
+		staleBefore.UTC().Format(types.TIME_LAYOUT_TIDB), limit)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		task := &types.ReplicationTask{}
+		var updateTime string
+		err = rows.Scan(&task.BucketName, &task.ObjectName, &task.Version, &task.Status,
+			&task.Attempts, &updateTime)
+		if err != nil {
+			return
+		}
+		task.UpdateTime, err = time.Parse(types.TIME_LAYOUT_TIDB, updateTime)
+		if err != nil {
+			return
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// Mark task as being replicated, returns false if it's changed since listed,
+// e.g. claimed by another replicator
+func (t *TidbClient) ClaimReplicationTask(task *types.ReplicationTask,
+	claimTime time.Time) (ok bool, err error) {
+
+	sqltext := "update replication set status=?,updatetime=? where bucketname=? and objectname=? " +
+		"and version=? and status=? and updatetime=?;"
+	result, err := t.Client.Exec(sqltext, types.ReplicationTaskReplicating,
+		claimTime.UTC().Format(types.TIME_LAYOUT_TIDB), task.BucketName, task.ObjectName,
+		task.Version, task.Status, task.UpdateTime.UTC().Format(types.TIME_LAYOUT_TIDB))
+	if err != nil {
+		return
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	return n > 0, nil
+}
+
+// Count a failed attempt and put the task back, to be retried at retryTime
+func (t *TidbClient) RetryReplicationTask(task *types.ReplicationTask, retryTime time.Time) error {
+	sqltext := "update replication set status=?,attempts=attempts+1,updatetime=? " +
+		"where bucketname=? and objectname=? and version=?;"
+	_, err := t.Client.Exec(sqltext, types.ReplicationTaskPending,
+		retryTime.UTC().Format(types.TIME_LAYOUT_TIDB), task.BucketName, task.ObjectName,
+		task.Version)
+	return err
+}
+
+// Save final replication status of the object version and remove the task
+func (t *TidbClient) FinishReplicationTask(task *types.ReplicationTask,
+	status types.ReplicationStatus) (err error) {
+
+	tx, err := t.Client.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	_, err = tx.Exec("update objects set replicationstatus=? where bucketname=? and name=? and version=?;",
+		status, task.BucketName, task.ObjectName, task.Version)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("delete from replication where bucketname=? and objectname=? and version=?;",
+		task.BucketName, task.ObjectName, task.Version)
+	return err
+}
@@ -0,0 +1,39 @@
+package tidbclient
+
+import (
+	"time"
+
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+func (t *TidbClient) PutScrubFinding(f types.ScrubFinding) error {
+	sqltext := "replace into scrubfindings(bucketname,objectname,version,partnumber,location,pool,objectid," +
+		"type,detail,detecttime) values(?,?,?,?,?,?,?,?,?,?);"
+	_, err := t.Client.Exec(sqltext, f.BucketName, f.ObjectName, f.VersionId, f.PartNumber, f.Location,
+		f.Pool, f.ObjectId, f.Type, f.Detail, f.DetectTime.Format(types.TIME_LAYOUT_TIDB))
+	return err
+}
+
+func (t *TidbClient) RemoveScrubFindingsBefore(before time.Time) error {
+	sqltext := "delete from scrubfindings where detecttime<?;"
+	_, err := t.Client.Exec(sqltext, before.Format(types.TIME_LAYOUT_TIDB))
+	return err
+}
+
+func (t *TidbClient) CountScrubFindings() (counts []types.ScrubFindingCount, err error) {
+	sqltext := "select location,type,count(*) from scrubfindings group by location,type;"
+	rows, err := t.Client.Query(sqltext)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c types.ScrubFindingCount
+		err = rows.Scan(&c.Location, &c.Type, &c.Count)
+		if err != nil {
+			return
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,17 @@
+package meta
+
+import "github.com/journeymidnight/yig/meta/types"
+
+// Layouts of erasure coded blobs, see erasure.LayoutStore
+
+func (m *Meta) PutErasureLayout(layout types.ErasureLayout) error {
+	return m.Client.PutErasureLayout(layout)
+}
+
+func (m *Meta) GetErasureLayout(location, pool, objectId string) (types.ErasureLayout, error) {
+	return m.Client.GetErasureLayout(location, pool, objectId)
+}
+
+func (m *Meta) RemoveErasureLayout(layout types.ErasureLayout) error {
+	return m.Client.RemoveErasureLayout(layout)
+}
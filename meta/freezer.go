@@ -2,6 +2,8 @@ package meta
 
 import (
 	"database/sql"
+	"time"
+
 	"github.com/journeymidnight/yig/meta/types"
 )
 
@@ -18,7 +20,23 @@ func (m *Meta) GetFreezerStatus(bucketName string, objectName string, version st
 }
 
 func (m *Meta) UpdateFreezerDate(freezer *types.Freezer) error {
-	return m.Client.UploadFreezerDate(freezer.BucketName, freezer.Name, freezer.LifeTime)
+	return m.Client.UploadFreezerDate(freezer.BucketName, freezer.Name, freezer.LifeTime, freezer.ExpireTime)
+}
+
+func (m *Meta) ListPendingFreezers(limit int, staleBefore time.Time) ([]*types.Freezer, error) {
+	return m.Client.ListPendingFreezers(limit, staleBefore)
+}
+
+func (m *Meta) ClaimFreezer(freezer *types.Freezer, claimTime time.Time) (bool, error) {
+	return m.Client.ClaimFreezer(freezer, claimTime)
+}
+
+func (m *Meta) FinishFreezer(freezer *types.Freezer) (bool, error) {
+	return m.Client.FinishFreezer(freezer)
+}
+
+func (m *Meta) ListExpiredFreezers(limit int, before time.Time) ([]*types.Freezer, error) {
+	return m.Client.ListExpiredFreezers(limit, before)
 }
 
 func (m *Meta) DeleteFreezer(freezer *types.Freezer) (err error) {
@@ -41,6 +59,10 @@ func (m *Meta) DeleteFreezer(freezer *types.Freezer) (err error) {
 		return err
 	}
 
+	// freezers not restored yet have no data
+	if freezer.ObjectId == "" && len(freezer.Parts) == 0 {
+		return nil
+	}
 	err = m.Client.PutFreezerToGarbageCollection(freezer, tx)
 	if err != nil {
 		return err
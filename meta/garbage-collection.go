@@ -12,5 +12,5 @@ func (m *Meta) ScanGarbageCollection(limit int, startRowKey string) ([]types.Gar
 }
 
 func (m *Meta) RemoveGarbageCollection(garbage types.GarbageCollection) error {
-	return m.Client.RemoveGarbageCollection(garbage)
+	return m.Client.RemoveGarbageCollection(garbage, nil)
 }
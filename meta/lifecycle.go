@@ -1,6 +1,10 @@
 package meta
 
-import "github.com/journeymidnight/yig/meta/types"
+import (
+	"time"
+
+	"github.com/journeymidnight/yig/meta/types"
+)
 
 func LifeCycleFromBucket(b types.Bucket) (lc types.LifeCycle) {
 	lc.BucketName = b.Name
@@ -20,3 +24,20 @@ func (m *Meta) RemoveBucketFromLifeCycle(bucket types.Bucket) error {
 func (m *Meta) ScanLifeCycle(limit int, marker string) (result types.ScanLifeCycleResult, err error) {
 	return m.Client.ScanLifeCycle(limit, marker)
 }
+
+// Lifecycle of a bucket is processed by one lc daemon at a time, which holds
+// the lease of the bucket and renews it before expire. Progress is saved with
+// the lease, so another daemon could resume the pass if the holder dies.
+func (m *Meta) AcquireLifeCycleLease(bucketName, owner string, expire,
+	passStart time.Time) (lc types.LifeCycle, ok bool, err error) {
+
+	return m.Client.AcquireLifeCycleLease(bucketName, owner, expire, passStart)
+}
+
+func (m *Meta) RenewLifeCycleLease(lc types.LifeCycle) (ok bool, err error) {
+	return m.Client.RenewLifeCycleLease(lc)
+}
+
+func (m *Meta) ReleaseLifeCycleLease(lc types.LifeCycle, finished bool) error {
+	return m.Client.ReleaseLifeCycleLease(lc, finished)
+}
@@ -2,6 +2,7 @@ package meta
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/journeymidnight/yig/meta/types"
 )
@@ -10,6 +11,7 @@ func (m *Meta) GetMultipart(bucketName, objectName, uploadId string) (multipart
 	return m.Client.GetMultipart(bucketName, objectName, uploadId)
 }
 
+// Remove multipart upload, and put its parts into gc in the same transaction
 func (m *Meta) DeleteMultipart(multipart types.Multipart) (err error) {
 	tx, err := m.Client.NewTrans()
 	if err != nil {
@@ -24,6 +26,22 @@ func (m *Meta) DeleteMultipart(multipart types.Multipart) (err error) {
 	if err != nil {
 		return
 	}
+	if len(multipart.Parts) != 0 {
+		// gc entries are keyed by bucket, object name and version, use abort
+		// time as version so it won't collide with objects
+		garbage := &types.Object{
+			BucketName:       multipart.BucketName,
+			Name:             multipart.ObjectName,
+			Location:         multipart.Metadata.Location,
+			Pool:             multipart.Metadata.Pool,
+			Parts:            multipart.Parts,
+			LastModifiedTime: time.Now().UTC(),
+		}
+		err = m.Client.PutObjectToGarbageCollection(garbage, tx)
+		if err != nil {
+			return
+		}
+	}
 	var removedSize int64 = 0
 	for _, p := range multipart.Parts {
 		removedSize += p.Size
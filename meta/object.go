@@ -2,6 +2,7 @@ package meta
 
 import (
 	"database/sql"
+	"time"
 
 	e "github.com/journeymidnight/yig/error"
 	"github.com/journeymidnight/yig/helper"
@@ -131,6 +132,15 @@ func (m *Meta) UpdateObjectAcl(object *types.Object) error {
 	return err
 }
 
+func (m *Meta) UpdateObjectTags(object *types.Object) error {
+	err := m.Client.UpdateObjectTags(object)
+	return err
+}
+
+func (m *Meta) UpdateObjectLock(object *types.Object) error {
+	return m.Client.UpdateObjectLock(object)
+}
+
 func (m *Meta) UpdateObjectAttrs(object *types.Object) error {
 	err := m.Client.UpdateObjectAttrs(object)
 	return err
@@ -254,3 +264,46 @@ func (m *Meta) AppendObject(object *types.Object, isExist bool) error {
 	}
 	return m.Client.CommitTrans(tx)
 }
+
+func (m *Meta) ScanObjectsByLocation(location string, limit int,
+	startRowKey string) ([]*types.Object, error) {
+
+	return m.Client.ScanObjectsByLocation(location, limit, startRowKey)
+}
+
+// Point an object to its data copied to another cluster, and put data at old
+// location into gc in the same transaction.
+func (m *Meta) MigrateObject(object, oldObject *types.Object) (err error) {
+	var tx *sql.Tx
+	tx, err = m.Client.NewTrans()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			err = m.Client.CommitTrans(tx)
+		}
+		if err != nil {
+			m.Client.AbortTrans(tx)
+		}
+	}()
+
+	err = m.Client.UpdateObjectLocation(object, oldObject.Location, oldObject.ObjectId, tx)
+	if err != nil {
+		return err
+	}
+	// gc entries are keyed by bucket, object name and version, use migration
+	// time as version so it won't collide with a later deletion of the object
+	garbage := *oldObject
+	garbage.LastModifiedTime = time.Now().UTC()
+	return m.Client.PutObjectToGarbageCollection(&garbage, tx)
+}
+
+// Change storage class of an object. If its data is copied to another pool as
+// well, data at old location is put into gc, see MigrateObject.
+func (m *Meta) TransitionObject(object, oldObject *types.Object, dataMoved bool) error {
+	if dataMoved {
+		return m.MigrateObject(object, oldObject)
+	}
+	return m.Client.UpdateObjectLocation(object, oldObject.Location, oldObject.ObjectId, nil)
+}
@@ -0,0 +1,47 @@
+package meta
+
+import (
+	"strconv"
+	"time"
+
+	e "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+// Save accesses of objects by one statement, last access time of an object is
+// only updated if it's later than the saved one. Accesses must be of different
+// object versions, large batches should be saved in chunks by callers.
+func (m *Meta) PutObjectAccesses(accesses []*types.ObjectAccess) error {
+	return m.Client.PutObjectAccesses(accesses)
+}
+
+// Objects in frequent access tier not accessed since `before`
+func (m *Meta) ListColdObjects(limit int, before time.Time) ([]*types.ObjectAccess, error) {
+	return m.Client.ListColdObjects(limit, before)
+}
+
+// Objects in infrequent access tier accessed after moved there
+func (m *Meta) ListWarmedObjects(limit int) ([]*types.ObjectAccess, error) {
+	return m.Client.ListWarmedObjects(limit)
+}
+
+func (m *Meta) UpdateObjectAccessTier(access *types.ObjectAccess) error {
+	return m.Client.UpdateObjectAccessTier(access)
+}
+
+func (m *Meta) RemoveObjectAccess(access *types.ObjectAccess) error {
+	return m.Client.RemoveObjectAccess(access)
+}
+
+// Get the object version an access refers to, bypassing cache
+func (m *Meta) GetAccessedObject(access *types.ObjectAccess) (*types.Object, error) {
+	object, err := m.Client.GetObject(access.BucketName, access.ObjectName,
+		strconv.FormatUint(access.Version, 10))
+	if err != nil {
+		return nil, err
+	}
+	if object.Name != access.ObjectName {
+		return nil, e.ErrNoSuchKey
+	}
+	return object, nil
+}
@@ -0,0 +1,20 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+func (m *Meta) PutRecycle(recycle types.Recycle) error {
+	return m.Client.PutRecycle(recycle, nil)
+}
+
+func (m *Meta) RemoveRecycle(recycle types.Recycle) error {
+	return m.Client.RemoveRecycle(recycle)
+}
+
+// Scan blobs to recycle which are saved before `before`
+func (m *Meta) ScanRecycles(limit int, startRowKey string, before time.Time) ([]types.Recycle, error) {
+	return m.Client.ScanRecycles(limit, startRowKey, before)
+}
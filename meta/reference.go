@@ -0,0 +1,24 @@
+package meta
+
+import "github.com/journeymidnight/yig/meta/types"
+
+// Call walkFn for every blob referenced by objects, multipart uploads,
+// restored objects and gc in cluster `location`, including shards of erasure
+// coded blobs, used to find orphaned blobs
+func (m *Meta) WalkBlobReferences(location string, walkFn func(pool, objectId string) error) error {
+	err := m.Client.WalkBlobReferences(location, walkFn)
+	if err != nil {
+		return err
+	}
+	return m.Client.WalkErasureLayouts(func(layout types.ErasureLayout) error {
+		for _, shard := range layout.Shards {
+			if shard.Cluster != location || shard.ObjectId == "" {
+				continue
+			}
+			if err := walkFn(layout.Pool, shard.ObjectId); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
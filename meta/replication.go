@@ -0,0 +1,43 @@
+package meta
+
+import (
+	"strconv"
+	"time"
+
+	e "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+// Tasks are created with object versions whose ReplicationStatus is
+// ReplicationPending, see Client.PutObject
+
+func (m *Meta) ListPendingReplicationTasks(limit int, now,
+	staleBefore time.Time) ([]*types.ReplicationTask, error) {
+
+	return m.Client.ListPendingReplicationTasks(limit, now, staleBefore)
+}
+
+func (m *Meta) ClaimReplicationTask(task *types.ReplicationTask, claimTime time.Time) (bool, error) {
+	return m.Client.ClaimReplicationTask(task, claimTime)
+}
+
+func (m *Meta) RetryReplicationTask(task *types.ReplicationTask, retryTime time.Time) error {
+	return m.Client.RetryReplicationTask(task, retryTime)
+}
+
+func (m *Meta) FinishReplicationTask(task *types.ReplicationTask, status types.ReplicationStatus) error {
+	return m.Client.FinishReplicationTask(task, status)
+}
+
+// Get the object version a task refers to, bypassing cache
+func (m *Meta) GetReplicationObject(task *types.ReplicationTask) (*types.Object, error) {
+	object, err := m.Client.GetObject(task.BucketName, task.ObjectName,
+		strconv.FormatUint(task.Version, 10))
+	if err != nil {
+		return nil, err
+	}
+	if object.Name != task.ObjectName {
+		return nil, e.ErrNoSuchKey
+	}
+	return object, nil
+}
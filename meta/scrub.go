@@ -0,0 +1,20 @@
+package meta
+
+import (
+	"time"
+
+	"github.com/journeymidnight/yig/meta/types"
+)
+
+func (m *Meta) PutScrubFinding(finding types.ScrubFinding) error {
+	return m.Client.PutScrubFinding(finding)
+}
+
+// Remove findings not detected again since `before`, i.e. fixed or deleted objects
+func (m *Meta) RemoveScrubFindingsBefore(before time.Time) error {
+	return m.Client.RemoveScrubFindingsBefore(before)
+}
+
+func (m *Meta) CountScrubFindings() ([]types.ScrubFindingCount, error) {
+	return m.Client.CountScrubFindings()
+}
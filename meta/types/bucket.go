@@ -32,6 +32,11 @@ type Bucket struct {
 	Encryption    datatype.EncryptionConfiguration
 	Versioning    string // actually enum: Disabled/Enabled/Suspended
 	Usage         int64
+	// bucket tags, at most MaxBucketTagsCount, see datatype.Tagging
+	Tags         map[string]string
+	Notification datatype.NotificationConfiguration
+	Replication  datatype.ReplicationConfiguration
+	ObjectLock   datatype.ObjectLockConfiguration
 }
 
 func (b *Bucket) String() (s string) {
@@ -47,6 +52,10 @@ func (b *Bucket) String() (s string) {
 	s += "Encryption" + fmt.Sprintf("%+v", b.Encryption) + "\t"
 	s += "Version: " + b.Versioning + "\t"
 	s += "Usage: " + humanize.Bytes(uint64(b.Usage)) + "\t"
+	s += "Tags: " + fmt.Sprintf("%+v", b.Tags) + "\t"
+	s += "Notification: " + fmt.Sprintf("%+v", b.Notification) + "\t"
+	s += "Replication: " + fmt.Sprintf("%+v", b.Replication) + "\t"
+	s += "ObjectLock: " + fmt.Sprintf("%+v", b.ObjectLock) + "\t"
 	return
 }
 
@@ -59,13 +68,17 @@ func (b Bucket) GetUpdateSql(client string) (string, []interface{}) {
 	bucket_policy, _ := json.Marshal(b.Policy)
 	website, _ := json.Marshal(b.Website)
 	encryption, _ := json.Marshal(b.Encryption)
+	tags, _ := json.Marshal(b.Tags)
+	notification, _ := json.Marshal(b.Notification)
+	replication, _ := json.Marshal(b.Replication)
+	objectLock, _ := json.Marshal(b.ObjectLock)
 	switch client {
 	case "crdb":
-		sql = "update buckets set bucketname=$1,acl=$2,policy=$3,cors=$4,logging=$5,lc=$6,website=$7,encryption=$8,uid=$9,versioning=$10 where bucketname=$11"
+		sql = "update buckets set bucketname=$1,acl=$2,policy=$3,cors=$4,logging=$5,lc=$6,website=$7,encryption=$8,uid=$9,versioning=$10,tags=$11,notification=$12,replication=$13,objectlock=$14 where bucketname=$15"
 	case "tidb":
-		sql = "update buckets set bucketname=?,acl=?,policy=?,cors=?,logging=?,lc=?,website=?,encryption=?,uid=?,versioning=? where bucketname=?"
+		sql = "update buckets set bucketname=?,acl=?,policy=?,cors=?,logging=?,lc=?,website=?,encryption=?,uid=?,versioning=?,tags=?,notification=?,replication=?,objectlock=? where bucketname=?"
 	}
-	args := []interface{}{b.Name, acl, bucket_policy, cors, logging, lc, website, encryption, b.OwnerId, b.Versioning, b.Name}
+	args := []interface{}{b.Name, acl, bucket_policy, cors, logging, lc, website, encryption, b.OwnerId, b.Versioning, tags, notification, replication, objectLock, b.Name}
 	return sql, args
 }
 
@@ -78,16 +91,20 @@ func (b Bucket) GetCreateSql(client string) (string, []interface{}) {
 	bucket_policy, _ := json.Marshal(b.Policy)
 	website, _ := json.Marshal(b.Website)
 	encryption, _ := json.Marshal(b.Encryption)
+	tags, _ := json.Marshal(b.Tags)
+	notification, _ := json.Marshal(b.Notification)
+	replication, _ := json.Marshal(b.Replication)
+	objectLock, _ := json.Marshal(b.ObjectLock)
 	createTime := b.CreateTime.Format(helper.CONFIG.TimeFormat)
 	switch client {
 	case "crdb":
-		sql = "insert into buckets(bucketname,acl,cors,logging,lc,uid,policy,website,encryption,createtime,usages,versioning) " +
-			"values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12);"
+		sql = "insert into buckets(bucketname,acl,cors,logging,lc,uid,policy,website,encryption,createtime,usages,versioning,tags,notification,replication,objectlock) " +
+			"values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16);"
 	case "tidb":
-		sql = "insert into buckets(bucketname,acl,cors,logging,lc,uid,policy,website,encryption,createtime,usages,versioning) " +
-			"values(?,?,?,?,?,?,?,?,?,?,?,?);"
+		sql = "insert into buckets(bucketname,acl,cors,logging,lc,uid,policy,website,encryption,createtime,usages,versioning,tags,notification,replication,objectlock) " +
+			"values(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?);"
 
 	}
-	args := []interface{}{b.Name, acl, cors, logging, lc, b.OwnerId, bucket_policy, website, encryption, createTime, b.Usage, b.Versioning}
+	args := []interface{}{b.Name, acl, cors, logging, lc, b.OwnerId, bucket_policy, website, encryption, createTime, b.Usage, b.Versioning, tags, notification, replication, objectLock}
 	return sql, args
 }
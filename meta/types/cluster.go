@@ -4,5 +4,8 @@ type Cluster struct {
 	Fsid   string
 	Pool   string
 	Weight int
+	// name of the storage class using this cluster, empty if shared by
+	// all classes without dedicated clusters
+	StorageClass string
 }
 
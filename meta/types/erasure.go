@@ -0,0 +1,21 @@
+package types
+
+// ErasureLayout describes how a blob of an erasure group is striped. It's
+// saved in table `erasurelayouts` by Location, Pool and ObjectId like any
+// other blob, so objects, gc and recycle entries only keep the short ObjectId.
+type ErasureLayout struct {
+	Location     string // ID of the erasure group
+	Pool         string
+	ObjectId     string
+	DataShards   int
+	ParityShards int
+	BlockSize    int64 // size of one shard block in a stripe
+	Size         int64 // object size before padding
+	Shards       []ErasureShard
+}
+
+// Shard i of a layout is stored as ObjectId in the member cluster with ID Cluster
+type ErasureShard struct {
+	Cluster  string `json:"cluster"`
+	ObjectId string `json:"objectid"`
+}
@@ -3,9 +3,36 @@ package types
 import (
 	"time"
 
+	e "github.com/journeymidnight/yig/error"
 	"github.com/journeymidnight/yig/helper"
 )
 
+// Priority of restore requests, smaller ones are processed first
+type RestoreTier uint8
+
+const (
+	RestoreTierExpedited RestoreTier = iota
+	RestoreTierStandard
+	RestoreTierBulk
+)
+
+var RestoreTierStringMap = map[string]RestoreTier{
+	"Expedited": RestoreTierExpedited,
+	"Standard":  RestoreTierStandard,
+	"Bulk":      RestoreTierBulk,
+}
+
+// Tier defaults to Standard if not specified
+func MatchRestoreTier(tier string) (RestoreTier, error) {
+	if tier == "" {
+		return RestoreTierStandard, nil
+	}
+	if index, ok := RestoreTierStringMap[tier]; ok {
+		return index, nil
+	}
+	return 0, e.ErrInvalidRestoreInfo
+}
+
 type Freezer struct {
 	Rowkey           []byte // Rowkey cache
 	Name             string
@@ -21,7 +48,9 @@ type Freezer struct {
 	PartsIndex       *SimpleIndex
 	VersionId        string // version cache
 	Status           Status
-	LifeTime         int
+	LifeTime         int // days to keep restored copy
+	Tier             RestoreTier
+	ExpireTime       time.Time // when restored copy is removed, only valid after restored
 }
 
 func (o *Freezer) GetCreateSql(client string) (string, []interface{}) {
@@ -30,12 +59,14 @@ func (o *Freezer) GetCreateSql(client string) (string, []interface{}) {
 	lastModifiedTime := o.LastModifiedTime.Format(helper.CONFIG.TimeFormat)
 	switch client {
 	case "crdb":
-		sql = "insert into restoreobjects(bucketname,objectname,status,lifetime,lastmodifiedtime) values($1,$2,$3,$4,$5)"
+		sql = "insert into restoreobjects(bucketname,objectname,version,status,lifetime,lastmodifiedtime,tier) " +
+			"values($1,$2,0,$3,$4,$5,$6)"
 	case "tidb":
-		sql = "insert into restoreobjects(bucketname,objectname,status,lifetime,lastmodifiedtime) values(?,?,?,?,?)"
+		sql = "insert into restoreobjects(bucketname,objectname,version,status,lifetime,lastmodifiedtime,tier) " +
+			"values(?,?,0,?,?,?,?)"
 
 	}
-	args := []interface{}{o.BucketName, o.Name, o.Status, o.LifeTime, lastModifiedTime}
+	args := []interface{}{o.BucketName, o.Name, o.Status, o.LifeTime, lastModifiedTime, o.Tier}
 	return sql, args
 }
 
@@ -44,14 +75,16 @@ func (o *Freezer) GetUpdateSql(client string, status Status) (string, []interfac
 	// version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
 	var sql string
 	lastModifiedTime := o.LastModifiedTime.Format(helper.CONFIG.TimeFormat)
+	expireTime := o.ExpireTime.Format(helper.CONFIG.TimeFormat)
 	switch client {
 	case "crdb":
 		sql = "update restoreobjects set status=$1,lastmodifiedtime=$2,location=$3,pool=$4," +
-			"ownerid=$5,size=$6,etag=$7 where bucketname=$8 and objectname=$9 and status=$10"
+			"ownerid=$5,size=$6,etag=$7,objectid=$8,expiretime=$9 where bucketname=$10 and objectname=$11 and status=$12"
 	case "tidb":
 		sql = "update restoreobjects set status=?,lastmodifiedtime=?,location=?,pool=?," +
-			"ownerid=?,size=?,etag=? where bucketname=? and objectname=? and status=?"
+			"ownerid=?,size=?,etag=?,objectid=?,expiretime=? where bucketname=? and objectname=? and status=?"
 	}
-	args := []interface{}{status, lastModifiedTime, o.Location, o.Pool, o.OwnerId, o.Size, o.Etag, o.BucketName, o.Name, o.Status}
+	args := []interface{}{status, lastModifiedTime, o.Location, o.Pool, o.OwnerId, o.Size, o.Etag, o.ObjectId,
+		expireTime, o.BucketName, o.Name, o.Status}
 	return sql, args
 }
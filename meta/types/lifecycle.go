@@ -1,8 +1,20 @@
 package types
 
+import "time"
+
 type LifeCycle struct {
 	BucketName string
 	Status     string // status of this entry, in Pending/Deleting
+	// lc daemon holding the lease of this bucket, empty if none
+	Owner       string
+	LeaseExpire time.Time
+	// name of the last object processed in current pass, empty if the pass
+	// is not started yet
+	Marker string
+	// object versions walked in current pass
+	Scanned    int64
+	LastStart  time.Time
+	LastFinish time.Time
 }
 
 type ScanLifeCycleResult struct {
@@ -11,4 +23,3 @@ type ScanLifeCycleResult struct {
 	// List of LifeCycles info for this request.
 	Lcs []LifeCycle
 }
-
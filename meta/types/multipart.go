@@ -37,6 +37,8 @@ type MultipartMetadata struct {
 	CipherKey     []byte
 	Attrs         map[string]string
 	StorageClass  StorageClass
+	Tags          map[string]string
+	ObjectLock    datatype.ObjectLock // default retention is applied on completion
 }
 
 type Multipart struct {
@@ -112,6 +114,33 @@ func (p *Part) GetCreateGcSql(client, bucketname, objectname string, version uin
 	return sql, args
 }
 
+// Parts of restored copy, freezers are not versioned yet so version is always 0
+func (p *Part) GetCreateFreezerSql(client, bucketname, objectname string) (string, []interface{}) {
+	var sql string
+	switch client {
+	case "crdb":
+		sql = "insert into restoreobjectpart(partnumber,size,objectid,\"offset\",etag,lastmodified,initializationvector,bucketname,objectname,version) " +
+			"values($1,$2,$3,$4,$5,$6,$7,$8,$9,0)"
+	case "tidb":
+		sql = "insert into restoreobjectpart(partnumber,size,objectid,offset,etag,lastmodified,initializationvector,bucketname,objectname,version) " +
+			"values(?,?,?,?,?,?,?,?,?,0)"
+	}
+	args := []interface{}{p.PartNumber, p.Size, p.ObjectId, p.Offset, p.Etag, p.LastModified, p.InitializationVector, bucketname, objectname}
+	return sql, args
+}
+
+func (p *Part) GetUpdateObjectIdSql(client, bucketname, objectname, version string) (string, []interface{}) {
+	var sql string
+	switch client {
+	case "crdb":
+		sql = "update objectpart set objectid=$1 where bucketname=$2 and objectname=$3 and version=$4 and partnumber=$5"
+	case "tidb":
+		sql = "update objectpart set objectid=? where bucketname=? and objectname=? and version=? and partnumber=?"
+	}
+	args := []interface{}{p.ObjectId, bucketname, objectname, version, p.PartNumber}
+	return sql, args
+}
+
 func (o *Object) GetUpdateObjectPartNameSql(client, sourceObject string) (string, []interface{}) {
 	var sql string
 	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
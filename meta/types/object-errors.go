@@ -17,9 +17,14 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 )
 
+// ErrObjectChanged - object was overwritten, deleted or moved by others
+// while its data was being migrated.
+var ErrObjectChanged = errors.New("object changed during data migration")
+
 // PartTooSmall - error if part size is less than 5MB.
 type PartTooSmall struct {
 	PartSize   int64
@@ -47,6 +47,11 @@ type Object struct {
 	// ObjectType include `Normal`, `Appendable`, 'Multipart'
 	Type         ObjectType
 	StorageClass StorageClass
+	// object tags, at most MaxObjectTagsCount, see datatype.Tagging
+	Tags              map[string]string
+	ReplicationStatus ReplicationStatus
+	// retention and legal hold, see datatype.ObjectLock
+	ObjectLock datatype.ObjectLock
 }
 
 type ObjectType int
@@ -146,20 +151,23 @@ func (o *Object) GetCreateSql(client string) (string, []interface{}) {
 	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
 	customAttributes, _ := json.Marshal(o.CustomAttributes)
 	acl, _ := json.Marshal(o.ACL)
+	tags, _ := json.Marshal(o.Tags)
+	objectLock, _ := json.Marshal(o.ObjectLock)
 	lastModifiedTime := o.LastModifiedTime.Format(helper.CONFIG.TimeFormat)
 	switch client {
 	case "crdb":
 		sql = "insert into objects(bucketname,name,version,location,pool,ownerid,size,objectid,lastmodifiedtime,etag," +
-			"contenttype,customattributes,acl,nullversion,deletemarker,ssetype,encryptionkey,initializationvector,type,storageclass) " +
-			"values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20)"
+			"contenttype,customattributes,acl,nullversion,deletemarker,ssetype,encryptionkey,initializationvector,type,storageclass,tags," +
+			"replicationstatus,objectlock) values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23)"
 	case "tidb":
 		sql = "insert into objects(bucketname,name,version,location,pool,ownerid,size,objectid,lastmodifiedtime,etag," +
-			"contenttype,customattributes,acl,nullversion,deletemarker,ssetype,encryptionkey,initializationvector,type,storageclass) " +
-			"values(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)"
+			"contenttype,customattributes,acl,nullversion,deletemarker,ssetype,encryptionkey,initializationvector,type,storageclass,tags," +
+			"replicationstatus,objectlock) values(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)"
 	}
 	args := []interface{}{o.BucketName, o.Name, version, o.Location, o.Pool, o.OwnerId, o.Size, o.ObjectId,
 		lastModifiedTime, o.Etag, o.ContentType, customAttributes, acl, o.NullVersion, o.DeleteMarker,
-		o.SseType, o.EncryptionKey, o.InitializationVector, o.Type, o.StorageClass}
+		o.SseType, o.EncryptionKey, o.InitializationVector, o.Type, o.StorageClass, tags, o.ReplicationStatus,
+		objectLock}
 	return sql, args
 }
 
@@ -193,6 +201,39 @@ func (o *Object) GetUpdateSql(client string) (string, []interface{}) {
 	return sql, args
 }
 
+// Only succeeds if data of the object is still at old location, see meta.MigrateObject
+func (o *Object) GetUpdateLocationSql(client, oldLocation, oldObjectId string) (string, []interface{}) {
+	var sql string
+	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
+	switch client {
+	case "crdb":
+		sql = "update objects set location=$1,pool=$2,objectid=$3,storageclass=$4 " +
+			"where bucketname=$5 and name=$6 and version=$7 and location=$8 and objectid=$9"
+	case "tidb":
+		sql = "update objects set location=?,pool=?,objectid=?,storageclass=? " +
+			"where bucketname=? and name=? and version=? and location=? and objectid=?"
+	}
+	args := []interface{}{o.Location, o.Pool, o.ObjectId, o.StorageClass, o.BucketName, o.Name, version,
+		oldLocation, oldObjectId}
+	return sql, args
+}
+
+// lock the object row and get where its data is stored
+func (o *Object) GetLockDataSql(client string) (string, []interface{}) {
+	var sql string
+	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
+	switch client {
+	case "crdb":
+		sql = "select coalesce(location,''),coalesce(pool,''),coalesce(objectid,'') from objects " +
+			"where bucketname=$1 and name=$2 and version=$3 for update"
+	case "tidb":
+		sql = "select coalesce(location,''),coalesce(pool,''),coalesce(objectid,'') from objects " +
+			"where bucketname=? and name=? and version=? for update"
+	}
+	args := []interface{}{o.BucketName, o.Name, version}
+	return sql, args
+}
+
 func (o *Object) GetUpdateAclSql(client string) (string, []interface{}) {
 	var sql string
 	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
@@ -207,6 +248,34 @@ func (o *Object) GetUpdateAclSql(client string) (string, []interface{}) {
 	return sql, args
 }
 
+func (o *Object) GetUpdateTagsSql(client string) (string, []interface{}) {
+	var sql string
+	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
+	tags, _ := json.Marshal(o.Tags)
+	switch client {
+	case "crdb":
+		sql = "update objects set tags=$1 where bucketname=$2 and name=$3 and version=$4"
+	case "tidb":
+		sql = "update objects set tags=? where bucketname=? and name=? and version=?"
+	}
+	args := []interface{}{tags, o.BucketName, o.Name, version}
+	return sql, args
+}
+
+func (o *Object) GetUpdateObjectLockSql(client string) (string, []interface{}) {
+	var sql string
+	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
+	objectLock, _ := json.Marshal(o.ObjectLock)
+	switch client {
+	case "crdb":
+		sql = "update objects set objectlock=$1 where bucketname=$2 and name=$3 and version=$4"
+	case "tidb":
+		sql = "update objects set objectlock=? where bucketname=? and name=? and version=?"
+	}
+	args := []interface{}{objectLock, o.BucketName, o.Name, version}
+	return sql, args
+}
+
 func (o *Object) GetUpdateAttrsSql(client string) (string, []interface{}) {
 	var sql string
 	customAttributes, _ := json.Marshal(o.CustomAttributes)
@@ -260,13 +329,15 @@ func (o *Object) GetSubUsageSql(client string) (string, []interface{}) {
 // TODO : with Version
 func (o *Object) GetReplaceObjectMetasSql(client string) (string, []interface{}) {
 	var sql string
+	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
 	customAttributes, _ := json.Marshal(o.CustomAttributes)
+	tags, _ := json.Marshal(o.Tags)
 	switch client {
 	case "crdb":
-		sql = "update objects set contenttype=$1,customattributes=$2,storageclass=$3 where bucketname=$4 and name=$5"
+		sql = "update objects set contenttype=$1,customattributes=$2,storageclass=$3,tags=$4 where bucketname=$5 and name=$6 and version=$7"
 	case "tidb":
-		sql = "update objects set contenttype=?,customattributes=?,storageclass=? where bucketname=? and name=?"
+		sql = "update objects set contenttype=?,customattributes=?,storageclass=?,tags=? where bucketname=? and name=? and version=?"
 	}
-	args := []interface{}{o.ContentType, customAttributes, o.StorageClass, o.BucketName, o.Name}
+	args := []interface{}{o.ContentType, customAttributes, o.StorageClass, tags, o.BucketName, o.Name, version}
 	return sql, args
 }
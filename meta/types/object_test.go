@@ -0,0 +1,31 @@
+package types
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Replacing metas of one version must not touch other versions of the object
+func TestGetReplaceObjectMetasSql(t *testing.T) {
+	o := &Object{
+		BucketName:       "bucket",
+		Name:             "key",
+		LastModifiedTime: time.Unix(1500000000, 0),
+		Tags:             map[string]string{"key": "value"},
+	}
+	version := math.MaxUint64 - uint64(o.LastModifiedTime.UnixNano())
+	for client, condition := range map[string]string{
+		"tidb": "and version=?",
+		"crdb": "and version=$7",
+	} {
+		sql, args := o.GetReplaceObjectMetasSql(client)
+		if !strings.HasSuffix(sql, condition) {
+			t.Errorf("%s sql is not limited to one version: %s", client, sql)
+		}
+		if args[len(args)-1] != version {
+			t.Errorf("%s version arg mismatch, expected %d, got %v", client, version, args[len(args)-1])
+		}
+	}
+}
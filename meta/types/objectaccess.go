@@ -0,0 +1,47 @@
+package types
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+type AccessTier uint8
+
+const (
+	AccessTierFrequent AccessTier = iota
+	AccessTierInfrequent
+)
+
+var AccessTierStringMap = map[AccessTier]string{
+	AccessTierFrequent:   "FREQUENT",
+	AccessTierInfrequent: "INFREQUENT",
+}
+
+// ObjectAccess is the last access of an INTELLIGENT_TIERING object, saved
+// to table `objectaccess`. Objects not accessed for days are moved to the
+// infrequent access tier, and moved back once accessed again, i.e accessed
+// after TierTime.
+type ObjectAccess struct {
+	BucketName     string
+	ObjectName     string
+	Version        uint64 // version of the object in table `objects`
+	LastAccessTime time.Time
+	Tier           AccessTier
+	TierTime       time.Time // when the object is moved to current tier
+}
+
+func NewObjectAccess(object *Object, accessTime time.Time) *ObjectAccess {
+	return &ObjectAccess{
+		BucketName:     object.BucketName,
+		ObjectName:     object.Name,
+		Version:        math.MaxUint64 - uint64(object.LastModifiedTime.UnixNano()),
+		LastAccessTime: accessTime,
+		TierTime:       accessTime,
+	}
+}
+
+func (a *ObjectAccess) GetRowkey() string {
+	return a.BucketName + ObjectNameSeparator + a.ObjectName + ObjectNameSeparator +
+		strconv.FormatUint(a.Version, 10)
+}
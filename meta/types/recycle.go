@@ -0,0 +1,19 @@
+package types
+
+import (
+	"time"
+)
+
+// Recycle is a blob in backend cluster not referenced by any object,
+// e.g. data of a failed PUT. It's saved to table `recycle` before the request
+// returns, and removed after the blob is deleted, so no blob leaks on restarts.
+type Recycle struct {
+	Location   string
+	Pool       string
+	ObjectId   string
+	CreateTime time.Time
+}
+
+func (r Recycle) GetRowkey() string {
+	return r.Location + ObjectNameSeparator + r.Pool + ObjectNameSeparator + r.ObjectId
+}
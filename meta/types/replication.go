@@ -0,0 +1,60 @@
+package types
+
+import (
+	"math"
+	"time"
+
+	"github.com/journeymidnight/yig/api/datatype"
+)
+
+// Replication status of an object version, returned as x-amz-replication-status
+type ReplicationStatus uint8
+
+const (
+	ReplicationNone ReplicationStatus = iota // not replicated
+	ReplicationPending
+	ReplicationCompleted
+	ReplicationFailed
+	ReplicationReplica // written by replication of another bucket
+)
+
+var ReplicationStatusStringMap = map[ReplicationStatus]string{
+	ReplicationPending:   datatype.ReplicationStatusPending,
+	ReplicationCompleted: datatype.ReplicationStatusCompleted,
+	ReplicationFailed:    datatype.ReplicationStatusFailed,
+	ReplicationReplica:   datatype.ReplicationStatusReplica,
+}
+
+func (s ReplicationStatus) ToString() string {
+	return ReplicationStatusStringMap[s]
+}
+
+type ReplicationTaskStatus uint8
+
+const (
+	ReplicationTaskPending ReplicationTaskStatus = iota
+	ReplicationTaskReplicating
+)
+
+// ReplicationTask is a version of object, or a delete marker, waiting to be
+// replicated, saved to table `replication` together with the version. Tasks
+// are claimed by replicate daemons, claims older than a timeout are
+// considered abandoned. Failed tasks are retried at UpdateTime.
+type ReplicationTask struct {
+	BucketName string
+	ObjectName string
+	Version    uint64 // version of the object in table `objects`
+	Status     ReplicationTaskStatus
+	Attempts   int       // failed attempts so far
+	UpdateTime time.Time // claim time if replicating, otherwise when to retry
+}
+
+func NewReplicationTask(object *Object) *ReplicationTask {
+	return &ReplicationTask{
+		BucketName: object.BucketName,
+		ObjectName: object.Name,
+		Version:    math.MaxUint64 - uint64(object.LastModifiedTime.UnixNano()),
+		Status:     ReplicationTaskPending,
+		UpdateTime: object.LastModifiedTime,
+	}
+}
@@ -0,0 +1,33 @@
+package types
+
+import (
+	"time"
+)
+
+// types of ScrubFinding
+const (
+	ScrubFindingMissing      = "missing"   // data not found or not readable
+	ScrubFindingTruncated    = "truncated" // data shorter than recorded size
+	ScrubFindingEtagMismatch = "etag_mismatch"
+)
+
+// ScrubFinding records a problem found by the data scrubber,
+// PartNumber is 0 for objects not uploaded by multipart.
+type ScrubFinding struct {
+	BucketName string
+	ObjectName string
+	VersionId  string
+	PartNumber int
+	Location   string
+	Pool       string
+	ObjectId   string
+	Type       string
+	Detail     string
+	DetectTime time.Time
+}
+
+type ScrubFindingCount struct {
+	Location string
+	Type     string
+	Count    int64
+}
@@ -0,0 +1,316 @@
+// Package webhook implements messagebus.MessageSender by POSTing messages to
+// HTTP endpoints, a lighter alternative to Kafka for small installations.
+//
+// Messages are appended to an on-disk spool of each endpoint before AsyncSend
+// returns, so they are kept across restarts of the process until delivered.
+// Spools are synced to disk every SYNC_INTERVAL and on Flush and Close.
+// Each endpoint receives batches of messages as a JSON array: messages which
+// are JSON themselves, e.g bucket events, are embedded as is, others e.g
+// msgpack packed access logs are base64 encoded strings. Batches failed are
+// retried with exponential backoff, later messages wait meanwhile, so the
+// order of messages is kept. Delivery is at least once.
+//
+// Requests are signed with header
+//
+//	X-Yig-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+//
+// where timestamp is the unix time in header X-Yig-Timestamp, see Sign.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	SIGNATURE_HEADER = "X-Yig-Signature"
+	TIMESTAMP_HEADER = "X-Yig-Timestamp"
+
+	DEFAULT_SPOOL_DIR    = "/var/lib/yig/webhook"
+	DEFAULT_BATCH_SIZE   = 100
+	DEFAULT_TIMEOUT      = 10 * time.Second
+	DEFAULT_MIN_BACKOFF  = time.Second
+	DEFAULT_MAX_BACKOFF  = 5 * time.Minute
+	DEFAULT_SEGMENT_SIZE = 64 << 20 // 64M
+	SYNC_INTERVAL        = time.Second
+)
+
+type Config struct {
+	URLs        []string
+	Secret      string // key of HMAC signatures, requests are not signed if empty
+	SpoolDir    string
+	BatchSize   int           // max messages in a request
+	Timeout     time.Duration // of a request
+	MinBackoff  time.Duration // first retry interval, doubled for each retry
+	MaxBackoff  time.Duration
+	SegmentSize int64 // spool file size before a new one is started
+}
+
+type endpoint struct {
+	url   string
+	spool *spool
+}
+
+type Sender struct {
+	config    Config
+	client    *http.Client
+	endpoints []*endpoint
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of timestamp and body, receivers
+// should compare it with X-Yig-Signature without the "sha256=" prefix
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Spools are separated by program and endpoint, e.g
+// /var/lib/yig/webhook/yig/<sha1 of url>, so yig and tools sharing yig.toml
+// on one host don't share spools, and changing order of urls is harmless
+func spoolDir(root, url string) string {
+	sum := sha1.Sum([]byte(url))
+	return filepath.Join(root, filepath.Base(os.Args[0]), hex.EncodeToString(sum[:]))
+}
+
+func NewSender(config Config) (*Sender, error) {
+	if len(config.URLs) == 0 {
+		return nil, errors.New("no webhook url configured")
+	}
+	if config.SpoolDir == "" {
+		config.SpoolDir = DEFAULT_SPOOL_DIR
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DEFAULT_BATCH_SIZE
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DEFAULT_TIMEOUT
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = DEFAULT_MIN_BACKOFF
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DEFAULT_MAX_BACKOFF
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = config.MinBackoff
+	}
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = DEFAULT_SEGMENT_SIZE
+	}
+
+	s := &Sender{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+	for _, url := range config.URLs {
+		spool, err := openSpool(spoolDir(config.SpoolDir, url), config.SegmentSize)
+		if err != nil {
+			for _, e := range s.endpoints {
+				e.spool.close()
+			}
+			return nil, err
+		}
+		s.endpoints = append(s.endpoints, &endpoint{url: url, spool: spool})
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	for _, e := range s.endpoints {
+		s.wg.Add(1)
+		go s.deliver(e)
+	}
+	s.wg.Add(1)
+	go s.syncSpools()
+	return s, nil
+}
+
+// Message is spooled for every endpoint before returning
+func (s *Sender) AsyncSend(value []byte) error {
+	if len(value) == 0 {
+		return errors.New("empty message")
+	}
+	for _, e := range s.endpoints {
+		err := e.spool.append(value)
+		if err != nil {
+			return fmt.Errorf("spool message for %s failed: %v", e.url, err)
+		}
+	}
+	return nil
+}
+
+// Wait until all messages are delivered, timeout is in ms
+func (s *Sender) Flush(timeout int) error {
+	for _, e := range s.endpoints {
+		e.spool.sync()
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+	for {
+		delivered := true
+		for _, e := range s.endpoints {
+			if !e.spool.empty() {
+				delivered = false
+				break
+			}
+		}
+		if delivered {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.New("flush webhook messages timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Stop delivering, messages not delivered are kept in spools
+func (s *Sender) Close() {
+	s.cancel()
+	s.wg.Wait()
+	for _, e := range s.endpoints {
+		e.spool.close()
+	}
+}
+
+func (s *Sender) syncSpools() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(SYNC_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+		for _, e := range s.endpoints {
+			err := e.spool.sync()
+			if err != nil {
+				helper.Logger.Error("Sync webhook spool of", e.url, "failed:", err)
+			}
+		}
+	}
+}
+
+// returns false if sender is closed during waiting
+func (s *Sender) wait(d time.Duration, wakeup <-chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-wakeup:
+	case <-s.ctx.Done():
+		return false
+	}
+	return true
+}
+
+func (s *Sender) deliver(e *endpoint) {
+	defer s.wg.Done()
+	backoff := s.config.MinBackoff
+	for {
+		messages, seq, offset, err := e.spool.read(s.config.BatchSize)
+		if err != nil {
+			helper.Logger.Error("Read webhook spool of", e.url, "failed:", err)
+			if !s.wait(backoff, nil) {
+				return
+			}
+			continue
+		}
+		if len(messages) == 0 {
+			// move over segments read through
+			err = e.spool.commit(seq, offset)
+			if err != nil {
+				helper.Logger.Error("Commit webhook spool of", e.url, "failed:", err)
+			}
+			if !s.wait(SYNC_INTERVAL, e.spool.appended) {
+				return
+			}
+			continue
+		}
+		for {
+			err = s.post(e.url, messages)
+			if err == nil {
+				break
+			}
+			helper.Logger.Warn("Post", len(messages), "messages to", e.url,
+				"failed, retry in", backoff, "err:", err)
+			if !s.wait(backoff, nil) {
+				return
+			}
+			backoff *= 2
+			if backoff > s.config.MaxBackoff {
+				backoff = s.config.MaxBackoff
+			}
+		}
+		backoff = s.config.MinBackoff
+		err = e.spool.commit(seq, offset)
+		if err != nil {
+			// messages are delivered again in next read
+			helper.Logger.Error("Commit webhook spool of", e.url, "failed:", err)
+			if !s.wait(backoff, nil) {
+				return
+			}
+		}
+	}
+}
+
+func encodeBatch(messages [][]byte) ([]byte, error) {
+	batch := make([]interface{}, 0, len(messages))
+	for _, message := range messages {
+		if json.Valid(message) {
+			batch = append(batch, json.RawMessage(message))
+		} else {
+			batch = append(batch, message) // base64 encoded by json
+		}
+	}
+	return json.Marshal(batch)
+}
+
+func (s *Sender) post(url string, messages [][]byte) error {
+	body, err := encodeBatch(messages)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(s.ctx)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(TIMESTAMP_HEADER, timestamp)
+	if s.config.Secret != "" {
+		request.Header.Set(SIGNATURE_HEADER,
+			"sha256="+Sign([]byte(s.config.Secret), timestamp, body))
+	}
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("%s responds %s", url, response.Status)
+	}
+	return nil
+}
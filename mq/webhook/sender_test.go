@@ -0,0 +1,218 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/mq/webhook"
+)
+
+const testSecret = "hehehehe"
+
+// receiver verifies signatures and records messages of batches received,
+// requests are rejected with 503 while failing
+type receiver struct {
+	sync.Mutex
+	t        *testing.T
+	failing  bool
+	requests int
+	messages []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+	r.requests++
+	if r.failing {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	signature := webhook.Sign([]byte(testSecret), req.Header.Get(webhook.TIMESTAMP_HEADER), body)
+	if req.Header.Get(webhook.SIGNATURE_HEADER) != "sha256="+signature {
+		r.t.Error("Invalid signature:", req.Header.Get(webhook.SIGNATURE_HEADER))
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	var batch []json.RawMessage
+	err := json.Unmarshal(body, &batch)
+	if err != nil {
+		r.t.Error("Invalid batch:", string(body))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, message := range batch {
+		r.messages = append(r.messages, string(message))
+	}
+}
+
+func (r *receiver) setFailing(failing bool) {
+	r.Lock()
+	r.failing = failing
+	r.Unlock()
+}
+
+func (r *receiver) received() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string(nil), r.messages...)
+}
+
+func setup(t *testing.T) (*receiver, *httptest.Server, string) {
+	helper.Logger = log.NewLogger(os.Stderr, log.ErrorLevel)
+	r := &receiver{t: t}
+	server := httptest.NewServer(r)
+	dir, err := ioutil.TempDir("", "webhook")
+	if err != nil {
+		t.Fatal("TempDir error:", err)
+	}
+	return r, server, dir
+}
+
+func newSender(t *testing.T, url, dir string) *webhook.Sender {
+	sender, err := webhook.NewSender(webhook.Config{
+		URLs:        []string{url},
+		Secret:      testSecret,
+		SpoolDir:    dir,
+		BatchSize:   2,
+		MinBackoff:  10 * time.Millisecond,
+		MaxBackoff:  50 * time.Millisecond,
+		SegmentSize: 64,
+	})
+	if err != nil {
+		t.Fatal("NewSender error:", err)
+	}
+	return sender
+}
+
+func send(t *testing.T, sender *webhook.Sender, messages ...string) {
+	for _, message := range messages {
+		err := sender.AsyncSend([]byte(message))
+		if err != nil {
+			t.Fatal("AsyncSend error:", err)
+		}
+	}
+}
+
+func checkReceived(t *testing.T, r *receiver, expected ...string) {
+	received := r.received()
+	if strings.Join(received, ",") != strings.Join(expected, ",") {
+		t.Fatal("Received", received, "expected", expected)
+	}
+}
+
+func TestSender_Deliver(t *testing.T) {
+	r, server, dir := setup(t)
+	defer server.Close()
+	defer os.RemoveAll(dir)
+	sender := newSender(t, server.URL, dir)
+	defer sender.Close()
+
+	// batches of 2 messages, spool segments of 64 bytes
+	send(t, sender, `{"id":1}`, `{"id":2}`, `{"id":3}`, `"four"`, `{"id":5}`)
+	err := sender.Flush(5000)
+	if err != nil {
+		t.Fatal("Flush error:", err)
+	}
+	checkReceived(t, r, `{"id":1}`, `{"id":2}`, `{"id":3}`, `"four"`, `{"id":5}`)
+
+	// non JSON messages are base64 encoded
+	send(t, sender, "\x82\xa3abc")
+	err = sender.Flush(5000)
+	if err != nil {
+		t.Fatal("Flush error:", err)
+	}
+	received := r.received()
+	if received[len(received)-1] != `"gqNhYmM="` {
+		t.Fatal("Binary message is not base64 encoded:", received[len(received)-1])
+	}
+
+	// segments delivered are removed
+	segments, _ := filepath.Glob(filepath.Join(dir, "*", "*", "*.seg"))
+	if len(segments) != 1 {
+		t.Fatal("Segments delivered are not removed:", segments)
+	}
+}
+
+func TestSender_Retry(t *testing.T) {
+	r, server, dir := setup(t)
+	defer server.Close()
+	defer os.RemoveAll(dir)
+	sender := newSender(t, server.URL, dir)
+	defer sender.Close()
+
+	r.setFailing(true)
+	send(t, sender, `{"id":1}`, `{"id":2}`, `{"id":3}`)
+	err := sender.Flush(200)
+	if err == nil {
+		t.Fatal("Flush should timeout while endpoint is failing")
+	}
+	r.setFailing(false)
+	err = sender.Flush(5000)
+	if err != nil {
+		t.Fatal("Flush error:", err)
+	}
+	checkReceived(t, r, `{"id":1}`, `{"id":2}`, `{"id":3}`)
+	if r.requests < 3 {
+		t.Fatal("Requests are not retried:", r.requests)
+	}
+}
+
+func TestSender_Restart(t *testing.T) {
+	r, server, dir := setup(t)
+	defer server.Close()
+	defer os.RemoveAll(dir)
+
+	r.setFailing(true)
+	sender := newSender(t, server.URL, dir)
+	send(t, sender, `{"id":1}`, `{"id":2}`, `{"id":3}`)
+	sender.Close()
+
+	// a crash in the middle of appending leaves a partial record
+	segments, _ := filepath.Glob(filepath.Join(dir, "*", "*", "*.seg"))
+	if len(segments) == 0 {
+		t.Fatal("Messages are not spooled")
+	}
+	f, err := os.OpenFile(segments[len(segments)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal("Open segment error:", err)
+	}
+	f.Write([]byte{0, 0, 0, 100, 1, 2})
+	f.Close()
+
+	r.setFailing(false)
+	sender = newSender(t, server.URL, dir)
+	defer sender.Close()
+	send(t, sender, `{"id":4}`)
+	err = sender.Flush(5000)
+	if err != nil {
+		t.Fatal("Flush error:", err)
+	}
+	checkReceived(t, r, `{"id":1}`, `{"id":2}`, `{"id":3}`, `{"id":4}`)
+}
+
+func TestSender_SpoolLocked(t *testing.T) {
+	_, server, dir := setup(t)
+	defer server.Close()
+	defer os.RemoveAll(dir)
+	sender := newSender(t, server.URL, dir)
+	defer sender.Close()
+
+	_, err := webhook.NewSender(webhook.Config{
+		URLs:     []string{server.URL},
+		SpoolDir: dir,
+	})
+	if err == nil {
+		t.Fatal("Spool should not be shared by two senders")
+	}
+}
@@ -0,0 +1,395 @@
+package webhook
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/journeymidnight/yig/helper"
+)
+
+// A spool is an on-disk queue of messages with a single writer and a single
+// reader. Messages are appended to numbered segment files, each message
+// prefixed by its length and crc32. The reader position is saved in file
+// `cursor` after messages are delivered, and segments read through are
+// removed. Since the cursor is saved after delivery, messages could be
+// delivered more than once if the process stops in between.
+
+const (
+	SEGMENT_SUFFIX     = ".seg"
+	CURSOR_FILE        = "cursor"
+	LOCK_FILE          = "lock"
+	RECORD_HEADER_SIZE = 8 // length and crc32 of message
+	MAX_RECORD_SIZE    = 64 << 20
+)
+
+var errCorruptedRecord = errors.New("corrupted record")
+
+type spool struct {
+	sync.Mutex
+	dir         string
+	segmentSize int64
+	lock        *os.File // holds flock of the spool directory
+	file        *os.File // segment appended to
+	writeSeq    uint64
+	writeSize   int64
+	dirty       bool // appended since last sync
+	readSeq     uint64
+	readOffset  int64
+	appended    chan struct{}
+}
+
+func segmentPath(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", seq, SEGMENT_SUFFIX))
+}
+
+func listSegments(dir string) (seqs []uint64, err error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if !strings.HasSuffix(name, SEGMENT_SUFFIX) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, SEGMENT_SUFFIX), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+func openSpool(dir string, segmentSize int64) (s *spool, err error) {
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := os.OpenFile(filepath.Join(dir, LOCK_FILE), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	err = syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("spool %s is used by another process: %v", dir, err)
+	}
+	s = &spool{
+		dir:         dir,
+		segmentSize: segmentSize,
+		lock:        lock,
+		appended:    make(chan struct{}, 1),
+	}
+	defer func() {
+		if err != nil {
+			s.close()
+		}
+	}()
+
+	seqs, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	s.loadCursor()
+	if len(seqs) == 0 {
+		seqs = []uint64{s.readSeq}
+		if seqs[0] == 0 {
+			seqs[0] = 1
+		}
+	}
+	if s.readSeq < seqs[0] {
+		s.readSeq, s.readOffset = seqs[0], 0
+	}
+	s.writeSeq = seqs[len(seqs)-1]
+	// drop partially written record at the tail, if the process crashed
+	// during appending
+	path := segmentPath(dir, s.writeSeq)
+	s.writeSize, err = validLength(path)
+	if err != nil {
+		return nil, err
+	}
+	s.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	err = s.file.Truncate(s.writeSize)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.file.Seek(s.writeSize, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	if s.readSeq > s.writeSeq {
+		s.readSeq, s.readOffset = s.writeSeq, s.writeSize
+	}
+	if s.readSeq == s.writeSeq && s.readOffset > s.writeSize {
+		s.readOffset = s.writeSize
+	}
+	return s, nil
+}
+
+// Cursor is saved as "<segment seq> <offset>", missing or invalid cursor
+// means reading from the oldest segment
+func (s *spool) loadCursor() {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, CURSOR_FILE))
+	if err != nil {
+		return
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return
+	}
+	seq, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return
+	}
+	offset, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return
+	}
+	s.readSeq, s.readOffset = seq, offset
+}
+
+// Returns length of the segment prefix made of complete records
+func validLength(path string) (int64, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	var length int64
+	for {
+		record, err := readRecord(reader)
+		if err != nil {
+			return length, nil
+		}
+		length += int64(RECORD_HEADER_SIZE + len(record))
+	}
+}
+
+func readRecord(reader io.Reader) ([]byte, error) {
+	var header [RECORD_HEADER_SIZE]byte
+	_, err := io.ReadFull(reader, header[:])
+	if err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:4])
+	if size > MAX_RECORD_SIZE {
+		return nil, errCorruptedRecord
+	}
+	record := make([]byte, size)
+	_, err = io.ReadFull(reader, record)
+	if err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(record) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, errCorruptedRecord
+	}
+	return record, nil
+}
+
+func (s *spool) append(message []byte) error {
+	if len(message) > MAX_RECORD_SIZE {
+		return fmt.Errorf("message of %d bytes is too large", len(message))
+	}
+	buffer := make([]byte, RECORD_HEADER_SIZE+len(message))
+	binary.BigEndian.PutUint32(buffer[:4], uint32(len(message)))
+	binary.BigEndian.PutUint32(buffer[4:RECORD_HEADER_SIZE], crc32.ChecksumIEEE(message))
+	copy(buffer[RECORD_HEADER_SIZE:], message)
+
+	s.Lock()
+	defer s.Unlock()
+	if s.file == nil {
+		return errors.New("spool is closed")
+	}
+	if s.writeSize >= s.segmentSize {
+		err := s.rotate()
+		if err != nil {
+			return err
+		}
+	}
+	_, err := s.file.Write(buffer)
+	if err != nil {
+		// remove the partial record, so later ones are readable
+		s.file.Truncate(s.writeSize)
+		s.file.Seek(s.writeSize, io.SeekStart)
+		return err
+	}
+	s.writeSize += int64(len(buffer))
+	s.dirty = true
+	select {
+	case s.appended <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Should be called with lock held
+func (s *spool) rotate() error {
+	err := s.file.Sync()
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(segmentPath(s.dir, s.writeSeq+1),
+		os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	s.file.Close()
+	s.file = file
+	s.writeSeq++
+	s.writeSize = 0
+	s.dirty = false
+	return nil
+}
+
+// Read at most max messages after the cursor, returns position after them
+// which should be committed once the messages are delivered
+func (s *spool) read(max int) (messages [][]byte, seq uint64, offset int64, err error) {
+	s.Lock()
+	seq, offset = s.readSeq, s.readOffset
+	writeSeq, writeSize := s.writeSeq, s.writeSize
+	s.Unlock()
+
+	for len(messages) < max {
+		limit := writeSize
+		if seq != writeSeq {
+			info, err := os.Stat(segmentPath(s.dir, seq))
+			if os.IsNotExist(err) {
+				seq, offset = seq+1, 0
+				continue
+			}
+			if err != nil {
+				return messages, seq, offset, err
+			}
+			limit = info.Size()
+		}
+		if offset >= limit {
+			if seq == writeSeq {
+				break
+			}
+			seq, offset = seq+1, 0
+			continue
+		}
+		var records [][]byte
+		records, offset, err = readSegment(segmentPath(s.dir, seq), offset, limit, max-len(messages))
+		messages = append(messages, records...)
+		if err == errCorruptedRecord && seq != writeSeq {
+			helper.Logger.Error("Skip corrupted spool segment", segmentPath(s.dir, seq),
+				"from offset", offset)
+			seq, offset = seq+1, 0
+			continue
+		}
+		if err != nil {
+			return messages, seq, offset, err
+		}
+	}
+	return messages, seq, offset, nil
+}
+
+func readSegment(path string, offset, limit int64, max int) (records [][]byte,
+	next int64, err error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, offset, err
+	}
+	defer f.Close()
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, offset, err
+	}
+	reader := bufio.NewReader(io.LimitReader(f, limit-offset))
+	next = offset
+	for len(records) < max && next < limit {
+		record, err := readRecord(reader)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return records, next, errCorruptedRecord
+		}
+		if err != nil {
+			return records, next, err
+		}
+		records = append(records, record)
+		next += int64(RECORD_HEADER_SIZE + len(record))
+	}
+	return records, next, nil
+}
+
+// Save cursor and remove segments read through
+func (s *spool) commit(seq uint64, offset int64) error {
+	s.Lock()
+	oldSeq, oldOffset := s.readSeq, s.readOffset
+	s.Unlock()
+	if seq == oldSeq && offset == oldOffset {
+		return nil
+	}
+	tmp := filepath.Join(s.dir, CURSOR_FILE+".tmp")
+	err := ioutil.WriteFile(tmp, []byte(fmt.Sprintf("%d %d", seq, offset)), 0644)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, filepath.Join(s.dir, CURSOR_FILE))
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	s.readSeq, s.readOffset = seq, offset
+	s.Unlock()
+	for old := oldSeq; old < seq; old++ {
+		err = os.Remove(segmentPath(s.dir, old))
+		if err != nil && !os.IsNotExist(err) {
+			helper.Logger.Warn("Remove spool segment", segmentPath(s.dir, old), "failed:", err)
+		}
+	}
+	return nil
+}
+
+// Returns true if all messages are read and committed
+func (s *spool) empty() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.readSeq == s.writeSeq && s.readOffset >= s.writeSize
+}
+
+func (s *spool) sync() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.file == nil || !s.dirty {
+		return nil
+	}
+	s.dirty = false
+	return s.file.Sync()
+}
+
+func (s *spool) close() {
+	s.sync()
+	s.Lock()
+	defer s.Unlock()
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	if s.lock != nil {
+		s.lock.Close() // releases flock
+		s.lock = nil
+	}
+}
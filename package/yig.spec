@@ -34,6 +34,12 @@ install -D -m 755 admin %{buildroot}%{_bindir}/yig_admin
 install -D -m 755 delete %{buildroot}%{_bindir}/yig_delete_daemon
 install -D -m 755 getrediskeys %{buildroot}%{_bindir}/yig_getrediskeys
 install -D -m 755 lc     %{buildroot}%{_bindir}/yig_lifecyle_daemon
+install -D -m 755 rebalance %{buildroot}%{_bindir}/yig_rebalance
+install -D -m 755 scrub %{buildroot}%{_bindir}/yig_scrub
+install -D -m 755 restore %{buildroot}%{_bindir}/yig_restore
+install -D -m 755 replicate %{buildroot}%{_bindir}/yig_replicate
+install -D -m 755 tiering %{buildroot}%{_bindir}/yig_tiering
+install -D -m 755 orphan %{buildroot}%{_bindir}/yig_orphan
 install -D -m 755 %{_builddir}/yig/yig %{buildroot}%{_bindir}/yig
 install -D -m 644 package/yig.logrotate %{buildroot}/etc/logrotate.d/yig.logrotate
 install -D -m 644 package/access.logrotate %{buildroot}/etc/logrotate.d/access.logrotate
@@ -69,6 +75,12 @@ rm -rf %{buildroot}
 /usr/bin/yig_delete_daemon
 /usr/bin/yig_getrediskeys
 /usr/bin/yig_lifecyle_daemon
+/usr/bin/yig_rebalance
+/usr/bin/yig_scrub
+/usr/bin/yig_restore
+/usr/bin/yig_replicate
+/usr/bin/yig_tiering
+/usr/bin/yig_orphan
 /etc/logrotate.d/yig.logrotate
 /etc/logrotate.d/access.logrotate
 /etc/logrotate.d/yig_delete.logrotate
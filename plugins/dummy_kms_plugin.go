@@ -45,6 +45,11 @@ func (d *DummyKMS) UnsealKey(keyName string, sealedKey []byte, context crypto.Co
 	return key, nil
 }
 
+func (d *DummyKMS) SealKey(keyName string, key [32]byte, context crypto.Context) (sealedKey []byte, err error) {
+	helper.Logger.Info("Seal key succeed! ciphertext:", ciphertextKey)
+	return []byte(ciphertextKey), nil
+}
+
 func (d *DummyKMS) GetKeyID() string {
 	return "yig"
 }
@@ -399,6 +399,39 @@ func (s *SDKMS) UnsealKey(keyName string, sealedKey []byte, context crypto.Conte
 	return key, nil
 }
 
+func (s *SDKMS) SealKey(keyName string, key [32]byte, context crypto.Context) (sealedKey []byte, err error) {
+	requestData := map[string]interface{}{
+		"KeyID":     s.KeyID,
+		"Plaintext": string(key[:]),
+	}
+	body, err := json.Marshal(requestData)
+	if err != nil {
+		return sealedKey, err
+	}
+	url := s.Config.Url + "/v1/Encrypt"
+
+	statusCode, respDate, err := HTTPRequest(url, s.Token, body)
+	if err != nil {
+		helper.Logger.Error("StatusCode: ", statusCode, "Seal key request err: ", err.Error())
+		return sealedKey, err
+	}
+	var data ResponseData
+	err = json.Unmarshal(respDate, &data)
+	if err != nil {
+		return sealedKey, err
+	}
+	if statusCode > 299 {
+		if data.Code == KMSResponseCodeOfTokenInvalid {
+			s.Token, err = getKMSAccessToken(s.Config.Url, s.Config.AccessKey, s.Config.SecretAccessKey)
+			if err != nil {
+				return sealedKey, err
+			}
+		}
+		return sealedKey, errors.New("error code: " + data.Code + " message: " + data.Message)
+	}
+	return []byte(data.CiphertextBlob), nil
+}
+
 func (s *SDKMS) GetKeyID() string {
 	return s.Config.KeyName
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/posix"
+)
+
+const pluginName = "posix_backend"
+
+//The variable MUST be named as Exported.
+//the code in yig-plugin will lookup this symbol
+var Exported = mods.YigPlugin{
+	Name:       pluginName,
+	PluginType: mods.BACKEND_PLUGIN,
+	Create:     GetPosixBackendPlugin,
+}
+
+func GetPosixBackendPlugin(config map[string]interface{}) (interface{}, error) {
+	var roots []string
+	if list, ok := config["roots"].([]interface{}); ok {
+		for _, root := range list {
+			if s, ok := root.(string); ok {
+				roots = append(roots, s)
+			}
+		}
+	}
+	return interface{}(posixBackendPlugin{roots: roots}), nil
+}
+
+type posixBackendPlugin struct {
+	roots []string
+}
+
+func (p posixBackendPlugin) Initialize(logger *log.Logger,
+	config helper.Config) map[string]backend.Cluster {
+
+	clusters := make(map[string]backend.Cluster)
+	for _, root := range p.roots {
+		c, err := posix.NewPosixStorage(root)
+		if err != nil {
+			panic("Failed to initialize posix backend " + root + ": " + err.Error())
+		}
+		logger.Info("posix backend plugin loaded cluster", c.ID(), "from", root)
+		clusters[c.ID()] = c
+	}
+	return clusters
+}
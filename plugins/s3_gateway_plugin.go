@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/s3gateway"
+)
+
+const pluginName = "s3_gateway"
+
+//The variable MUST be named as Exported.
+//the code in yig-plugin will lookup this symbol
+var Exported = mods.YigPlugin{
+	Name:       pluginName,
+	PluginType: mods.BACKEND_PLUGIN,
+	Create:     GetS3GatewayPlugin,
+}
+
+func GetS3GatewayPlugin(config map[string]interface{}) (interface{}, error) {
+	var tables []map[string]interface{}
+	switch clusters := config["clusters"].(type) {
+	case []map[string]interface{}:
+		tables = clusters
+	case []interface{}:
+		for _, c := range clusters {
+			if t, ok := c.(map[string]interface{}); ok {
+				tables = append(tables, t)
+			}
+		}
+	}
+
+	var configs []s3gateway.Config
+	for _, t := range tables {
+		configs = append(configs, s3gateway.Config{
+			ID:             stringArg(t, "id"),
+			Endpoint:       stringArg(t, "endpoint"),
+			Region:         stringArg(t, "region"),
+			AccessKey:      stringArg(t, "access_key"),
+			SecretKey:      stringArg(t, "secret_key"),
+			Bucket:         stringArg(t, "bucket"),
+			ForcePathStyle: t["force_path_style"] == true,
+		})
+	}
+	return interface{}(s3gateway.Plugin{Configs: configs}), nil
+}
+
+func stringArg(config map[string]interface{}, key string) string {
+	s, _ := config[key].(string)
+	return s
+}
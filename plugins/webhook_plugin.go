@@ -0,0 +1,65 @@
+package main
+
+import (
+	"time"
+
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/mq/webhook"
+)
+
+const pluginName = "webhook"
+
+//The variable MUST be named as Exported.
+//the code in yig-plugin will lookup this symbol
+var Exported = mods.YigPlugin{
+	Name:       pluginName,
+	PluginType: mods.MQ_PLUGIN,
+	Create:     GetWebhookSender,
+}
+
+func GetWebhookSender(config map[string]interface{}) (interface{}, error) {
+	helper.Logger.Info("Get webhook plugin config:", config)
+	var urls []string
+	switch v := config["urls"].(type) {
+	case []string:
+		urls = v
+	case []interface{}:
+		for _, url := range v {
+			if s, ok := url.(string); ok {
+				urls = append(urls, s)
+			}
+		}
+	}
+	sender, err := webhook.NewSender(webhook.Config{
+		URLs:        urls,
+		Secret:      stringArg(config, "secret"),
+		SpoolDir:    stringArg(config, "spool_dir"),
+		BatchSize:   int(intArg(config, "batch_size")),
+		Timeout:     time.Duration(intArg(config, "timeout_ms")) * time.Millisecond,
+		MinBackoff:  time.Duration(intArg(config, "min_backoff_ms")) * time.Millisecond,
+		MaxBackoff:  time.Duration(intArg(config, "max_backoff_ms")) * time.Millisecond,
+		SegmentSize: intArg(config, "segment_size"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	helper.Logger.Info("start webhook sender, urls:", urls)
+	return interface{}(sender), nil
+}
+
+func stringArg(config map[string]interface{}, key string) string {
+	s, _ := config[key].(string)
+	return s
+}
+
+// toml decodes integers as int64, missing ones are 0 i.e defaults
+func intArg(config map[string]interface{}, key string) int64 {
+	switch v := config[key].(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	}
+	return 0
+}
@@ -0,0 +1,327 @@
+package posix
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/helper"
+)
+
+const (
+	FSID_FILE_NAME     = "fsid"
+	TEMP_DIR_NAME      = ".tmp"
+	SUB_DIR_COUNT      = 256
+	DIR_PERMISSION     = 0755
+	FILE_PERMISSION    = 0644
+	DEFAULT_POSIX_ROOT = "/var/lib/yig/data"
+)
+
+func Initialize(config helper.Config) map[string]backend.Cluster {
+	roots := config.PosixRoots
+	if len(roots) == 0 {
+		roots = []string{DEFAULT_POSIX_ROOT}
+	}
+	helper.Logger.Info("Loading posix data directories:", roots)
+
+	clusters := make(map[string]backend.Cluster)
+	for _, root := range roots {
+		c, err := NewPosixStorage(root)
+		if err != nil {
+			helper.Logger.Error("Failed to initialize posix storage", root, "err:", err)
+			continue
+		}
+		clusters[c.Name] = c
+	}
+
+	return clusters
+}
+
+// PosixCluster stores every object as a regular file under
+// <root>/<pool>/<xx>/<oid>, where <xx> is a hashed subdirectory
+// to keep single directories reasonably small. Unfinished writes are
+// kept in <root>/<pool>/.tmp and named ".tmp/<file>" when listed.
+type PosixCluster struct {
+	Name       string
+	Root       string
+	InstanceId uint64
+	counter    uint64
+}
+
+func NewPosixStorage(root string) (*PosixCluster, error) {
+	helper.Logger.Info("Loading posix directory", root)
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, pool := range backend.Pools() {
+		err = os.MkdirAll(filepath.Join(root, pool, TEMP_DIR_NAME), DIR_PERMISSION)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	name, err := loadOrCreateFsid(root)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := PosixCluster{
+		Name:       name,
+		Root:       root,
+		InstanceId: uint64(time.Now().UnixNano()),
+	}
+
+	helper.Logger.Info("Posix Cluster", name, "is ready, root is", root)
+	return &cluster, nil
+}
+
+// The fsid file plays the role of ceph FSID, so that entries in table
+// `cluster` and `objects` keep pointing to the same directory after restart.
+func loadOrCreateFsid(root string) (string, error) {
+	fsidPath := filepath.Join(root, FSID_FILE_NAME)
+	data, err := ioutil.ReadFile(fsidPath)
+	if err == nil {
+		fsid := strings.TrimSpace(string(data))
+		if fsid == "" {
+			return "", errors.New("empty fsid file " + fsidPath)
+		}
+		return fsid, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	fsid := "posix-" + strings.ToLower(string(helper.GenerateRandomId()))
+	err = ioutil.WriteFile(fsidPath, []byte(fsid+"\n"), FILE_PERMISSION)
+	if err != nil {
+		return "", err
+	}
+	return fsid, nil
+}
+
+func (cluster *PosixCluster) getUniqUploadName() string {
+	v := atomic.AddUint64(&cluster.counter, 1)
+	oid := fmt.Sprintf("%d:%d", cluster.InstanceId, v)
+	return oid
+}
+
+// Directories of pools are created at startup, pools removed from config
+// later are still readable since their directories are kept.
+func (cluster *PosixCluster) checkPool(poolName string) error {
+	if poolName == "" || poolName == TEMP_DIR_NAME ||
+		strings.ContainsRune(poolName, filepath.Separator) {
+		return fmt.Errorf("Bad poolname %s", poolName)
+	}
+	info, err := os.Stat(filepath.Join(cluster.Root, poolName))
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("Bad poolname %s", poolName)
+	}
+	return nil
+}
+
+func (cluster *PosixCluster) objectPath(poolName, oid string) string {
+	if dir, name := filepath.Split(oid); dir == TEMP_DIR_NAME+string(filepath.Separator) &&
+		name != "." && name != ".." {
+		return filepath.Join(cluster.Root, poolName, TEMP_DIR_NAME, name)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(oid))
+	subDir := fmt.Sprintf("%02x", h.Sum32()%SUB_DIR_COUNT)
+	return filepath.Join(cluster.Root, poolName, subDir, oid)
+}
+
+func (cluster *PosixCluster) ID() string {
+	return cluster.Name
+}
+
+func (cluster *PosixCluster) GetUsage() (usage backend.Usage, err error) {
+	var stat syscall.Statfs_t
+	err = syscall.Statfs(cluster.Root, &stat)
+	if err != nil {
+		return usage, err
+	}
+	if stat.Blocks == 0 {
+		return usage, nil
+	}
+	usage.UsedSpacePercent = int((stat.Blocks - stat.Bavail) * uint64(100) / stat.Blocks)
+	return
+}
+
+// Data is written to a temporary file first and then renamed into place,
+// so a reader never sees a partially written object.
+func (cluster *PosixCluster) Put(poolname string, data io.Reader) (oid string,
+	size uint64, err error) {
+
+	oid = cluster.getUniqUploadName()
+	if err = cluster.checkPool(poolname); err != nil {
+		return oid, 0, err
+	}
+
+	tmpDir := filepath.Join(cluster.Root, poolname, TEMP_DIR_NAME)
+	if err = os.MkdirAll(tmpDir, DIR_PERMISSION); err != nil {
+		return oid, 0, err
+	}
+	tmp, err := ioutil.TempFile(tmpDir, "put-")
+	if err != nil {
+		return oid, 0, err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	written, err := io.Copy(tmp, data)
+	if err != nil {
+		return oid, 0,
+			fmt.Errorf("Read from client failed. pool:%s oid:%s err:%v", poolname, oid, err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return oid, 0, err
+	}
+	if err = tmp.Close(); err != nil {
+		return oid, 0, err
+	}
+
+	target := cluster.objectPath(poolname, oid)
+	if err = os.MkdirAll(filepath.Dir(target), DIR_PERMISSION); err != nil {
+		return oid, 0, err
+	}
+	if err = os.Rename(tmp.Name(), target); err != nil {
+		return oid, 0, err
+	}
+	return oid, uint64(written), nil
+}
+
+func (cluster *PosixCluster) Append(poolname string, existName string, data io.Reader,
+	offset int64) (oid string, size uint64, err error) {
+
+	oid = existName
+	if len(oid) == 0 {
+		oid = cluster.getUniqUploadName()
+	}
+	if err = cluster.checkPool(poolname); err != nil {
+		return oid, 0, err
+	}
+
+	target := cluster.objectPath(poolname, oid)
+	if err = os.MkdirAll(filepath.Dir(target), DIR_PERMISSION); err != nil {
+		return oid, 0, err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE, FILE_PERMISSION)
+	if err != nil {
+		return oid, 0, err
+	}
+	defer f.Close()
+
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return oid, 0, err
+	}
+	written, err := io.Copy(f, data)
+	if err != nil {
+		return oid, uint64(written),
+			fmt.Errorf("Bad io. pool:%s oid:%s err:%v", poolname, oid, err)
+	}
+	err = f.Sync()
+	return oid, uint64(written), err
+}
+
+type fileReader struct {
+	io.Reader
+	file *os.File
+}
+
+func (r *fileReader) Close() error {
+	return r.file.Close()
+}
+
+func (cluster *PosixCluster) GetReader(poolName string, oid string, startOffset int64,
+	length uint64) (reader io.ReadCloser, err error) {
+
+	if err = cluster.checkPool(poolName); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(cluster.objectPath(poolName, oid))
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Seek(startOffset, io.SeekStart)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	var r io.Reader = f
+	if length > 0 {
+		r = io.LimitReader(f, int64(length))
+	}
+	return &fileReader{Reader: r, file: f}, nil
+}
+
+func (cluster *PosixCluster) Remove(poolname string, oid string) error {
+	if err := cluster.checkPool(poolname); err != nil {
+		return err
+	}
+	return os.Remove(cluster.objectPath(poolname, oid))
+}
+
+func (cluster *PosixCluster) ListObjects(poolName string,
+	walkFn func(objectName string, modifiedTime time.Time) error) error {
+
+	if err := cluster.checkPool(poolName); err != nil {
+		return err
+	}
+	subDirs, err := ioutil.ReadDir(filepath.Join(cluster.Root, poolName))
+	if err != nil {
+		return err
+	}
+	for _, subDir := range subDirs {
+		if !subDir.IsDir() {
+			continue
+		}
+		files, err := ioutil.ReadDir(filepath.Join(cluster.Root, poolName, subDir.Name()))
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			name := f.Name()
+			if subDir.Name() == TEMP_DIR_NAME {
+				// left by crashed writes, let caller decide by modified time
+				name = filepath.Join(TEMP_DIR_NAME, name)
+			}
+			if err = walkFn(name, f.ModTime()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Data is copied on local disk, never leaves the host
+func (cluster *PosixCluster) Copy(srcPool, srcName, dstPool string) (oid string,
+	size uint64, err error) {
+
+	if err = cluster.checkPool(srcPool); err != nil {
+		return "", 0, err
+	}
+	f, err := os.Open(cluster.objectPath(srcPool, srcName))
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	return cluster.Put(dstPool, f)
+}
@@ -0,0 +1,225 @@
+package posix_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/posix"
+)
+
+func setupPosixCluster(t *testing.T) (*posix.PosixCluster, func()) {
+	helper.Logger = log.NewLogger(os.Stderr, log.ErrorLevel)
+	root, err := ioutil.TempDir("", "yig-posix-")
+	if err != nil {
+		t.Fatal("TempDir error:", err)
+	}
+	cluster, err := posix.NewPosixStorage(root)
+	if err != nil {
+		os.RemoveAll(root)
+		t.Fatal("NewPosixStorage error:", err)
+	}
+	return cluster, func() { os.RemoveAll(root) }
+}
+
+func TestPosixCluster_PutAndGetReader(t *testing.T) {
+	cluster, cleanup := setupPosixCluster(t)
+	defer cleanup()
+
+	data := []byte("0123456789abcdefghij")
+	oid, size, err := cluster.Put(backend.SMALL_FILE_POOLNAME, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("Put error:", err)
+	}
+	if size != uint64(len(data)) {
+		t.Fatal("Put size mismatch:", size)
+	}
+
+	reader, err := cluster.GetReader(backend.SMALL_FILE_POOLNAME, oid, 0, 0)
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	whole, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil || !bytes.Equal(whole, data) {
+		t.Fatal("Whole object mismatch:", string(whole), err)
+	}
+
+	reader, err = cluster.GetReader(backend.SMALL_FILE_POOLNAME, oid, 5, 7)
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	ranged, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil || !bytes.Equal(ranged, data[5:12]) {
+		t.Fatal("Ranged read mismatch:", string(ranged), err)
+	}
+}
+
+func TestPosixCluster_Append(t *testing.T) {
+	cluster, cleanup := setupPosixCluster(t)
+	defer cleanup()
+
+	oid, n, err := cluster.Append(backend.BIG_FILE_POOLNAME, "", bytes.NewReader([]byte("hello ")), 0)
+	if err != nil || n != 6 {
+		t.Fatal("First append error:", n, err)
+	}
+	_, n, err = cluster.Append(backend.BIG_FILE_POOLNAME, oid, bytes.NewReader([]byte("world")), 6)
+	if err != nil || n != 5 {
+		t.Fatal("Second append error:", n, err)
+	}
+
+	reader, err := cluster.GetReader(backend.BIG_FILE_POOLNAME, oid, 0, 0)
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	defer reader.Close()
+	got, _ := ioutil.ReadAll(reader)
+	if string(got) != "hello world" {
+		t.Fatal("Appended object mismatch:", string(got))
+	}
+}
+
+func TestPosixCluster_Remove(t *testing.T) {
+	cluster, cleanup := setupPosixCluster(t)
+	defer cleanup()
+
+	oid, _, err := cluster.Put(backend.GLACIER_FILE_POOLNAME, bytes.NewReader([]byte("cold")))
+	if err != nil {
+		t.Fatal("Put error:", err)
+	}
+	if err = cluster.Remove(backend.GLACIER_FILE_POOLNAME, oid); err != nil {
+		t.Fatal("Remove error:", err)
+	}
+	if _, err = cluster.GetReader(backend.GLACIER_FILE_POOLNAME, oid, 0, 0); err == nil {
+		t.Fatal("Object still readable after Remove")
+	}
+	if _, _, err = cluster.Put("nopool", bytes.NewReader([]byte("x"))); err == nil {
+		t.Fatal("Put to unknown pool should fail")
+	}
+}
+
+func TestPosixCluster_StorageClassPools(t *testing.T) {
+	helper.CONFIG.StorageClasses = map[string]helper.StorageClassConfig{
+		"STANDARD_IA": {SmallFilePool: "rabbit-ia", BigFilePool: "tiger-ia"},
+	}
+	defer func() { helper.CONFIG.StorageClasses = nil }()
+	cluster, cleanup := setupPosixCluster(t)
+	defer cleanup()
+
+	for _, pool := range []string{"rabbit-ia", "tiger-ia", backend.BIG_FILE_POOLNAME} {
+		oid, _, err := cluster.Put(pool, bytes.NewReader([]byte("x")))
+		if err != nil {
+			t.Fatal("Put to pool", pool, "error:", err)
+		}
+		if err = cluster.Remove(pool, oid); err != nil {
+			t.Fatal("Remove from pool", pool, "error:", err)
+		}
+	}
+	for _, pool := range []string{"", "../rabbit", ".tmp"} {
+		if _, _, err := cluster.Put(pool, bytes.NewReader([]byte("x"))); err == nil {
+			t.Fatal("Put to bad pool", pool, "should fail")
+		}
+	}
+}
+
+func TestPosixCluster_StableID(t *testing.T) {
+	cluster, cleanup := setupPosixCluster(t)
+	defer cleanup()
+
+	reopened, err := posix.NewPosixStorage(cluster.Root)
+	if err != nil {
+		t.Fatal("NewPosixStorage error:", err)
+	}
+	if reopened.ID() != cluster.ID() {
+		t.Fatal("Cluster ID changed after reopen:", cluster.ID(), reopened.ID())
+	}
+	if _, err = cluster.GetUsage(); err != nil {
+		t.Fatal("GetUsage error:", err)
+	}
+}
+
+func TestPosixCluster_ListObjects(t *testing.T) {
+	cluster, cleanup := setupPosixCluster(t)
+	defer cleanup()
+
+	expected := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		oid, _, err := cluster.Put(backend.BIG_FILE_POOLNAME, bytes.NewReader([]byte("hello")))
+		if err != nil {
+			t.Fatal("Put error:", err)
+		}
+		expected[oid] = true
+	}
+	_, _, err := cluster.Put(backend.SMALL_FILE_POOLNAME, bytes.NewReader([]byte("small")))
+	if err != nil {
+		t.Fatal("Put error:", err)
+	}
+
+	// temporary file left by a crashed write
+	tmpName := filepath.Join(posix.TEMP_DIR_NAME, "put-crashed")
+	err = ioutil.WriteFile(filepath.Join(cluster.Root, backend.BIG_FILE_POOLNAME, tmpName),
+		[]byte("partial"), 0644)
+	if err != nil {
+		t.Fatal("WriteFile error:", err)
+	}
+	expected[tmpName] = true
+
+	listed := make(map[string]bool)
+	err = cluster.ListObjects(backend.BIG_FILE_POOLNAME, func(oid string, modifiedTime time.Time) error {
+		if modifiedTime.IsZero() {
+			t.Fatal("Modified time not set for", oid)
+		}
+		listed[oid] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal("ListObjects error:", err)
+	}
+	if len(listed) != len(expected) {
+		t.Fatal("Listed objects mismatch:", listed)
+	}
+	for oid := range expected {
+		if !listed[oid] {
+			t.Fatal("Object not listed:", oid)
+		}
+	}
+	if err = cluster.Remove(backend.BIG_FILE_POOLNAME, tmpName); err != nil {
+		t.Fatal("Remove temporary file error:", err)
+	}
+}
+
+func TestPosixCluster_Copy(t *testing.T) {
+	cluster, cleanup := setupPosixCluster(t)
+	defer cleanup()
+
+	oid, _, err := cluster.Put(backend.SMALL_FILE_POOLNAME, bytes.NewReader([]byte("hello world")))
+	if err != nil {
+		t.Fatal("Put error:", err)
+	}
+	newOid, size, err := cluster.Copy(backend.SMALL_FILE_POOLNAME, oid, backend.GLACIER_FILE_POOLNAME)
+	if err != nil {
+		t.Fatal("Copy error:", err)
+	}
+	if size != 11 || newOid == oid {
+		t.Fatal("Bad copy result:", newOid, size)
+	}
+	if err = cluster.Remove(backend.SMALL_FILE_POOLNAME, oid); err != nil {
+		t.Fatal("Remove error:", err)
+	}
+	reader, err := cluster.GetReader(backend.GLACIER_FILE_POOLNAME, newOid, 0, 0)
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	defer reader.Close()
+	data, _ := ioutil.ReadAll(reader)
+	if string(data) != "hello world" {
+		t.Fatal("Copied object mismatch:", string(data))
+	}
+}
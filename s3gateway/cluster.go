@@ -0,0 +1,277 @@
+package s3gateway
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/journeymidnight/aws-sdk-go/aws"
+	"github.com/journeymidnight/aws-sdk-go/aws/awserr"
+	"github.com/journeymidnight/aws-sdk-go/aws/credentials"
+	"github.com/journeymidnight/aws-sdk-go/aws/session"
+	"github.com/journeymidnight/aws-sdk-go/service/s3"
+	"github.com/journeymidnight/aws-sdk-go/service/s3/s3manager"
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+)
+
+const (
+	DEFAULT_REGION       = "us-east-1"
+	UPLOAD_PART_SIZE     = 16 << 20 // 16M
+	UPLOAD_CONCURRENCY   = 4
+	MAX_UPLOAD_PARTS     = s3manager.MaxUploadParts
+	NO_SUCH_KEY_ERR_CODE = "NoSuchKey"
+	MAX_COPY_SIZE        = 5 << 30 // 5G, limit of a single CopyObject
+)
+
+// Config describes one remote S3-compatible bucket used as a data cluster
+type Config struct {
+	ID             string // cluster ID used in table `cluster` and `objects`
+	Endpoint       string // e.g. http://10.0.0.1:9000
+	Region         string
+	AccessKey      string
+	SecretKey      string
+	Bucket         string
+	ForcePathStyle bool
+}
+
+// S3Cluster stores every blob as an object named <pool>/<oid> in a
+// bucket on a remote S3-compatible endpoint.
+type S3Cluster struct {
+	Name       string
+	Bucket     string
+	Client     *s3.S3
+	uploader   *s3manager.Uploader
+	InstanceId uint64
+	counter    uint64
+}
+
+// Plugin implements backend.Plugin, so remote endpoints could be
+// loaded as a BACKEND_PLUGIN
+type Plugin struct {
+	Configs []Config
+}
+
+func (p Plugin) Initialize(logger *log.Logger, config helper.Config) map[string]backend.Cluster {
+	clusters := make(map[string]backend.Cluster)
+	for _, c := range p.Configs {
+		cluster, err := NewS3Cluster(c)
+		if err != nil {
+			panic("Failed to initialize s3 gateway cluster " + c.ID + ": " + err.Error())
+		}
+		logger.Info("S3 gateway cluster", cluster.Name, "is ready, endpoint is",
+			c.Endpoint, "bucket is", c.Bucket)
+		clusters[cluster.Name] = cluster
+	}
+	return clusters
+}
+
+// NewClient returns a client of S3-compatible endpoint, e.g http://10.0.0.1:9000
+func NewClient(endpoint, region, accessKey, secretKey string, forcePathStyle bool) (*s3.S3, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if region == "" {
+		region = DEFAULT_REGION
+	}
+	sess, err := session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials(accessKey, secretKey, ""),
+		Endpoint:         aws.String(u.Host),
+		DisableSSL:       aws.Bool(u.Scheme == "http"),
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(forcePathStyle),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(sess), nil
+}
+
+func NewS3Cluster(c Config) (*S3Cluster, error) {
+	if c.ID == "" || c.Endpoint == "" || c.Bucket == "" {
+		return nil, errors.New("id, endpoint and bucket must be specified")
+	}
+	client, err := NewClient(c.Endpoint, c.Region, c.AccessKey, c.SecretKey, c.ForcePathStyle)
+	if err != nil {
+		return nil, err
+	}
+	uploader := s3manager.NewUploaderWithClient(client, func(u *s3manager.Uploader) {
+		u.PartSize = UPLOAD_PART_SIZE
+		u.Concurrency = UPLOAD_CONCURRENCY
+		u.MaxUploadParts = MAX_UPLOAD_PARTS
+	})
+	return &S3Cluster{
+		Name:       c.ID,
+		Bucket:     c.Bucket,
+		Client:     client,
+		uploader:   uploader,
+		InstanceId: uint64(time.Now().UnixNano()),
+	}, nil
+}
+
+func (cluster *S3Cluster) getUniqUploadName() string {
+	v := atomic.AddUint64(&cluster.counter, 1)
+	oid := fmt.Sprintf("%d:%d", cluster.InstanceId, v)
+	return oid
+}
+
+func objectKey(poolName, oid string) string {
+	return poolName + "/" + oid
+}
+
+func (cluster *S3Cluster) ID() string {
+	return cluster.Name
+}
+
+// Remote endpoints do not expose capacity, treat them as always available
+func (cluster *S3Cluster) GetUsage() (usage backend.Usage, err error) {
+	return usage, nil
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  uint64
+}
+
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.count += uint64(n)
+	return
+}
+
+func (cluster *S3Cluster) upload(key string, data io.Reader) error {
+	_, err := cluster.uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(cluster.Bucket),
+		Key:    aws.String(key),
+		Body:   data,
+	})
+	return err
+}
+
+func (cluster *S3Cluster) Put(poolname string, data io.Reader) (oid string,
+	size uint64, err error) {
+
+	oid = cluster.getUniqUploadName()
+	reader := &countingReader{reader: data}
+	err = cluster.upload(objectKey(poolname, oid), reader)
+	if err != nil {
+		return oid, 0, fmt.Errorf("Upload to s3 failed. pool:%s oid:%s err:%v",
+			poolname, oid, err)
+	}
+	return oid, reader.count, nil
+}
+
+// S3 has no append semantic, so the first `offset` bytes of the existing
+// object are streamed back and uploaded again together with the new chunk.
+func (cluster *S3Cluster) Append(poolname string, existName string, data io.Reader,
+	offset int64) (oid string, size uint64, err error) {
+
+	oid = existName
+	if len(oid) == 0 {
+		oid = cluster.getUniqUploadName()
+	}
+	reader := &countingReader{reader: data}
+	var body io.Reader = reader
+	if len(existName) != 0 && offset > 0 {
+		existing, err := cluster.GetReader(poolname, oid, 0, uint64(offset))
+		if err != nil {
+			return oid, 0, err
+		}
+		defer existing.Close()
+		body = io.MultiReader(existing, reader)
+	}
+	err = cluster.upload(objectKey(poolname, oid), body)
+	if err != nil {
+		return oid, 0, fmt.Errorf("Append to s3 failed. pool:%s oid:%s err:%v",
+			poolname, oid, err)
+	}
+	return oid, reader.count, nil
+}
+
+func (cluster *S3Cluster) GetReader(poolName string, oid string, startOffset int64,
+	length uint64) (reader io.ReadCloser, err error) {
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(cluster.Bucket),
+		Key:    aws.String(objectKey(poolName, oid)),
+	}
+	if length > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-%d",
+			startOffset, startOffset+int64(length)-1))
+	} else if startOffset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", startOffset))
+	}
+	output, err := cluster.Client.GetObject(input)
+	if err != nil {
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+func (cluster *S3Cluster) Remove(poolname string, oid string) error {
+	_, err := cluster.Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(cluster.Bucket),
+		Key:    aws.String(objectKey(poolname, oid)),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == NO_SUCH_KEY_ERR_CODE {
+		return nil
+	}
+	return err
+}
+
+func (cluster *S3Cluster) ListObjects(poolName string,
+	walkFn func(objectName string, modifiedTime time.Time) error) error {
+
+	prefix := objectKey(poolName, "")
+	var walkErr error
+	err := cluster.Client.ListObjectsPages(&s3.ListObjectsInput{
+		Bucket: aws.String(cluster.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, object := range page.Contents {
+			oid := aws.StringValue(object.Key)[len(prefix):]
+			walkErr = walkFn(oid, aws.TimeValue(object.LastModified))
+			if walkErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return walkErr
+}
+
+// Copied by the remote endpoint with CopyObject, objects larger than
+// MAX_COPY_SIZE are rejected and should be streamed instead
+func (cluster *S3Cluster) Copy(srcPool, srcName, dstPool string) (oid string,
+	size uint64, err error) {
+
+	head, err := cluster.Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(cluster.Bucket),
+		Key:    aws.String(objectKey(srcPool, srcName)),
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	size = uint64(aws.Int64Value(head.ContentLength))
+	if size > MAX_COPY_SIZE {
+		return "", 0, fmt.Errorf("object too large to copy: %d", size)
+	}
+	oid = cluster.getUniqUploadName()
+	_, err = cluster.Client.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(cluster.Bucket),
+		Key:        aws.String(objectKey(dstPool, oid)),
+		CopySource: aws.String(cluster.Bucket + "/" + objectKey(srcPool, srcName)),
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("Copy in s3 failed. pool:%s oid:%s err:%v", dstPool, oid, err)
+	}
+	return oid, size, nil
+}
@@ -0,0 +1,242 @@
+package s3gateway_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/s3gateway"
+)
+
+// fakeS3 serves PUT, copy, HEAD, ranged GET, list and DELETE for path style requests
+type fakeS3 struct {
+	sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	key := r.URL.Path
+	switch r.Method {
+	case "PUT":
+		if source := r.Header.Get("X-Amz-Copy-Source"); source != "" {
+			data, ok := f.objects["/"+strings.TrimPrefix(source, "/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, "<Error><Code>NoSuchKey</Code><Message>no such key</Message></Error>")
+				return
+			}
+			f.objects[key] = data
+			fmt.Fprint(w, "<CopyObjectResult><ETag>\"etag\"</ETag></CopyObjectResult>")
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		f.objects[key] = data
+		w.Header().Set("ETag", "\"etag\"")
+	case "GET":
+		if bucket := strings.Trim(key, "/"); !strings.Contains(bucket, "/") {
+			f.list(w, bucket, r.URL.Query().Get("prefix"))
+			return
+		}
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>NoSuchKey</Code><Message>no such key</Message></Error>")
+			return
+		}
+		if rg := r.Header.Get("Range"); rg != "" {
+			bounds := strings.SplitN(strings.TrimPrefix(rg, "bytes="), "-", 2)
+			start, _ := strconv.Atoi(bounds[0])
+			end := len(data) - 1
+			if bounds[1] != "" {
+				end, _ = strconv.Atoi(bounds[1])
+			}
+			data = data[start : end+1]
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		w.Write(data)
+	case "HEAD":
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	case "DELETE":
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, bucket, prefix string) {
+	var keys []string
+	for key := range f.objects {
+		key = strings.TrimPrefix(key, "/"+bucket+"/")
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(w, "<ListBucketResult><Name>%s</Name><Prefix>%s</Prefix><IsTruncated>false</IsTruncated>",
+		bucket, prefix)
+	for _, key := range keys {
+		fmt.Fprintf(w, "<Contents><Key>%s</Key><LastModified>%s</LastModified><Size>%d</Size></Contents>",
+			key, time.Now().UTC().Format(time.RFC3339), len(f.objects["/"+bucket+"/"+key]))
+	}
+	fmt.Fprint(w, "</ListBucketResult>")
+}
+
+func setupS3Cluster(t *testing.T) (*s3gateway.S3Cluster, *fakeS3, func()) {
+	helper.Logger = log.NewLogger(os.Stderr, log.ErrorLevel)
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	cluster, err := s3gateway.NewS3Cluster(s3gateway.Config{
+		ID:             "s3-test",
+		Endpoint:       server.URL,
+		AccessKey:      "hehehehe",
+		SecretKey:      "hehehehe",
+		Bucket:         "yigdata",
+		ForcePathStyle: true,
+	})
+	if err != nil {
+		server.Close()
+		t.Fatal("NewS3Cluster error:", err)
+	}
+	return cluster, fake, server.Close
+}
+
+func readAll(t *testing.T, cluster *s3gateway.S3Cluster, pool, oid string,
+	offset int64, length uint64) string {
+
+	reader, err := cluster.GetReader(pool, oid, offset, length)
+	if err != nil {
+		t.Fatal("GetReader error:", err)
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal("Read error:", err)
+	}
+	return string(data)
+}
+
+func TestS3Cluster_PutGetRemove(t *testing.T) {
+	cluster, fake, cleanup := setupS3Cluster(t)
+	defer cleanup()
+
+	oid, size, err := cluster.Put(backend.BIG_FILE_POOLNAME, bytes.NewReader([]byte("0123456789")))
+	if err != nil {
+		t.Fatal("Put error:", err)
+	}
+	if size != 10 {
+		t.Fatal("Put size mismatch:", size)
+	}
+	if _, ok := fake.objects["/yigdata/tiger/"+oid]; !ok {
+		t.Fatal("Object not stored under pool prefix, got:", fake.objects)
+	}
+	if got := readAll(t, cluster, backend.BIG_FILE_POOLNAME, oid, 0, 0); got != "0123456789" {
+		t.Fatal("Whole object mismatch:", got)
+	}
+	if got := readAll(t, cluster, backend.BIG_FILE_POOLNAME, oid, 3, 4); got != "3456" {
+		t.Fatal("Ranged read mismatch:", got)
+	}
+
+	if err = cluster.Remove(backend.BIG_FILE_POOLNAME, oid); err != nil {
+		t.Fatal("Remove error:", err)
+	}
+	if len(fake.objects) != 0 {
+		t.Fatal("Object still exists after Remove")
+	}
+}
+
+func TestS3Cluster_Append(t *testing.T) {
+	cluster, _, cleanup := setupS3Cluster(t)
+	defer cleanup()
+
+	oid, n, err := cluster.Append(backend.BIG_FILE_POOLNAME, "", bytes.NewReader([]byte("hello ")), 0)
+	if err != nil || n != 6 {
+		t.Fatal("First append error:", n, err)
+	}
+	_, n, err = cluster.Append(backend.BIG_FILE_POOLNAME, oid, bytes.NewReader([]byte("world")), 6)
+	if err != nil || n != 5 {
+		t.Fatal("Second append error:", n, err)
+	}
+	if got := readAll(t, cluster, backend.BIG_FILE_POOLNAME, oid, 0, 0); got != "hello world" {
+		t.Fatal("Appended object mismatch:", got)
+	}
+}
+
+func TestS3Cluster_ListObjects(t *testing.T) {
+	cluster, _, cleanup := setupS3Cluster(t)
+	defer cleanup()
+
+	expected := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		oid, _, err := cluster.Put(backend.BIG_FILE_POOLNAME, bytes.NewReader([]byte("hello")))
+		if err != nil {
+			t.Fatal("Put error:", err)
+		}
+		expected[oid] = true
+	}
+	if _, _, err := cluster.Put(backend.SMALL_FILE_POOLNAME, bytes.NewReader([]byte("small"))); err != nil {
+		t.Fatal("Put error:", err)
+	}
+
+	listed := make(map[string]bool)
+	err := cluster.ListObjects(backend.BIG_FILE_POOLNAME, func(oid string, modifiedTime time.Time) error {
+		if modifiedTime.IsZero() {
+			t.Fatal("Modified time not set for", oid)
+		}
+		listed[oid] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal("ListObjects error:", err)
+	}
+	if len(listed) != len(expected) {
+		t.Fatal("Listed objects mismatch:", listed)
+	}
+	for oid := range expected {
+		if !listed[oid] {
+			t.Fatal("Object not listed:", oid)
+		}
+	}
+}
+
+func TestS3Cluster_Copy(t *testing.T) {
+	cluster, fake, cleanup := setupS3Cluster(t)
+	defer cleanup()
+
+	oid, _, err := cluster.Put(backend.SMALL_FILE_POOLNAME, bytes.NewReader([]byte("hello world")))
+	if err != nil {
+		t.Fatal("Put error:", err)
+	}
+	newOid, size, err := cluster.Copy(backend.SMALL_FILE_POOLNAME, oid, backend.GLACIER_FILE_POOLNAME)
+	if err != nil {
+		t.Fatal("Copy error:", err)
+	}
+	if size != 11 {
+		t.Fatal("Copy size mismatch:", size)
+	}
+	if _, ok := fake.objects["/yigdata/turtle/"+newOid]; !ok {
+		t.Fatal("Copied object not stored under target pool, got:", fake.objects)
+	}
+	if got := readAll(t, cluster, backend.GLACIER_FILE_POOLNAME, newOid, 0, 0); got != "hello world" {
+		t.Fatal("Copied object mismatch:", got)
+	}
+}
@@ -90,7 +90,7 @@ func buildCanonicalizedResource(req *http.Request) string {
 		"response-content-language",
 		"response-content-type",
 		"response-expires",
-		"torrent", "uploadId", "uploads", "versionId",
+		"tagging", "torrent", "uploadId", "uploads", "versionId",
 		"versioning", "versions", "website",
 	}
 	requestQuery := req.URL.Query()
@@ -11,16 +11,21 @@ import (
 	"github.com/journeymidnight/yig/backend"
 	"github.com/journeymidnight/yig/ceph"
 	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/erasure"
 	e "github.com/journeymidnight/yig/error"
 	"github.com/journeymidnight/yig/helper"
 	"github.com/journeymidnight/yig/iam/common"
 	"github.com/journeymidnight/yig/meta"
 	"github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/posix"
 	"github.com/journeymidnight/yig/redis"
 	"github.com/journeymidnight/yig/signature"
 )
 
-func New(metaCacheType int, enableDataCache bool, kms crypto.KMS) *YigStorage {
+func New(metaCacheType int, enableDataCache bool, kms crypto.KMS,
+	plugins map[string]*mods.YigPlugin) *YigStorage {
+
 	yig := YigStorage{
 		DataStorage: make(map[string]backend.Cluster),
 		DataCache:   newDataCache(enableDataCache),
@@ -30,12 +35,33 @@ func New(metaCacheType int, enableDataCache bool, kms crypto.KMS) *YigStorage {
 		WaitGroup:   new(sync.WaitGroup),
 	}
 
-	yig.DataStorage = ceph.Initialize(helper.CONFIG)
+	switch helper.CONFIG.DataStore {
+	case "ceph":
+		yig.DataStorage = ceph.Initialize(helper.CONFIG)
+	case "posix":
+		yig.DataStorage = posix.Initialize(helper.CONFIG)
+	case "none": // only use clusters from backend plugins
+	default:
+		panic("unsupported data store " + helper.CONFIG.DataStore)
+	}
+	for id, cluster := range backend.InitializeFromPlugins(plugins) {
+		if _, ok := yig.DataStorage[id]; ok {
+			panic("Duplicated data storage cluster ID " + id)
+		}
+		yig.DataStorage[id] = cluster
+	}
+	for id, cluster := range erasure.Initialize(helper.CONFIG, yig.DataStorage, yig.MetaStorage) {
+		if _, ok := yig.DataStorage[id]; ok {
+			panic("Duplicated data storage cluster ID " + id)
+		}
+		yig.DataStorage[id] = cluster
+	}
 	if len(yig.DataStorage) == 0 {
 		panic("No data storage can be used!")
 	}
 
 	initializeRecycler(&yig)
+	initializeAccessTracker(&yig)
 	return &yig
 }
 
@@ -70,17 +96,16 @@ func (yig *YigStorage) AppendObject(bucketName string, objectName string, creden
 	var objSize int64
 	if objInfo != nil {
 		cephCluster = yig.DataStorage[objInfo.Location]
-		// Every appendable file must be treated as a big file
-		poolName = backend.BIG_FILE_POOLNAME
+		poolName = objInfo.Pool
 		oid = objInfo.ObjectId
 		initializationVector = objInfo.InitializationVector
 		objSize = objInfo.Size
 		storageClass = objInfo.StorageClass
 		helper.Logger.Println(20, "request append oid:", oid, "iv:", initializationVector, "size:", objSize)
 	} else {
-		// New appendable object
+		// New appendable object, every appendable file must be treated as a big file
 		cephCluster, poolName = yig.pickClusterAndPool(bucketName, objectName, storageClass, size, true)
-		if cephCluster == nil || poolName != backend.BIG_FILE_POOLNAME {
+		if cephCluster == nil || poolName == backend.SMALL_FILE_POOLNAME {
 			helper.Logger.Warn("PickOneClusterAndPool error")
 			return result, e.ErrInternalError
 		}
@@ -161,5 +186,12 @@ func (yig *YigStorage) AppendObject(bucketName string, objectName string, creden
 		yig.MetaStorage.Cache.Remove(redis.ObjectTable, bucketName+":"+objectName+":")
 		yig.DataCache.Remove(bucketName + ":" + objectName + ":" + object.GetVersionId())
 	}
+	// S3 has no event for appends, every append is sent as a put
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		helper.Logger.Warn("No event sent for append of", bucketName, objectName, "error:", err)
+		return result, nil
+	}
+	yig.notify(bucket, datatype.EventObjectCreatedPut, object, credential.UserId)
 	return result, nil
 }
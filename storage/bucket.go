@@ -2,6 +2,7 @@ package storage
 
 import (
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,7 +20,9 @@ import (
 	"github.com/journeymidnight/yig/redis"
 )
 
-func (yig *YigStorage) MakeBucket(bucketName string, acl datatype.Acl,
+// Buckets created with object lock enabled have versioning enabled, and both
+// could never be disabled.
+func (yig *YigStorage) MakeBucket(bucketName string, acl datatype.Acl, objectLockEnabled bool,
 	credential common.Credential) error {
 	// Input validation.
 	if err := api.CheckValidBucketName(bucketName); err != nil {
@@ -34,6 +37,10 @@ func (yig *YigStorage) MakeBucket(bucketName string, acl datatype.Acl,
 		ACL:        acl,
 		Versioning: meta.VersionDisabled, // it's the default
 	}
+	if objectLockEnabled {
+		bucket.Versioning = meta.VersionEnabled
+		bucket.ObjectLock.ObjectLockEnabled = datatype.ObjectLockEnabled
+	}
 	processed, err := yig.MetaStorage.Client.CheckAndPutBucket(bucket)
 	if err != nil {
 		helper.Logger.Error("Error making CheckAndPut:", err)
@@ -257,6 +264,12 @@ func (yig *YigStorage) SetBucketVersioning(bucketName string, versioning datatyp
 	if bucket.OwnerId != credential.UserId {
 		return e.ErrBucketAccessForbidden
 	}
+	if !bucket.Replication.IsEmpty() && versioning.Status != meta.VersionEnabled {
+		return e.ErrReplicationVersioningSuspended
+	}
+	if bucket.ObjectLock.IsEnabled() && versioning.Status != meta.VersionEnabled {
+		return e.ErrObjectLockVersioningSuspended
+	}
 	bucket.Versioning = versioning.Status
 	err = yig.MetaStorage.Client.PutBucket(*bucket)
 	if err != nil {
@@ -451,6 +464,37 @@ func (yig *YigStorage) DeleteBucketWebsite(bucket *meta.Bucket) error {
 	return nil
 }
 
+func (yig *YigStorage) SetBucketTagging(bucket *meta.Bucket, tags map[string]string) (err error) {
+	bucket.Tags = tags
+	err = yig.MetaStorage.Client.PutBucket(*bucket)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.BucketTable, bucket.Name)
+	return nil
+}
+
+func (yig *YigStorage) GetBucketTagging(bucketName string) (tags map[string]string, err error) {
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		return
+	}
+	if len(bucket.Tags) == 0 {
+		return nil, e.ErrNoSuchTagSet
+	}
+	return bucket.Tags, nil
+}
+
+func (yig *YigStorage) DeleteBucketTagging(bucket *meta.Bucket) error {
+	bucket.Tags = nil
+	err := yig.MetaStorage.Client.PutBucket(*bucket)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.BucketTable, bucket.Name)
+	return nil
+}
+
 func (yig *YigStorage) SetBucketEncryption(bucket *meta.Bucket, config datatype.EncryptionConfiguration) (err error) {
 	bucket.Encryption = config
 	err = yig.MetaStorage.Client.PutBucket(*bucket)
@@ -578,7 +622,17 @@ func (yig *YigStorage) ListObjectsInternal(bucketName string,
 	var verIdMarker string
 	if request.Versioned {
 		marker = request.KeyMarker
-		verIdMarker = request.VersionIdMarker
+		if request.VersionIdMarker != "" {
+			verIdMarker, err = util.Decrypt(request.VersionIdMarker)
+			if err != nil {
+				err = e.ErrNoSuchVersion
+				return
+			}
+			if _, err = strconv.ParseUint(verIdMarker, 10, 64); err != nil {
+				err = e.ErrNoSuchVersion
+				return
+			}
+		}
 	} else if request.Version == 2 {
 		if request.ContinuationToken != "" {
 			marker, err = util.Decrypt(request.ContinuationToken)
@@ -595,7 +649,12 @@ func (yig *YigStorage) ListObjectsInternal(bucketName string,
 	helper.Logger.Info("Prefix:", request.Prefix, "Marker:", request.Marker, "MaxKeys:",
 		request.MaxKeys, "Delimiter:", request.Delimiter, "Version:", request.Version,
 		"keyMarker:", request.KeyMarker, "versionIdMarker:", request.VersionIdMarker)
-	return yig.MetaStorage.Client.ListObjects(bucketName, marker, verIdMarker, request.Prefix, request.Delimiter, request.Versioned, request.MaxKeys)
+	retObjects, prefixes, truncated, nextMarker, nextVerIdMarker, err = yig.MetaStorage.Client.ListObjects(bucketName,
+		marker, verIdMarker, request.Prefix, request.Delimiter, request.Versioned, request.MaxKeys)
+	if nextVerIdMarker != "" {
+		nextVerIdMarker = util.Encrypt(nextVerIdMarker)
+	}
+	return
 }
 
 func (yig *YigStorage) ListObjects(credential common.Credential, bucketName string,
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/journeymidnight/yig/api/datatype"
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/crypto"
+	e "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	meta "github.com/journeymidnight/yig/meta/types"
+)
+
+// Data of source object could be used by target as is, only if both are
+// unencrypted or encrypted with the same key. SSE-S3 data keys are sealed
+// with bucket and object name, so the key of source is sealed again for
+// target by resealKey, if KMS supports it.
+func (yig *YigStorage) sameEncryptionKey(targetObject, sourceObject *meta.Object,
+	sseRequest datatype.SseRequest) bool {
+
+	switch sseRequest.Type {
+	case "":
+		return sourceObject.SseType == ""
+	case crypto.SSEC.String():
+		return sourceObject.SseType == crypto.SSEC.String() &&
+			bytes.Equal(sseRequest.CopySourceSseCustomerKey, sseRequest.SseCustomerKey)
+	case crypto.S3.String():
+		if sourceObject.SseType != crypto.S3.String() {
+			return false
+		}
+		if sourceObject.BucketName == targetObject.BucketName &&
+			sourceObject.Name == targetObject.Name {
+			return true
+		}
+		_, ok := yig.KMS.(crypto.KeySealer)
+		return ok
+	}
+	return false
+}
+
+// Returns sealed data key of source object for target object, which should
+// be encrypted with the same key, see sameEncryptionKey
+func (yig *YigStorage) resealKey(targetObject, sourceObject *meta.Object) ([]byte, error) {
+	if sourceObject.SseType != crypto.S3.String() ||
+		sourceObject.BucketName == targetObject.BucketName &&
+			sourceObject.Name == targetObject.Name {
+		return sourceObject.EncryptionKey, nil
+	}
+	sealer, ok := yig.KMS.(crypto.KeySealer)
+	if !ok {
+		return nil, e.ErrNotImplemented
+	}
+	key, err := yig.KMS.UnsealKey(yig.KMS.GetKeyID(), sourceObject.EncryptionKey,
+		crypto.Context{sourceObject.BucketName: path.Join(sourceObject.BucketName, sourceObject.Name)})
+	if err != nil {
+		return nil, err
+	}
+	return sealer.SealKey(yig.KMS.GetKeyID(), key,
+		crypto.Context{targetObject.BucketName: path.Join(targetObject.BucketName, targetObject.Name)})
+}
+
+// Target object could reference data of source object directly, instead of
+// copying it, if data is stored in the same pool. Caller should make sure data
+// is encrypted the same way, see sameEncryptionKey. Appendable objects are
+// never shared since appending modifies data in place.
+func (yig *YigStorage) canShareData(sourceObject *meta.Object, poolName string) bool {
+	if sourceObject.Type == meta.ObjectTypeAppendable ||
+		sourceObject.StorageClass == meta.ObjectStorageClassGlacier ||
+		sourceObject.Pool != poolName {
+		return false
+	}
+	_, ok := yig.DataStorage[sourceObject.Location]
+	return ok
+}
+
+// Pick the source cluster as copy target, if it implements backend.Copier
+// and still accepts new data of storage class in pool
+func (yig *YigStorage) pickCopier(location string, storageClass meta.StorageClass,
+	poolName string) (backend.Cluster, bool) {
+	cluster, ok := yig.DataStorage[location]
+	if !ok {
+		return nil, false
+	}
+	if _, ok = cluster.(backend.Copier); !ok {
+		return nil, false
+	}
+	metaClusters, err := yig.MetaStorage.GetClusters()
+	if err != nil {
+		return nil, false
+	}
+	for _, c := range clustersForStorageClass(metaClusters, storageClass, poolName) {
+		if c.Fsid == location {
+			return cluster, true
+		}
+	}
+	return nil, false
+}
+
+// Copy data of source object to pool inside its cluster, and fill ObjectId,
+// Parts and InitializationVector of target object. Returns objects written,
+// which should be recycled if metadata update fails later.
+func (yig *YigStorage) copyInCluster(cluster backend.Cluster, targetObject *meta.Object,
+	sourceObject *meta.Object, poolName string) (copied []objectToRecycle, err error) {
+
+	copier := cluster.(backend.Copier)
+	defer func() {
+		if err != nil {
+			for _, o := range copied {
+				yig.recycle(o)
+			}
+			copied = nil
+		}
+	}()
+	copyBlob := func(objectId string, size int64) (string, error) {
+		oid, n, err := copier.Copy(sourceObject.Pool, objectId, poolName)
+		if err != nil {
+			return "", err
+		}
+		copied = append(copied, objectToRecycle{
+			location: cluster.ID(),
+			pool:     poolName,
+			objectId: oid,
+		})
+		if int64(n) != size {
+			return "", fmt.Errorf("copied %d bytes, expect %d", n, size)
+		}
+		return oid, nil
+	}
+
+	if len(sourceObject.Parts) == 0 {
+		oid, err := copyBlob(sourceObject.ObjectId, sourceObject.Size)
+		if err != nil {
+			return copied, err
+		}
+		targetObject.ObjectId = oid
+		targetObject.InitializationVector = sourceObject.InitializationVector
+		return copied, nil
+	}
+	parts := make(map[int]*meta.Part, len(sourceObject.Parts))
+	for n, part := range sourceObject.Parts {
+		newPart := *part
+		newPart.ObjectId, err = copyBlob(part.ObjectId, part.Size)
+		if err != nil {
+			return copied, err
+		}
+		newPart.LastModified = time.Now().UTC().Format(helper.CONFIG.TimeFormat)
+		parts[n] = &newPart
+	}
+	targetObject.ObjectId = ""
+	targetObject.Parts = parts
+	return copied, nil
+}
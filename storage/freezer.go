@@ -1,6 +1,12 @@
 package storage
 
 import (
+	"errors"
+	"time"
+
+	"github.com/journeymidnight/yig/api/datatype"
+	e "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
 	meta "github.com/journeymidnight/yig/meta/types"
 )
 
@@ -9,30 +15,125 @@ func (yig *YigStorage) GetFreezerStatus(bucketName string, objectName string, ve
 }
 
 func (yig *YigStorage) CreateFreezer(freezer *meta.Freezer) (err error) {
-	return yig.MetaStorage.CreateFreezer(freezer)
+	err = yig.MetaStorage.CreateFreezer(freezer)
+	if err != nil {
+		return err
+	}
+	// restore request is saved already, only the event is lost on errors below
+	bucket, err := yig.MetaStorage.GetBucket(freezer.BucketName, true)
+	if err != nil {
+		helper.Logger.Warn("Get bucket", freezer.BucketName, "for restore event failed:", err)
+		return nil
+	}
+	object, err := yig.MetaStorage.GetObject(freezer.BucketName, freezer.Name, true)
+	if err != nil {
+		helper.Logger.Warn("Get object", freezer.BucketName, freezer.Name,
+			"for restore event failed:", err)
+		return nil
+	}
+	yig.notify(bucket, datatype.EventObjectRestorePost, object, "")
+	return nil
 }
 
 func (yig *YigStorage) GetFreezer(bucketName string, objectName string, version string) (freezer *meta.Freezer, err error) {
 	return yig.MetaStorage.GetFreezer(bucketName, objectName, version)
 }
 
-func (yig *YigStorage) UpdateFreezerDate(freezer *meta.Freezer, date int, isIncrement bool) (err error) {
-	if date > 30 || date < 1 {
-		date = 1
+// Set days to keep restored copy. As S3 does, if the object is restored
+// already its copy expires days after now instead of after restored.
+func (yig *YigStorage) UpdateFreezerDate(freezer *meta.Freezer, days int) (err error) {
+	if days > helper.CONFIG.RestoreMaxDays {
+		days = helper.CONFIG.RestoreMaxDays
 	}
-	var lifeTime int
-	if isIncrement {
-		freezerInfo, err := yig.GetFreezer(freezer.BucketName, freezer.Name, freezer.VersionId)
-		if err != nil {
-			return err
-		}
-		lifeTime = freezerInfo.LifeTime + date
-		if lifeTime > 30 {
-			lifeTime = 30
-		}
+	freezer.LifeTime = days
+	if freezer.Status == meta.ObjectHasRestored {
+		freezer.ExpireTime = datatype.DaysAfter(time.Now(), days, helper.CONFIG.DebugMode)
 	} else {
-		lifeTime = date
+		freezer.ExpireTime = time.Time{}
 	}
-	freezer.LifeTime = lifeTime
 	return yig.MetaStorage.UpdateFreezerDate(freezer)
 }
+
+// RestoreFreezer copies data of glacier object to a readable pool, then marks
+// freezer as restored with copy expiring LifeTime days later. Freezer should be
+// claimed by ClaimFreezer. If the object is gone or not in glacier anymore, the
+// freezer is removed.
+func (yig *YigStorage) RestoreFreezer(freezer *meta.Freezer) (err error) {
+	object, err := yig.MetaStorage.GetObject(freezer.BucketName, freezer.Name, false)
+	if err == e.ErrNoSuchKey || err == nil &&
+		(object.DeleteMarker || object.StorageClass != meta.ObjectStorageClassGlacier) {
+
+		helper.Logger.Info("Object of freezer is gone, remove freezer:", freezer.BucketName, freezer.Name)
+		return yig.MetaStorage.DeleteFreezer(freezer)
+	}
+	if err != nil {
+		return err
+	}
+	source, ok := yig.DataStorage[object.Location]
+	if !ok {
+		return errors.New("Cannot find specified cluster: " + object.Location)
+	}
+	cluster, poolName := yig.pickClusterAndPool(object.BucketName, object.Name,
+		meta.ObjectStorageClassStandard, object.Size, false)
+	if cluster == nil {
+		return e.ErrInternalError
+	}
+
+	objectId, parts, written, err := copyObjectData(object, source, cluster, poolName)
+	defer func() {
+		if err != nil {
+			for _, o := range written {
+				yig.recycle(o)
+			}
+		}
+	}()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	restored := *freezer
+	restored.Location = cluster.ID()
+	restored.Pool = poolName
+	restored.OwnerId = object.OwnerId
+	restored.Size = object.Size
+	restored.Etag = object.Etag
+	restored.ObjectId = objectId
+	restored.Parts = parts
+	restored.LastModifiedTime = now
+	restored.ExpireTime = datatype.DaysAfter(now, freezer.LifeTime, helper.CONFIG.DebugMode)
+	ok, err = yig.MetaStorage.FinishFreezer(&restored)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		err = errors.New("freezer removed during restore")
+		return err
+	}
+	// object is restored already, only the event is lost if bucket is not found
+	bucket, err := yig.MetaStorage.GetBucket(object.BucketName, true)
+	if err != nil {
+		helper.Logger.Warn("Get bucket", object.BucketName, "for restore event failed:", err)
+		return nil
+	}
+	yig.notifyRestored(bucket, object, restored.ExpireTime)
+	return nil
+}
+
+// Remove restored copy of glacier object, data is removed by gc
+func (yig *YigStorage) ExpireFreezer(bucketName, objectName string) error {
+	freezer, err := yig.MetaStorage.GetFreezer(bucketName, objectName, "")
+	if err == e.ErrNoSuchKey {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if freezer.Status != meta.ObjectHasRestored {
+		return nil
+	}
+	err = yig.MetaStorage.DeleteFreezer(freezer)
+	if err != nil {
+		return err
+	}
+	yig.DataCache.Remove(bucketName + ":" + objectName + ":")
+	return nil
+}
@@ -83,8 +83,9 @@ func (yig *YigStorage) ListMultipartUploads(credential common.Credential, bucket
 }
 
 func (yig *YigStorage) NewMultipartUpload(credential common.Credential, bucketName, objectName string,
-	metadata map[string]string, acl datatype.Acl,
-	sseRequest datatype.SseRequest, storageClass meta.StorageClass) (uploadId string, err error) {
+	metadata map[string]string, acl datatype.Acl, sseRequest datatype.SseRequest,
+	storageClass meta.StorageClass, tags map[string]string,
+	objectLock datatype.ObjectLock) (uploadId string, err error) {
 
 	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
 	if err != nil {
@@ -98,6 +99,9 @@ func (yig *YigStorage) NewMultipartUpload(credential common.Credential, bucketNa
 			return "", e.ErrBucketAccessForbidden
 		}
 	}
+	if !bucket.ObjectLock.IsEnabled() && !objectLock.IsEmpty() {
+		return "", e.ErrObjectLockNotEnabled
+	}
 	// TODO policy and fancy ACL
 
 	contentType, ok := metadata["Content-Type"]
@@ -116,6 +120,8 @@ func (yig *YigStorage) NewMultipartUpload(credential common.Credential, bucketNa
 		SseRequest:   sseRequest,
 		Attrs:        metadata,
 		StorageClass: storageClass,
+		Tags:         tags,
+		ObjectLock:   objectLock,
 	}
 	if sseRequest.Type == crypto.S3.String() {
 		multipartMetadata.EncryptionKey, multipartMetadata.CipherKey, err = yig.encryptionKeyFromSseRequest(sseRequest, bucketName, objectName)
@@ -198,7 +204,7 @@ func (yig *YigStorage) PutObjectPart(bucketName, objectName string, credential c
 	if err != nil {
 		return
 	}
-	// Should metadata update failed, add `maybeObjectToRecycle` to `RecycleQueue`,
+	// Should metadata update failed, recycle `maybeObjectToRecycle`,
 	// so the object in Ceph could be removed asynchronously
 	maybeObjectToRecycle := objectToRecycle{
 		location: cluster.ID(),
@@ -206,14 +212,14 @@ func (yig *YigStorage) PutObjectPart(bucketName, objectName string, credential c
 		objectId: objectId,
 	}
 	if int64(bytesWritten) < size {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycle(maybeObjectToRecycle)
 		err = e.ErrIncompleteBody
 		return
 	}
 
 	calculatedMd5 := hex.EncodeToString(md5Writer.Sum(nil))
 	if md5Hex != "" && md5Hex != calculatedMd5 {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycle(maybeObjectToRecycle)
 		err = e.ErrBadDigest
 		return
 	}
@@ -221,14 +227,14 @@ func (yig *YigStorage) PutObjectPart(bucketName, objectName string, credential c
 	if signVerifyReader, ok := data.(*signature.SignVerifyReadCloser); ok {
 		credential, err = signVerifyReader.Verify()
 		if err != nil {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycle(maybeObjectToRecycle)
 			return
 		}
 	}
 
 	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycle(maybeObjectToRecycle)
 		return
 	}
 	switch bucket.ACL.CannedAcl {
@@ -236,7 +242,7 @@ func (yig *YigStorage) PutObjectPart(bucketName, objectName string, credential c
 		break
 	default:
 		if bucket.OwnerId != credential.UserId {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycle(maybeObjectToRecycle)
 			return result, e.ErrBucketAccessForbidden
 		}
 	} // TODO policy and fancy ACL
@@ -251,16 +257,16 @@ func (yig *YigStorage) PutObjectPart(bucketName, objectName string, credential c
 	}
 	err = yig.MetaStorage.PutObjectPart(multipart, part)
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycle(maybeObjectToRecycle)
 		return
 	}
 	// remove possible old object in Ceph
 	if part, ok := multipart.Parts[partId]; ok {
-		RecycleQueue <- objectToRecycle{
+		yig.recycle(objectToRecycle{
 			location: multipart.Metadata.Location,
 			pool:     multipart.Metadata.Pool,
 			objectId: part.ObjectId,
-		}
+		})
 	}
 
 	result.ETag = calculatedMd5
@@ -327,7 +333,7 @@ func (yig *YigStorage) CopyObjectPart(bucketName, objectName, uploadId string, p
 	if err != nil {
 		return
 	}
-	// Should metadata update failed, add `maybeObjectToRecycle` to `RecycleQueue`,
+	// Should metadata update failed, recycle `maybeObjectToRecycle`,
 	// so the object in Ceph could be removed asynchronously
 	maybeObjectToRecycle := objectToRecycle{
 		location: cephCluster.ID(),
@@ -336,7 +342,7 @@ func (yig *YigStorage) CopyObjectPart(bucketName, objectName, uploadId string, p
 	}
 
 	if int64(bytesWritten) < size {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycle(maybeObjectToRecycle)
 		err = e.ErrIncompleteBody
 		return
 	}
@@ -345,7 +351,7 @@ func (yig *YigStorage) CopyObjectPart(bucketName, objectName, uploadId string, p
 
 	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycle(maybeObjectToRecycle)
 		return
 	}
 	switch bucket.ACL.CannedAcl {
@@ -353,7 +359,7 @@ func (yig *YigStorage) CopyObjectPart(bucketName, objectName, uploadId string, p
 		break
 	default:
 		if bucket.OwnerId != credential.UserId {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycle(maybeObjectToRecycle)
 			err = e.ErrBucketAccessForbidden
 			return
 		}
@@ -375,17 +381,17 @@ func (yig *YigStorage) CopyObjectPart(bucketName, objectName, uploadId string, p
 
 	err = yig.MetaStorage.PutObjectPart(multipart, part)
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycle(maybeObjectToRecycle)
 		return
 	}
 
 	// remove possible old object in Ceph
 	if part, ok := multipart.Parts[partId]; ok {
-		RecycleQueue <- objectToRecycle{
+		yig.recycle(objectToRecycle{
 			location: multipart.Metadata.Location,
 			pool:     multipart.Metadata.Pool,
 			objectId: part.ObjectId,
-		}
+		})
 	}
 
 	return result, nil
@@ -496,22 +502,8 @@ func (yig *YigStorage) AbortMultipartUpload(credential common.Credential,
 		return err
 	}
 
-	err = yig.MetaStorage.DeleteMultipart(multipart)
-	if err != nil {
-		return err
-	}
-	// remove parts in Ceph
-	var removedSize int64 = 0
-	for _, p := range multipart.Parts {
-		RecycleQueue <- objectToRecycle{
-			location: multipart.Metadata.Location,
-			pool:     multipart.Metadata.Pool,
-			objectId: p.ObjectId,
-		}
-		removedSize += p.Size
-	}
-
-	return nil
+	// parts in Ceph are removed by gc
+	return yig.MetaStorage.DeleteMultipart(multipart)
 }
 
 func (yig *YigStorage) CompleteMultipartUpload(credential common.Credential, bucketName,
@@ -608,6 +600,12 @@ func (yig *YigStorage) CompleteMultipartUpload(credential common.Credential, buc
 		CustomAttributes: multipart.Metadata.Attrs,
 		Type:             meta.ObjectTypeMultipart,
 		StorageClass:     multipart.Metadata.StorageClass,
+		Tags:             multipart.Metadata.Tags,
+		ObjectLock:       multipart.Metadata.ObjectLock,
+	}
+	err = setObjectLock(bucket, &object.ObjectLock, object.LastModifiedTime)
+	if err != nil {
+		return
 	}
 
 	var nullVerNum uint64
@@ -640,6 +638,7 @@ func (yig *YigStorage) CompleteMultipartUpload(credential common.Credential, buc
 		}
 	}
 
+	markReplication(bucket, object)
 	if nullVerNum != 0 {
 		err = yig.MetaStorage.PutObject(object, &multipart, objMap, false)
 	} else {
@@ -655,6 +654,8 @@ func (yig *YigStorage) CompleteMultipartUpload(credential common.Credential, buc
 	if err == nil {
 		yig.MetaStorage.Cache.Remove(redis.ObjectTable, bucketName+":"+objectName+":")
 		yig.DataCache.Remove(bucketName + ":" + objectName + ":" + object.GetVersionId())
+		yig.trackAccess(object)
+		yig.notify(bucket, datatype.EventObjectCreatedCompleteMultipartUpload, object, credential.UserId)
 	}
 
 	return
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/journeymidnight/yig/api/datatype"
+	"github.com/journeymidnight/yig/helper"
+	meta "github.com/journeymidnight/yig/meta/types"
+	bus "github.com/journeymidnight/yig/mq"
+	"github.com/journeymidnight/yig/redis"
+)
+
+// Events of object operations matching notification configurations of the
+// bucket are sent in S3 format through the message queue plugin, one message
+// for each matching configuration. Events are dropped if no message sender
+// is initialized, e.g in tools not sending messages.
+
+func (yig *YigStorage) SetBucketNotification(bucket *meta.Bucket,
+	config datatype.NotificationConfiguration) (err error) {
+
+	bucket.Notification = config
+	err = yig.MetaStorage.Client.PutBucket(*bucket)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.BucketTable, bucket.Name)
+	return nil
+}
+
+func (yig *YigStorage) GetBucketNotification(bucketName string) (config datatype.NotificationConfiguration,
+	err error) {
+
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		return
+	}
+	return bucket.Notification, nil
+}
+
+// Send event of object if bucket is interested, eventName is one of the
+// specific event types e.g datatype.EventObjectCreatedPut
+func (yig *YigStorage) notify(bucket *meta.Bucket, eventName string, object *meta.Object,
+	principalId string) {
+
+	sendEvent(bucket, eventName, object.Name, func() datatype.EventRecord {
+		return newEventRecord(bucket, eventName, object, principalId)
+	})
+}
+
+// Send event of restored copy of glacier object, which expires at expireTime
+func (yig *YigStorage) notifyRestored(bucket *meta.Bucket, object *meta.Object,
+	expireTime time.Time) {
+
+	eventName := datatype.EventObjectRestoreCompleted
+	sendEvent(bucket, eventName, object.Name, func() datatype.EventRecord {
+		record := newEventRecord(bucket, eventName, object, "")
+		record.GlacierEventData = &datatype.GlacierEventData{
+			RestoreEventData: datatype.RestoreEventData{
+				LifecycleRestorationExpiryTime: expireTime.Format(helper.CONFIG.TimeFormat),
+				LifecycleRestoreStorageClass:   object.StorageClass.ToString(),
+			},
+		}
+		return record
+	})
+}
+
+// Record is only built if any configuration matches
+func sendEvent(bucket *meta.Bucket, eventName, objectName string,
+	build func() datatype.EventRecord) {
+
+	if bus.MsgSender == nil || bucket.Notification.IsEmpty() {
+		return
+	}
+	ids := bucket.Notification.Match(eventName, objectName)
+	if len(ids) == 0 {
+		return
+	}
+	record := build()
+	for _, id := range ids {
+		record.S3.ConfigurationId = id
+		message, err := json.Marshal(datatype.EventMessage{
+			Records: []datatype.EventRecord{record},
+		})
+		if err != nil {
+			helper.Logger.Error("Failed to marshal event", eventName, "of",
+				bucket.Name, objectName, "err:", err)
+			return
+		}
+		err = bus.MsgSender.AsyncSend(message)
+		if err != nil {
+			helper.Logger.Error("Failed to send event", eventName, "of",
+				bucket.Name, objectName, "err:", err)
+		}
+	}
+}
+
+func newEventRecord(bucket *meta.Bucket, eventName string, object *meta.Object,
+	principalId string) datatype.EventRecord {
+
+	now := time.Now().UTC()
+	record := datatype.EventRecord{
+		EventVersion: "2.1",
+		EventSource:  "aws:s3",
+		AwsRegion:    helper.CONFIG.Region,
+		EventTime:    now.Format(helper.CONFIG.TimeFormat),
+		EventName:    strings.TrimPrefix(eventName, "s3:"),
+		UserIdentity: datatype.EventIdentity{PrincipalId: principalId},
+		S3: datatype.EventS3{
+			SchemaVersion: "1.0",
+			Bucket: datatype.EventBucket{
+				Name:          bucket.Name,
+				OwnerIdentity: datatype.EventIdentity{PrincipalId: bucket.OwnerId},
+				Arn:           "arn:aws:s3:::" + bucket.Name,
+			},
+			Object: datatype.EventObject{
+				Key: url.QueryEscape(object.Name),
+				// events of the same key are ordered by sequencer
+				Sequencer: fmt.Sprintf("%016X", now.UnixNano()),
+			},
+		},
+	}
+	if !object.DeleteMarker {
+		record.S3.Object.Size = object.Size
+		record.S3.Object.ETag = object.Etag
+	}
+	if bucket.Versioning != meta.VersionDisabled {
+		record.S3.Object.VersionId = object.GetVersionId()
+	}
+	return record
+}
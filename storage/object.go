@@ -14,6 +14,7 @@ import (
 	"github.com/journeymidnight/yig/api/datatype"
 	"github.com/journeymidnight/yig/backend"
 	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/erasure"
 	e "github.com/journeymidnight/yig/error"
 	"github.com/journeymidnight/yig/helper"
 	"github.com/journeymidnight/yig/iam"
@@ -23,62 +24,105 @@ import (
 	"github.com/journeymidnight/yig/signature"
 )
 
-var latestQueryTime [3]time.Time // 0 is for SMALL_FILE_POOLNAME, 1 is for BIG_FILE_POOLNAME, 2 is for GLACIER_FILE_POOLNAME
+var (
+	latestQueryTime     = make(map[string]time.Time) // pool -> last time used space of its clusters checked
+	latestQueryTimeLock sync.Mutex
+)
+
 const (
 	CLUSTER_MAX_USED_SPACE_PERCENT = 85
 	BIG_FILE_THRESHOLD             = 128 << 10 /* 128K */
 )
 
-func (yig *YigStorage) pickRandomCluster() (cluster backend.Cluster) {
+func (yig *YigStorage) pickRandomCluster(isAppend bool) (cluster backend.Cluster) {
 	helper.Logger.Warn("Error picking cluster from table cluster in DB, " +
 		"use first cluster in config to write.")
 	for _, c := range yig.DataStorage {
+		if _, ok := c.(*erasure.ErasureCluster); ok && isAppend {
+			// erasure coded objects could not be appended
+			continue
+		}
 		cluster = c
 		break
 	}
 	return
 }
 
+// Pools objects of storage class are written to, defaults could be
+// overridden by `storage_classes` in yig.toml
+func poolsOfStorageClass(storageClass meta.StorageClass) (smallFilePool, bigFilePool string) {
+	if storageClass == meta.ObjectStorageClassGlacier {
+		smallFilePool = backend.GLACIER_FILE_POOLNAME
+		bigFilePool = backend.GLACIER_FILE_POOLNAME
+	} else {
+		smallFilePool = backend.SMALL_FILE_POOLNAME
+		bigFilePool = backend.BIG_FILE_POOLNAME
+	}
+	config := helper.CONFIG.StorageClasses[storageClass.ToString()]
+	if config.SmallFilePool != "" {
+		smallFilePool = config.SmallFilePool
+	}
+	if config.BigFilePool != "" {
+		bigFilePool = config.BigFilePool
+	}
+	return
+}
+
+// Clusters of table `cluster` accepting new data of storage class in pool.
+// Rows dedicated to the storage class are used if any, otherwise rows with
+// empty storage class, which are shared by all classes.
+func clustersForStorageClass(metaClusters []meta.Cluster, storageClass meta.StorageClass,
+	poolName string) (clusters []meta.Cluster) {
+
+	className := storageClass.ToString()
+	var dedicated, shared []meta.Cluster
+	for _, cluster := range metaClusters {
+		if cluster.Weight == 0 || cluster.Pool != poolName {
+			continue
+		}
+		switch cluster.StorageClass {
+		case className:
+			dedicated = append(dedicated, cluster)
+		case "":
+			shared = append(shared, cluster)
+		}
+	}
+	if len(dedicated) > 0 {
+		return dedicated
+	}
+	return shared
+}
+
 func (yig *YigStorage) pickClusterAndPool(bucket string, object string, storageClass meta.StorageClass,
 	size int64, isAppend bool) (cluster backend.Cluster, poolName string) {
 
-	var idx int
-	if storageClass == meta.ObjectStorageClassGlacier {
-		poolName = backend.GLACIER_FILE_POOLNAME
-		idx = 2
+	smallFilePool, bigFilePool := poolsOfStorageClass(storageClass)
+	if isAppend {
+		poolName = bigFilePool
+	} else if size < 0 { // request.ContentLength is -1 if length is unknown
+		poolName = bigFilePool
+	} else if size < BIG_FILE_THRESHOLD {
+		poolName = smallFilePool
 	} else {
-		if isAppend {
-			poolName = backend.BIG_FILE_POOLNAME
-			idx = 1
-		} else if size < 0 { // request.ContentLength is -1 if length is unknown
-			poolName = backend.BIG_FILE_POOLNAME
-			idx = 1
-		} else if size < BIG_FILE_THRESHOLD {
-			poolName = backend.SMALL_FILE_POOLNAME
-			idx = 0
-		} else {
-			poolName = backend.BIG_FILE_POOLNAME
-			idx = 1
-		}
+		poolName = bigFilePool
 	}
 	var needCheck bool
-	queryTime := latestQueryTime[idx]
-	if time.Since(queryTime).Hours() > 24 { // check used space every 24 hours
-		latestQueryTime[idx] = time.Now()
+	latestQueryTimeLock.Lock()
+	if time.Since(latestQueryTime[poolName]).Hours() > 24 { // check used space every 24 hours
+		latestQueryTime[poolName] = time.Now()
 		needCheck = true
 	}
+	latestQueryTimeLock.Unlock()
 	var totalWeight int
 	clusterWeights := make(map[string]int, len(yig.DataStorage))
 	metaClusters, err := yig.MetaStorage.GetClusters()
 	if err != nil {
-		cluster = yig.pickRandomCluster()
+		cluster = yig.pickRandomCluster(isAppend)
 		return
 	}
-	for _, cluster := range metaClusters {
-		if cluster.Weight == 0 {
-			continue
-		}
-		if cluster.Pool != poolName {
+	for _, cluster := range clustersForStorageClass(metaClusters, storageClass, poolName) {
+		if _, ok := yig.DataStorage[cluster.Fsid].(*erasure.ErasureCluster); ok && isAppend {
+			// erasure coded objects could not be appended
 			continue
 		}
 		if needCheck {
@@ -98,7 +142,7 @@ func (yig *YigStorage) pickClusterAndPool(bucket string, object string, storageC
 		clusterWeights[cluster.Fsid] = cluster.Weight
 	}
 	if len(clusterWeights) == 0 || totalWeight == 0 {
-		cluster = yig.pickRandomCluster()
+		cluster = yig.pickRandomCluster(isAppend)
 		return
 	}
 	N := rand.Intn(totalWeight)
@@ -183,8 +227,18 @@ func getAlignedReader(cluster backend.Cluster, poolName, objectName string,
 	return cluster.GetReader(poolName, objectName, alignedOffset, length)
 }
 
+// Read object for clients, counted as an access of the object
 func (yig *YigStorage) GetObject(object *meta.Object, startOffset int64,
 	length int64, writer io.Writer, sseRequest datatype.SseRequest) (err error) {
+	yig.trackAccess(object)
+	return yig.readObject(object, startOffset, length, writer, sseRequest)
+}
+
+// Read object without tracking access, for daemons like yig_replicate, so
+// their reads do not keep INTELLIGENT_TIERING objects in frequent access tier
+func (yig *YigStorage) readObject(object *meta.Object, startOffset int64,
+	length int64, writer io.Writer, sseRequest datatype.SseRequest) (err error) {
+
 	var encryptionKey []byte
 	if object.SseType == crypto.S3.String() {
 		if yig.KMS == nil {
@@ -506,7 +560,8 @@ func (yig *YigStorage) SetObjectAcl(bucketName string, objectName string, versio
 // Encryptor is enabled when user set SSE headers
 func (yig *YigStorage) PutObject(bucketName string, objectName string, credential common.Credential,
 	size int64, data io.ReadCloser, metadata map[string]string, acl datatype.Acl,
-	sseRequest datatype.SseRequest, storageClass meta.StorageClass) (result datatype.PutObjectResult, err error) {
+	sseRequest datatype.SseRequest, storageClass meta.StorageClass,
+	tags map[string]string, objectLock datatype.ObjectLock) (result datatype.PutObjectResult, err error) {
 
 	defer data.Close()
 	encryptionKey, cipherKey, err := yig.encryptionKeyFromSseRequest(sseRequest, bucketName, objectName)
@@ -529,6 +584,10 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 			return result, e.ErrBucketAccessForbidden
 		}
 	}
+	err = setObjectLock(bucket, &objectLock, time.Now().UTC())
+	if err != nil {
+		return
+	}
 
 	md5Writer := md5.New()
 
@@ -563,7 +622,7 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 	if err != nil {
 		return
 	}
-	// Should metadata update failed, add `maybeObjectToRecycle` to `RecycleQueue`,
+	// Should metadata update failed, recycle `maybeObjectToRecycle`,
 	// so the object in Ceph could be removed asynchronously
 	maybeObjectToRecycle := objectToRecycle{
 		location: cluster.ID(),
@@ -571,7 +630,7 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 		objectId: objectId,
 	}
 	if int64(bytesWritten) < size {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycle(maybeObjectToRecycle)
 		helper.Logger.Error("Failed to write objects, already written",
 			bytesWritten, "total size", size)
 		return result, e.ErrIncompleteBody
@@ -581,7 +640,7 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 	helper.Logger.Info("CalculatedMd5:", calculatedMd5, "userMd5:", metadata["md5Sum"])
 	if userMd5, ok := metadata["md5Sum"]; ok {
 		if userMd5 != "" && userMd5 != calculatedMd5 {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycle(maybeObjectToRecycle)
 			return result, e.ErrBadDigest
 		}
 	}
@@ -591,7 +650,7 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 	if signVerifyReader, ok := data.(*signature.SignVerifyReadCloser); ok {
 		credential, err = signVerifyReader.Verify()
 		if err != nil {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycle(maybeObjectToRecycle)
 			return
 		}
 	}
@@ -617,13 +676,15 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 		CustomAttributes:     metadata,
 		Type:                 meta.ObjectTypeNormal,
 		StorageClass:         storageClass,
+		Tags:                 tags,
+		ObjectLock:           objectLock,
 	}
 
 	result.LastModified = object.LastModifiedTime
 	var nullVerNum uint64
 	nullVerNum, err = yig.checkOldObject(bucketName, objectName, bucket.Versioning)
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycle(maybeObjectToRecycle)
 		return
 	}
 	if bucket.Versioning == meta.VersionEnabled {
@@ -646,6 +707,7 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 		}
 	}
 
+	markReplication(bucket, object)
 	if nullVerNum != 0 {
 		objMap := &meta.ObjMap{
 			Name:       objectName,
@@ -657,7 +719,7 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 	}
 
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		yig.recycle(maybeObjectToRecycle)
 		return
 	}
 
@@ -665,6 +727,8 @@ func (yig *YigStorage) PutObject(bucketName string, objectName string, credentia
 		yig.MetaStorage.Cache.Remove(redis.ObjectTable, bucketName+":"+objectName+":")
 		yig.DataCache.Remove(bucketName + ":" + objectName + ":" + object.GetVersionId())
 	}
+	yig.trackAccess(object)
+	yig.notify(bucket, datatype.EventObjectCreatedPut, object, credential.UserId)
 	return result, nil
 }
 
@@ -731,6 +795,8 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 
 	var oid string
 	var maybeObjectToRecycle objectToRecycle
+	// all objects written to backend, should be recycled if metadata update failed
+	var objectsToRecycle []objectToRecycle
 	var encryptionKey []byte
 	encryptionKey, cipherKey, err := yig.encryptionKeyFromSseRequest(sseRequest, targetObject.BucketName, targetObject.Name)
 	if err != nil {
@@ -751,7 +817,15 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 		}
 	}
 
+	// versions are never rewritten in place in buckets with object lock,
+	// copying an object to itself adds a new version with the requested lock
+	if isMetadataOnly && bucket.ObjectLock.IsEnabled() {
+		isMetadataOnly = false
+	}
 	if isMetadataOnly {
+		if err = checkObjectLock(sourceObject, false); err != nil {
+			return
+		}
 		if sourceObject.StorageClass == meta.ObjectStorageClassGlacier {
 			targetObject.LastModifiedTime = sourceObject.LastModifiedTime
 			err = yig.MetaStorage.UpdateGlacierObject(targetObject, sourceObject, true)
@@ -765,13 +839,19 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 			}
 			yig.MetaStorage.Cache.Remove(redis.ObjectTable, targetObject.BucketName+":"+targetObject.Name+":")
 			yig.DataCache.Remove(targetObject.BucketName + ":" + targetObject.Name + ":" + targetObject.GetVersionId())
+			yig.notify(bucket, datatype.EventObjectCreatedCopy, targetObject, credential.UserId)
 			return result, nil
 		}
+		// only the copied version is replaced
+		targetObject.LastModifiedTime = sourceObject.LastModifiedTime
+		versionId := targetObject.GetVersionId()
 		err = yig.MetaStorage.ReplaceObjectMetas(targetObject)
 		if err != nil {
 			helper.Logger.Error("Copy Object with same source and target, sql fails:", err)
 			return result, e.ErrInternalError
 		}
+		yig.MetaStorage.Cache.Remove(redis.ObjectTable,
+			targetObject.BucketName+":"+targetObject.Name+":"+versionId)
 		targetObject.LastModifiedTime = time.Now().UTC()
 		result.LastModified = targetObject.LastModifiedTime
 		if bucket.Versioning == "Enabled" {
@@ -779,8 +859,13 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 		}
 		yig.MetaStorage.Cache.Remove(redis.ObjectTable, targetObject.BucketName+":"+targetObject.Name+":")
 		yig.DataCache.Remove(targetObject.BucketName + ":" + targetObject.Name + ":" + targetObject.GetVersionId())
+		yig.notify(bucket, datatype.EventObjectCreatedCopy, targetObject, credential.UserId)
 		return result, nil
 	}
+	err = setObjectLock(bucket, &targetObject.ObjectLock, time.Now().UTC())
+	if err != nil {
+		return
+	}
 
 	// Limit the reader to its provided size if specified.
 	var limitedDataReader io.Reader
@@ -789,7 +874,37 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 	cephCluster, poolName := yig.pickClusterAndPool(targetObject.BucketName,
 		targetObject.Name, targetObject.StorageClass, targetObject.Size, false)
 
-	if len(targetObject.Parts) != 0 {
+	copied, shared := false, false
+	if yig.sameEncryptionKey(targetObject, sourceObject, sseRequest) {
+		sealedKey, resealErr := yig.resealKey(targetObject, sourceObject)
+		if resealErr != nil {
+			helper.Logger.Warn("Reseal key of", sourceObject.BucketName, sourceObject.Name,
+				"failed, fallback to streaming:", resealErr)
+		} else if yig.canShareData(sourceObject, poolName) {
+			// reference the same data, see meta.PutSharedObject
+			shared = true
+			cephCluster = yig.DataStorage[sourceObject.Location]
+			targetObject.ObjectId = sourceObject.ObjectId
+			targetObject.Parts = sourceObject.Parts
+			targetObject.InitializationVector = sourceObject.InitializationVector
+			result.Md5 = targetObject.Etag
+			cipherKey = sealedKey
+		} else if cluster, ok := yig.pickCopier(sourceObject.Location, targetObject.StorageClass, poolName); ok {
+			objectsToRecycle, err = yig.copyInCluster(cluster, targetObject, sourceObject, poolName)
+			if err == nil {
+				copied = true
+				cephCluster = cluster
+				result.Md5 = targetObject.Etag
+				cipherKey = sealedKey
+			} else {
+				helper.Logger.Warn("Copy in cluster", cluster.ID(), "failed, fallback to streaming:", err)
+			}
+		}
+	}
+
+	if copied || shared {
+		// data is already copied by backend cluster, or shared with source
+	} else if len(targetObject.Parts) != 0 {
 		var targetParts map[int]*meta.Part = make(map[int]*meta.Part, len(targetObject.Parts))
 		//		etaglist := make([]string, len(sourceObject.Parts))
 		for i := 1; i <= len(targetObject.Parts); i++ {
@@ -825,7 +940,7 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 					objectId: oid,
 				}
 				if bytesW < uint64(part.Size) {
-					RecycleQueue <- maybeObjectToRecycle
+					yig.recycle(maybeObjectToRecycle)
 					return result, e.ErrIncompleteBody
 				}
 				if err != nil {
@@ -835,16 +950,20 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 				//we will only chack part etag,overall etag will be same if each part of etag is same
 				if calculatedMd5 != part.Etag {
 					err = e.ErrInternalError
-					RecycleQueue <- maybeObjectToRecycle
+					yig.recycle(maybeObjectToRecycle)
 					return result, err
 				}
 				part.LastModified = time.Now().UTC().Format(helper.CONFIG.TimeFormat)
 				part.ObjectId = oid
 
 				part.InitializationVector = initializationVector
+				objectsToRecycle = append(objectsToRecycle, maybeObjectToRecycle)
 				return result, nil
 			}()
 			if err != nil {
+				for _, o := range objectsToRecycle {
+					yig.recycle(o)
+				}
 				return result, err
 			}
 		}
@@ -873,7 +992,7 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 		if err != nil {
 			return
 		}
-		// Should metadata update failed, add `maybeObjectToRecycle` to `RecycleQueue`,
+		// Should metadata update failed, recycle `maybeObjectToRecycle`,
 		// so the object in Ceph could be removed asynchronously
 		maybeObjectToRecycle = objectToRecycle{
 			location: cephCluster.ID(),
@@ -881,18 +1000,19 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 			objectId: oid,
 		}
 		if int64(bytesWritten) < targetObject.Size {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycle(maybeObjectToRecycle)
 			return result, e.ErrIncompleteBody
 		}
 
 		calculatedMd5 := hex.EncodeToString(md5Writer.Sum(nil))
 		if calculatedMd5 != targetObject.Etag {
-			RecycleQueue <- maybeObjectToRecycle
+			yig.recycle(maybeObjectToRecycle)
 			return result, e.ErrBadDigest
 		}
 		result.Md5 = calculatedMd5
 		targetObject.ObjectId = oid
 		targetObject.InitializationVector = initializationVector
+		objectsToRecycle = append(objectsToRecycle, maybeObjectToRecycle)
 	}
 	// TODO validate bucket policy and fancy ACL
 
@@ -913,7 +1033,9 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 	var nullVerNum uint64
 	nullVerNum, err = yig.checkOldObject(targetObject.BucketName, targetObject.Name, bucket.Versioning)
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		for _, o := range objectsToRecycle {
+			yig.recycle(o)
+		}
 		return
 	}
 	if bucket.Versioning == "Enabled" {
@@ -929,26 +1051,42 @@ func (yig *YigStorage) CopyObject(targetObject *meta.Object, sourceObject *meta.
 		BucketName: targetObject.BucketName,
 	}
 
+	markReplication(bucket, targetObject)
 	if targetObject.StorageClass == meta.ObjectStorageClassGlacier && targetObject.Name == sourceObject.Name && targetObject.BucketName == sourceObject.BucketName {
+		// the same version is rewritten, keep its lock
 		targetObject.LastModifiedTime = sourceObject.LastModifiedTime
+		targetObject.ObjectLock = sourceObject.ObjectLock
 		result.LastModified = targetObject.LastModifiedTime
 		err = yig.MetaStorage.UpdateGlacierObject(targetObject, sourceObject, false)
 	} else {
 		if nullVerNum != 0 {
 			objMap.NullVerNum = nullVerNum
-			err = yig.MetaStorage.PutObject(targetObject, nil, objMap, true)
 		} else {
-			err = yig.MetaStorage.PutObject(targetObject, nil, nil, true)
+			objMap = nil
+		}
+		if shared {
+			err = yig.MetaStorage.PutSharedObject(targetObject, sourceObject, objMap, true)
+			if err == meta.ErrObjectChanged {
+				helper.Logger.Warn("Source object changed during copy:", sourceObject.BucketName,
+					sourceObject.Name, sourceObject.GetVersionId())
+				err = e.ErrInternalError
+			}
+		} else {
+			err = yig.MetaStorage.PutObject(targetObject, nil, objMap, true)
 		}
 	}
 
 	if err != nil {
-		RecycleQueue <- maybeObjectToRecycle
+		for _, o := range objectsToRecycle {
+			yig.recycle(o)
+		}
 		return
 	}
 
 	yig.MetaStorage.Cache.Remove(redis.ObjectTable, targetObject.BucketName+":"+targetObject.Name+":")
 	yig.DataCache.Remove(targetObject.BucketName + ":" + targetObject.Name + ":" + targetObject.GetVersionId())
+	yig.trackAccess(targetObject)
+	yig.notify(bucket, datatype.EventObjectCreatedCopy, targetObject, credential.UserId)
 
 	return result, nil
 }
@@ -974,7 +1112,8 @@ func (yig *YigStorage) getObjWithVersion(bucketName, objectName, version string)
 
 }
 
-func (yig *YigStorage) removeAllObjectsEntryByName(bucketName, objectName string) (err error) {
+func (yig *YigStorage) removeAllObjectsEntryByName(bucketName, objectName string,
+	bypassGovernance bool) (err error) {
 
 	objs, err := yig.MetaStorage.GetAllObject(bucketName, objectName)
 	if err == e.ErrNoSuchKey {
@@ -983,6 +1122,13 @@ func (yig *YigStorage) removeAllObjectsEntryByName(bucketName, objectName string
 	if err != nil {
 		return err
 	}
+	// remove nothing if any version is locked
+	for _, obj := range objs {
+		err = checkObjectLock(obj, bypassGovernance)
+		if err != nil {
+			return err
+		}
+	}
 	for _, obj := range objs {
 		if obj.StorageClass == meta.ObjectStorageClassGlacier {
 			freezer, err := yig.GetFreezer(bucketName, objectName, "")
@@ -1008,7 +1154,7 @@ func (yig *YigStorage) removeAllObjectsEntryByName(bucketName, objectName string
 func (yig *YigStorage) checkOldObject(bucketName, objectName, versioning string) (version uint64, err error) {
 
 	if versioning == meta.VersionDisabled {
-		err = yig.removeAllObjectsEntryByName(bucketName, objectName)
+		err = yig.removeAllObjectsEntryByName(bucketName, objectName, false)
 		return
 	}
 
@@ -1056,6 +1202,10 @@ func (yig *YigStorage) checkOldObject(bucketName, objectName, versioning string)
 		} else {
 			helper.Logger.Info("object.NullVersion:", object.NullVersion)
 			if objectExist && object.NullVersion {
+				err = checkObjectLock(object, false)
+				if err != nil {
+					return
+				}
 				err = yig.MetaStorage.DeleteObject(object, object.DeleteMarker, nil)
 				if err != nil {
 					return
@@ -1068,7 +1218,9 @@ func (yig *YigStorage) checkOldObject(bucketName, objectName, versioning string)
 	return 0, errors.New("No Such versioning status!")
 }
 
-func (yig *YigStorage) removeObjectVersion(bucketName, objectName, version string) error {
+func (yig *YigStorage) removeObjectVersion(bucketName, objectName, version string,
+	bypassGovernance bool) error {
+
 	object, err := yig.getObjWithVersion(bucketName, objectName, version)
 	if err == e.ErrNoSuchKey {
 		return nil
@@ -1076,6 +1228,10 @@ func (yig *YigStorage) removeObjectVersion(bucketName, objectName, version strin
 	if err != nil {
 		return err
 	}
+	err = checkObjectLock(object, bypassGovernance)
+	if err != nil {
+		return err
+	}
 
 	if version == "null" {
 		objMap := &meta.ObjMap{
@@ -1090,8 +1246,42 @@ func (yig *YigStorage) removeObjectVersion(bucketName, objectName, version strin
 	return nil
 }
 
+// ExpireObjectVersion removes a version or delete marker of an object, used by
+// lifecycle to clean up noncurrent versions and expired delete markers.
+// Locked versions are kept until their retention expires. Like expiration of
+// current versions by DeleteObject, s3:ObjectRemoved:Delete is sent.
+func (yig *YigStorage) ExpireObjectVersion(object *meta.Object) (err error) {
+	err = checkObjectLock(object, false)
+	if err != nil {
+		return
+	}
+	var objMap *meta.ObjMap
+	if object.NullVersion {
+		objMap = &meta.ObjMap{
+			Name:       object.Name,
+			BucketName: object.BucketName,
+		}
+	}
+	err = yig.removeByObject(object, objMap)
+	if err != nil {
+		return
+	}
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, object.BucketName+":"+object.Name+":")
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable,
+		object.BucketName+":"+object.Name+":"+object.GetVersionId())
+	yig.DataCache.Remove(object.BucketName + ":" + object.Name + ":" + object.GetVersionId())
+	bucket, err := yig.MetaStorage.GetBucket(object.BucketName, true)
+	if err != nil {
+		helper.Logger.Warn("No event sent for expired version of", object.BucketName,
+			object.Name, object.GetVersionId(), "error:", err)
+		return nil
+	}
+	yig.notify(bucket, datatype.EventObjectRemovedDelete, object, "")
+	return nil
+}
+
 func (yig *YigStorage) addDeleteMarker(bucket meta.Bucket, objectName string,
-	nullVersion bool) (versionId string, err error) {
+	nullVersion bool, replica bool) (versionId string, err error) {
 
 	deleteMarker := &meta.Object{
 		Name:             objectName,
@@ -1101,6 +1291,11 @@ func (yig *YigStorage) addDeleteMarker(bucket meta.Bucket, objectName string,
 		NullVersion:      nullVersion,
 		DeleteMarker:     true,
 	}
+	if replica {
+		deleteMarker.ReplicationStatus = meta.ReplicationReplica
+	} else {
+		markReplication(&bucket, deleteMarker)
+	}
 
 	versionId = deleteMarker.GetVersionId()
 	objMap := &meta.ObjMap{
@@ -1127,8 +1322,12 @@ func (yig *YigStorage) addDeleteMarker(bucket meta.Bucket, objectName string,
 // |           |                              | null version delete marker                             |
 //
 // See http://docs.aws.amazon.com/AmazonS3/latest/dev/Versioning.html
+//
+// Versions locked by object lock are not removed, versions retained in
+// governance mode could be removed if bypassGovernance is set. Delete markers
+// added by replication of another bucket are saved as replicas if replica is set.
 func (yig *YigStorage) DeleteObject(bucketName string, objectName string, version string,
-	credential common.Credential) (result datatype.DeleteObjectResult, err error) {
+	bypassGovernance bool, replica bool, credential common.Credential) (result datatype.DeleteObjectResult, err error) {
 
 	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
 	if err != nil {
@@ -1148,19 +1347,19 @@ func (yig *YigStorage) DeleteObject(bucketName string, objectName string, versio
 		if version != "" && version != "null" {
 			return result, e.ErrNoSuchVersion
 		}
-		err = yig.removeAllObjectsEntryByName(bucketName, objectName)
+		err = yig.removeAllObjectsEntryByName(bucketName, objectName, bypassGovernance)
 		if err != nil {
 			return
 		}
 	case meta.VersionEnabled:
 		if version == "" {
-			result.VersionId, err = yig.addDeleteMarker(*bucket, objectName, false)
+			result.VersionId, err = yig.addDeleteMarker(*bucket, objectName, false, replica)
 			if err != nil {
 				return
 			}
 			result.DeleteMarker = true
 		} else {
-			err = yig.removeObjectVersion(bucketName, objectName, version)
+			err = yig.removeObjectVersion(bucketName, objectName, version, bypassGovernance)
 			if err != nil {
 				return
 			}
@@ -1168,17 +1367,17 @@ func (yig *YigStorage) DeleteObject(bucketName string, objectName string, versio
 		}
 	case meta.VersionSuspended:
 		if version == "" {
-			err = yig.removeObjectVersion(bucketName, objectName, "null")
+			err = yig.removeObjectVersion(bucketName, objectName, "null", bypassGovernance)
 			if err != nil {
 				return
 			}
-			result.VersionId, err = yig.addDeleteMarker(*bucket, objectName, true)
+			result.VersionId, err = yig.addDeleteMarker(*bucket, objectName, true, replica)
 			if err != nil {
 				return
 			}
 			result.DeleteMarker = true
 		} else {
-			err = yig.removeObjectVersion(bucketName, objectName, version)
+			err = yig.removeObjectVersion(bucketName, objectName, version, bypassGovernance)
 			if err != nil {
 				return
 			}
@@ -1199,5 +1398,14 @@ func (yig *YigStorage) DeleteObject(bucketName string, objectName string, versio
 			yig.DataCache.Remove(bucketName + ":" + objectName + ":" + version)
 		}
 	}
+	removed := &meta.Object{
+		Name:         objectName,
+		BucketName:   bucketName,
+		VersionId:    result.VersionId,
+		DeleteMarker: result.DeleteMarker,
+	}
+	yig.notify(bucket, helper.Ternary(result.DeleteMarker,
+		datatype.EventObjectRemovedDeleteMarkerCreated, datatype.EventObjectRemovedDelete).(string),
+		removed, credential.UserId)
 	return result, nil
 }
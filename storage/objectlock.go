@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/journeymidnight/yig/api/datatype"
+	e "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/iam/common"
+	meta "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/redis"
+)
+
+// Object lock is saved with each version, in JSON column `objectlock` of table
+// `objects`. Like AWS, object lock requires versioning enabled, so overwriting
+// an object or deleting it without a version only adds a new version or a
+// delete marker, and locked versions are kept. Versions are only removed by
+// deleting them with their version id, or by noncurrent version expiration
+// of lifecycle, both refused by checkObjectLock.
+
+func (yig *YigStorage) SetBucketObjectLock(bucket *meta.Bucket,
+	config datatype.ObjectLockConfiguration) error {
+
+	if bucket.Versioning != meta.VersionEnabled {
+		return e.ErrObjectLockRequiresVersioning
+	}
+	bucket.ObjectLock = config
+	err := yig.MetaStorage.Client.PutBucket(*bucket)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.BucketTable, bucket.Name)
+	return nil
+}
+
+func (yig *YigStorage) GetBucketObjectLock(bucketName string) (config datatype.ObjectLockConfiguration,
+	err error) {
+
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		return
+	}
+	if !bucket.ObjectLock.IsEnabled() {
+		return config, e.ErrObjectLockConfigurationNotFound
+	}
+	return bucket.ObjectLock, nil
+}
+
+// Check object lock requested for a new version, and apply default retention
+// of the bucket if no retention is requested
+func setObjectLock(bucket *meta.Bucket, lock *datatype.ObjectLock, now time.Time) error {
+	if !bucket.ObjectLock.IsEnabled() {
+		if !lock.IsEmpty() {
+			return e.ErrObjectLockNotEnabled
+		}
+		return nil
+	}
+	if lock.Mode == "" && bucket.ObjectLock.Rule != nil {
+		retention := bucket.ObjectLock.Rule.DefaultRetention
+		lock.Mode = retention.Mode
+		lock.RetainUntilDate = retention.RetainUntil(now).UTC()
+	}
+	return nil
+}
+
+// Returns ErrObjectLocked if the version could not be removed or overwritten
+func checkObjectLock(object *meta.Object, bypassGovernance bool) error {
+	lock := object.ObjectLock
+	if lock.LegalHold {
+		return e.ErrObjectLocked
+	}
+	if !lock.IsRetained(time.Now()) {
+		return nil
+	}
+	if lock.Mode == datatype.RetentionModeGovernance && bypassGovernance {
+		return nil
+	}
+	return e.ErrObjectLocked
+}
+
+func (yig *YigStorage) getLockedObject(bucketName, objectName, version string,
+	credential common.Credential) (bucket *meta.Bucket, object *meta.Object, err error) {
+
+	bucket, err = yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		return
+	}
+	if !bucket.ObjectLock.IsEnabled() {
+		return nil, nil, e.ErrObjectLockNotEnabled
+	}
+	if version == "" {
+		object, err = yig.MetaStorage.GetObject(bucketName, objectName, false)
+	} else {
+		object, err = yig.getObjWithVersion(bucketName, objectName, version)
+	}
+	if err != nil {
+		return
+	}
+	if !credential.AllowOtherUserAccess && bucket.OwnerId != credential.UserId &&
+		object.OwnerId != credential.UserId {
+		return nil, nil, e.ErrAccessDenied
+	}
+	// delete markers are not locked, same as tagging
+	err = checkTaggedObject(object, version)
+	return
+}
+
+func (yig *YigStorage) updateObjectLock(object *meta.Object, version string) error {
+	err := yig.MetaStorage.UpdateObjectLock(object)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, object.BucketName+":"+object.Name+":")
+	if version != "" {
+		yig.MetaStorage.Cache.Remove(redis.ObjectTable,
+			object.BucketName+":"+object.Name+":"+version)
+	}
+	return nil
+}
+
+func (yig *YigStorage) GetObjectRetention(bucketName, objectName, version string,
+	credential common.Credential) (retention datatype.ObjectLockRetention, err error) {
+
+	_, object, err := yig.getLockedObject(bucketName, objectName, version, credential)
+	if err != nil {
+		return
+	}
+	if object.ObjectLock.Mode == "" {
+		return retention, e.ErrNoSuchObjectLockConfiguration
+	}
+	return object.ObjectLock.Retention(), nil
+}
+
+// Retention in compliance mode could only be extended. Retention in governance
+// mode could be shortened or removed only if bypassGovernance is set.
+func (yig *YigStorage) PutObjectRetention(bucketName, objectName, version string,
+	mode string, retainUntil time.Time, bypassGovernance bool, credential common.Credential) error {
+
+	_, object, err := yig.getLockedObject(bucketName, objectName, version, credential)
+	if err != nil {
+		return err
+	}
+	lock := object.ObjectLock
+	if !lock.CanExtendTo(mode, retainUntil, time.Now()) {
+		if lock.Mode != datatype.RetentionModeGovernance || !bypassGovernance {
+			return e.ErrObjectLocked
+		}
+	}
+	object.ObjectLock.Mode = mode
+	object.ObjectLock.RetainUntilDate = retainUntil
+	return yig.updateObjectLock(object, version)
+}
+
+func (yig *YigStorage) GetObjectLegalHold(bucketName, objectName, version string,
+	credential common.Credential) (legalHold datatype.ObjectLegalHold, err error) {
+
+	_, object, err := yig.getLockedObject(bucketName, objectName, version, credential)
+	if err != nil {
+		return
+	}
+	legalHold.Status = object.ObjectLock.LegalHoldStatus()
+	return legalHold, nil
+}
+
+func (yig *YigStorage) PutObjectLegalHold(bucketName, objectName, version string,
+	legalHold bool, credential common.Credential) error {
+
+	_, object, err := yig.getLockedObject(bucketName, objectName, version, credential)
+	if err != nil {
+		return err
+	}
+	object.ObjectLock.LegalHold = legalHold
+	return yig.updateObjectLock(object, version)
+}
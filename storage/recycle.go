@@ -1,18 +1,31 @@
 package storage
 
 import (
-	"github.com/journeymidnight/yig/helper"
+	"os"
+	"strings"
 	"time"
+
+	"github.com/journeymidnight/yig/helper"
+	meta "github.com/journeymidnight/yig/meta/types"
 )
 
 // Remove
 // 1. deleted objects
 // 2. objects that already stored to Ceph but failed to update metadata
 // asynchronously
+//
+// Every object to recycle is saved to table `recycle` first, and the entry is
+// removed only after the object is deleted. Entries left by full queue, failed
+// removal or restarts are picked up by a periodic scan of the table, which also
+// runs at startup.
 
 const (
 	RECYCLE_QUEUE_SIZE = 100
 	MAX_TRY_TIMES      = 3
+	RECYCLE_SCAN_LIMIT = 100
+	// entries younger than this are still being handled by in-memory queues
+	RECYCLE_SCAN_DELAY    = 1 * time.Minute
+	RECYCLE_SCAN_INTERVAL = 10 * time.Minute
 )
 
 type objectToRecycle struct {
@@ -27,28 +40,73 @@ var RecycleQueue chan objectToRecycle
 func initializeRecycler(yig *YigStorage) {
 	if RecycleQueue == nil {
 		RecycleQueue = make(chan objectToRecycle, RECYCLE_QUEUE_SIZE)
+		// one scanner is enough for a process
+		go scanRecycles(yig)
 	}
 	// TODO: move this part of code to an isolated daemon
 	go removeFailed(yig)
 }
 
+// Save object to table `recycle` before returning to client, and queue it
+// for removal if the queue is not full
+func (yig *YigStorage) recycle(object objectToRecycle) {
+	err := yig.MetaStorage.PutRecycle(meta.Recycle{
+		Location:   object.location,
+		Pool:       object.pool,
+		ObjectId:   object.objectId,
+		CreateTime: time.Now().UTC(),
+	})
+	if err != nil {
+		helper.Logger.Error("Failed to save object to recycle:",
+			object.location, object.pool, object.objectId, "with error", err)
+	}
+	select {
+	case RecycleQueue <- object:
+	default:
+		if err != nil {
+			// not saved, wait for the queue rather than leak it
+			RecycleQueue <- object
+		}
+	}
+}
+
+func isNotExist(err error) bool {
+	return os.IsNotExist(err) || strings.Contains(err.Error(), "ret=-2")
+}
+
 func removeFailed(yig *YigStorage) {
 	yig.WaitGroup.Add(1)
 	defer yig.WaitGroup.Done()
 	for {
 		select {
 		case object := <-RecycleQueue:
-			err := yig.DataStorage[object.location].Remove(object.pool, object.objectId)
-			if err != nil {
+			cluster, ok := yig.DataStorage[object.location]
+			if !ok {
+				helper.Logger.Warn("Cannot find cluster to recycle object:",
+					object.location, object.pool, object.objectId)
+				continue
+			}
+			err := cluster.Remove(object.pool, object.objectId)
+			if err != nil && !isNotExist(err) {
 				object.triedTimes += 1
 				if object.triedTimes > MAX_TRY_TIMES {
 					helper.Logger.Warn("Failed to remove object in Ceph:",
 						object.location, object.pool, object.objectId,
-						"with error", err)
+						"with error", err, ", will retry later")
 					continue
 				}
 				RecycleQueue <- object
 				time.Sleep(1 * time.Second)
+				continue
+			}
+			err = yig.MetaStorage.RemoveRecycle(meta.Recycle{
+				Location: object.location,
+				Pool:     object.pool,
+				ObjectId: object.objectId,
+			})
+			if err != nil {
+				helper.Logger.Warn("Failed to remove recycle entry:",
+					object.location, object.pool, object.objectId, "with error", err)
 			}
 		default:
 			if yig.Stopping {
@@ -64,3 +122,47 @@ func removeFailed(yig *YigStorage) {
 	}
 }
 
+// Feed entries in table `recycle` to RecycleQueue, at startup and every
+// RECYCLE_SCAN_INTERVAL
+func scanRecycles(yig *YigStorage) {
+	for !yig.Stopping {
+		var startRowKey string
+		before := time.Now().UTC().Add(-RECYCLE_SCAN_DELAY)
+		for !yig.Stopping {
+			recycles, err := yig.MetaStorage.ScanRecycles(RECYCLE_SCAN_LIMIT, startRowKey, before)
+			if err != nil {
+				helper.Logger.Error("Failed to scan recycle entries:", err)
+				break
+			}
+			if len(recycles) == 0 {
+				break
+			}
+			helper.Logger.Info("Recovering", len(recycles), "objects to recycle")
+			for _, r := range recycles {
+				if !enqueueRecycle(yig, objectToRecycle{
+					location: r.Location,
+					pool:     r.Pool,
+					objectId: r.ObjectId,
+				}) {
+					return
+				}
+			}
+			startRowKey = recycles[len(recycles)-1].GetRowkey()
+		}
+		for i := time.Duration(0); i < RECYCLE_SCAN_INTERVAL && !yig.Stopping; i += time.Second {
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// returns false if service is stopping
+func enqueueRecycle(yig *YigStorage, object objectToRecycle) bool {
+	for !yig.Stopping {
+		select {
+		case RecycleQueue <- object:
+			return true
+		case <-time.After(time.Second):
+		}
+	}
+	return false
+}
@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/journeymidnight/aws-sdk-go/aws"
+	"github.com/journeymidnight/aws-sdk-go/aws/request"
+	"github.com/journeymidnight/aws-sdk-go/service/s3"
+	"github.com/journeymidnight/aws-sdk-go/service/s3/s3manager"
+	"github.com/journeymidnight/yig/api/datatype"
+	"github.com/journeymidnight/yig/crypto"
+	e "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	meta "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/redis"
+	"github.com/journeymidnight/yig/s3gateway"
+)
+
+// Versions and delete markers matching replication rules of their bucket are
+// saved as PENDING, together with a task in table `replication`. Tasks are
+// processed by tools/replicate with ReplicateObject, which copies the version
+// to destination bucket of the rule, then the version is marked COMPLETED, or
+// FAILED if it could never be replicated.
+//
+// Replication requires versioning enabled, so every replicated change could
+// be found by its version. Like AWS, deletes of specific versions are not
+// replicated, so versions deleted by mistake are kept in destination.
+
+const (
+	REPLICATION_PART_SIZE   = 16 << 20 // 16M
+	REPLICATION_CONCURRENCY = 4
+)
+
+type replicationClient struct {
+	client   *s3.S3
+	uploader *s3manager.Uploader
+}
+
+var (
+	replicationClientsLock sync.Mutex
+	replicationClients     = make(map[string]*replicationClient) // target ID -> client
+)
+
+func getReplicationClient(target helper.ReplicationTargetConfig) (*replicationClient, error) {
+	replicationClientsLock.Lock()
+	defer replicationClientsLock.Unlock()
+	if c, ok := replicationClients[target.ID]; ok {
+		return c, nil
+	}
+	client, err := s3gateway.NewClient(target.Endpoint, target.Region, target.AccessKey,
+		target.SecretKey, target.ForcePathStyle)
+	if err != nil {
+		return nil, err
+	}
+	c := &replicationClient{
+		client: client,
+		uploader: s3manager.NewUploaderWithClient(client, func(u *s3manager.Uploader) {
+			u.PartSize = REPLICATION_PART_SIZE
+			u.Concurrency = REPLICATION_CONCURRENCY
+		}),
+	}
+	replicationClients[target.ID] = c
+	return c, nil
+}
+
+// Mark requests of replication with x-amz-replication-status: REPLICA, so
+// destination saves replicas and never replicates them again
+func markReplica(r *request.Request) {
+	r.HTTPRequest.Header.Set(datatype.ReplicationStatusHeader, datatype.ReplicationStatusReplica)
+}
+
+func (yig *YigStorage) SetBucketReplication(bucket *meta.Bucket,
+	config datatype.ReplicationConfiguration) error {
+
+	if bucket.Versioning != meta.VersionEnabled {
+		return e.ErrReplicationRequiresVersioning
+	}
+	bucket.Replication = config
+	err := yig.MetaStorage.Client.PutBucket(*bucket)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.BucketTable, bucket.Name)
+	return nil
+}
+
+func (yig *YigStorage) GetBucketReplication(bucketName string) (config datatype.ReplicationConfiguration,
+	err error) {
+
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		return
+	}
+	if bucket.Replication.IsEmpty() {
+		return config, e.ErrReplicationConfigurationNotFound
+	}
+	return bucket.Replication, nil
+}
+
+// Versions already PENDING are still replicated if rules of them are kept
+func (yig *YigStorage) DeleteBucketReplication(bucket *meta.Bucket) error {
+	bucket.Replication = datatype.ReplicationConfiguration{}
+	err := yig.MetaStorage.Client.PutBucket(*bucket)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.BucketTable, bucket.Name)
+	return nil
+}
+
+func matchReplicationRule(bucket *meta.Bucket, object *meta.Object) *datatype.ReplicationRule {
+	if bucket.Replication.IsEmpty() || bucket.Versioning != meta.VersionEnabled ||
+		object.Type == meta.ObjectTypeAppendable {
+		return nil
+	}
+	if object.DeleteMarker {
+		return bucket.Replication.MatchDeleteMarker(object.Name)
+	}
+	return bucket.Replication.Match(object.Name, object.Tags)
+}
+
+// Mark object PENDING if it should be replicated, must be called before the
+// object is saved, so the replication task is saved with it. Objects written by
+// replication of another bucket carry x-amz-replication-status in metadata,
+// they are saved as replicas and never replicated again, otherwise buckets
+// replicating to each other would copy the same object back and forth.
+func markReplication(bucket *meta.Bucket, object *meta.Object) {
+	if _, ok := object.CustomAttributes[datatype.ReplicationStatusHeader]; ok {
+		delete(object.CustomAttributes, datatype.ReplicationStatusHeader)
+		object.ReplicationStatus = meta.ReplicationReplica
+		return
+	}
+	if matchReplicationRule(bucket, object) != nil {
+		object.ReplicationStatus = meta.ReplicationPending
+	} else {
+		object.ReplicationStatus = meta.ReplicationNone
+	}
+}
+
+// Copy version of task to its destination. Returns an error if it should be
+// retried later, FAILED if it could never be replicated, e.g. its rule is
+// removed, and ReplicationNone if the version or bucket is deleted already.
+func (yig *YigStorage) ReplicateObject(task *meta.ReplicationTask) (status meta.ReplicationStatus,
+	err error) {
+
+	bucket, err := yig.MetaStorage.GetBucket(task.BucketName, false)
+	if err == e.ErrNoSuchBucket {
+		return meta.ReplicationNone, nil
+	}
+	if err != nil {
+		return
+	}
+	object, err := yig.MetaStorage.GetReplicationObject(task)
+	if err == e.ErrNoSuchKey {
+		return meta.ReplicationNone, nil
+	}
+	if err != nil {
+		return
+	}
+	rule := matchReplicationRule(bucket, object)
+	if rule == nil {
+		helper.Logger.Warn("No replication rule matches", task.BucketName, task.ObjectName,
+			"anymore")
+		return meta.ReplicationFailed, nil
+	}
+	target, destination, err := datatype.ParseReplicationDestination(rule.Destination.Bucket)
+	if err != nil {
+		helper.Logger.Warn("Invalid replication destination", rule.Destination.Bucket,
+			"of bucket", task.BucketName)
+		return meta.ReplicationFailed, nil
+	}
+	if object.SseType == crypto.SSEC.String() {
+		// keys of customers are not saved
+		helper.Logger.Warn("SSE-C object", task.BucketName, task.ObjectName, "is not replicated")
+		return meta.ReplicationFailed, nil
+	}
+	client, err := getReplicationClient(target)
+	if err != nil {
+		return
+	}
+
+	if object.DeleteMarker {
+		_, err = client.client.DeleteObjectWithContext(aws.BackgroundContext(),
+			&s3.DeleteObjectInput{
+				Bucket: aws.String(destination),
+				Key:    aws.String(object.Name),
+			}, markReplica)
+		if err != nil {
+			return
+		}
+		return meta.ReplicationCompleted, nil
+	}
+
+	input := newReplicationInput(object, destination, rule.Destination.StorageClass)
+	if object.Size == 0 {
+		input.Body = bytes.NewReader(nil)
+	} else {
+		reader, writer := io.Pipe()
+		defer reader.Close() // unblocks GetObject if upload fails
+		go func() {
+			err := yig.readObject(object, 0, object.Size, writer, datatype.SseRequest{})
+			writer.CloseWithError(err)
+		}()
+		input.Body = reader
+	}
+	_, err = client.uploader.Upload(input, s3manager.WithUploaderRequestOptions(markReplica))
+	if err != nil {
+		return
+	}
+	return meta.ReplicationCompleted, nil
+}
+
+// Save replication status of the version and remove its task
+func (yig *YigStorage) FinishReplication(task *meta.ReplicationTask, status meta.ReplicationStatus) error {
+	err := yig.MetaStorage.FinishReplicationTask(task, status)
+	if err != nil {
+		return err
+	}
+	version := &meta.Object{
+		LastModifiedTime: time.Unix(0, int64(math.MaxUint64-task.Version)),
+	}
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, task.BucketName+":"+task.ObjectName+":")
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable,
+		task.BucketName+":"+task.ObjectName+":"+version.GetVersionId())
+	return nil
+}
+
+// Replicas keep metadata and tags of the object, and its storage class if
+// storageClass is empty. Data of SSE-S3 objects is decrypted when read, so
+// replicas are encrypted again with SSE-S3 of destination.
+func newReplicationInput(object *meta.Object, bucket, storageClass string) *s3manager.UploadInput {
+	if storageClass == "" {
+		storageClass = object.StorageClass.ToString()
+	}
+	input := &s3manager.UploadInput{
+		Bucket:       aws.String(bucket),
+		Key:          aws.String(object.Name),
+		StorageClass: aws.String(storageClass),
+		Metadata:     make(map[string]*string),
+	}
+	if object.ContentType != "" {
+		input.ContentType = aws.String(object.ContentType)
+	}
+	if object.SseType == crypto.S3.String() {
+		input.ServerSideEncryption = aws.String(crypto.SSEAlgorithmAES256)
+	}
+	for key, value := range object.CustomAttributes {
+		lowerKey := strings.ToLower(key)
+		switch lowerKey {
+		case "cache-control":
+			input.CacheControl = aws.String(value)
+		case "content-disposition":
+			input.ContentDisposition = aws.String(value)
+		case "content-encoding":
+			input.ContentEncoding = aws.String(value)
+		case "content-language":
+			input.ContentLanguage = aws.String(value)
+		case "website-redirect-location":
+			input.WebsiteRedirectLocation = aws.String(value)
+		case "expires":
+			if expires, err := http.ParseTime(value); err == nil {
+				input.Expires = aws.Time(expires)
+			}
+		default:
+			if strings.HasPrefix(lowerKey, "x-amz-meta-") {
+				input.Metadata[key[len("x-amz-meta-"):]] = aws.String(value)
+			}
+		}
+	}
+	if len(object.Tags) != 0 {
+		tags := url.Values{}
+		for key, value := range object.Tags {
+			tags.Set(key, value)
+		}
+		input.Tagging = aws.String(tags.Encode())
+	}
+	return input
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/helper"
+	meta "github.com/journeymidnight/yig/meta/types"
+)
+
+// VerifyObjectData reads all data of an object back from its cluster and
+// compares it against the recorded size and Etag. Reads are throttled by limiter.
+// Etag is only checked for objects whose Etag is the MD5 of the plain text,
+// i.e. not appendable and not encrypted with SSE-C, whose key is unknown to us.
+// SSE-S3 objects are decrypted before checksumming.
+func (yig *YigStorage) VerifyObjectData(object *meta.Object,
+	limiter *helper.RateLimiter) (findings []meta.ScrubFinding, err error) {
+
+	if object.DeleteMarker {
+		return nil, nil
+	}
+	cluster, ok := yig.DataStorage[object.Location]
+	if !ok {
+		return nil, errors.New("Cannot find specified cluster: " + object.Location)
+	}
+	checkEtag := object.Type != meta.ObjectTypeAppendable &&
+		(object.SseType == "" || object.SseType == crypto.S3.String())
+	var encryptionKey []byte
+	if checkEtag && object.SseType == crypto.S3.String() {
+		if yig.KMS == nil {
+			return nil, errors.New("KMS is not configured")
+		}
+		key, err := yig.KMS.UnsealKey(yig.KMS.GetKeyID(), object.EncryptionKey,
+			crypto.Context{object.BucketName: path.Join(object.BucketName, object.Name)})
+		if err != nil {
+			return nil, err
+		}
+		encryptionKey = key[:]
+	}
+
+	newFinding := func(partNumber int, objectId, findingType, detail string) meta.ScrubFinding {
+		return meta.ScrubFinding{
+			BucketName: object.BucketName,
+			ObjectName: object.Name,
+			VersionId:  object.GetVersionId(),
+			PartNumber: partNumber,
+			Location:   object.Location,
+			Pool:       object.Pool,
+			ObjectId:   objectId,
+			Type:       findingType,
+			Detail:     detail,
+			DetectTime: time.Now().UTC(),
+		}
+	}
+
+	if len(object.Parts) == 0 {
+		etag := ""
+		if checkEtag {
+			etag = object.Etag
+		}
+		findingType, detail := verifyBlob(cluster, object.Pool, object.ObjectId, object.Size,
+			etag, encryptionKey, object.InitializationVector, limiter)
+		if findingType != "" {
+			findings = append(findings, newFinding(0, object.ObjectId, findingType, detail))
+		}
+		return findings, nil
+	}
+	for n := 1; n <= len(object.Parts); n++ {
+		part, ok := object.Parts[n]
+		if !ok {
+			continue
+		}
+		etag := ""
+		if checkEtag {
+			etag = part.Etag
+		}
+		findingType, detail := verifyBlob(cluster, object.Pool, part.ObjectId, part.Size,
+			etag, encryptionKey, part.InitializationVector, limiter)
+		if findingType != "" {
+			findings = append(findings, newFinding(n, part.ObjectId, findingType, detail))
+		}
+	}
+	return findings, nil
+}
+
+// returns empty findingType if data is fine, Etag is not checked if etag is empty
+func verifyBlob(cluster backend.Cluster, pool, objectId string, size int64, etag string,
+	encryptionKey, initializationVector []byte,
+	limiter *helper.RateLimiter) (findingType string, detail string) {
+
+	if size == 0 {
+		return "", ""
+	}
+	reader, err := cluster.GetReader(pool, objectId, 0, uint64(size))
+	if err != nil {
+		return meta.ScrubFindingMissing, err.Error()
+	}
+	defer reader.Close()
+	decryptedReader, err := wrapEncryptionReader(limiter.Reader(reader),
+		encryptionKey, initializationVector)
+	if err != nil {
+		return meta.ScrubFindingMissing, err.Error()
+	}
+	md5Writer := md5.New()
+	n, err := io.Copy(md5Writer, decryptedReader)
+	if err != nil {
+		return meta.ScrubFindingMissing, err.Error()
+	}
+	if n < size {
+		return meta.ScrubFindingTruncated, fmt.Sprintf("expect %d bytes, read %d", size, n)
+	}
+	if etag == "" {
+		return "", ""
+	}
+	calculatedMd5 := hex.EncodeToString(md5Writer.Sum(nil))
+	if calculatedMd5 != etag {
+		return meta.ScrubFindingEtagMismatch,
+			fmt.Sprintf("expect etag %s, calculated %s", etag, calculatedMd5)
+	}
+	return "", ""
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	e "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/iam/common"
+	meta "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/redis"
+)
+
+// Tags are saved with each version of an object, so a version without tags
+// is not affected when tagging another version.
+
+func checkTaggedObject(object *meta.Object, version string) error {
+	if object.DeleteMarker {
+		if version == "" {
+			return e.ErrNoSuchKey
+		}
+		return e.ErrMethodNotAllowed
+	}
+	return nil
+}
+
+func (yig *YigStorage) GetObjectTagging(bucketName, objectName, version string,
+	credential common.Credential) (tags map[string]string, err error) {
+
+	object, err := yig.GetObjectInfo(bucketName, objectName, version, credential)
+	if err != nil {
+		return
+	}
+	err = checkTaggedObject(object, version)
+	if err != nil {
+		return
+	}
+	return object.Tags, nil
+}
+
+func (yig *YigStorage) PutObjectTagging(bucketName, objectName, version string,
+	tags map[string]string, credential common.Credential) error {
+
+	return yig.setObjectTags(bucketName, objectName, version, tags, credential)
+}
+
+func (yig *YigStorage) DeleteObjectTagging(bucketName, objectName, version string,
+	credential common.Credential) error {
+
+	return yig.setObjectTags(bucketName, objectName, version, nil, credential)
+}
+
+func (yig *YigStorage) setObjectTags(bucketName, objectName, version string,
+	tags map[string]string, credential common.Credential) error {
+
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, true)
+	if err != nil {
+		return err
+	}
+	var object *meta.Object
+	if version == "" {
+		object, err = yig.MetaStorage.GetObject(bucketName, objectName, false)
+	} else {
+		object, err = yig.getObjWithVersion(bucketName, objectName, version)
+	}
+	if err != nil {
+		return err
+	}
+	if !credential.AllowOtherUserAccess && bucket.OwnerId != credential.UserId &&
+		object.OwnerId != credential.UserId {
+		return e.ErrAccessDenied
+	}
+	err = checkTaggedObject(object, version)
+	if err != nil {
+		return err
+	}
+	object.Tags = tags
+	err = yig.MetaStorage.UpdateObjectTags(object)
+	if err != nil {
+		return err
+	}
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, bucketName+":"+objectName+":")
+	if version != "" {
+		yig.MetaStorage.Cache.Remove(redis.ObjectTable,
+			bucketName+":"+objectName+":"+version)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	e "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	meta "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/redis"
+)
+
+// Objects of storage class INTELLIGENT_TIERING are moved between frequent and
+// infrequent access tiers by tools/tiering, according to their last access.
+//
+// Accesses are batched in memory and saved to table `objectaccess` every
+// ACCESS_FLUSH_INTERVAL, so reads are not slowed by extra writes to DB, and an
+// object read many times in an interval is only saved once. They are saved in
+// chunks of ACCESS_BATCH_SIZE rows sorted by rowkey, so gateways flushing at
+// the same time lock rows in the same order. Chunks failed to save are kept
+// for the next flush unless accessed again. Accesses not saved yet are lost if
+// the process crashes, which only delays the object being moved to the right
+// tier.
+//
+// Only reads of clients are accesses, reads of daemons use readObject.
+//
+// Frequent access tier uses pools of INTELLIGENT_TIERING, infrequent access
+// tier uses pools and clusters of STANDARD_IA, see `storage_classes` in yig.toml.
+
+const (
+	ACCESS_FLUSH_INTERVAL = 10 * time.Second
+	ACCESS_BATCH_SIZE     = 500 // rows saved by one statement
+)
+
+type accessTracker struct {
+	sync.Mutex
+	pending map[string]*meta.ObjectAccess // rowkey -> access
+}
+
+var tracker *accessTracker
+
+func initializeAccessTracker(yig *YigStorage) {
+	if tracker == nil {
+		tracker = &accessTracker{
+			pending: make(map[string]*meta.ObjectAccess),
+		}
+		yig.WaitGroup.Add(1)
+		go flushAccesses(yig)
+	}
+}
+
+// Record an access of object, if it's of storage class INTELLIGENT_TIERING
+func (yig *YigStorage) trackAccess(object *meta.Object) {
+	if tracker == nil || !tierable(object) {
+		return
+	}
+	access := meta.NewObjectAccess(object, time.Now().UTC())
+	tracker.Lock()
+	tracker.pending[access.GetRowkey()] = access
+	tracker.Unlock()
+}
+
+func tierable(object *meta.Object) bool {
+	return object.StorageClass == meta.ObjectStorageClassIntelligentTiering &&
+		!object.DeleteMarker && object.Type != meta.ObjectTypeAppendable
+}
+
+func flushAccesses(yig *YigStorage) {
+	defer yig.WaitGroup.Done()
+	for {
+		stopping := yig.Stopping
+		tracker.Lock()
+		pending := tracker.pending
+		tracker.pending = make(map[string]*meta.ObjectAccess)
+		tracker.Unlock()
+		saveAccesses(yig, pending)
+		if stopping {
+			return
+		}
+		for i := time.Duration(0); i < ACCESS_FLUSH_INTERVAL && !yig.Stopping; i += time.Second {
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+func saveAccesses(yig *YigStorage, pending map[string]*meta.ObjectAccess) {
+	rowkeys := make([]string, 0, len(pending))
+	for rowkey := range pending {
+		rowkeys = append(rowkeys, rowkey)
+	}
+	sort.Strings(rowkeys)
+	for start := 0; start < len(rowkeys); start += ACCESS_BATCH_SIZE {
+		end := start + ACCESS_BATCH_SIZE
+		if end > len(rowkeys) {
+			end = len(rowkeys)
+		}
+		accesses := make([]*meta.ObjectAccess, 0, end-start)
+		for _, rowkey := range rowkeys[start:end] {
+			accesses = append(accesses, pending[rowkey])
+		}
+		err := yig.MetaStorage.PutObjectAccesses(accesses)
+		if err == nil {
+			continue
+		}
+		helper.Logger.Error("Failed to save", len(accesses),
+			"object accesses with error", err)
+		tracker.Lock()
+		for _, rowkey := range rowkeys[start:end] {
+			if _, ok := tracker.pending[rowkey]; !ok {
+				tracker.pending[rowkey] = pending[rowkey]
+			}
+		}
+		tracker.Unlock()
+	}
+}
+
+// Storage class whose pools and clusters are used by access tier
+func storageClassOfTier(tier meta.AccessTier) meta.StorageClass {
+	if tier == meta.AccessTierInfrequent {
+		return meta.ObjectStorageClassStandardIa
+	}
+	return meta.ObjectStorageClassIntelligentTiering
+}
+
+// TierObject moves data of an INTELLIGENT_TIERING object to the pools of
+// access tier. Data is not moved if it's in a cluster and pool usable by the
+// tier already, e.g. the two tiers share pools. Returns meta.ErrObjectChanged
+// if the object is modified during moving.
+func (yig *YigStorage) TierObject(object *meta.Object, tier meta.AccessTier) (err error) {
+	if !tierable(object) {
+		return errors.New("object is not of a tierable storage class")
+	}
+	storageClass := storageClassOfTier(tier)
+	cluster, poolName := yig.pickClusterAndPool(object.BucketName, object.Name,
+		storageClass, object.Size, false)
+	if cluster == nil {
+		return e.ErrInternalError
+	}
+	if poolName == object.Pool {
+		metaClusters, err := yig.MetaStorage.GetClusters()
+		if err != nil {
+			return err
+		}
+		for _, c := range clustersForStorageClass(metaClusters, storageClass, poolName) {
+			if c.Fsid == object.Location {
+				return nil
+			}
+		}
+	}
+	source, ok := yig.DataStorage[object.Location]
+	if !ok {
+		return errors.New("Cannot find specified cluster: " + object.Location)
+	}
+
+	var written []objectToRecycle
+	defer func() {
+		if err != nil {
+			for _, o := range written {
+				yig.recycle(o)
+			}
+		}
+	}()
+	newObject := *object
+	newObject.Location = cluster.ID()
+	newObject.Pool = poolName
+	newObject.ObjectId, newObject.Parts, written, err = copyObjectData(object, source,
+		cluster, poolName)
+	if err != nil {
+		return err
+	}
+	err = yig.MetaStorage.MigrateObject(&newObject, object)
+	if err != nil {
+		return err
+	}
+	helper.Logger.Info("Moved", object.BucketName, object.Name, object.GetVersionId(),
+		"to", meta.AccessTierStringMap[tier], "access tier")
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, object.BucketName+":"+object.Name+":")
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable,
+		object.BucketName+":"+object.Name+":"+object.GetVersionId())
+	yig.DataCache.Remove(object.BucketName + ":" + object.Name + ":" + object.GetVersionId())
+	return nil
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/journeymidnight/yig/backend"
+	e "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	meta "github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/redis"
+)
+
+// TransitionObject changes storage class of an object, used by lifecycle.
+// Data is moved to the pool for the new storage class if it differs from
+// current one. Data is copied as is, so encryption keys and IVs still apply.
+// Returns meta.ErrObjectChanged if the object is modified during transition.
+func (yig *YigStorage) TransitionObject(object *meta.Object, storageClass meta.StorageClass) (err error) {
+	if object.DeleteMarker || object.StorageClass == storageClass {
+		return nil
+	}
+	if object.Type == meta.ObjectTypeAppendable {
+		return errors.New("appendable object could not be transitioned")
+	}
+	if object.StorageClass == meta.ObjectStorageClassGlacier {
+		return errors.New("glacier object could not be transitioned")
+	}
+	source, ok := yig.DataStorage[object.Location]
+	if !ok {
+		return errors.New("Cannot find specified cluster: " + object.Location)
+	}
+	cluster, poolName := yig.pickClusterAndPool(object.BucketName, object.Name,
+		storageClass, object.Size, false)
+	if cluster == nil {
+		return e.ErrInternalError
+	}
+
+	newObject := *object
+	newObject.StorageClass = storageClass
+	dataMoved := poolName != object.Pool
+	if dataMoved {
+		var written []objectToRecycle
+		defer func() {
+			if err != nil {
+				for _, o := range written {
+					yig.recycle(o)
+				}
+			}
+		}()
+		newObject.Location = cluster.ID()
+		newObject.Pool = poolName
+		newObject.ObjectId, newObject.Parts, written, err = copyObjectData(object, source,
+			cluster, poolName)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = yig.MetaStorage.TransitionObject(&newObject, object, dataMoved)
+	if err != nil {
+		return err
+	}
+	helper.Logger.Info("Transitioned", object.BucketName, object.Name, object.GetVersionId(),
+		"from", object.StorageClass.ToString(), "to", storageClass.ToString())
+	if tierable(&newObject) {
+		// saved synchronously since lc may exit before accesses are flushed
+		accessErr := yig.MetaStorage.PutObjectAccesses([]*meta.ObjectAccess{
+			meta.NewObjectAccess(&newObject, time.Now().UTC()),
+		})
+		if accessErr != nil {
+			helper.Logger.Error("Failed to save access of", object.BucketName, object.Name,
+				object.GetVersionId(), "with error", accessErr)
+		}
+	}
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, object.BucketName+":"+object.Name+":")
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable,
+		object.BucketName+":"+object.Name+":"+object.GetVersionId())
+	yig.DataCache.Remove(object.BucketName + ":" + object.Name + ":" + object.GetVersionId())
+	return nil
+}
+
+// Copy all blobs of object to pool of cluster, returns ObjectId and Parts
+// referencing the copies. Blobs written are returned even on error, which
+// should be recycled if not referenced by meta later.
+func copyObjectData(object *meta.Object, source backend.Cluster, target backend.Cluster,
+	poolName string) (objectId string, parts map[int]*meta.Part, written []objectToRecycle, err error) {
+
+	copyBlob := func(objectId string, size int64) (string, error) {
+		oid, n, err := copyBlobToPool(source, object.Pool, objectId, size, target, poolName)
+		if err != nil {
+			return "", err
+		}
+		written = append(written, objectToRecycle{
+			location: target.ID(),
+			pool:     poolName,
+			objectId: oid,
+		})
+		if int64(n) != size {
+			return "", fmt.Errorf("copied %d bytes, expect %d", n, size)
+		}
+		return oid, nil
+	}
+	if len(object.Parts) == 0 {
+		objectId, err = copyBlob(object.ObjectId, object.Size)
+		return
+	}
+	parts = make(map[int]*meta.Part, len(object.Parts))
+	for n, part := range object.Parts {
+		newPart := *part
+		newPart.ObjectId, err = copyBlob(part.ObjectId, part.Size)
+		if err != nil {
+			return
+		}
+		parts[n] = &newPart
+	}
+	return
+}
+
+// Copy a blob to pool of cluster, inside the cluster if it implements backend.Copier
+func copyBlobToPool(source backend.Cluster, sourcePool, objectId string, size int64,
+	target backend.Cluster, targetPool string) (oid string, n uint64, err error) {
+
+	if copier, ok := target.(backend.Copier); ok && source.ID() == target.ID() {
+		return copier.Copy(sourcePool, objectId, targetPool)
+	}
+	reader, err := source.GetReader(sourcePool, objectId, 0, uint64(size))
+	if err != nil {
+		return "", 0, err
+	}
+	defer reader.Close()
+	return target.Put(targetPool, reader)
+}
@@ -2,9 +2,13 @@ package _go
 
 import (
 	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/journeymidnight/aws-sdk-go/aws"
+	"github.com/journeymidnight/aws-sdk-go/service/s3"
 	"github.com/journeymidnight/yig/api/datatype"
 	. "github.com/journeymidnight/yig/test/go/lib"
-	"testing"
 )
 
 const (
@@ -15,6 +19,13 @@ const (
 		</GlacierJobParameters>
 	</RestoreRequest>`
 
+	RESTOREXML_INVALID_TIER = `<RestoreRequest xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+		<Days>1</Days>
+		<GlacierJobParameters>
+			<Tier>Fastest</Tier>
+		</GlacierJobParameters>
+	</RestoreRequest>`
+
 	RESTOREXML2 = `<RestoreRequest xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
 		<Days>2</Days>     
 		<GlacierJobParameters>
@@ -58,6 +69,30 @@ func Test_RestoreObject(t *testing.T) {
 	}
 	t.Log("RestoreObject Success!")
 
+	// restore daemon may not have processed the request yet
+	head, err := sc.Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(TEST_BUCKET),
+		Key:    aws.String(TEST_KEY),
+	})
+	if err != nil {
+		t.Fatal("HeadObject err:", err)
+	}
+	if head.Restore == nil || !strings.Contains(*head.Restore, "ongoing-request=") {
+		t.Fatal("HeadObject x-amz-restore not set:", head.Restore)
+	}
+	t.Log("HeadObject x-amz-restore:", *head.Restore)
+
+	invalid := &datatype.Restore{}
+	err = xml.Unmarshal([]byte(RESTOREXML_INVALID_TIER), invalid)
+	if err != nil {
+		t.Fatal("Unmarshal restore request err:", err)
+	}
+	err = sc.RestoreObject(TEST_BUCKET, TEST_KEY, TransferToS3AccessRestoreRequest(invalid))
+	if err == nil {
+		t.Fatal("RestoreObject with invalid tier should fail")
+	}
+	t.Log("RestoreObject with invalid tier failed as expected:", err)
+
 	err = sc.DeleteObject(TEST_BUCKET,TEST_KEY)
 	if err != nil {
 		t.Fatal("DeleteObject err:", err)
@@ -0,0 +1,26 @@
+package lib
+
+import (
+	"github.com/journeymidnight/aws-sdk-go/aws"
+	"github.com/journeymidnight/aws-sdk-go/service/s3"
+)
+
+func (s3client *S3Client) PutBucketNotification(bucketName string,
+	config *s3.NotificationConfiguration) (err error) {
+
+	params := &s3.PutBucketNotificationConfigurationInput{
+		Bucket:                    aws.String(bucketName),
+		NotificationConfiguration: config,
+	}
+	_, err = s3client.Client.PutBucketNotificationConfiguration(params)
+	return err
+}
+
+func (s3client *S3Client) GetBucketNotification(bucketName string) (
+	config *s3.NotificationConfiguration, err error) {
+
+	params := &s3.GetBucketNotificationConfigurationRequest{
+		Bucket: aws.String(bucketName),
+	}
+	return s3client.Client.GetBucketNotificationConfiguration(params)
+}
@@ -0,0 +1,133 @@
+package lib
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/journeymidnight/aws-sdk-go/aws"
+	"github.com/journeymidnight/aws-sdk-go/service/s3"
+)
+
+func (s3client *S3Client) MakeObjectLockBucket(bucketName string) (err error) {
+	params := &s3.CreateBucketInput{
+		Bucket:                     aws.String(bucketName),
+		ObjectLockEnabledForBucket: aws.Bool(true),
+	}
+	_, err = s3client.Client.CreateBucket(params)
+	return err
+}
+
+func (s3client *S3Client) PutObjectLockConfiguration(bucketName string,
+	config *s3.ObjectLockConfiguration) (err error) {
+
+	params := &s3.PutObjectLockConfigurationInput{
+		Bucket:                  aws.String(bucketName),
+		ObjectLockConfiguration: config,
+	}
+	_, err = s3client.Client.PutObjectLockConfiguration(params)
+	return err
+}
+
+func (s3client *S3Client) GetObjectLockConfiguration(bucketName string) (
+	config *s3.ObjectLockConfiguration, err error) {
+
+	params := &s3.GetObjectLockConfigurationInput{
+		Bucket: aws.String(bucketName),
+	}
+	out, err := s3client.Client.GetObjectLockConfiguration(params)
+	if err != nil {
+		return nil, err
+	}
+	return out.ObjectLockConfiguration, nil
+}
+
+// Returns version id of the new object
+func (s3client *S3Client) PutObjectWithRetention(bucketName, key, value, mode string,
+	retainUntil time.Time) (versionId string, err error) {
+
+	params := &s3.PutObjectInput{
+		Bucket:                    aws.String(bucketName),
+		Key:                       aws.String(key),
+		Body:                      bytes.NewReader([]byte(value)),
+		ObjectLockMode:            aws.String(mode),
+		ObjectLockRetainUntilDate: aws.Time(retainUntil),
+	}
+	out, err := s3client.Client.PutObject(params)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.VersionId), nil
+}
+
+func (s3client *S3Client) PutObjectRetention(bucketName, key, versionId, mode string,
+	retainUntil time.Time, bypassGovernance bool) (err error) {
+
+	params := &s3.PutObjectRetentionInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionId),
+		Retention: &s3.ObjectLockRetention{
+			Mode:            aws.String(mode),
+			RetainUntilDate: aws.Time(retainUntil),
+		},
+		BypassGovernanceRetention: aws.Bool(bypassGovernance),
+	}
+	_, err = s3client.Client.PutObjectRetention(params)
+	return err
+}
+
+func (s3client *S3Client) GetObjectRetention(bucketName, key, versionId string) (
+	retention *s3.ObjectLockRetention, err error) {
+
+	params := &s3.GetObjectRetentionInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionId),
+	}
+	out, err := s3client.Client.GetObjectRetention(params)
+	if err != nil {
+		return nil, err
+	}
+	return out.Retention, nil
+}
+
+func (s3client *S3Client) PutObjectLegalHold(bucketName, key, versionId, status string) (err error) {
+	params := &s3.PutObjectLegalHoldInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionId),
+		LegalHold: &s3.ObjectLockLegalHold{
+			Status: aws.String(status),
+		},
+	}
+	_, err = s3client.Client.PutObjectLegalHold(params)
+	return err
+}
+
+func (s3client *S3Client) GetObjectLegalHold(bucketName, key, versionId string) (status string,
+	err error) {
+
+	params := &s3.GetObjectLegalHoldInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionId),
+	}
+	out, err := s3client.Client.GetObjectLegalHold(params)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.LegalHold.Status), nil
+}
+
+func (s3client *S3Client) DeleteObjectVersion(bucketName, key, versionId string,
+	bypassGovernance bool) (err error) {
+
+	params := &s3.DeleteObjectInput{
+		Bucket:                    aws.String(bucketName),
+		Key:                       aws.String(key),
+		VersionId:                 aws.String(versionId),
+		BypassGovernanceRetention: aws.Bool(bypassGovernance),
+	}
+	_, err = s3client.Client.DeleteObject(params)
+	return err
+}
@@ -0,0 +1,87 @@
+package lib
+
+import (
+	"bytes"
+
+	"github.com/journeymidnight/aws-sdk-go/aws"
+	"github.com/journeymidnight/aws-sdk-go/service/s3"
+)
+
+func (s3client *S3Client) PutBucketVersioning(bucketName, status string) (err error) {
+	params := &s3.PutBucketVersioningInput{
+		Bucket: aws.String(bucketName),
+		VersioningConfiguration: &s3.VersioningConfiguration{
+			Status: aws.String(status),
+		},
+	}
+	_, err = s3client.Client.PutBucketVersioning(params)
+	return err
+}
+
+func (s3client *S3Client) PutBucketReplication(bucketName string,
+	config *s3.ReplicationConfiguration) (err error) {
+
+	params := &s3.PutBucketReplicationInput{
+		Bucket:                   aws.String(bucketName),
+		ReplicationConfiguration: config,
+	}
+	_, err = s3client.Client.PutBucketReplication(params)
+	return err
+}
+
+func (s3client *S3Client) GetBucketReplication(bucketName string) (
+	config *s3.ReplicationConfiguration, err error) {
+
+	params := &s3.GetBucketReplicationInput{
+		Bucket: aws.String(bucketName),
+	}
+	out, err := s3client.Client.GetBucketReplication(params)
+	if err != nil {
+		return nil, err
+	}
+	return out.ReplicationConfiguration, nil
+}
+
+func (s3client *S3Client) DeleteBucketReplication(bucketName string) (err error) {
+	params := &s3.DeleteBucketReplicationInput{
+		Bucket: aws.String(bucketName),
+	}
+	_, err = s3client.Client.DeleteBucketReplication(params)
+	return err
+}
+
+func (s3client *S3Client) GetReplicationStatus(bucketName, key string) (status string, err error) {
+	return s3client.GetVersionReplicationStatus(bucketName, key, "")
+}
+
+// status of the latest version if versionId is empty
+func (s3client *S3Client) GetVersionReplicationStatus(bucketName, key,
+	versionId string) (status string, err error) {
+
+	params := &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+	if versionId != "" {
+		params.VersionId = aws.String(versionId)
+	}
+	out, err := s3client.Client.HeadObject(params)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.ReplicationStatus), nil
+}
+
+// Returns version id of the new object
+func (s3client *S3Client) PutObjectVersion(bucketName, key, value string) (versionId string, err error) {
+	params := &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader([]byte(value)),
+	}
+	out, err := s3client.Client.PutObject(params)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.VersionId), nil
+}
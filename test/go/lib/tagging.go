@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"bytes"
+
+	"github.com/journeymidnight/aws-sdk-go/aws"
+	"github.com/journeymidnight/aws-sdk-go/service/s3"
+)
+
+// tagging is URL query encoded, e.g. "key1=value1&key2=value2"
+func (s3client *S3Client) PutObjectWithTagging(bucketName, key, value, tagging string) (err error) {
+	_, err = s3client.PutObjectVersionWithTagging(bucketName, key, value, tagging)
+	return err
+}
+
+// Returns version id of the new object
+func (s3client *S3Client) PutObjectVersionWithTagging(bucketName, key, value,
+	tagging string) (versionId string, err error) {
+
+	params := &s3.PutObjectInput{
+		Bucket:  aws.String(bucketName),
+		Key:     aws.String(key),
+		Body:    bytes.NewReader([]byte(value)),
+		Tagging: aws.String(tagging),
+	}
+	out, err := s3client.Client.PutObject(params)
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.VersionId), nil
+}
+
+func (s3client *S3Client) CopyObjectWithTagging(bucketName, key, source, directive, tagging string) (err error) {
+	params := &s3.CopyObjectInput{
+		Bucket:           aws.String(bucketName),
+		Key:              aws.String(key),
+		CopySource:       aws.String(source),
+		TaggingDirective: aws.String(directive),
+	}
+	if tagging != "" {
+		params.Tagging = aws.String(tagging)
+	}
+	_, err = s3client.Client.CopyObject(params)
+	return err
+}
+
+func (s3client *S3Client) PutObjectTagging(bucketName, key string, tags map[string]string) (err error) {
+	tagSet := make([]*s3.Tag, 0, len(tags))
+	for k, v := range tags {
+		tagSet = append(tagSet, &s3.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	params := &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(bucketName),
+		Key:     aws.String(key),
+		Tagging: &s3.Tagging{TagSet: tagSet},
+	}
+	_, err = s3client.Client.PutObjectTagging(params)
+	return err
+}
+
+func (s3client *S3Client) GetObjectTagging(bucketName, key string) (tags map[string]string, err error) {
+	return s3client.GetObjectVersionTagging(bucketName, key, "")
+}
+
+// tags of the latest version if versionId is empty
+func (s3client *S3Client) GetObjectVersionTagging(bucketName, key,
+	versionId string) (tags map[string]string, err error) {
+
+	params := &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+	if versionId != "" {
+		params.VersionId = aws.String(versionId)
+	}
+	out, err := s3client.Client.GetObjectTagging(params)
+	if err != nil {
+		return nil, err
+	}
+	tags = make(map[string]string)
+	for _, tag := range out.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
+func (s3client *S3Client) DeleteObjectTagging(bucketName, key string) (err error) {
+	params := &s3.DeleteObjectTaggingInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+	_, err = s3client.Client.DeleteObjectTagging(params)
+	return err
+}
+
+func (s3client *S3Client) PutBucketTagging(bucketName string, tags map[string]string) (err error) {
+	tagSet := make([]*s3.Tag, 0, len(tags))
+	for k, v := range tags {
+		tagSet = append(tagSet, &s3.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	params := &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucketName),
+		Tagging: &s3.Tagging{TagSet: tagSet},
+	}
+	_, err = s3client.Client.PutBucketTagging(params)
+	return err
+}
+
+func (s3client *S3Client) GetBucketTagging(bucketName string) (tags map[string]string, err error) {
+	params := &s3.GetBucketTaggingInput{
+		Bucket: aws.String(bucketName),
+	}
+	out, err := s3client.Client.GetBucketTagging(params)
+	if err != nil {
+		return nil, err
+	}
+	tags = make(map[string]string)
+	for _, tag := range out.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
+func (s3client *S3Client) DeleteBucketTagging(bucketName string) (err error) {
+	params := &s3.DeleteBucketTaggingInput{
+		Bucket: aws.String(bucketName),
+	}
+	_, err = s3client.Client.DeleteBucketTagging(params)
+	return err
+}
@@ -12,6 +12,27 @@ import (
 	. "github.com/journeymidnight/yig/test/go/lib"
 )
 
+const (
+	TEST_LC_BUCKET = "mylcbucket"
+)
+
+// Run a pass of lc over all buckets with lifecycle. lc runs in debug mode,
+// so days of rules are counted as seconds.
+func runLc(t *testing.T) {
+	err := os.Chdir("../../")
+	if err != nil {
+		t.Fatal("change dir in lc err:", err)
+	}
+	defer os.Chdir("../test/go")
+	cmd := exec.Command("make", "runlc")
+	err = cmd.Run()
+	if err != nil {
+		t.Fatal("lc err:", err)
+	}
+	time.Sleep(time.Second * 3)
+	t.Log("lc Success!")
+}
+
 func Test_LifeCycle(t *testing.T) {
 	sc := NewS3()
 
@@ -63,6 +84,19 @@ func Test_LifeCycle(t *testing.T) {
 	}
 	t.Log("GetBucketLifecycle Success!")
 
+	//HeadObject should return x-amz-expiration with the matching rule.
+	head, err := sc.Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(TEST_BUCKET),
+		Key:    aws.String(TEST_KEY),
+	})
+	if err != nil {
+		t.Fatal("HeadObject err:", err)
+	}
+	if head.Expiration == nil || !strings.Contains(*head.Expiration, `rule-id="test"`) {
+		t.Fatal("HeadObject x-amz-expiration err:", head.Expiration)
+	}
+	t.Log("HeadObject x-amz-expiration Success:", *head.Expiration)
+
 	//Get object before lc.
 	v, err := sc.GetObject(TEST_BUCKET, TEST_KEY)
 	if err != nil {
@@ -74,18 +108,7 @@ func Test_LifeCycle(t *testing.T) {
 	t.Log("GetObject before lc Success value:", v)
 
 	//Test "lifecycle.go".
-	err = os.Chdir("../../")
-	if err != nil {
-		t.Fatal("change dir in lc err:", err)
-	}
-	cmd := exec.Command("make", "runlc")
-	err = cmd.Run()
-	if err != nil {
-		t.Fatal("lc err:", err)
-	}
-	time.Sleep(time.Second * 3)
-	t.Log("lc Success!")
-	os.Chdir("../test/go")
+	runLc(t)
 
 	//Get object after lc.
 	v, err = sc.GetObject(TEST_BUCKET, TEST_KEY)
@@ -112,6 +135,283 @@ func Test_LifeCycle(t *testing.T) {
 
 }
 
+func Test_LifeCycleFilter(t *testing.T) {
+	sc := NewS3()
+
+	//PutBucketLifecycle with prefix filter and expiration date.
+	putPut := &s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(TEST_BUCKET),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{
+			Rules: []*s3.LifecycleRule{
+				{
+					Expiration: &s3.LifecycleExpiration{
+						Date: aws.Time(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)),
+					},
+					Filter: &s3.LifecycleRuleFilter{
+						Prefix: aws.String("logs/"),
+					},
+					ID:     aws.String("prefix"),
+					Status: aws.String("Enabled"),
+				},
+			},
+		},
+	}
+	_, err := sc.Client.PutBucketLifecycleConfiguration(putPut)
+	if err != nil {
+		t.Fatal("PutBucketLifecycle with prefix filter err:", err)
+	}
+	t.Log("PutBucketLifecycle with prefix filter Success!")
+
+	//PutBucketLifecycle with And filter of prefix and tags.
+	putPut.LifecycleConfiguration.Rules[0].Filter = &s3.LifecycleRuleFilter{
+		And: &s3.LifecycleRuleAndOperator{
+			Prefix: aws.String("logs/"),
+			Tags: []*s3.Tag{
+				{Key: aws.String("type"), Value: aws.String("log")},
+			},
+		},
+	}
+	_, err = sc.Client.PutBucketLifecycleConfiguration(putPut)
+	if err != nil {
+		t.Fatal("PutBucketLifecycle with And filter err:", err)
+	}
+	t.Log("PutBucketLifecycle with And filter Success!")
+
+	//Invalid configurations should be rejected.
+	invalidRules := [][]*s3.LifecycleRule{
+		// no action
+		{{
+			Filter: &s3.LifecycleRuleFilter{Prefix: aws.String("")},
+			Status: aws.String("Enabled"),
+		}},
+		// expiration date not at midnight
+		{{
+			Expiration: &s3.LifecycleExpiration{
+				Date: aws.Time(time.Date(2030, 1, 1, 8, 0, 0, 0, time.UTC)),
+			},
+			Filter: &s3.LifecycleRuleFilter{Prefix: aws.String("")},
+			Status: aws.String("Enabled"),
+		}},
+		// ExpiredObjectDeleteMarker with tag filter
+		{{
+			Expiration: &s3.LifecycleExpiration{
+				ExpiredObjectDeleteMarker: aws.Bool(true),
+			},
+			Filter: &s3.LifecycleRuleFilter{
+				Tag: &s3.Tag{Key: aws.String("type"), Value: aws.String("log")},
+			},
+			Status: aws.String("Enabled"),
+		}},
+		// duplicate rule ID
+		{{
+			Expiration: &s3.LifecycleExpiration{Days: aws.Int64(1)},
+			Filter:     &s3.LifecycleRuleFilter{Prefix: aws.String("a")},
+			ID:         aws.String("dup"),
+			Status:     aws.String("Enabled"),
+		}, {
+			Expiration: &s3.LifecycleExpiration{Days: aws.Int64(1)},
+			Filter:     &s3.LifecycleRuleFilter{Prefix: aws.String("b")},
+			ID:         aws.String("dup"),
+			Status:     aws.String("Enabled"),
+		}},
+	}
+	for i, rules := range invalidRules {
+		_, err = sc.Client.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+			Bucket:                 aws.String(TEST_BUCKET),
+			LifecycleConfiguration: &s3.BucketLifecycleConfiguration{Rules: rules},
+		})
+		if err == nil {
+			t.Fatal("PutBucketLifecycle should fail for invalid configuration", i)
+		}
+		t.Log("PutBucketLifecycle rejected invalid configuration", i, ":", err)
+	}
+
+	_, err = sc.Client.DeleteBucketLifecycle(&s3.DeleteBucketLifecycleInput{
+		Bucket: aws.String(TEST_BUCKET),
+	})
+	if err != nil {
+		t.Fatal("DeleteBucketLifecycle err:", err)
+	}
+}
+
+func Test_LifeCycleTransition(t *testing.T) {
+	sc := NewS3()
+	err := sc.MakeBucket(TEST_LC_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+	defer sc.DeleteBucket(TEST_LC_BUCKET)
+	defer sc.DeleteObject(TEST_LC_BUCKET, TEST_KEY)
+	err = sc.PutObject(TEST_LC_BUCKET, TEST_KEY, TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutObject err:", err)
+	}
+
+	_, err = sc.Client.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(TEST_LC_BUCKET),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{
+			Rules: []*s3.LifecycleRule{
+				{
+					Filter: &s3.LifecycleRuleFilter{Prefix: aws.String("")},
+					ID:     aws.String("transition"),
+					Status: aws.String("Enabled"),
+					Transitions: []*s3.Transition{
+						{Days: aws.Int64(1), StorageClass: aws.String("GLACIER")},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal("PutBucketLifecycle with transition err:", err)
+	}
+	defer sc.Client.DeleteBucketLifecycle(&s3.DeleteBucketLifecycleInput{
+		Bucket: aws.String(TEST_LC_BUCKET),
+	})
+	time.Sleep(time.Second * 2)
+
+	//Object should be transitioned to GLACIER after lc.
+	runLc(t)
+	head, err := sc.Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(TEST_LC_BUCKET),
+		Key:    aws.String(TEST_KEY),
+	})
+	if err != nil {
+		t.Fatal("HeadObject after lc err:", err)
+	}
+	if head.StorageClass == nil || *head.StorageClass != "GLACIER" {
+		t.Fatal("HeadObject after lc err: storage class is:", aws.StringValue(head.StorageClass),
+			", but should be: GLACIER")
+	}
+	t.Log("Transition to GLACIER Success!")
+}
+
+func Test_LifeCycleNoncurrentVersion(t *testing.T) {
+	sc := NewS3()
+	err := sc.MakeBucket(TEST_LC_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+	defer removeAllVersions(t, sc, TEST_LC_BUCKET)
+	err = sc.PutBucketVersioning(TEST_LC_BUCKET, "Enabled")
+	if err != nil {
+		t.Fatal("PutBucketVersioning err:", err)
+	}
+	//The first version of TEST_KEY becomes noncurrent, the deleted key is left
+	//with a delete marker and a noncurrent version.
+	for i := 0; i < 2; i++ {
+		err = sc.PutObject(TEST_LC_BUCKET, TEST_KEY, TEST_VALUE)
+		if err != nil {
+			t.Fatal("PutObject err:", err)
+		}
+	}
+	err = sc.PutObject(TEST_LC_BUCKET, "deleted", TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutObject err:", err)
+	}
+	err = sc.DeleteObject(TEST_LC_BUCKET, "deleted")
+	if err != nil {
+		t.Fatal("DeleteObject err:", err)
+	}
+
+	_, err = sc.Client.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(TEST_LC_BUCKET),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{
+			Rules: []*s3.LifecycleRule{
+				{
+					Expiration: &s3.LifecycleExpiration{
+						ExpiredObjectDeleteMarker: aws.Bool(true),
+					},
+					Filter: &s3.LifecycleRuleFilter{Prefix: aws.String("")},
+					ID:     aws.String("noncurrent"),
+					NoncurrentVersionExpiration: &s3.NoncurrentVersionExpiration{
+						NoncurrentDays: aws.Int64(1),
+					},
+					Status: aws.String("Enabled"),
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal("PutBucketLifecycle with noncurrent version expiration err:", err)
+	}
+	defer sc.Client.DeleteBucketLifecycle(&s3.DeleteBucketLifecycleInput{
+		Bucket: aws.String(TEST_LC_BUCKET),
+	})
+	time.Sleep(time.Second * 2)
+
+	//Only the current version of TEST_KEY should be left after lc.
+	runLc(t)
+	out, err := sc.Client.ListObjectVersions(&s3.ListObjectVersionsInput{
+		Bucket: aws.String(TEST_LC_BUCKET),
+	})
+	if err != nil {
+		t.Fatal("ListObjectVersions err:", err)
+	}
+	if len(out.Versions) != 1 || *out.Versions[0].Key != TEST_KEY || !*out.Versions[0].IsLatest {
+		t.Fatal("Noncurrent versions should be expired after lc:", out.Versions)
+	}
+	if len(out.DeleteMarkers) != 0 {
+		t.Fatal("Expired delete markers should be removed after lc:", out.DeleteMarkers)
+	}
+	t.Log("NoncurrentVersionExpiration and ExpiredObjectDeleteMarker Success!")
+}
+
+func Test_LifeCycleAbortUpload(t *testing.T) {
+	sc := NewS3()
+	err := sc.MakeBucket(TEST_LC_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+	defer sc.DeleteBucket(TEST_LC_BUCKET)
+	abortedId, err := sc.CreateMultiPartUpload(TEST_LC_BUCKET, "tmp/"+TEST_KEY, "STANDARD")
+	if err != nil {
+		t.Fatal("CreateMultiPartUpload err:", err)
+	}
+	keptId, err := sc.CreateMultiPartUpload(TEST_LC_BUCKET, TEST_KEY, "STANDARD")
+	if err != nil {
+		t.Fatal("CreateMultiPartUpload err:", err)
+	}
+	defer sc.AbortMultiPartUpload(TEST_LC_BUCKET, TEST_KEY, keptId)
+
+	_, err = sc.Client.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(TEST_LC_BUCKET),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{
+			Rules: []*s3.LifecycleRule{
+				{
+					AbortIncompleteMultipartUpload: &s3.AbortIncompleteMultipartUpload{
+						DaysAfterInitiation: aws.Int64(1),
+					},
+					Filter: &s3.LifecycleRuleFilter{Prefix: aws.String("tmp/")},
+					ID:     aws.String("abort"),
+					Status: aws.String("Enabled"),
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal("PutBucketLifecycle with abort upload err:", err)
+	}
+	defer sc.Client.DeleteBucketLifecycle(&s3.DeleteBucketLifecycleInput{
+		Bucket: aws.String(TEST_LC_BUCKET),
+	})
+	time.Sleep(time.Second * 2)
+
+	//Only the upload out of prefix tmp/ should be left after lc.
+	runLc(t)
+	out, err := sc.Client.ListMultipartUploads(&s3.ListMultipartUploadsInput{
+		Bucket: aws.String(TEST_LC_BUCKET),
+	})
+	if err != nil {
+		t.Fatal("ListMultipartUploads err:", err)
+	}
+	if len(out.Uploads) != 1 || *out.Uploads[0].UploadId != keptId {
+		sc.AbortMultiPartUpload(TEST_LC_BUCKET, "tmp/"+TEST_KEY, abortedId)
+		t.Fatal("Upload", abortedId, "should be aborted after lc:", out.Uploads)
+	}
+	t.Log("AbortIncompleteMultipartUpload Success!")
+}
+
 func Test_LC_End(t *testing.T) {
 	sc := NewS3()
 	err := sc.DeleteObject(TEST_BUCKET, TEST_KEY)
@@ -0,0 +1,110 @@
+package _go
+
+import (
+	"testing"
+
+	"github.com/journeymidnight/aws-sdk-go/aws"
+	"github.com/journeymidnight/aws-sdk-go/service/s3"
+	. "github.com/journeymidnight/yig/test/go/lib"
+)
+
+func newTopicConfiguration(id string, events []string, prefix string) *s3.TopicConfiguration {
+	return &s3.TopicConfiguration{
+		Id:       aws.String(id),
+		TopicArn: aws.String("arn:aws:sns:cn-bj-1:123456789012:yig-test"),
+		Events:   aws.StringSlice(events),
+		Filter: &s3.NotificationConfigurationFilter{
+			Key: &s3.KeyFilter{
+				FilterRules: []*s3.FilterRule{
+					{Name: aws.String("prefix"), Value: aws.String(prefix)},
+				},
+			},
+		},
+	}
+}
+
+func Test_BucketNotification(t *testing.T) {
+	sc := NewS3()
+	defer sc.CleanEnv()
+	sc.CleanEnv()
+	err := sc.MakeBucket(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+
+	config, err := sc.GetBucketNotification(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("GetBucketNotification err:", err)
+	}
+	if len(config.TopicConfigurations) != 0 || len(config.QueueConfigurations) != 0 {
+		t.Fatal("Notification should be empty:", config)
+	}
+
+	err = sc.PutBucketNotification(TEST_BUCKET, &s3.NotificationConfiguration{
+		TopicConfigurations: []*s3.TopicConfiguration{
+			newTopicConfiguration("created", []string{"s3:ObjectCreated:*"}, "images/"),
+			newTopicConfiguration("removed", []string{"s3:ObjectRemoved:Delete",
+				"s3:ObjectRestore:*"}, "logs/"),
+		},
+	})
+	if err != nil {
+		t.Fatal("PutBucketNotification err:", err)
+	}
+	config, err = sc.GetBucketNotification(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("GetBucketNotification err:", err)
+	}
+	if len(config.TopicConfigurations) != 2 ||
+		aws.StringValue(config.TopicConfigurations[0].Id) != "created" ||
+		aws.StringValue(config.TopicConfigurations[1].Events[1]) != "s3:ObjectRestore:*" {
+		t.Fatal("Notification is not correct:", config)
+	}
+
+	// objects are still written with notifications configured
+	err = sc.PutObject(TEST_BUCKET, "images/"+TEST_KEY, TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutObject err:", err)
+	}
+	err = sc.DeleteObject(TEST_BUCKET, "images/"+TEST_KEY)
+	if err != nil {
+		t.Fatal("DeleteObject err:", err)
+	}
+
+	invalidCases := []*s3.TopicConfiguration{
+		newTopicConfiguration("invalid", []string{"s3:ObjectAccessed:*"}, ""),
+		newTopicConfiguration("invalid", []string{}, ""),
+		{
+			Id:       aws.String("invalid"),
+			TopicArn: aws.String("arn:aws:sns:cn-bj-1:123456789012:yig-test"),
+			Events:   aws.StringSlice([]string{"s3:ObjectCreated:*"}),
+			Filter: &s3.NotificationConfigurationFilter{
+				Key: &s3.KeyFilter{
+					FilterRules: []*s3.FilterRule{
+						{Name: aws.String("middle"), Value: aws.String("value")},
+					},
+				},
+			},
+		},
+	}
+	for _, c := range invalidCases {
+		err = sc.PutBucketNotification(TEST_BUCKET, &s3.NotificationConfiguration{
+			TopicConfigurations: []*s3.TopicConfiguration{c},
+		})
+		if err == nil {
+			t.Fatal("PutBucketNotification should fail for:", c)
+		}
+	}
+
+	// empty configuration disables notifications
+	err = sc.PutBucketNotification(TEST_BUCKET, &s3.NotificationConfiguration{})
+	if err != nil {
+		t.Fatal("PutBucketNotification err:", err)
+	}
+	config, err = sc.GetBucketNotification(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("GetBucketNotification err:", err)
+	}
+	if len(config.TopicConfigurations) != 0 {
+		t.Fatal("Notification is not disabled:", config)
+	}
+}
@@ -0,0 +1,202 @@
+package _go
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/journeymidnight/aws-sdk-go/aws"
+	"github.com/journeymidnight/aws-sdk-go/service/s3"
+	. "github.com/journeymidnight/yig/test/go/lib"
+)
+
+const TEST_OBJECT_LOCK_BUCKET = "mybucket-objectlock"
+
+// legal holds are turned off and governance mode bypassed before removing
+func removeLockedVersions(t *testing.T, sc *S3Client, bucketName string) {
+	out, err := sc.Client.ListObjectVersions(&s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		return
+	}
+	for _, v := range out.Versions {
+		sc.PutObjectLegalHold(bucketName, *v.Key, *v.VersionId, s3.ObjectLockLegalHoldStatusOff)
+		err = sc.DeleteObjectVersion(bucketName, *v.Key, *v.VersionId, true)
+		if err != nil {
+			t.Log("DeleteObject version err:", err)
+		}
+	}
+	for _, m := range out.DeleteMarkers {
+		err = sc.DeleteObjectVersion(bucketName, *m.Key, *m.VersionId, false)
+		if err != nil {
+			t.Log("DeleteObject delete marker err:", err)
+		}
+	}
+	sc.DeleteBucket(bucketName)
+}
+
+func Test_ObjectLock(t *testing.T) {
+	sc := NewS3()
+	defer removeLockedVersions(t, sc, TEST_OBJECT_LOCK_BUCKET)
+	removeLockedVersions(t, sc, TEST_OBJECT_LOCK_BUCKET)
+	err := sc.MakeObjectLockBucket(TEST_OBJECT_LOCK_BUCKET)
+	if err != nil {
+		t.Fatal("MakeObjectLockBucket err:", err)
+	}
+
+	err = sc.PutBucketVersioning(TEST_OBJECT_LOCK_BUCKET, "Suspended")
+	if err == nil {
+		t.Fatal("Versioning of object lock bucket should not be suspended")
+	}
+
+	config := &s3.ObjectLockConfiguration{
+		ObjectLockEnabled: aws.String(s3.ObjectLockEnabledEnabled),
+		Rule: &s3.ObjectLockRule{
+			DefaultRetention: &s3.DefaultRetention{
+				Mode: aws.String(s3.ObjectLockRetentionModeGovernance),
+				Days: aws.Int64(1),
+			},
+		},
+	}
+	err = sc.PutObjectLockConfiguration(TEST_OBJECT_LOCK_BUCKET, config)
+	if err != nil {
+		t.Fatal("PutObjectLockConfiguration err:", err)
+	}
+	out, err := sc.GetObjectLockConfiguration(TEST_OBJECT_LOCK_BUCKET)
+	if err != nil {
+		t.Fatal("GetObjectLockConfiguration err:", err)
+	}
+	if aws.StringValue(out.ObjectLockEnabled) != s3.ObjectLockEnabledEnabled ||
+		aws.Int64Value(out.Rule.DefaultRetention.Days) != 1 {
+		t.Fatal("GetObjectLockConfiguration returns", out)
+	}
+
+	// default retention
+	put, err := sc.Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(TEST_OBJECT_LOCK_BUCKET),
+		Key:    aws.String(TEST_KEY),
+		Body:   strings.NewReader(TEST_VALUE),
+	})
+	if err != nil {
+		t.Fatal("PutObject err:", err)
+	}
+	versionId := aws.StringValue(put.VersionId)
+	retention, err := sc.GetObjectRetention(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, versionId)
+	if err != nil {
+		t.Fatal("GetObjectRetention err:", err)
+	}
+	if aws.StringValue(retention.Mode) != s3.ObjectLockRetentionModeGovernance {
+		t.Fatal("Default retention is not applied:", retention)
+	}
+	err = sc.DeleteObjectVersion(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, versionId, false)
+	if err == nil {
+		t.Fatal("DeleteObject of version in governance mode should fail")
+	}
+	// delete markers are always allowed
+	err = sc.DeleteObject(TEST_OBJECT_LOCK_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("DeleteObject err:", err)
+	}
+
+	// legal hold is not bypassed
+	err = sc.PutObjectLegalHold(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, versionId,
+		s3.ObjectLockLegalHoldStatusOn)
+	if err != nil {
+		t.Fatal("PutObjectLegalHold err:", err)
+	}
+	status, err := sc.GetObjectLegalHold(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, versionId)
+	if err != nil || status != s3.ObjectLockLegalHoldStatusOn {
+		t.Fatal("GetObjectLegalHold returns", status, "err:", err)
+	}
+	err = sc.DeleteObjectVersion(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, versionId, true)
+	if err == nil {
+		t.Fatal("DeleteObject of version with legal hold should fail")
+	}
+	err = sc.PutObjectLegalHold(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, versionId,
+		s3.ObjectLockLegalHoldStatusOff)
+	if err != nil {
+		t.Fatal("PutObjectLegalHold err:", err)
+	}
+	err = sc.DeleteObjectVersion(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, versionId, true)
+	if err != nil {
+		t.Fatal("DeleteObject bypassing governance mode err:", err)
+	}
+
+	// compliance mode could only be extended
+	retainUntil := time.Now().Add(3 * time.Second)
+	versionId, err = sc.PutObjectWithRetention(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, TEST_VALUE,
+		s3.ObjectLockRetentionModeCompliance, retainUntil)
+	if err != nil {
+		t.Fatal("PutObjectWithRetention err:", err)
+	}
+	err = sc.PutObjectRetention(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, versionId,
+		s3.ObjectLockRetentionModeGovernance, retainUntil, true)
+	if err == nil {
+		t.Fatal("PutObjectRetention should not change compliance mode")
+	}
+	err = sc.DeleteObjectVersion(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, versionId, true)
+	if err == nil {
+		t.Fatal("DeleteObject of version in compliance mode should fail")
+	}
+	time.Sleep(time.Until(retainUntil) + time.Second)
+	err = sc.DeleteObjectVersion(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, versionId, false)
+	if err != nil {
+		t.Fatal("DeleteObject of expired version err:", err)
+	}
+}
+
+// Copying a locked version to itself adds a new version with the requested
+// lock, the locked version is never rewritten in place
+func Test_ObjectLock_CopyToItself(t *testing.T) {
+	sc := NewS3()
+	defer removeLockedVersions(t, sc, TEST_OBJECT_LOCK_BUCKET)
+	removeLockedVersions(t, sc, TEST_OBJECT_LOCK_BUCKET)
+	err := sc.MakeObjectLockBucket(TEST_OBJECT_LOCK_BUCKET)
+	if err != nil {
+		t.Fatal("MakeObjectLockBucket err:", err)
+	}
+
+	retainUntil := time.Now().Add(time.Hour)
+	versionId, err := sc.PutObjectWithRetention(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, TEST_VALUE,
+		s3.ObjectLockRetentionModeGovernance, retainUntil)
+	if err != nil {
+		t.Fatal("PutObjectWithRetention err:", err)
+	}
+	out, err := sc.Client.CopyObject(&s3.CopyObjectInput{
+		Bucket:                    aws.String(TEST_OBJECT_LOCK_BUCKET),
+		Key:                       aws.String(TEST_KEY),
+		CopySource:                aws.String(TEST_OBJECT_LOCK_BUCKET + "/" + TEST_KEY + "?versionId=" + versionId),
+		MetadataDirective:         aws.String("REPLACE"),
+		Metadata:                  map[string]*string{"Color": aws.String("red")},
+		ObjectLockMode:            aws.String(s3.ObjectLockRetentionModeGovernance),
+		ObjectLockRetainUntilDate: aws.Time(retainUntil.Add(time.Hour)),
+	})
+	if err != nil {
+		t.Fatal("CopyObject err:", err)
+	}
+	newVersionId := aws.StringValue(out.VersionId)
+	if newVersionId == "" || newVersionId == versionId {
+		t.Fatal("CopyObject should add a new version, got", newVersionId)
+	}
+
+	retention, err := sc.GetObjectRetention(TEST_OBJECT_LOCK_BUCKET, TEST_KEY, newVersionId)
+	if err != nil {
+		t.Fatal("GetObjectRetention err:", err)
+	}
+	if !aws.TimeValue(retention.RetainUntilDate).After(retainUntil.Add(time.Minute)) {
+		t.Fatal("Lock of copy is not applied:", retention)
+	}
+	head, err := sc.Client.HeadObject(&s3.HeadObjectInput{
+		Bucket:    aws.String(TEST_OBJECT_LOCK_BUCKET),
+		Key:       aws.String(TEST_KEY),
+		VersionId: aws.String(versionId),
+	})
+	if err != nil {
+		t.Fatal("HeadObject err:", err)
+	}
+	if len(head.Metadata) != 0 ||
+		aws.TimeValue(head.ObjectLockRetainUntilDate).After(retainUntil.Add(time.Minute)) {
+		t.Fatal("Locked version is changed:", head)
+	}
+}
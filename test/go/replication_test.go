@@ -0,0 +1,385 @@
+package _go
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/journeymidnight/aws-sdk-go/aws"
+	"github.com/journeymidnight/aws-sdk-go/service/s3"
+	. "github.com/journeymidnight/yig/test/go/lib"
+)
+
+const (
+	TEST_REPLICATION_DESTINATION = "arn:aws:s3:self::" + TEST_COPY_BUCKET
+	// replicate daemon scans every 10s, and retries a failed version in 1 minute
+	TEST_REPLICATION_SCAN_WAIT = 30 * time.Second
+	TEST_REPLICATION_TIMEOUT   = 5 * time.Minute
+)
+
+func newReplicationRule(id, prefix, destination string) *s3.ReplicationRule {
+	return &s3.ReplicationRule{
+		ID:     aws.String(id),
+		Status: aws.String("Enabled"),
+		Filter: &s3.ReplicationRuleFilter{
+			Prefix: aws.String(prefix),
+		},
+		DeleteMarkerReplication: &s3.DeleteMarkerReplication{
+			Status: aws.String("Enabled"),
+		},
+		Destination: &s3.Destination{
+			Bucket: aws.String(destination),
+		},
+	}
+}
+
+// versions are kept by DeleteObject of versioned buckets
+func removeAllVersions(t *testing.T, sc *S3Client, bucketName string) {
+	out, err := sc.Client.ListObjectVersions(&s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		return
+	}
+	for _, v := range out.Versions {
+		_, err = sc.Client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket:    aws.String(bucketName),
+			Key:       v.Key,
+			VersionId: v.VersionId,
+		})
+		if err != nil {
+			t.Log("DeleteObject version err:", err)
+		}
+	}
+	for _, m := range out.DeleteMarkers {
+		_, err = sc.Client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket:    aws.String(bucketName),
+			Key:       m.Key,
+			VersionId: m.VersionId,
+		})
+		if err != nil {
+			t.Log("DeleteObject delete marker err:", err)
+		}
+	}
+	sc.DeleteBucket(bucketName)
+}
+
+func Test_BucketReplication(t *testing.T) {
+	sc := NewS3()
+	defer removeAllVersions(t, sc, TEST_BUCKET)
+	defer removeAllVersions(t, sc, TEST_COPY_BUCKET)
+	removeAllVersions(t, sc, TEST_BUCKET)
+	removeAllVersions(t, sc, TEST_COPY_BUCKET)
+	for _, bucket := range []string{TEST_BUCKET, TEST_COPY_BUCKET} {
+		err := sc.MakeBucket(bucket)
+		if err != nil {
+			t.Fatal("MakeBucket err:", err)
+		}
+	}
+
+	_, err := sc.GetBucketReplication(TEST_BUCKET)
+	if err == nil {
+		t.Fatal("GetBucketReplication should fail without replication configured")
+	}
+
+	config := &s3.ReplicationConfiguration{
+		Role: aws.String("arn:aws:iam::123456789012:role/yig-test"),
+		Rules: []*s3.ReplicationRule{
+			newReplicationRule("backup", "backup/", TEST_REPLICATION_DESTINATION),
+		},
+	}
+	// versioning is required
+	err = sc.PutBucketReplication(TEST_BUCKET, config)
+	if err == nil {
+		t.Fatal("PutBucketReplication should fail without versioning")
+	}
+	err = sc.PutBucketVersioning(TEST_BUCKET, "Enabled")
+	if err != nil {
+		t.Fatal("PutBucketVersioning err:", err)
+	}
+	err = sc.PutBucketReplication(TEST_BUCKET, config)
+	if err != nil {
+		t.Fatal("PutBucketReplication err:", err)
+	}
+	config, err = sc.GetBucketReplication(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("GetBucketReplication err:", err)
+	}
+	if len(config.Rules) != 1 || aws.StringValue(config.Rules[0].ID) != "backup" ||
+		aws.StringValue(config.Rules[0].Destination.Bucket) != TEST_REPLICATION_DESTINATION {
+		t.Fatal("Replication is not correct:", config)
+	}
+
+	// versioning can't be suspended with replication configured
+	err = sc.PutBucketVersioning(TEST_BUCKET, "Suspended")
+	if err == nil {
+		t.Fatal("PutBucketVersioning should fail with replication configured")
+	}
+
+	// objects matching rules are PENDING until replicated
+	err = sc.PutObject(TEST_BUCKET, "backup/"+TEST_KEY, TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutObject err:", err)
+	}
+	status, err := sc.GetReplicationStatus(TEST_BUCKET, "backup/"+TEST_KEY)
+	if err != nil {
+		t.Fatal("HeadObject err:", err)
+	}
+	if status != "PENDING" && status != "COMPLETED" {
+		t.Fatal("Replication status is not correct:", status)
+	}
+	err = sc.PutObject(TEST_BUCKET, TEST_KEY, TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutObject err:", err)
+	}
+	status, err = sc.GetReplicationStatus(TEST_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("HeadObject err:", err)
+	}
+	if status != "" {
+		t.Fatal("Object should not be replicated:", status)
+	}
+
+	invalidCases := []*s3.ReplicationRule{
+		newReplicationRule("invalid", "", "arn:aws:s3:unknown::"+TEST_COPY_BUCKET),
+		newReplicationRule("invalid", "", TEST_COPY_BUCKET),
+		{
+			ID:          aws.String("invalid"),
+			Status:      aws.String("Unknown"),
+			Prefix:      aws.String(""),
+			Destination: &s3.Destination{Bucket: aws.String(TEST_REPLICATION_DESTINATION)},
+		},
+	}
+	for _, c := range invalidCases {
+		err = sc.PutBucketReplication(TEST_BUCKET, &s3.ReplicationConfiguration{
+			Role:  aws.String("arn:aws:iam::123456789012:role/yig-test"),
+			Rules: []*s3.ReplicationRule{c},
+		})
+		if err == nil {
+			t.Fatal("PutBucketReplication should fail for:", c)
+		}
+	}
+
+	err = sc.DeleteBucketReplication(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("DeleteBucketReplication err:", err)
+	}
+	_, err = sc.GetBucketReplication(TEST_BUCKET)
+	if err == nil {
+		t.Fatal("GetBucketReplication should fail after DeleteBucketReplication")
+	}
+}
+
+// returns the final replication status of version, or the last status seen
+// if it's still PENDING after timeout
+func waitReplication(sc *S3Client, bucketName, key, versionId string,
+	timeout time.Duration) (status string, err error) {
+
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err = sc.GetVersionReplicationStatus(bucketName, key, versionId)
+		if err != nil || status != "PENDING" || time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// An older version failed to replicate must not overwrite a newer one in
+// destination when it's retried
+func Test_BucketReplication_RetryOrder(t *testing.T) {
+	sc := NewS3()
+	defer removeAllVersions(t, sc, TEST_BUCKET)
+	defer removeAllVersions(t, sc, TEST_COPY_BUCKET)
+	removeAllVersions(t, sc, TEST_BUCKET)
+	removeAllVersions(t, sc, TEST_COPY_BUCKET)
+	for _, bucket := range []string{TEST_BUCKET, TEST_COPY_BUCKET} {
+		err := sc.MakeBucket(bucket)
+		if err != nil {
+			t.Fatal("MakeBucket err:", err)
+		}
+	}
+	err := sc.PutBucketVersioning(TEST_BUCKET, "Enabled")
+	if err != nil {
+		t.Fatal("PutBucketVersioning err:", err)
+	}
+	err = sc.PutBucketReplication(TEST_BUCKET, &s3.ReplicationConfiguration{
+		Role: aws.String("arn:aws:iam::123456789012:role/yig-test"),
+		Rules: []*s3.ReplicationRule{
+			newReplicationRule("all", "", TEST_REPLICATION_DESTINATION),
+		},
+	})
+	if err != nil {
+		t.Fatal("PutBucketReplication err:", err)
+	}
+
+	probe, err := sc.PutObjectVersion(TEST_BUCKET, "probe", TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutObjectVersion err:", err)
+	}
+	status, err := waitReplication(sc, TEST_BUCKET, "probe", probe, TEST_REPLICATION_SCAN_WAIT)
+	if err != nil {
+		t.Fatal("HeadObject err:", err)
+	}
+	if status != "COMPLETED" {
+		t.Skip("replicate daemon is not running, status:", status)
+	}
+
+	// the older version fails since destination is removed
+	removeAllVersions(t, sc, TEST_COPY_BUCKET)
+	older, err := sc.PutObjectVersion(TEST_BUCKET, TEST_KEY, "older")
+	if err != nil {
+		t.Fatal("PutObjectVersion err:", err)
+	}
+	time.Sleep(TEST_REPLICATION_SCAN_WAIT)
+	err = sc.MakeBucket(TEST_COPY_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+	newer, err := sc.PutObjectVersion(TEST_BUCKET, TEST_KEY, "newer")
+	if err != nil {
+		t.Fatal("PutObjectVersion err:", err)
+	}
+
+	for _, versionId := range []string{older, newer} {
+		status, err = waitReplication(sc, TEST_BUCKET, TEST_KEY, versionId, TEST_REPLICATION_TIMEOUT)
+		if err != nil {
+			t.Fatal("HeadObject err:", err)
+		}
+		if status != "COMPLETED" {
+			t.Fatal("Version", versionId, "is not replicated:", status)
+		}
+	}
+	value, err := sc.GetObject(TEST_COPY_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("GetObject err:", err)
+	}
+	if value != "newer" {
+		t.Fatal("Newer version is overwritten by retried older version:", value)
+	}
+}
+
+// Replicas are marked REPLICA in destination and never replicated back, even
+// if destination replicates to the source bucket too. Data of SSE-S3 objects
+// is encrypted in destination as well.
+func Test_BucketReplication_Replica(t *testing.T) {
+	sc := NewS3()
+	defer removeAllVersions(t, sc, TEST_BUCKET)
+	defer removeAllVersions(t, sc, TEST_COPY_BUCKET)
+	removeAllVersions(t, sc, TEST_BUCKET)
+	removeAllVersions(t, sc, TEST_COPY_BUCKET)
+	pairs := map[string]string{
+		TEST_BUCKET:      TEST_REPLICATION_DESTINATION,
+		TEST_COPY_BUCKET: "arn:aws:s3:self::" + TEST_BUCKET,
+	}
+	for bucket, destination := range pairs {
+		err := sc.MakeBucket(bucket)
+		if err != nil {
+			t.Fatal("MakeBucket err:", err)
+		}
+		err = sc.PutBucketVersioning(bucket, "Enabled")
+		if err != nil {
+			t.Fatal("PutBucketVersioning err:", err)
+		}
+		err = sc.PutBucketReplication(bucket, &s3.ReplicationConfiguration{
+			Role: aws.String("arn:aws:iam::123456789012:role/yig-test"),
+			Rules: []*s3.ReplicationRule{
+				newReplicationRule("all", "", destination),
+			},
+		})
+		if err != nil {
+			t.Fatal("PutBucketReplication err:", err)
+		}
+	}
+
+	out, err := sc.Client.PutObject(&s3.PutObjectInput{
+		Bucket:               aws.String(TEST_BUCKET),
+		Key:                  aws.String(TEST_KEY),
+		Body:                 bytes.NewReader([]byte(TEST_VALUE)),
+		ServerSideEncryption: aws.String("AES256"),
+	})
+	if err != nil {
+		t.Fatal("PutObject err:", err)
+	}
+	status, err := waitReplication(sc, TEST_BUCKET, TEST_KEY, aws.StringValue(out.VersionId),
+		TEST_REPLICATION_SCAN_WAIT)
+	if err != nil {
+		t.Fatal("HeadObject err:", err)
+	}
+	if status != "COMPLETED" {
+		t.Skip("replicate daemon is not running, status:", status)
+	}
+
+	head, err := sc.Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(TEST_COPY_BUCKET),
+		Key:    aws.String(TEST_KEY),
+	})
+	if err != nil {
+		t.Fatal("HeadObject err:", err)
+	}
+	if aws.StringValue(head.ReplicationStatus) != "REPLICA" {
+		t.Fatal("Replica is not marked:", aws.StringValue(head.ReplicationStatus))
+	}
+	if aws.StringValue(head.ServerSideEncryption) != "AES256" {
+		t.Fatal("Replica is not encrypted:", aws.StringValue(head.ServerSideEncryption))
+	}
+
+	time.Sleep(TEST_REPLICATION_SCAN_WAIT)
+	versions, err := sc.Client.ListObjectVersions(&s3.ListObjectVersionsInput{
+		Bucket: aws.String(TEST_BUCKET),
+	})
+	if err != nil {
+		t.Fatal("ListObjectVersions err:", err)
+	}
+	if len(versions.Versions) != 1 {
+		t.Fatal("Replica is replicated back to source:", len(versions.Versions))
+	}
+}
+
+// Only yig_replicate could mark objects as REPLICA, the header sent by an
+// ordinary user must not keep the object from being replicated
+func Test_BucketReplication_ReplicaHeaderIgnored(t *testing.T) {
+	sc := NewS3()
+	defer removeAllVersions(t, sc, TEST_BUCKET)
+	defer removeAllVersions(t, sc, TEST_COPY_BUCKET)
+	removeAllVersions(t, sc, TEST_BUCKET)
+	removeAllVersions(t, sc, TEST_COPY_BUCKET)
+	for _, bucket := range []string{TEST_BUCKET, TEST_COPY_BUCKET} {
+		err := sc.MakeBucket(bucket)
+		if err != nil {
+			t.Fatal("MakeBucket err:", err)
+		}
+		err = sc.PutBucketVersioning(bucket, "Enabled")
+		if err != nil {
+			t.Fatal("PutBucketVersioning err:", err)
+		}
+	}
+	err := sc.PutBucketReplication(TEST_BUCKET, &s3.ReplicationConfiguration{
+		Role: aws.String("arn:aws:iam::123456789012:role/yig-test"),
+		Rules: []*s3.ReplicationRule{
+			newReplicationRule("all", "", TEST_REPLICATION_DESTINATION),
+		},
+	})
+	if err != nil {
+		t.Fatal("PutBucketReplication err:", err)
+	}
+
+	req, out := sc.Client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(TEST_BUCKET),
+		Key:    aws.String(TEST_KEY),
+		Body:   bytes.NewReader([]byte(TEST_VALUE)),
+	})
+	req.HTTPRequest.Header.Set("X-Amz-Replication-Status", "REPLICA")
+	err = req.Send()
+	if err != nil {
+		t.Fatal("PutObject err:", err)
+	}
+	status, err := sc.GetVersionReplicationStatus(TEST_BUCKET, TEST_KEY,
+		aws.StringValue(out.VersionId))
+	if err != nil {
+		t.Fatal("HeadObject err:", err)
+	}
+	if status != "PENDING" && status != "COMPLETED" {
+		t.Fatal("Object of ordinary user is not replicated:", status)
+	}
+}
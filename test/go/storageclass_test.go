@@ -20,6 +20,7 @@ func Test_PutObject_With_StorageClass(t *testing.T) {
 	testCases := []TestStorageClassCase{
 		{TEST_BUCKET, TEST_KEY, []byte(TEST_VALUE), s3.ObjectStorageClassStandard, s3.ObjectStorageClassStandard},
 		{TEST_BUCKET, TEST_KEY, []byte(TEST_VALUE), s3.ObjectStorageClassStandardIa, s3.ObjectStorageClassStandardIa},
+		{TEST_BUCKET, TEST_KEY, []byte(TEST_VALUE), s3.ObjectStorageClassIntelligentTiering, s3.ObjectStorageClassIntelligentTiering},
 		{TEST_BUCKET, TEST_KEY, []byte(TEST_VALUE), s3.ObjectStorageClassGlacier, s3.ObjectStorageClassGlacier},
 	}
 	sc := NewS3()
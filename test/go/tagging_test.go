@@ -0,0 +1,246 @@
+package _go
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/journeymidnight/aws-sdk-go/service/s3"
+	. "github.com/journeymidnight/yig/test/go/lib"
+)
+
+const (
+	TEST_TAGGING_COPY_KEY          = "testtaggingcopy"
+	TEST_TAGGING_VERSIONING_BUCKET = "mybucket-taggingversions"
+)
+
+func Test_ObjectTagging(t *testing.T) {
+	sc := NewS3()
+	defer sc.CleanEnv()
+	sc.CleanEnv()
+	err := sc.MakeBucket(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+
+	err = sc.PutObjectWithTagging(TEST_BUCKET, TEST_KEY, TEST_VALUE, "project=yig&env=test")
+	if err != nil {
+		t.Fatal("PutObjectWithTagging err:", err)
+	}
+	out, err := sc.GetObjectOutPut(TEST_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("GetObjectOutPut err:", err)
+	}
+	if out.TagCount == nil || *out.TagCount != 2 {
+		t.Fatal("x-amz-tagging-count is not correct:", out.TagCount)
+	}
+	tags, err := sc.GetObjectTagging(TEST_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("GetObjectTagging err:", err)
+	}
+	if !reflect.DeepEqual(tags, map[string]string{"project": "yig", "env": "test"}) {
+		t.Fatal("Tags are not correct:", tags)
+	}
+
+	err = sc.PutObjectTagging(TEST_BUCKET, TEST_KEY, map[string]string{"owner": "someone"})
+	if err != nil {
+		t.Fatal("PutObjectTagging err:", err)
+	}
+	tags, err = sc.GetObjectTagging(TEST_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("GetObjectTagging err:", err)
+	}
+	if !reflect.DeepEqual(tags, map[string]string{"owner": "someone"}) {
+		t.Fatal("Tags are not replaced:", tags)
+	}
+
+	// tags are copied by default, or replaced by header x-amz-tagging
+	source := TEST_BUCKET + "/" + TEST_KEY
+	err = sc.CopyObjectWithTagging(TEST_BUCKET, TEST_TAGGING_COPY_KEY, source, s3.TaggingDirectiveCopy, "")
+	if err != nil {
+		t.Fatal("CopyObjectWithTagging err:", err)
+	}
+	tags, err = sc.GetObjectTagging(TEST_BUCKET, TEST_TAGGING_COPY_KEY)
+	if err != nil {
+		t.Fatal("GetObjectTagging err:", err)
+	}
+	if !reflect.DeepEqual(tags, map[string]string{"owner": "someone"}) {
+		t.Fatal("Tags are not copied:", tags)
+	}
+	err = sc.CopyObjectWithTagging(TEST_BUCKET, TEST_TAGGING_COPY_KEY, source, s3.TaggingDirectiveReplace, "copied=true")
+	if err != nil {
+		t.Fatal("CopyObjectWithTagging err:", err)
+	}
+	tags, err = sc.GetObjectTagging(TEST_BUCKET, TEST_TAGGING_COPY_KEY)
+	if err != nil {
+		t.Fatal("GetObjectTagging err:", err)
+	}
+	if !reflect.DeepEqual(tags, map[string]string{"copied": "true"}) {
+		t.Fatal("Tags are not replaced on copy:", tags)
+	}
+
+	err = sc.DeleteObjectTagging(TEST_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("DeleteObjectTagging err:", err)
+	}
+	tags, err = sc.GetObjectTagging(TEST_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("GetObjectTagging err:", err)
+	}
+	if len(tags) != 0 {
+		t.Fatal("Tags are not deleted:", tags)
+	}
+	out, err = sc.GetObjectOutPut(TEST_BUCKET, TEST_KEY)
+	if err != nil {
+		t.Fatal("GetObjectOutPut err:", err)
+	}
+	if out.TagCount != nil {
+		t.Fatal("x-amz-tagging-count should be absent:", *out.TagCount)
+	}
+	sc.DeleteObject(TEST_BUCKET, TEST_TAGGING_COPY_KEY)
+}
+
+// replacing tags by copying a version to itself keeps tags of other versions
+func Test_ObjectTagging_Versions(t *testing.T) {
+	sc := NewS3()
+	defer removeLockedVersions(t, sc, TEST_TAGGING_VERSIONING_BUCKET)
+	removeLockedVersions(t, sc, TEST_TAGGING_VERSIONING_BUCKET)
+	err := sc.MakeBucket(TEST_TAGGING_VERSIONING_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+	err = sc.PutBucketVersioning(TEST_TAGGING_VERSIONING_BUCKET, s3.BucketVersioningStatusEnabled)
+	if err != nil {
+		t.Fatal("PutBucketVersioning err:", err)
+	}
+
+	oldVersion, err := sc.PutObjectVersionWithTagging(TEST_TAGGING_VERSIONING_BUCKET, TEST_KEY,
+		TEST_VALUE, "version=old")
+	if err != nil {
+		t.Fatal("PutObjectVersionWithTagging err:", err)
+	}
+	newVersion, err := sc.PutObjectVersionWithTagging(TEST_TAGGING_VERSIONING_BUCKET, TEST_KEY,
+		TEST_VALUE, "version=new")
+	if err != nil {
+		t.Fatal("PutObjectVersionWithTagging err:", err)
+	}
+	source := TEST_TAGGING_VERSIONING_BUCKET + "/" + TEST_KEY + "?versionId=" + newVersion
+	err = sc.CopyObjectWithTagging(TEST_TAGGING_VERSIONING_BUCKET, TEST_KEY, source,
+		s3.TaggingDirectiveReplace, "version=copied")
+	if err != nil {
+		t.Fatal("CopyObjectWithTagging err:", err)
+	}
+
+	tags, err := sc.GetObjectVersionTagging(TEST_TAGGING_VERSIONING_BUCKET, TEST_KEY, newVersion)
+	if err != nil {
+		t.Fatal("GetObjectVersionTagging err:", err)
+	}
+	if !reflect.DeepEqual(tags, map[string]string{"version": "copied"}) {
+		t.Fatal("Tags of copied version are not replaced:", tags)
+	}
+	tags, err = sc.GetObjectVersionTagging(TEST_TAGGING_VERSIONING_BUCKET, TEST_KEY, oldVersion)
+	if err != nil {
+		t.Fatal("GetObjectVersionTagging err:", err)
+	}
+	if !reflect.DeepEqual(tags, map[string]string{"version": "old"}) {
+		t.Fatal("Tags of other version are changed:", tags)
+	}
+}
+
+func Test_ObjectTagging_Invalid(t *testing.T) {
+	sc := NewS3()
+	defer sc.CleanEnv()
+	sc.CleanEnv()
+	err := sc.MakeBucket(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+	err = sc.PutObject(TEST_BUCKET, TEST_KEY, TEST_VALUE)
+	if err != nil {
+		t.Fatal("PutObject err:", err)
+	}
+
+	tooMany := make(map[string]string)
+	var pairs []string
+	for i := 0; i < 11; i++ {
+		tooMany["key"+strconv.Itoa(i)] = "value"
+		pairs = append(pairs, "key"+strconv.Itoa(i)+"=value")
+	}
+	invalidCases := []map[string]string{
+		tooMany,
+		{"aws:reserved": "value"},
+		{"key": "invalid<value>"},
+		{strings.Repeat("k", 129): "value"},
+	}
+	for _, c := range invalidCases {
+		err = sc.PutObjectTagging(TEST_BUCKET, TEST_KEY, c)
+		if err == nil {
+			t.Fatal("PutObjectTagging should fail for:", c)
+		}
+	}
+	err = sc.PutObjectWithTagging(TEST_BUCKET, TEST_KEY, TEST_VALUE, strings.Join(pairs, "&"))
+	if err == nil {
+		t.Fatal("PutObjectWithTagging should fail with more than 10 tags")
+	}
+	err = sc.CopyObjectWithTagging(TEST_BUCKET, TEST_KEY, TEST_BUCKET+"/"+TEST_KEY,
+		"KEEP", "")
+	if err == nil {
+		t.Fatal("CopyObjectWithTagging should fail with invalid tagging directive")
+	}
+}
+
+func Test_BucketTagging(t *testing.T) {
+	sc := NewS3()
+	defer sc.CleanEnv()
+	sc.CleanEnv()
+	err := sc.MakeBucket(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("MakeBucket err:", err)
+	}
+
+	_, err = sc.GetBucketTagging(TEST_BUCKET)
+	if err == nil || !strings.Contains(err.Error(), "NoSuchTagSet") {
+		t.Fatal("GetBucketTagging should fail with NoSuchTagSet:", err)
+	}
+
+	err = sc.PutBucketTagging(TEST_BUCKET, map[string]string{"cost-center": "1001", "team": "storage"})
+	if err != nil {
+		t.Fatal("PutBucketTagging err:", err)
+	}
+	tags, err := sc.GetBucketTagging(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("GetBucketTagging err:", err)
+	}
+	if !reflect.DeepEqual(tags, map[string]string{"cost-center": "1001", "team": "storage"}) {
+		t.Fatal("Tags are not correct:", tags)
+	}
+
+	// bucket allows up to 50 tags, more than objects
+	many := make(map[string]string)
+	for i := 0; i < 50; i++ {
+		many["key"+strconv.Itoa(i)] = "value"
+	}
+	err = sc.PutBucketTagging(TEST_BUCKET, many)
+	if err != nil {
+		t.Fatal("PutBucketTagging with 50 tags err:", err)
+	}
+	many["key50"] = "value"
+	err = sc.PutBucketTagging(TEST_BUCKET, many)
+	if err == nil {
+		t.Fatal("PutBucketTagging should fail with more than 50 tags")
+	}
+	err = sc.PutBucketTagging(TEST_BUCKET, map[string]string{"aws:reserved": "value"})
+	if err == nil {
+		t.Fatal("PutBucketTagging should fail with reserved key")
+	}
+
+	err = sc.DeleteBucketTagging(TEST_BUCKET)
+	if err != nil {
+		t.Fatal("DeleteBucketTagging err:", err)
+	}
+	_, err = sc.GetBucketTagging(TEST_BUCKET)
+	if err == nil {
+		t.Fatal("Tags are not deleted")
+	}
+}
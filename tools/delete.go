@@ -38,41 +38,38 @@ func deleteFromCeph(index int) {
 			helper.Logger.Info("Shutting down...")
 			return
 		}
-		var (
-			p   *types.Part
-			err error
-		)
 		garbage := <-gcTaskQ
 		gcWaitgroup.Add(1)
-		if len(garbage.Parts) == 0 {
-			err = yigs[index].DataStorage[garbage.Location].
-				Remove(garbage.Pool, garbage.ObjectId)
-			if err != nil {
-				if strings.Contains(err.Error(), "ret=-2") {
-					helper.Logger.Error("delete failed:", garbage.BucketName, ":", garbage.ObjectName, ":",
-						garbage.Location, ":", garbage.Pool, ":", garbage.ObjectId, " error:", err)
-					goto release
-				}
-			} else {
-				helper.Logger.Info("delete succeeded", garbage.BucketName, ":", garbage.ObjectName, ":",
-					garbage.Location, ":", garbage.Pool, ":", garbage.ObjectId)
+		// blobs shared with other objects are only dereferenced, unshared ones
+		// are moved to table `recycle` together with removing the gc entry
+		unshared, err := yigs[index].MetaStorage.ReleaseGarbageCollection(garbage)
+		if err != nil {
+			helper.Logger.Error("release gc entry failed:", garbage.BucketName, ":", garbage.ObjectName,
+				" error:", err)
+			gcWaitgroup.Done()
+			continue
+		}
+		for _, blob := range unshared {
+			cluster, ok := yigs[index].DataStorage[blob.Location]
+			if !ok {
+				helper.Logger.Error("delete failed, no cluster:", blob.Location, ":", blob.Pool, ":", blob.ObjectId)
+				continue
 			}
-		} else {
-			for _, p = range garbage.Parts {
-				err = yigs[index].DataStorage[garbage.Location].
-					Remove(garbage.Pool, p.ObjectId)
-				if err != nil {
-					if strings.Contains(err.Error(), "ret=-2") {
-						helper.Logger.Error("failed delete part", garbage.Location, ":", garbage.Pool, ":", p.ObjectId, " error:", err)
-						goto release
-					}
-				} else {
-					helper.Logger.Info("success delete part", garbage.Location, ":", garbage.Pool, ":", p.ObjectId)
-				}
+			err = cluster.Remove(blob.Pool, blob.ObjectId)
+			if err != nil && !strings.Contains(err.Error(), "ret=-2") {
+				// left in table `recycle` to retry later
+				helper.Logger.Error("delete failed:", garbage.BucketName, ":", garbage.ObjectName, ":",
+					blob.Location, ":", blob.Pool, ":", blob.ObjectId, " error:", err)
+				continue
+			}
+			helper.Logger.Info("delete succeeded", garbage.BucketName, ":", garbage.ObjectName, ":",
+				blob.Location, ":", blob.Pool, ":", blob.ObjectId)
+			err = yigs[index].MetaStorage.RemoveRecycle(blob)
+			if err != nil {
+				helper.Logger.Warn("remove recycle entry failed:", blob.Location, ":", blob.Pool, ":",
+					blob.ObjectId, " error:", err)
 			}
 		}
-	release:
-		yigs[index].MetaStorage.RemoveGarbageCollection(garbage)
 		gcWaitgroup.Done()
 	}
 }
@@ -140,10 +137,10 @@ func main() {
 
 	numOfWorkers := helper.CONFIG.GcThread
 	yigs = make([]*storage.YigStorage, helper.CONFIG.GcThread+1)
-	yigs[0] = storage.New(int(meta.NoCache), false, kms)
+	yigs[0] = storage.New(int(meta.NoCache), false, kms, allPluginMap)
 	helper.Logger.Info("start gc thread:", numOfWorkers)
 	for i := 0; i < numOfWorkers; i++ {
-		yigs[i+1] = storage.New(int(meta.NoCache), false, kms)
+		yigs[i+1] = storage.New(int(meta.NoCache), false, kms, allPluginMap)
 		go deleteFromCeph(i + 1)
 	}
 	go removeDeleted()
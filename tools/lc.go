@@ -1,6 +1,9 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"github.com/journeymidnight/yig/api/datatype"
 	"github.com/journeymidnight/yig/crypto"
 	"github.com/journeymidnight/yig/helper"
@@ -12,6 +15,7 @@ import (
 	"github.com/journeymidnight/yig/storage"
 	"os"
 	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,8 +35,167 @@ var (
 	waitgroup   sync.WaitGroup
 	empty       bool
 	stop        bool
+	// not nil in dry run, actions are only reported instead of done
+	report *dryRunReport
+	// identity of this daemon in lease of buckets
+	owner string
+	// buckets finished by any daemon after this are not processed again
+	passStart time.Time
 )
 
+var (
+	errLeaseLost = errors.New("lease of bucket lost")
+	errStopped   = errors.New("lc is shutting down")
+)
+
+// Lease of a bucket held by this daemon, renewed in background until released.
+// Progress of the pass is saved on renewal, so another daemon could resume it
+// from Marker if this one dies. A nil lease is used when no lease is needed,
+// e.g. in dry run.
+type bucketLease struct {
+	mutex  sync.Mutex
+	lc     types.LifeCycle
+	lost   bool
+	stopCh chan struct{}
+}
+
+func leaseDuration() time.Duration {
+	return time.Duration(helper.CONFIG.LcLease) * time.Second
+}
+
+// Returns nil if the bucket is held by another daemon or finished in this pass
+func acquireBucket(bucketName string) (*bucketLease, error) {
+	lc, ok, err := yig.MetaStorage.AcquireLifeCycleLease(bucketName, owner,
+		time.Now().Add(leaseDuration()), passStart)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if lc.Marker == "" {
+		lc.LastStart = time.Now()
+		lc.Scanned = 0
+	} else {
+		helper.Logger.Info("Resume lifecycle of bucket", bucketName, "from", lc.Marker)
+	}
+	lease := &bucketLease{
+		lc:     lc,
+		stopCh: make(chan struct{}),
+	}
+	lease.renew()
+	go func() {
+		ticker := time.NewTicker(leaseDuration() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				lease.renew()
+			case <-lease.stopCh:
+				return
+			}
+		}
+	}()
+	return lease, nil
+}
+
+func (l *bucketLease) renew() {
+	l.mutex.Lock()
+	l.lc.LeaseExpire = time.Now().Add(leaseDuration())
+	lc := l.lc
+	l.mutex.Unlock()
+	ok, err := yig.MetaStorage.RenewLifeCycleLease(lc)
+	if err != nil {
+		helper.Logger.Error("Renew lease of bucket", lc.BucketName, "failed:", err)
+		return
+	}
+	if !ok {
+		helper.Logger.Warn("Lease of bucket", lc.BucketName, "is taken by another lc")
+		l.mutex.Lock()
+		l.lost = true
+		l.mutex.Unlock()
+	}
+}
+
+func (l *bucketLease) marker() string {
+	if l == nil {
+		return ""
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.lc.Marker
+}
+
+// Record an object version walked, returns error if processing of the bucket
+// should stop
+func (l *bucketLease) scan() error {
+	if stop {
+		return errStopped
+	}
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.lost {
+		return errLeaseLost
+	}
+	l.lc.Scanned += 1
+	return nil
+}
+
+// Record all versions of objects up to name are processed
+func (l *bucketLease) advance(name string) {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	l.lc.Marker = name
+	l.mutex.Unlock()
+}
+
+func (l *bucketLease) release(finished bool) {
+	close(l.stopCh)
+	l.mutex.Lock()
+	lc, lost := l.lc, l.lost
+	l.mutex.Unlock()
+	if lost {
+		return
+	}
+	err := yig.MetaStorage.ReleaseLifeCycleLease(lc, finished)
+	if err != nil {
+		helper.Logger.Error("Release lease of bucket", lc.BucketName, "failed:", err)
+	}
+}
+
+// Keys, bytes and actions lifecycle would apply, printed to stdout
+type dryRunReport struct {
+	counts map[string]int
+	bytes  map[string]int64
+}
+
+func newDryRunReport() *dryRunReport {
+	return &dryRunReport{
+		counts: make(map[string]int),
+		bytes:  make(map[string]int64),
+	}
+}
+
+func (r *dryRunReport) add(action, key, versionId string, size int64) {
+	fmt.Printf("%s\t%s\t%s\t%d\n", action, key, versionId, size)
+	r.counts[action] += 1
+	r.bytes[action] += size
+}
+
+func (r *dryRunReport) summary() {
+	var actions []string
+	for action := range r.counts {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	fmt.Println("Summary:")
+	for _, action := range actions {
+		fmt.Printf("%s\tobjects: %d\tbytes: %d\n", action, r.counts[action], r.bytes[action])
+	}
+}
+
 func getLifeCycles() {
 	var marker string
 	helper.Logger.Info("all bucket lifecycle handle start")
@@ -64,133 +227,275 @@ func getLifeCycles() {
 
 }
 
-func checkIfExpiration(updateTime time.Time, days int) bool {
-	if helper.CONFIG.DebugMode == false {
-		return int(time.Since(updateTime).Seconds()) >= days*24*3600
-	} else {
-		return int(time.Since(updateTime).Seconds()) >= days
+// Storage class rank of object, objects are only transitioned to colder classes
+func storageClassRank(storageClass types.StorageClass) int {
+	return datatype.TransitionStorageClassRank(storageClass.ToString())
+}
+
+func transitionObject(object *types.Object, storageClass string) {
+	if storageClass == "" || object.DeleteMarker || object.Type == types.ObjectTypeAppendable ||
+		datatype.TransitionStorageClassRank(storageClass) <= storageClassRank(object.StorageClass) {
+		return
+	}
+	class, err := types.MatchStorageClassIndex(storageClass)
+	if err != nil {
+		helper.Logger.Error("Bad storage class in lifecycle of", object.BucketName, storageClass)
+		return
+	}
+	if report != nil {
+		report.add("Transition:"+storageClass, object.Name, object.GetVersionId(), object.Size)
+		return
+	}
+	err = yig.TransitionObject(object, class)
+	if err == types.ErrObjectChanged {
+		helper.Logger.Info("Object changed during transition, skip:",
+			object.BucketName, object.Name, object.GetVersionId())
+	} else if err != nil {
+		helper.Logger.Error(object.BucketName, object.Name, object.GetVersionId(),
+			"transition to", storageClass, "failed:", err)
 	}
 }
 
-// If a rule has an empty prefix, the days in it will be consider as a default days for all objects that not specified in
-// other rules. For this reason, we have two conditions to check if a object has expired and should be deleted
-//  if defaultConfig == true
-//                    for each object           check if object name has a prifix
-//  list all objects --------------->loop rules---------------------------------->
-//                                                                      |     NO
-//                                                                      |--------> days = default days ---
-//                                                                      |     YES                         |->delete object if expired
-//                                                                      |--------> days = specify days ---
-//
-//  if defaultConfig == false
-//                 for each rule get objects by prefix
-//  iterator rules ----------------------------------> loop objects-------->delete object if expired
-func retrieveBucket(lc types.LifeCycle) error {
-	defaultConfig := false
-	defaultDays := 0
-	bucket, err := yig.MetaStorage.GetBucket(lc.BucketName, false)
+// Delete current version of object if it's expired, otherwise transition it
+// to the coldest storage class due. In versioned buckets deleting adds a
+// delete marker.
+func applyCurrentVersion(lc *datatype.Lifecycle, object *types.Object) {
+	expire, storageClass := lc.CurrentVersionAction(object.Name, object.Size, object.Tags,
+		object.LastModifiedTime, time.Now(), helper.CONFIG.DebugMode)
+	if !expire {
+		transitionObject(object, storageClass)
+		return
+	}
+	if report != nil {
+		report.add("Expiration", object.Name, object.GetVersionId(), object.Size)
+		return
+	}
+	_, err := yig.DeleteObject(object.BucketName, object.Name, "", false, false, common.Credential{})
 	if err != nil {
-		return err
+		helper.Logger.Error(object.BucketName, object.Name, "failed:", err)
+		return
 	}
-	rules := bucket.Lifecycle.Rule
-	for _, rule := range rules {
-		if rule.Prefix == "" {
-			defaultConfig = true
-			defaultDays, err = strconv.Atoi(rule.Expiration)
-			if err != nil {
-				return err
-			}
-		}
+	helper.Logger.Info("Deleted:", object.BucketName, object.Name)
+}
+
+// Apply noncurrent version actions to the n-th newest noncurrent version of
+// an object, which became noncurrent at noncurrentSince.
+// Returns true if the version is removed.
+func applyNoncurrentVersion(lc *datatype.Lifecycle, object *types.Object, n int,
+	noncurrentSince time.Time) (removed bool) {
+
+	expire, storageClass := lc.NoncurrentVersionAction(object.Name, object.Size, object.Tags,
+		n, noncurrentSince, time.Now(), helper.CONFIG.DebugMode)
+	if !expire {
+		transitionObject(object, storageClass)
+		return false
+	}
+	if report != nil {
+		report.add("NoncurrentVersionExpiration", object.Name, object.GetVersionId(), object.Size)
+		return true
 	}
+	err := yig.ExpireObjectVersion(object)
+	if err != nil {
+		helper.Logger.Error(object.BucketName, object.Name, object.GetVersionId(), "failed:", err)
+		return false
+	}
+	helper.Logger.Info("Deleted noncurrent version:", object.BucketName, object.Name, object.GetVersionId())
+	return true
+}
+
+// Walk all versions and delete markers of objects with prefix, ordered by name
+// and from newest to oldest version
+func walkVersions(bucketName, prefix, keyMarker string, walkFn func(object *types.Object) error) error {
 	var request datatype.ListObjectsRequest
-	request.Versioned = false
+	request.Versioned = true
 	request.MaxKeys = 1000
-	if defaultConfig == true {
-		for {
-			retObjects, _, truncated, nextMarker, nextVerIdMarker, err := yig.ListObjectsInternal(bucket.Name, request)
+	request.Prefix = prefix
+	request.KeyMarker = keyMarker
+	for {
+		retObjects, _, truncated, nextMarker, nextVerIdMarker, err := yig.ListObjectsInternal(bucketName, request)
+		if err != nil {
+			return err
+		}
+		for _, object := range retObjects {
+			err = walkFn(object)
 			if err != nil {
 				return err
 			}
+		}
+		if truncated == false {
+			return nil
+		}
+		request.KeyMarker = nextMarker
+		request.VersionIdMarker = nextVerIdMarker
+	}
+}
+
+// Applies lifecycle to versions of objects in the order of walkVersions. The
+// first version of an object is the current one, and the rest are noncurrent.
+type versionWalker struct {
+	lc    *datatype.Lifecycle
+	lease *bucketLease
+	name  string
+	// current version of object
+	current *types.Object
+	// noncurrent versions walked, and those not removed
+	noncurrent int
+	remaining  int
+	// time the version walked next becomes noncurrent
+	successorTime time.Time
+}
+
+func (w *versionWalker) walk(object *types.Object) error {
+	err := w.lease.scan()
+	if err != nil {
+		return err
+	}
+	if object.Name != w.name {
+		w.flush()
+		w.name, w.current = object.Name, object
+		w.remaining, w.noncurrent = 0, 0
+		w.successorTime = object.LastModifiedTime
+		if !object.DeleteMarker {
+			applyCurrentVersion(w.lc, object)
+		}
+		return nil
+	}
+
+	w.noncurrent += 1
+	noncurrentSince := w.successorTime
+	w.successorTime = object.LastModifiedTime
+	if !applyNoncurrentVersion(w.lc, object, w.noncurrent, noncurrentSince) {
+		w.remaining += 1
+	}
+	return nil
+}
 
-			for _, object := range retObjects {
-				prefixMatch := false
-				matchDays := 0
-				for _, rule := range rules {
-					if rule.Prefix == "" {
-						continue
-					}
-					if strings.HasPrefix(object.Name, rule.Prefix) == false {
-						continue
-					}
-					prefixMatch = true
-					matchDays, err = strconv.Atoi(rule.Expiration)
-					if err != nil {
-						return err
-					}
-				}
-				days := 0
-				if prefixMatch == true {
-					days = matchDays
-				} else {
-					days = defaultDays
-				}
-				helper.Logger.Info("inteval:", time.Since(object.LastModifiedTime).Seconds())
-				if checkIfExpiration(object.LastModifiedTime, days) {
-					helper.Logger.Info("come here")
-					if object.NullVersion {
-						object.VersionId = ""
-					}
-					_, err = yig.DeleteObject(object.BucketName, object.Name, object.VersionId, common.Credential{})
-					if err != nil {
-						helper.Logger.Error(object.BucketName, object.Name, object.VersionId, err)
-						continue
-					}
-					helper.Logger.Info("Deleted:", object.BucketName, object.Name, object.VersionId)
-				}
+// Remove current version of the object walked last, if it's a delete marker
+// with no noncurrent versions left. All versions of the object are processed
+// after flush.
+func (w *versionWalker) flush() {
+	if w.current == nil {
+		return
+	}
+	defer w.lease.advance(w.name)
+	if !w.current.DeleteMarker || w.remaining > 0 || !w.lc.ExpireDeleteMarker(w.current.Name) {
+		return
+	}
+	if report != nil {
+		report.add("ExpiredObjectDeleteMarker", w.current.Name, w.current.GetVersionId(), 0)
+		return
+	}
+	err := yig.ExpireObjectVersion(w.current)
+	if err != nil {
+		helper.Logger.Error(w.current.BucketName, w.current.Name, w.current.GetVersionId(),
+			"failed:", err)
+		return
+	}
+	helper.Logger.Info("Deleted expired delete marker:", w.current.BucketName, w.current.Name,
+		w.current.GetVersionId())
+}
+
+// Abort multipart uploads initiated before DaysAfterInitiation of the rules
+// they match, their parts are removed by gc
+func abortIncompleteUploads(bucket *types.Bucket) error {
+	hasAction := false
+	for _, rule := range bucket.Lifecycle.Rule {
+		if rule.AbortIncompleteMultipartUpload != nil {
+			hasAction = true
+		}
+	}
+	if !hasAction {
+		return nil
+	}
+	owner := common.Credential{UserId: bucket.OwnerId}
+	var request datatype.ListUploadsRequest
+	request.MaxUploads = 1000
+	now := time.Now()
+	for {
+		result, err := yig.ListMultipartUploads(owner, bucket.Name, request)
+		if err != nil {
+			return err
+		}
+		for _, upload := range result.Uploads {
+			initiated, err := time.Parse(helper.CONFIG.TimeFormat, upload.Initiated)
+			if err != nil {
+				helper.Logger.Error("Bad initiated time of upload", bucket.Name, upload.Key,
+					upload.UploadId, upload.Initiated)
+				continue
 			}
-			if truncated == true {
-				request.KeyMarker = nextMarker
-				request.VersionIdMarker = nextVerIdMarker
-			} else {
-				break
+			if !bucket.Lifecycle.AbortUpload(upload.Key, initiated, now, helper.CONFIG.DebugMode) {
+				continue
 			}
-		}
-	} else {
-		for _, rule := range rules {
-			if rule.Prefix == "" {
+			if report != nil {
+				reportUpload(bucket.Name, upload.Key, upload.UploadId)
 				continue
 			}
-			days, _ := strconv.Atoi(rule.Expiration)
+			err = yig.AbortMultipartUpload(owner, bucket.Name, upload.Key, upload.UploadId)
 			if err != nil {
-				return err
-			}
-			request.Prefix = rule.Prefix
-			for {
-
-				retObjects, _, truncated, nextMarker, nextVerIdMarker, err := yig.ListObjectsInternal(bucket.Name, request)
-				if err != nil {
-					return err
-				}
-				for _, object := range retObjects {
-					if checkIfExpiration(object.LastModifiedTime, days) {
-						_, err = yig.DeleteObject(object.BucketName, object.Name, object.VersionId, common.Credential{})
-						if err != nil {
-							helper.Logger.Error(object.BucketName, object.Name, object.VersionId, "failed:", err)
-							continue
-						}
-						helper.Logger.Info("Deleted:", object.BucketName, object.Name, object.VersionId)
-					}
-				}
-				if truncated == true {
-					request.KeyMarker = nextMarker
-					request.VersionIdMarker = nextVerIdMarker
-				} else {
-					break
-				}
-
+				helper.Logger.Error("Abort upload", bucket.Name, upload.Key, upload.UploadId,
+					"failed:", err)
+				continue
 			}
+			helper.Logger.Info("Aborted incomplete upload:", bucket.Name, upload.Key, upload.UploadId)
+		}
+		if !result.IsTruncated {
+			return nil
 		}
+		request.KeyMarker = result.NextKeyMarker
+		request.UploadIdMarker = result.NextUploadIdMarker
+	}
+}
 
+func reportUpload(bucketName, key, uploadId string) {
+	multipart, err := yig.MetaStorage.GetMultipart(bucketName, key, uploadId)
+	if err != nil {
+		helper.Logger.Error("Get upload", bucketName, key, uploadId, "failed:", err)
+		return
+	}
+	var size int64
+	for _, part := range multipart.Parts {
+		size += part.Size
+	}
+	report.add("AbortIncompleteMultipartUpload", key, uploadId, size)
+}
+
+// All versions of objects under key prefixes of enabled rules are listed.
+// Every rule matching an object applies, see datatype.Lifecycle for how
+// actions of different rules are combined. Current versions are expired or
+// transitioned, noncurrent versions and expired delete markers are handled by
+// noncurrent version actions and ExpiredObjectDeleteMarker.
+func retrieveBucket(lc types.LifeCycle, lease *bucketLease) error {
+	bucket, err := yig.MetaStorage.GetBucket(lc.BucketName, false)
+	if err != nil {
+		return err
+	}
+	return processBucket(bucket, lease)
+}
+
+// Objects are walked in order of name, so a pass could be resumed after the
+// marker of lease. Prefixes of rules never overlap, see KeyPrefixes.
+func processBucket(bucket *types.Bucket, lease *bucketLease) error {
+	err := abortIncompleteUploads(bucket)
+	if err != nil {
+		return err
+	}
+	marker := lease.marker()
+	prefixes := bucket.Lifecycle.KeyPrefixes()
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		var keyMarker string
+		if strings.HasPrefix(marker, prefix) {
+			keyMarker = marker
+		} else if marker > prefix {
+			// all objects with prefix are before marker
+			continue
+		}
+		w := &versionWalker{lc: &bucket.Lifecycle, lease: lease}
+		err = walkVersions(bucket.Name, prefix, keyMarker, w.walk)
+		if err != nil {
+			return err
+		}
+		w.flush()
 	}
 	return nil
 }
@@ -205,7 +510,19 @@ func processLifecycle() {
 		waitgroup.Add(1)
 		select {
 		case item := <-taskQ:
-			err := retrieveBucket(item)
+			lease, err := acquireBucket(item.BucketName)
+			if err != nil {
+				helper.Logger.Error("Bucket", item.BucketName, "acquire lease error:", err)
+				waitgroup.Done()
+				continue
+			}
+			if lease == nil {
+				helper.Logger.Info("Bucket", item.BucketName, "skipped, held by another lc or done")
+				waitgroup.Done()
+				continue
+			}
+			err = retrieveBucket(item, lease)
+			lease.release(err == nil)
 			if err != nil {
 				helper.Logger.Error("Bucket", item.BucketName, "retrieve error:", err)
 				waitgroup.Done()
@@ -224,7 +541,57 @@ func processLifecycle() {
 	}
 }
 
+// Process lifecycle of a single bucket, with configuration read from
+// configFile instead of the bucket's own if not empty
+func runBucket(bucketName, configFile string) error {
+	bucket, err := yig.MetaStorage.GetBucket(bucketName, false)
+	if err != nil {
+		return err
+	}
+	if configFile != "" {
+		f, err := os.Open(configFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		lc, err := datatype.ParseLifecycleConfig(f)
+		if err != nil {
+			return err
+		}
+		bucket.Lifecycle = *lc
+	}
+	if report != nil {
+		return processBucket(bucket, nil)
+	}
+	lease, err := acquireBucket(bucketName)
+	if err != nil {
+		return err
+	}
+	if lease == nil {
+		return errors.New("bucket is being processed by another lc")
+	}
+	err = processBucket(bucket, lease)
+	lease.release(err == nil)
+	return err
+}
+
+// Without flags all buckets with lifecycle are processed. Several lc could run
+// at the same time on different nodes, buckets are split among them by leases.
+// To see what a configuration would do to a bucket before setting it:
+//
+//	lc -bucket <bucket> -dry-run -config lifecycle.xml
 func main() {
+	bucketName := flag.String("bucket", "", "only process lifecycle of the bucket")
+	dryRun := flag.Bool("dry-run", false,
+		"report keys, bytes and actions lifecycle would apply to the bucket without doing them")
+	configFile := flag.String("config", "",
+		"lifecycle configuration XML to evaluate instead of the one set on the bucket, only in dry run")
+	flag.Parse()
+	if (*dryRun || *configFile != "") && *bucketName == "" || *configFile != "" && !*dryRun {
+		flag.Usage()
+		os.Exit(1)
+	}
+
 	stop = false
 
 	helper.SetupConfig()
@@ -241,7 +608,25 @@ func main() {
 	allPluginMap := mods.InitialPlugins()
 	kms := crypto.NewKMS(allPluginMap)
 
-	yig = storage.New(helper.CONFIG.MetaCacheType, helper.CONFIG.EnableDataCache, kms)
+	yig = storage.New(helper.CONFIG.MetaCacheType, helper.CONFIG.EnableDataCache, kms, allPluginMap)
+	hostname, _ := os.Hostname()
+	owner = hostname + ":" + strconv.Itoa(os.Getpid())
+	passStart = time.Now()
+	if *bucketName != "" {
+		if *dryRun {
+			report = newDryRunReport()
+		}
+		err := runBucket(*bucketName, *configFile)
+		if err != nil {
+			helper.Logger.Error("Bucket", *bucketName, "lifecycle error:", err)
+			fmt.Fprintln(os.Stderr, "Bucket", *bucketName, "lifecycle error:", err)
+			os.Exit(1)
+		}
+		if report != nil {
+			report.summary()
+		}
+		return
+	}
 	taskQ = make(chan types.LifeCycle, SCAN_LIMIT)
 	signal.Ignore()
 	signalQueue = make(chan os.Signal)
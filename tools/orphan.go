@@ -0,0 +1,147 @@
+// Find blobs stored in backend clusters but not referenced by any row in meta,
+// left by failed PUTs, crashes before the recycle queue drains, or gc entries
+// dropped by the delete daemon, e.g.
+//
+//	orphan -cluster <cluster id> -grace 48
+//	orphan -cluster <cluster id> -grace 48 -reclaim
+//
+// Only blobs older than the grace window are considered, so in-flight uploads
+// are not reported. Without -reclaim orphans are only reported to stdout.
+// Clusters must implement backend.Lister to be checked.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/meta"
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/storage"
+)
+
+const (
+	DEFAULT_ORPHAN_LOG_PATH = "/var/log/yig/orphan.log"
+	DEFAULT_GRACE_HOURS     = 24
+)
+
+var yig *storage.YigStorage
+
+// pool -> object ID -> modified time
+type candidates map[string]map[string]time.Time
+
+func (c candidates) remove(pool, oid string) {
+	if objects, ok := c[pool]; ok {
+		delete(objects, oid)
+	}
+}
+
+func listCandidates(lister backend.Lister, location string, before time.Time) candidates {
+	c := make(candidates)
+	for _, pool := range backend.Pools() {
+		objects := make(map[string]time.Time)
+		err := lister.ListObjects(pool, func(oid string, modifiedTime time.Time) error {
+			if modifiedTime.Before(before) {
+				objects[oid] = modifiedTime
+			}
+			return nil
+		})
+		if err != nil {
+			helper.Logger.Warn("List pool", pool, "of cluster", location, "failed:", err)
+			continue
+		}
+		c[pool] = objects
+	}
+	return c
+}
+
+// Remove blobs referenced by meta from candidates, including shards of
+// erasure coded objects stored in this cluster
+func removeReferenced(location string, c candidates) error {
+	return yig.MetaStorage.WalkBlobReferences(location, func(pool, oid string) error {
+		c.remove(pool, oid)
+		return nil
+	})
+}
+
+func checkCluster(location string, grace time.Duration, reclaim bool) (found, reclaimed int) {
+	cluster := yig.DataStorage[location]
+	lister, ok := cluster.(backend.Lister)
+	if !ok {
+		helper.Logger.Warn("Cluster", location, "does not support listing, skip")
+		return
+	}
+	// list data before scanning meta, blobs written after listing are not candidates
+	c := listCandidates(lister, location, time.Now().Add(-grace))
+	if err := removeReferenced(location, c); err != nil {
+		helper.Logger.Error("Walk references of cluster", location, "failed:", err)
+		fmt.Fprintln(os.Stderr, "Walk references of cluster", location, "failed:", err)
+		return
+	}
+	for pool, objects := range c {
+		for oid, modifiedTime := range objects {
+			found++
+			fmt.Println(location, pool, oid, modifiedTime.UTC().Format(time.RFC3339))
+			if !reclaim {
+				continue
+			}
+			if err := cluster.Remove(pool, oid); err != nil {
+				helper.Logger.Error("Remove orphan", location, pool, oid, "failed:", err)
+				continue
+			}
+			helper.Logger.Info("Removed orphan", location, pool, oid)
+			reclaimed++
+		}
+	}
+	return
+}
+
+func main() {
+	location := flag.String("cluster", "", "ID of the cluster to check, all clusters if empty")
+	graceHours := flag.Int("grace", DEFAULT_GRACE_HOURS,
+		"only blobs not modified in these hours are considered orphans")
+	reclaim := flag.Bool("reclaim", false, "remove orphans found instead of only reporting them")
+	flag.Parse()
+	if *graceHours <= 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	helper.SetupConfig()
+	logLevel := log.ParseLevel(helper.CONFIG.LogLevel)
+
+	helper.Logger = log.NewFileLogger(DEFAULT_ORPHAN_LOG_PATH, logLevel)
+	defer helper.Logger.Close()
+
+	// Read all *.so from plugins directory, and fill the variable allPlugins
+	allPluginMap := mods.InitialPlugins()
+	kms := crypto.NewKMS(allPluginMap)
+
+	yig = storage.New(int(meta.NoCache), false, kms, allPluginMap)
+	var locations []string
+	if *location != "" {
+		if _, ok := yig.DataStorage[*location]; !ok {
+			panic("Cannot find cluster " + *location)
+		}
+		locations = append(locations, *location)
+	} else {
+		for id := range yig.DataStorage {
+			locations = append(locations, id)
+		}
+		sort.Strings(locations)
+	}
+
+	grace := time.Duration(*graceHours) * time.Hour
+	for _, l := range locations {
+		helper.Logger.Info("Start checking cluster", l, "grace:", grace, "reclaim:", *reclaim)
+		found, reclaimed := checkCluster(l, grace, *reclaim)
+		helper.Logger.Info("Finished checking cluster", l, "orphans:", found, "reclaimed:", reclaimed)
+		fmt.Fprintln(os.Stderr, "Cluster", l, "orphans:", found, "reclaimed:", reclaimed)
+	}
+}
@@ -0,0 +1,332 @@
+// Move data of all objects stored in cluster `-from` to cluster `-to`, e.g.
+//
+//	rebalance -from <fsid of old cluster> -to <fsid of new cluster> -rate 100
+//
+// Set weight of the source cluster to 0 in table `cluster` first, so no new
+// data is written to it. Old data goes through table `gc` and is removed by
+// the delete daemon. Progress is saved to a checkpoint file after every batch,
+// so the tool could be stopped and restarted at any time.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/journeymidnight/yig/backend"
+	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/erasure"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/redis"
+	"github.com/journeymidnight/yig/storage"
+)
+
+const (
+	SCAN_LIMIT                 = 50
+	PASS_INTERVAL              = 60 * time.Second
+	DEFAULT_REBALANCE_LOG_PATH = "/var/log/yig/rebalance.log"
+	DEFAULT_CHECKPOINT_DIR     = "/var/lib/yig"
+)
+
+var (
+	yig         *storage.YigStorage
+	source      backend.Cluster
+	target      backend.Cluster
+	limiter     *helper.RateLimiter
+	taskQ       chan *types.Object
+	batchGroup  sync.WaitGroup
+	signalQueue chan os.Signal
+	stopping    chan struct{} // closed when the tool should stop
+	counter     struct {
+		sync.Mutex
+		migrated, skipped, failed int64
+	}
+)
+
+type Checkpoint struct {
+	Source     string
+	Target     string
+	Marker     string // rowkey of the last object processed
+	Migrated   int64
+	Skipped    int64
+	Failed     int64
+	UpdateTime time.Time
+}
+
+func loadCheckpoint(path string) (checkpoint Checkpoint, err error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return checkpoint, nil
+	} else if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &checkpoint)
+	return
+}
+
+func saveCheckpoint(path string, checkpoint Checkpoint) error {
+	checkpoint.UpdateTime = time.Now().UTC()
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func copyBlob(pool, oid string, size int64) (newOid string, err error) {
+	reader, err := source.GetReader(pool, oid, 0, uint64(size))
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	newOid, written, err := target.Put(pool, limiter.Reader(reader))
+	if err != nil {
+		return "", err
+	}
+	if int64(written) != size {
+		target.Remove(pool, newOid)
+		return "", fmt.Errorf("size mismatch, expect %d, copied %d", size, written)
+	}
+	return newOid, nil
+}
+
+// returns false if the object is skipped
+func migrateObject(object *types.Object) (migrated bool, err error) {
+	if object.DeleteMarker {
+		return false, nil
+	}
+	if _, ok := target.(*erasure.ErasureCluster); ok && object.Type == types.ObjectTypeAppendable {
+		helper.Logger.Warn("Skip appendable object", object.BucketName, object.Name,
+			"since target is erasure coded")
+		return false, nil
+	}
+
+	newObject := *object
+	newObject.Location = target.ID()
+	var newOids []string
+	defer func() {
+		if err != nil {
+			for _, oid := range newOids {
+				target.Remove(object.Pool, oid)
+			}
+		}
+	}()
+	if len(object.Parts) == 0 {
+		newObject.ObjectId, err = copyBlob(object.Pool, object.ObjectId, object.Size)
+		if err != nil {
+			return false, err
+		}
+		newOids = append(newOids, newObject.ObjectId)
+	} else {
+		newObject.Parts = make(map[int]*types.Part, len(object.Parts))
+		for n, part := range object.Parts {
+			newPart := *part
+			newPart.ObjectId, err = copyBlob(object.Pool, part.ObjectId, part.Size)
+			if err != nil {
+				return false, err
+			}
+			newOids = append(newOids, newPart.ObjectId)
+			newObject.Parts[n] = &newPart
+		}
+	}
+
+	err = yig.MetaStorage.MigrateObject(&newObject, object)
+	if err == types.ErrObjectChanged {
+		helper.Logger.Info("Object changed during migration, skip:",
+			object.BucketName, object.Name, object.GetVersionId())
+		return false, err
+	} else if err != nil {
+		return false, err
+	}
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable, object.BucketName+":"+object.Name+":")
+	yig.MetaStorage.Cache.Remove(redis.ObjectTable,
+		object.BucketName+":"+object.Name+":"+object.GetVersionId())
+	return true, nil
+}
+
+func migrateWorker() {
+	for object := range taskQ {
+		migrated, err := migrateObject(object)
+		counter.Lock()
+		if err != nil && err != types.ErrObjectChanged {
+			counter.failed++
+			helper.Logger.Error("Migrate object", object.BucketName, object.Name,
+				object.GetVersionId(), "failed:", err)
+		} else if migrated {
+			counter.migrated++
+			helper.Logger.Info("Migrated object", object.BucketName, object.Name,
+				object.GetVersionId(), "to", target.ID())
+		} else {
+			counter.skipped++
+		}
+		counter.Unlock()
+		batchGroup.Done()
+	}
+}
+
+func stopped() bool {
+	select {
+	case <-stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep for d, or until the tool is stopped
+func wait(d time.Duration) {
+	select {
+	case <-stopping:
+	case <-time.After(d):
+	}
+}
+
+// Scan objects at source cluster batch by batch, one checkpoint per batch.
+// A new pass starts if any object was migrated in the last pass, since objects
+// failed or written during the pass may be left.
+func rebalance(checkpointPath string, checkpoint Checkpoint) {
+	var migratedInPass int64
+	for !stopped() {
+		objects, err := yig.MetaStorage.ScanObjectsByLocation(source.ID(), SCAN_LIMIT,
+			checkpoint.Marker)
+		if err != nil {
+			helper.Logger.Error("ScanObjectsByLocation failed:", err)
+			wait(PASS_INTERVAL)
+			continue
+		}
+		if len(objects) == 0 {
+			helper.Logger.Info("Pass finished, migrated:", checkpoint.Migrated,
+				"skipped:", checkpoint.Skipped, "failed:", checkpoint.Failed)
+			checkpoint.Marker = ""
+			if err = saveCheckpoint(checkpointPath, checkpoint); err != nil {
+				helper.Logger.Error("Save checkpoint failed:", err)
+			}
+			if migratedInPass == 0 {
+				helper.Logger.Info("Nothing more to migrate from", source.ID())
+				signalQueue <- syscall.SIGQUIT
+				return
+			}
+			migratedInPass = 0
+			wait(PASS_INTERVAL)
+			continue
+		}
+
+		counter.Lock()
+		counter.migrated, counter.skipped, counter.failed = 0, 0, 0
+		counter.Unlock()
+		batchGroup.Add(len(objects))
+		for _, object := range objects {
+			taskQ <- object
+		}
+		batchGroup.Wait()
+
+		checkpoint.Marker = string(objects[len(objects)-1].Rowkey)
+		checkpoint.Migrated += counter.migrated
+		checkpoint.Skipped += counter.skipped
+		checkpoint.Failed += counter.failed
+		migratedInPass += counter.migrated
+		if err = saveCheckpoint(checkpointPath, checkpoint); err != nil {
+			helper.Logger.Error("Save checkpoint failed:", err)
+		}
+	}
+	helper.Logger.Info("Shutting down...")
+}
+
+func main() {
+	from := flag.String("from", "", "ID of the cluster to drain")
+	to := flag.String("to", "", "ID of the cluster to move data to")
+	workers := flag.Int("workers", 4, "number of objects migrated concurrently")
+	rate := flag.Int64("rate", 0, "max bandwidth in MB/s, 0 means unlimited")
+	checkpointPath := flag.String("checkpoint", "", "checkpoint file, defaults to "+
+		DEFAULT_CHECKPOINT_DIR+"/rebalance-<from>.json")
+	flag.Parse()
+	if *from == "" || *to == "" || *from == *to || *workers <= 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *checkpointPath == "" {
+		*checkpointPath = filepath.Join(DEFAULT_CHECKPOINT_DIR, "rebalance-"+*from+".json")
+	}
+
+	helper.SetupConfig()
+	logLevel := log.ParseLevel(helper.CONFIG.LogLevel)
+
+	helper.Logger = log.NewFileLogger(DEFAULT_REBALANCE_LOG_PATH, logLevel)
+	defer helper.Logger.Close()
+	if helper.CONFIG.MetaCacheType > 0 || helper.CONFIG.EnableDataCache {
+		redis.Initialize()
+		defer redis.Close()
+	}
+
+	// Read all *.so from plugins directory, and fill the variable allPlugins
+	allPluginMap := mods.InitialPlugins()
+	kms := crypto.NewKMS(allPluginMap)
+
+	yig = storage.New(helper.CONFIG.MetaCacheType, helper.CONFIG.EnableDataCache, kms, allPluginMap)
+	var ok bool
+	if source, ok = yig.DataStorage[*from]; !ok {
+		panic("Cannot find source cluster " + *from)
+	}
+	if target, ok = yig.DataStorage[*to]; !ok {
+		panic("Cannot find target cluster " + *to)
+	}
+	checkpoint, err := loadCheckpoint(*checkpointPath)
+	if err != nil {
+		panic("Failed to load checkpoint " + *checkpointPath + ": " + err.Error())
+	}
+	if checkpoint.Source != "" && checkpoint.Source != *from {
+		panic("Checkpoint " + *checkpointPath + " belongs to cluster " + checkpoint.Source)
+	}
+	checkpoint.Source, checkpoint.Target = *from, *to
+	helper.Logger.Info("Start rebalance from", *from, "to", *to, "workers:", *workers,
+		"rate(MB/s):", *rate, "marker:", checkpoint.Marker)
+
+	limiter = helper.NewRateLimiter(*rate << 20)
+	taskQ = make(chan *types.Object, SCAN_LIMIT)
+	for i := 0; i < *workers; i++ {
+		go migrateWorker()
+	}
+
+	signal.Ignore()
+	signalQueue = make(chan os.Signal, 1)
+	stopping = make(chan struct{})
+	done := make(chan bool)
+	go func() {
+		rebalance(*checkpointPath, checkpoint)
+		close(done)
+	}()
+	signal.Notify(signalQueue, syscall.SIGINT, syscall.SIGTERM,
+		syscall.SIGQUIT, syscall.SIGHUP)
+	for {
+		s := <-signalQueue
+		switch s {
+		case syscall.SIGHUP:
+			// reload config file
+			helper.SetupConfig()
+		default:
+			// finish current batch and save checkpoint before exit
+			close(stopping)
+			<-done
+			return
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Replicate copies object versions and delete markers saved as PENDING to
+// destination buckets of the replication rules of their buckets, on another
+// YIG or S3 endpoint configured in `replication_targets`. Pending versions
+// are saved in table `replication` together with the version itself.
+//
+// Versions of the same object are replicated one by one from the oldest, a
+// version is not listed until older ones are finished, so later versions are
+// not overwritten by earlier ones in destination, even if an earlier one has
+// to be retried. Failed attempts are retried with exponential backoff, after
+// MAX_ATTEMPTS the version is marked FAILED.
+//
+// Several replicate daemons could run at the same time, a task is claimed by
+// one of them before processing. Claims older than REPLICATION_STALE_TIMEOUT
+// are considered abandoned and processed again.
+package main
+
+import (
+	"hash/fnv"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/redis"
+	"github.com/journeymidnight/yig/storage"
+)
+
+const (
+	SCAN_LIMIT                 = 100
+	RETRY_INTERVAL             = 10 * time.Second
+	REPLICATION_STALE_TIMEOUT  = 6 * time.Hour
+	MIN_RETRY_BACKOFF          = time.Minute
+	MAX_RETRY_BACKOFF          = time.Hour
+	MAX_ATTEMPTS               = 24
+	DEFAULT_REPLICATE_LOG_PATH = "/var/log/yig/replicate.log"
+)
+
+var (
+	yig         *storage.YigStorage
+	taskQs      []chan *types.ReplicationTask
+	batchGroup  sync.WaitGroup
+	signalQueue chan os.Signal
+	stop        bool
+)
+
+func retryBackoff(attempts int) time.Duration {
+	backoff := MIN_RETRY_BACKOFF
+	for i := 0; i < attempts && backoff < MAX_RETRY_BACKOFF; i++ {
+		backoff *= 2
+	}
+	if backoff > MAX_RETRY_BACKOFF {
+		backoff = MAX_RETRY_BACKOFF
+	}
+	return backoff
+}
+
+func replicate(task *types.ReplicationTask) {
+	status, err := yig.ReplicateObject(task)
+	if err != nil {
+		if task.Attempts+1 < MAX_ATTEMPTS {
+			backoff := retryBackoff(task.Attempts)
+			helper.Logger.Warn("Replicate", task.BucketName, task.ObjectName, task.Version,
+				"failed, retry in", backoff, "err:", err)
+			err = yig.MetaStorage.RetryReplicationTask(task, time.Now().UTC().Add(backoff))
+			if err != nil {
+				helper.Logger.Error("RetryReplicationTask", task.BucketName, task.ObjectName,
+					"failed:", err)
+			}
+			return
+		}
+		helper.Logger.Error("Replicate", task.BucketName, task.ObjectName, task.Version,
+			"failed after", MAX_ATTEMPTS, "attempts, err:", err)
+		status = types.ReplicationFailed
+	}
+	err = yig.FinishReplication(task, status)
+	if err != nil {
+		helper.Logger.Error("FinishReplicationTask", task.BucketName, task.ObjectName,
+			"failed:", err)
+		return
+	}
+	helper.Logger.Info("Replicated", task.BucketName, task.ObjectName, task.Version,
+		"status:", status.ToString())
+}
+
+func replicateWorker(taskQ chan *types.ReplicationTask) {
+	for task := range taskQ {
+		replicate(task)
+		batchGroup.Done()
+	}
+}
+
+// versions of the same object go to the same worker
+func pickTaskQ(task *types.ReplicationTask) chan *types.ReplicationTask {
+	h := fnv.New32a()
+	h.Write([]byte(task.BucketName))
+	h.Write([]byte{0})
+	h.Write([]byte(task.ObjectName))
+	return taskQs[h.Sum32()%uint32(len(taskQs))]
+}
+
+// returns number of tasks processed
+func replicatePending() int {
+	now := time.Now().UTC()
+	tasks, err := yig.MetaStorage.ListPendingReplicationTasks(SCAN_LIMIT, now,
+		now.Add(-REPLICATION_STALE_TIMEOUT))
+	if err != nil {
+		helper.Logger.Error("ListPendingReplicationTasks failed:", err)
+		return 0
+	}
+	var claimed int
+	for _, task := range tasks {
+		if stop {
+			break
+		}
+		ok, err := yig.MetaStorage.ClaimReplicationTask(task, now)
+		if err != nil {
+			helper.Logger.Error("ClaimReplicationTask", task.BucketName, task.ObjectName,
+				"failed:", err)
+			continue
+		}
+		if !ok {
+			// claimed by another replicate daemon
+			continue
+		}
+		claimed++
+		batchGroup.Add(1)
+		pickTaskQ(task) <- task
+	}
+	batchGroup.Wait()
+	return claimed
+}
+
+func replicateLoop() {
+	for !stop {
+		if replicatePending() > 0 {
+			continue
+		}
+		// wait for new versions
+		next := time.Now().Add(RETRY_INTERVAL)
+		for !stop && time.Now().Before(next) {
+			time.Sleep(time.Second)
+		}
+	}
+	helper.Logger.Info("Shutting down...")
+}
+
+func main() {
+	helper.SetupConfig()
+	logLevel := log.ParseLevel(helper.CONFIG.LogLevel)
+
+	helper.Logger = log.NewFileLogger(DEFAULT_REPLICATE_LOG_PATH, logLevel)
+	defer helper.Logger.Close()
+	if helper.CONFIG.MetaCacheType > 0 || helper.CONFIG.EnableDataCache {
+		redis.Initialize()
+		defer redis.Close()
+	}
+
+	// Read all *.so from plugins directory, and fill the variable allPlugins
+	allPluginMap := mods.InitialPlugins()
+	kms := crypto.NewKMS(allPluginMap)
+
+	yig = storage.New(helper.CONFIG.MetaCacheType, helper.CONFIG.EnableDataCache, kms, allPluginMap)
+	numOfWorkers := helper.CONFIG.ReplicationThread
+	helper.Logger.Info("start replicate thread:", numOfWorkers)
+	for i := 0; i < numOfWorkers; i++ {
+		taskQ := make(chan *types.ReplicationTask, SCAN_LIMIT)
+		taskQs = append(taskQs, taskQ)
+		go replicateWorker(taskQ)
+	}
+
+	signal.Ignore()
+	signalQueue = make(chan os.Signal, 1)
+	done := make(chan bool)
+	go func() {
+		replicateLoop()
+		close(done)
+	}()
+	signal.Notify(signalQueue, syscall.SIGINT, syscall.SIGTERM,
+		syscall.SIGQUIT, syscall.SIGHUP)
+	for {
+		s := <-signalQueue
+		switch s {
+		case syscall.SIGHUP:
+			// reload config file
+			helper.SetupConfig()
+		default:
+			// finish current batch before exit
+			stop = true
+			<-done
+			return
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Restore processes restore requests of glacier objects saved in table
+// `restoreobjects`. Pending requests are handled in Expedited, Standard and
+// Bulk order, each copying object data from the turtle pool to a temporary
+// readable copy. Restored copies are removed after their lifetime elapses,
+// then the object is glacier only again.
+//
+// Several restore daemons could run at the same time, a request is claimed by
+// one of them before processing. Claims older than RESTORE_STALE_TIMEOUT are
+// considered abandoned and processed again.
+//
+// Events of restored objects are sent through the message queue plugin, if
+// buckets have notifications configured.
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/mods"
+	bus "github.com/journeymidnight/yig/mq"
+	"github.com/journeymidnight/yig/redis"
+	"github.com/journeymidnight/yig/storage"
+)
+
+const (
+	SCAN_LIMIT               = 50
+	RETRY_INTERVAL           = 60 * time.Second
+	RESTORE_STALE_TIMEOUT    = 6 * time.Hour
+	DEFAULT_RESTORE_LOG_PATH = "/var/log/yig/restore.log"
+)
+
+var (
+	yig         *storage.YigStorage
+	taskQ       chan *types.Freezer
+	batchGroup  sync.WaitGroup
+	signalQueue chan os.Signal
+	stop        bool
+)
+
+func restoreWorker() {
+	for freezer := range taskQ {
+		helper.Logger.Info("Restoring", freezer.BucketName, freezer.Name,
+			"tier:", freezer.Tier)
+		err := yig.RestoreFreezer(freezer)
+		if err != nil {
+			helper.Logger.Error("Restore", freezer.BucketName, freezer.Name,
+				"failed:", err)
+		}
+		batchGroup.Done()
+	}
+}
+
+// returns number of requests restored
+func restorePending() int {
+	now := time.Now().UTC()
+	freezers, err := yig.MetaStorage.ListPendingFreezers(SCAN_LIMIT,
+		now.Add(-RESTORE_STALE_TIMEOUT))
+	if err != nil {
+		helper.Logger.Error("ListPendingFreezers failed:", err)
+		return 0
+	}
+	var claimed int
+	for _, freezer := range freezers {
+		if stop {
+			break
+		}
+		ok, err := yig.MetaStorage.ClaimFreezer(freezer, now)
+		if err != nil {
+			helper.Logger.Error("ClaimFreezer", freezer.BucketName, freezer.Name,
+				"failed:", err)
+			continue
+		}
+		if !ok {
+			// claimed by another restore daemon or changed by user
+			continue
+		}
+		claimed++
+		batchGroup.Add(1)
+		taskQ <- freezer
+	}
+	batchGroup.Wait()
+	return claimed
+}
+
+// returns number of restored copies removed
+func expireRestored() int {
+	freezers, err := yig.MetaStorage.ListExpiredFreezers(SCAN_LIMIT, time.Now().UTC())
+	if err != nil {
+		helper.Logger.Error("ListExpiredFreezers failed:", err)
+		return 0
+	}
+	var expired int
+	for _, freezer := range freezers {
+		if stop {
+			break
+		}
+		err = yig.ExpireFreezer(freezer.BucketName, freezer.Name)
+		if err != nil {
+			helper.Logger.Error("Expire restored copy of", freezer.BucketName,
+				freezer.Name, "failed:", err)
+			continue
+		}
+		helper.Logger.Info("Expired restored copy of", freezer.BucketName, freezer.Name)
+		expired++
+	}
+	return expired
+}
+
+func restore() {
+	for !stop {
+		restored := restorePending()
+		expired := expireRestored()
+		if restored > 0 || expired > 0 {
+			continue
+		}
+		// wait for new requests
+		next := time.Now().Add(RETRY_INTERVAL)
+		for !stop && time.Now().Before(next) {
+			time.Sleep(time.Second)
+		}
+	}
+	helper.Logger.Info("Shutting down...")
+}
+
+func main() {
+	helper.SetupConfig()
+	logLevel := log.ParseLevel(helper.CONFIG.LogLevel)
+
+	helper.Logger = log.NewFileLogger(DEFAULT_RESTORE_LOG_PATH, logLevel)
+	defer helper.Logger.Close()
+	if helper.CONFIG.MetaCacheType > 0 || helper.CONFIG.EnableDataCache {
+		redis.Initialize()
+		defer redis.Close()
+	}
+
+	// Read all *.so from plugins directory, and fill the variable allPlugins
+	allPluginMap := mods.InitialPlugins()
+	kms := crypto.NewKMS(allPluginMap)
+
+	yig = storage.New(helper.CONFIG.MetaCacheType, helper.CONFIG.EnableDataCache, kms, allPluginMap)
+	mqSender, err := bus.InitMessageSender(allPluginMap)
+	if err != nil {
+		helper.Logger.Error("Failed to create message queue sender, err:", err)
+		panic("failed to create message bus sender")
+	}
+	defer mqSender.Close()
+	taskQ = make(chan *types.Freezer, SCAN_LIMIT)
+	numOfWorkers := helper.CONFIG.RestoreThread
+	helper.Logger.Info("start restore thread:", numOfWorkers)
+	for i := 0; i < numOfWorkers; i++ {
+		go restoreWorker()
+	}
+
+	signal.Ignore()
+	signalQueue = make(chan os.Signal, 1)
+	done := make(chan bool)
+	go func() {
+		restore()
+		close(done)
+	}()
+	signal.Notify(signalQueue, syscall.SIGINT, syscall.SIGTERM,
+		syscall.SIGQUIT, syscall.SIGHUP)
+	for {
+		s := <-signalQueue
+		switch s {
+		case syscall.SIGHUP:
+			// reload config file
+			helper.SetupConfig()
+		default:
+			// finish current batch before exit
+			stop = true
+			<-done
+			return
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Scrub reads back data of all objects in every cluster and checks it against
+// the size and Etag recorded in meta, to find bit rot or lost data before users
+// do. Problems found are saved to table `scrubfindings` and exported as
+// Prometheus metric `yig_scrub_findings` by the admin server. Findings not seen
+// again in a whole pass are removed, since the object is fixed or deleted.
+//
+// Bandwidth used is limited by `scrub_bandwidth`, which could be changed at
+// runtime by editing yig.toml and sending SIGHUP.
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/journeymidnight/yig/crypto"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/redis"
+	"github.com/journeymidnight/yig/storage"
+)
+
+const (
+	SCAN_LIMIT             = 50
+	RETRY_INTERVAL         = 60 * time.Second
+	DEFAULT_SCRUB_LOG_PATH = "/var/log/yig/scrub.log"
+)
+
+var (
+	yig         *storage.YigStorage
+	limiter     *helper.RateLimiter
+	taskQ       chan *types.Object
+	batchGroup  sync.WaitGroup
+	signalQueue chan os.Signal
+	stopping    chan struct{} // closed when the tool should stop
+)
+
+func scrubWorker() {
+	for object := range taskQ {
+		findings, err := yig.VerifyObjectData(object, limiter)
+		if err != nil {
+			helper.Logger.Error("Verify object", object.BucketName, object.Name,
+				object.GetVersionId(), "failed:", err)
+		}
+		for _, finding := range findings {
+			helper.Logger.Warn("Scrub found", finding.Type, "in", finding.BucketName,
+				finding.ObjectName, finding.VersionId, "part:", finding.PartNumber,
+				"cluster:", finding.Location, finding.Detail)
+			err = yig.MetaStorage.PutScrubFinding(finding)
+			if err != nil {
+				helper.Logger.Error("PutScrubFinding failed:", err)
+			}
+		}
+		batchGroup.Done()
+	}
+}
+
+func stopped() bool {
+	select {
+	case <-stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep for d, or until the tool is stopped
+func wait(d time.Duration) {
+	select {
+	case <-stopping:
+	case <-time.After(d):
+	}
+}
+
+// returns false if stopped before the whole cluster is scanned
+func scrubCluster(location string) bool {
+	var marker string
+	var scanned int64
+	for !stopped() {
+		objects, err := yig.MetaStorage.ScanObjectsByLocation(location, SCAN_LIMIT, marker)
+		if err != nil {
+			helper.Logger.Error("ScanObjectsByLocation failed:", err)
+			wait(RETRY_INTERVAL)
+			continue
+		}
+		if len(objects) == 0 {
+			helper.Logger.Info("Scrubbed cluster", location, "objects:", scanned)
+			return true
+		}
+		batchGroup.Add(len(objects))
+		for _, object := range objects {
+			taskQ <- object
+		}
+		batchGroup.Wait()
+		scanned += int64(len(objects))
+		marker = string(objects[len(objects)-1].Rowkey)
+	}
+	return false
+}
+
+func scrub() {
+	for !stopped() {
+		passStart := time.Now().UTC()
+		var locations []string
+		for location := range yig.DataStorage {
+			locations = append(locations, location)
+		}
+		sort.Strings(locations)
+		finished := true
+		for _, location := range locations {
+			if !scrubCluster(location) {
+				finished = false
+				break
+			}
+		}
+		if !finished {
+			break
+		}
+		err := yig.MetaStorage.RemoveScrubFindingsBefore(passStart)
+		if err != nil {
+			helper.Logger.Error("RemoveScrubFindingsBefore failed:", err)
+		}
+		helper.Logger.Info("Scrub pass finished, started at", passStart)
+
+		next := passStart.Add(time.Duration(helper.CONFIG.ScrubInterval) * time.Hour)
+		wait(time.Until(next))
+	}
+	helper.Logger.Info("Shutting down...")
+}
+
+func main() {
+	helper.SetupConfig()
+	logLevel := log.ParseLevel(helper.CONFIG.LogLevel)
+
+	helper.Logger = log.NewFileLogger(DEFAULT_SCRUB_LOG_PATH, logLevel)
+	defer helper.Logger.Close()
+	if helper.CONFIG.MetaCacheType > 0 || helper.CONFIG.EnableDataCache {
+		redis.Initialize()
+		defer redis.Close()
+	}
+
+	// Read all *.so from plugins directory, and fill the variable allPlugins
+	allPluginMap := mods.InitialPlugins()
+	kms := crypto.NewKMS(allPluginMap)
+
+	yig = storage.New(helper.CONFIG.MetaCacheType, helper.CONFIG.EnableDataCache, kms, allPluginMap)
+	limiter = helper.NewRateLimiter(helper.CONFIG.ScrubBandwidth << 20)
+	taskQ = make(chan *types.Object, SCAN_LIMIT)
+	numOfWorkers := helper.CONFIG.ScrubThread
+	helper.Logger.Info("start scrub thread:", numOfWorkers,
+		"bandwidth(MB/s):", helper.CONFIG.ScrubBandwidth)
+	for i := 0; i < numOfWorkers; i++ {
+		go scrubWorker()
+	}
+
+	signal.Ignore()
+	signalQueue = make(chan os.Signal, 1)
+	stopping = make(chan struct{})
+	done := make(chan bool)
+	go func() {
+		scrub()
+		close(done)
+	}()
+	signal.Notify(signalQueue, syscall.SIGINT, syscall.SIGTERM,
+		syscall.SIGQUIT, syscall.SIGHUP)
+	for {
+		s := <-signalQueue
+		switch s {
+		case syscall.SIGHUP:
+			// reload config file
+			helper.SetupConfig()
+			limiter.SetRate(helper.CONFIG.ScrubBandwidth << 20)
+		default:
+			// finish current batch before exit
+			close(stopping)
+			<-done
+			return
+		}
+	}
+}
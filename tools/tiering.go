@@ -0,0 +1,186 @@
+// Tiering moves objects of storage class INTELLIGENT_TIERING between access
+// tiers, according to their last access saved in table `objectaccess`.
+// Objects not accessed for `tiering_days` are moved to infrequent access tier,
+// i.e pools of STANDARD_IA, and moved back to frequent access tier once
+// accessed again. Storage class of the objects never changes.
+//
+// Rows of objects deleted, overwritten or transitioned to another storage
+// class are removed when found.
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/journeymidnight/yig/crypto"
+	e "github.com/journeymidnight/yig/error"
+	"github.com/journeymidnight/yig/helper"
+	"github.com/journeymidnight/yig/log"
+	"github.com/journeymidnight/yig/meta/types"
+	"github.com/journeymidnight/yig/mods"
+	"github.com/journeymidnight/yig/redis"
+	"github.com/journeymidnight/yig/storage"
+)
+
+const (
+	SCAN_LIMIT               = 50
+	RETRY_INTERVAL           = 60 * time.Second
+	DEFAULT_TIERING_LOG_PATH = "/var/log/yig/tiering.log"
+)
+
+type tieringTask struct {
+	access *types.ObjectAccess
+	tier   types.AccessTier
+}
+
+var (
+	yig         *storage.YigStorage
+	taskQ       chan tieringTask
+	batchGroup  sync.WaitGroup
+	doneInBatch int64
+	signalQueue chan os.Signal
+	stop        bool
+)
+
+// returns true if the object is moved to tier, or its row is removed
+func tierObject(access *types.ObjectAccess, tier types.AccessTier) bool {
+	object, err := yig.MetaStorage.GetAccessedObject(access)
+	if err == e.ErrNoSuchKey || (err == nil && (object.DeleteMarker ||
+		object.StorageClass != types.ObjectStorageClassIntelligentTiering)) {
+
+		err = yig.MetaStorage.RemoveObjectAccess(access)
+		if err != nil {
+			helper.Logger.Error("RemoveObjectAccess", access.BucketName, access.ObjectName,
+				access.Version, "failed:", err)
+			return false
+		}
+		return true
+	}
+	if err != nil {
+		helper.Logger.Error("GetAccessedObject", access.BucketName, access.ObjectName,
+			access.Version, "failed:", err)
+		return false
+	}
+	// accesses during moving are later than tierTime, so the object
+	// is moved back in next pass if it's accessed meanwhile
+	tierTime := time.Now().UTC()
+	err = yig.TierObject(object, tier)
+	if err != nil {
+		helper.Logger.Error("Move", access.BucketName, access.ObjectName, access.Version,
+			"to", types.AccessTierStringMap[tier], "access tier failed:", err)
+		return false
+	}
+	access.Tier = tier
+	access.TierTime = tierTime
+	err = yig.MetaStorage.UpdateObjectAccessTier(access)
+	if err != nil {
+		helper.Logger.Error("UpdateObjectAccessTier", access.BucketName, access.ObjectName,
+			access.Version, "failed:", err)
+		return false
+	}
+	return true
+}
+
+func tieringWorker() {
+	for task := range taskQ {
+		if tierObject(task.access, task.tier) {
+			atomic.AddInt64(&doneInBatch, 1)
+		}
+		batchGroup.Done()
+	}
+}
+
+// returns number of rows done with
+func runBatch(accesses []*types.ObjectAccess, tier types.AccessTier) int64 {
+	atomic.StoreInt64(&doneInBatch, 0)
+	for _, access := range accesses {
+		if stop {
+			break
+		}
+		batchGroup.Add(1)
+		taskQ <- tieringTask{access: access, tier: tier}
+	}
+	batchGroup.Wait()
+	return atomic.LoadInt64(&doneInBatch)
+}
+
+func tiering() {
+	for !stop {
+		var doneInPass int64
+		before := time.Now().UTC().Add(-time.Duration(helper.CONFIG.TieringDays) * 24 * time.Hour)
+		cold, err := yig.MetaStorage.ListColdObjects(SCAN_LIMIT, before)
+		if err != nil {
+			helper.Logger.Error("ListColdObjects failed:", err)
+		} else {
+			doneInPass += runBatch(cold, types.AccessTierInfrequent)
+		}
+		warmed, err := yig.MetaStorage.ListWarmedObjects(SCAN_LIMIT)
+		if err != nil {
+			helper.Logger.Error("ListWarmedObjects failed:", err)
+		} else {
+			doneInPass += runBatch(warmed, types.AccessTierFrequent)
+		}
+		if doneInPass > 0 {
+			helper.Logger.Info("Processed", doneInPass, "objects")
+			continue
+		}
+		// nothing to move, or only objects failing to move are left
+		next := time.Now().Add(RETRY_INTERVAL)
+		for !stop && time.Now().Before(next) {
+			time.Sleep(time.Second)
+		}
+	}
+	helper.Logger.Info("Shutting down...")
+}
+
+func main() {
+	helper.SetupConfig()
+	logLevel := log.ParseLevel(helper.CONFIG.LogLevel)
+
+	helper.Logger = log.NewFileLogger(DEFAULT_TIERING_LOG_PATH, logLevel)
+	defer helper.Logger.Close()
+	if helper.CONFIG.MetaCacheType > 0 || helper.CONFIG.EnableDataCache {
+		redis.Initialize()
+		defer redis.Close()
+	}
+
+	// Read all *.so from plugins directory, and fill the variable allPlugins
+	allPluginMap := mods.InitialPlugins()
+	kms := crypto.NewKMS(allPluginMap)
+
+	yig = storage.New(helper.CONFIG.MetaCacheType, helper.CONFIG.EnableDataCache, kms, allPluginMap)
+	taskQ = make(chan tieringTask, SCAN_LIMIT)
+	numOfWorkers := helper.CONFIG.TieringThread
+	helper.Logger.Info("start tiering thread:", numOfWorkers,
+		"days:", helper.CONFIG.TieringDays)
+	for i := 0; i < numOfWorkers; i++ {
+		go tieringWorker()
+	}
+
+	signal.Ignore()
+	signalQueue = make(chan os.Signal, 1)
+	done := make(chan bool)
+	go func() {
+		tiering()
+		close(done)
+	}()
+	signal.Notify(signalQueue, syscall.SIGINT, syscall.SIGTERM,
+		syscall.SIGQUIT, syscall.SIGHUP)
+	for {
+		s := <-signalQueue
+		switch s {
+		case syscall.SIGHUP:
+			// reload config file
+			helper.SetupConfig()
+		default:
+			// finish current batch before exit
+			stop = true
+			<-done
+			return
+		}
+	}
+}